@@ -0,0 +1,244 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"manu-node-cli/internal/attribute"
+	"manu-node-cli/internal/auth"
+	"manu-node-cli/internal/node"
+	"manu-node-cli/internal/storage"
+)
+
+// handleAttr manages custom attribute definitions: attr define|list|remove
+func handleAttr(store *storage.Storage, attrs *attribute.Store, sess *auth.Session, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: attr define <name> --type T [flags] | list | remove <name>")
+	}
+	green := color.New(color.FgGreen).SprintFunc()
+
+	switch args[0] {
+	case "define":
+		if err := sess.Can(auth.PermAttributesManage); err != nil {
+			return err
+		}
+		fs := flag.NewFlagSet("attr define", flag.ContinueOnError)
+		typ := fs.String("type", "string", "string, number, enum, bool, date or duration")
+		unit := fs.String("unit", "", "unit shown next to values, e.g. kW")
+		required := fs.Bool("required", false, "every node must have a value")
+		def := fs.String("default", "", "value used when none is given")
+		desc := fs.String("description", "", "what the attribute means")
+		var values stringList
+		fs.Var(&values, "value", "allowed value of an enum attribute (repeatable)")
+		name, err := parseWithName(fs, args[1:])
+		if err != nil {
+			return err
+		}
+
+		t, err := attribute.ParseType(*typ)
+		if err != nil {
+			return err
+		}
+		defs, err := attrs.Load()
+		if err != nil {
+			return err
+		}
+		previous, existed := attribute.Find(defs, name)
+
+		// Define and check the stored values in one write, so that a
+		// redefinition never leaves nodes failing validation; required
+		// attributes get their default on nodes without a value
+		var d *attribute.Definition
+		var failed []string
+		err = store.Transaction(func(nodes []*node.Node) ([]*node.Node, error) {
+			var err error
+			d, err = attrs.Define(attribute.Definition{
+				Name:        name,
+				Type:        t,
+				Unit:        strings.TrimSpace(*unit),
+				Required:    *required,
+				Default:     *def,
+				Values:      values,
+				Description: strings.TrimSpace(*desc),
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, n := range nodes {
+				if err := attrs.Apply(n); err != nil {
+					failed = append(failed, err.Error())
+				}
+			}
+			if len(failed) > 0 {
+				return nil, fmt.Errorf("%d node(s) do not fit the new definition; fix their values first:\n  %s",
+					len(failed), strings.Join(failed, "\n  "))
+			}
+			return nodes, nil
+		})
+		if err != nil {
+			if d == nil {
+				return err
+			}
+			// Put back what the nodes were valid against
+			var rerr error
+			if existed {
+				_, rerr = attrs.Define(*previous)
+			} else {
+				rerr = attrs.Remove(name)
+			}
+			if rerr != nil {
+				return fmt.Errorf("attribute was not defined: %v, and failed to restore the previous definition: %w", err, rerr)
+			}
+			return fmt.Errorf("attribute was not defined: %w", err)
+		}
+		fmt.Printf("%s Attribute '%s' defined (%s)\n", green("✓"), d.Label(), d.Type)
+		return nil
+
+	case "list":
+		if err := sess.Can(auth.PermNodesRead); err != nil {
+			return err
+		}
+		defs, err := attrs.Load()
+		if err != nil {
+			return err
+		}
+		if len(defs) == 0 {
+			fmt.Println("No attributes defined. Add one with 'attr define <name> --type T'.")
+			return nil
+		}
+		fmt.Printf("%-20s %-10s %-8s %-9s %-12s %s\n", "Name", "Type", "Unit", "Required", "Default", "Values")
+		fmt.Println(strings.Repeat("-", 90))
+		for _, d := range defs {
+			required := ""
+			if d.Required {
+				required = "yes"
+			}
+			fmt.Printf("%-20s %-10s %-8s %-9s %-12s %s\n", d.Name, d.Type, d.Unit, required,
+				truncate(d.Default, 12), strings.Join(d.Values, ", "))
+		}
+		return nil
+
+	case "remove":
+		if len(args) != 2 {
+			return errors.New("usage: attr remove <name>")
+		}
+		if err := sess.Can(auth.PermAttributesManage); err != nil {
+			return err
+		}
+		name := args[1]
+		defs, err := attrs.Load()
+		if err != nil {
+			return err
+		}
+		def, ok := attribute.Find(defs, name)
+		if !ok {
+			return fmt.Errorf("attribute '%s' not found", name)
+		}
+
+		// Strip the values and drop the definition in one write, so that
+		// neither outlives the other and a required attribute does not
+		// fail validation on the way out
+		stripped, removed := 0, false
+		err = store.Transaction(func(nodes []*node.Node) ([]*node.Node, error) {
+			for _, n := range nodes {
+				if _, ok := n.Attributes[name]; ok {
+					delete(n.Attributes, name)
+					if len(n.Attributes) == 0 {
+						n.Attributes = nil
+					}
+					stripped++
+				}
+			}
+			if err := attrs.Remove(name); err != nil {
+				return nil, err
+			}
+			removed = true
+			return nodes, nil
+		})
+		if err != nil {
+			if removed {
+				// The nodes still hold values, so they need the definition
+				if _, derr := attrs.Define(*def); derr != nil {
+					return fmt.Errorf("failed to clear node values (%v), and failed to restore the definition: %w", err, derr)
+				}
+			}
+			return fmt.Errorf("attribute was not removed: %w", err)
+		}
+		fmt.Printf("%s Attribute '%s' removed from %d node(s)\n", green("✓"), name, stripped)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown attr command: %s", args[0])
+	}
+}
+
+// promptAttributes asks for a value for every defined attribute, re-asking
+// until the value is valid. The current value, or the default for new
+// nodes, is offered for editing; clearing it removes an optional value.
+func promptAttributes(defs []attribute.Definition, current map[string]string, p Prompter) (map[string]string, error) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	values := map[string]string{}
+	for k, v := range current {
+		values[k] = v
+	}
+
+	for i := range defs {
+		d := &defs[i]
+		keep := values[d.Name]
+		if keep == "" && (current == nil || d.Required) {
+			keep = d.Default
+		}
+		label := fmt.Sprintf("%s <%s>", d.Label(), d.Hint())
+
+		for {
+			input, err := p.Ask(label, keep)
+			if err != nil {
+				return nil, err
+			}
+			input = strings.TrimSpace(input)
+
+			if input == "" {
+				if d.Required {
+					fmt.Printf("%s: %s is required\n", red("Error"), d.Name)
+					continue
+				}
+				delete(values, d.Name)
+				break
+			}
+			v, err := d.Normalize(input)
+			if err != nil {
+				fmt.Printf("%s: %v\n", red("Error"), err)
+				continue
+			}
+			values[d.Name] = v
+			break
+		}
+	}
+
+	if len(values) == 0 {
+		return nil, nil
+	}
+	return values, nil
+}
+
+// printAttributes shows a node's attribute values in definition order
+func printAttributes(defs []attribute.Definition, values map[string]string) {
+	if len(values) == 0 {
+		return
+	}
+	fmt.Println("Attributes:")
+	for _, d := range defs {
+		v, ok := values[d.Name]
+		if !ok {
+			continue
+		}
+		if d.Unit != "" {
+			v += " " + d.Unit
+		}
+		fmt.Printf("  %-18s %s\n", d.Name+":", v)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"manu-node-cli/internal/attribute"
+	"manu-node-cli/internal/node"
+)
+
+func TestAttrRemove(t *testing.T) {
+	store, attrs, cleanup := setupTestCommands(t)
+	defer cleanup()
+	if _, err := attrs.Define(attribute.Definition{Name: "power", Type: attribute.TypeNumber, Required: true, Default: "1"}); err != nil {
+		t.Fatalf("Failed to define attribute: %v", err)
+	}
+	saw := node.NewNode("Saw", "", nil, "")
+	saw.ID = "saw"
+	saw.Attributes = map[string]string{"power": "5"}
+	if err := store.SaveNode(saw); err != nil {
+		t.Fatalf("Failed to save node: %v", err)
+	}
+
+	// A failed write keeps both the definition and the values
+	store.SetValidator(func(n *node.Node) error { return errors.New("disk full") })
+	if err := handleAttr(store, attrs, nil, []string{"remove", "power"}); err == nil {
+		t.Fatal("Expected the failed write to be reported")
+	}
+	defs, _ := attrs.Load()
+	if _, ok := attribute.Find(defs, "power"); !ok {
+		t.Error("Expected the definition to be kept when nodes were not updated")
+	}
+
+	// A required attribute can be removed along with its values
+	store.SetValidator(attrs.Apply)
+	if err := handleAttr(store, attrs, nil, []string{"remove", "power"}); err != nil {
+		t.Fatalf("remove failed: %v", err)
+	}
+	got, _ := store.GetNode("saw")
+	defs, _ = attrs.Load()
+	if got.Attributes != nil || len(defs) != 0 {
+		t.Errorf("Expected the values and the definition to be gone, got %v and %v", got.Attributes, defs)
+	}
+	if err := handleAttr(store, attrs, nil, []string{"remove", "power"}); err == nil {
+		t.Error("Expected removing an unknown attribute to fail")
+	}
+}
+
+func TestAttrRedefine(t *testing.T) {
+	store, attrs, cleanup := setupTestCommands(t)
+	defer cleanup()
+	store.SetValidator(attrs.Apply)
+	if err := handleAttr(store, attrs, nil, []string{"define", "finish", "--type", "enum", "--value", "oak", "--value", "pine"}); err != nil {
+		t.Fatalf("define failed: %v", err)
+	}
+	for _, title := range []string{"Saw", "Press"} {
+		n := node.NewNode(title, "", nil, "")
+		n.ID = strings.ToLower(title)
+		if title == "Saw" {
+			n.Attributes = map[string]string{"finish": "pine"}
+		}
+		if err := store.SaveNode(n); err != nil {
+			t.Fatalf("Failed to save node: %v", err)
+		}
+	}
+
+	// Narrowing the values would leave the saw invalid
+	err := handleAttr(store, attrs, nil, []string{"define", "finish", "--type", "enum", "--value", "oak"})
+	if err == nil || !strings.Contains(err.Error(), "node 'Saw'") {
+		t.Fatalf("Expected the saw to be reported, got %v", err)
+	}
+	defs, _ := attrs.Load()
+	if d, _ := attribute.Find(defs, "finish"); len(d.Values) != 2 {
+		t.Errorf("Expected the previous definition to be kept, got %+v", d)
+	}
+
+	// Making it required fills in the default
+	if err := handleAttr(store, attrs, nil, []string{"define", "finish", "--type", "enum", "--value", "oak", "--value", "pine", "--required", "--default", "oak"}); err != nil {
+		t.Fatalf("define failed: %v", err)
+	}
+	press, _ := store.GetNodeByIDOrTitle("Press")
+	if press.Attributes["finish"] != "oak" {
+		t.Errorf("Expected the default on the press, got %v", press.Attributes)
+	}
+	if err := store.SaveNode(press); err != nil {
+		t.Errorf("Expected unrelated writes to keep working, got %v", err)
+	}
+}
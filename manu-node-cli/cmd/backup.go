@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"manu-node-cli/internal/auth"
+	"manu-node-cli/internal/backup"
+)
+
+// handleBackup manages snapshots of the data directory:
+// backup create|list|restore
+func handleBackup(backups *backup.Manager, sess *auth.Session, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: backup create [--note text] | list | restore <id>")
+	}
+	green := color.New(color.FgGreen).SprintFunc()
+
+	switch args[0] {
+	case "create":
+		if err := sess.Can(auth.PermNodesWrite); err != nil {
+			return err
+		}
+		fs := flag.NewFlagSet("backup create", flag.ContinueOnError)
+		note := fs.String("note", "", "why the backup was taken")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		reason := "manual"
+		if *note != "" {
+			reason = "manual: " + *note
+		}
+		info, err := backups.Create(reason)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s Backup %s created (%d file(s), %s)\n", green("✓"), info.ID,
+			len(info.Files), formatSize(info.Size))
+		return nil
+
+	case "list":
+		if err := sess.Can(auth.PermNodesRead); err != nil {
+			return err
+		}
+		infos, err := backups.List()
+		if err != nil {
+			return err
+		}
+		if len(infos) == 0 {
+			fmt.Println("No backups yet. Create one with 'backup create'.")
+			return nil
+		}
+		fmt.Printf("%-24s %-20s %-9s %s\n", "ID", "Created", "Size", "Reason")
+		fmt.Println(strings.Repeat("-", 90))
+		for _, info := range infos {
+			created := ""
+			if !info.CreatedAt.IsZero() {
+				created = info.CreatedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%-24s %-20s %-9s %s\n", info.ID, created, formatSize(info.Size),
+				truncate(info.Reason, 40))
+		}
+		return nil
+
+	case "restore":
+		if len(args) < 2 {
+			return errors.New("usage: backup restore <id>")
+		}
+		if err := sess.Can(auth.PermBackupsManage); err != nil {
+			return err
+		}
+		info, safety, err := backups.Restore(args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s Restored backup %s from %s\n", green("✓"), info.ID,
+			info.CreatedAt.Format(time.RFC3339))
+		fmt.Printf("The previous state was saved as backup %s\n", safety.ID)
+		fmt.Println("Users and API tokens were left as they are.")
+		return nil
+
+	default:
+		return fmt.Errorf("unknown backup command: %s", args[0])
+	}
+}
+
+// snapshotBefore takes an automatic backup ahead of a destructive operation
+func snapshotBefore(backups *backup.Manager, reason string) error {
+	if backups == nil {
+		return nil
+	}
+	if _, err := backups.Snapshot(reason); err != nil {
+		return fmt.Errorf("pre-operation backup failed, nothing was changed: %w", err)
+	}
+	return nil
+}
+
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"manu-node-cli/internal/attribute"
+	"manu-node-cli/internal/auth"
+	"manu-node-cli/internal/backup"
+	"manu-node-cli/internal/bulk"
+	"manu-node-cli/internal/node"
+	"manu-node-cli/internal/storage"
+)
+
+// handleBulkUpdate applies one edit to every node matching a filter:
+// bulk-update --where <selector> [--add-op X] [--remove-op Y] [--set field=value]
+func handleBulkUpdate(store *storage.Storage, attrs *attribute.Store, backups *backup.Manager, sess *auth.Session, args []string, p Prompter) error {
+	fs := flag.NewFlagSet("bulk-update", flag.ContinueOnError)
+	var qf queryFlags
+	qf.register(fs)
+	var addOps, removeOps, sets stringList
+	fs.Var(&addOps, "add-op", "operation to add (repeatable)")
+	fs.Var(&removeOps, "remove-op", "operation to remove, case-insensitive (repeatable)")
+	fs.Var(&sets, "set", "description=..., uns_address=..., labels.<key>=... or attributes.<name>=...; an empty value clears a label or attribute (repeatable)")
+	dryRun := fs.Bool("dry-run", false, "show the preview without changing anything")
+	yes := fs.Bool("yes", false, "apply without asking for confirmation")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected argument: %s", fs.Arg(0))
+	}
+
+	query, err := qf.build(attrs, "")
+	if err != nil {
+		return err
+	}
+	// Refuse to touch the whole catalog by accident
+	if query.empty() {
+		return errors.New("usage: bulk-update --where <selector> [--uns-prefix path] [--attr expr] [--add-op X] [--remove-op Y] [--set field=value]")
+	}
+	edit := &bulk.Edit{AddOps: addOps, RemoveOps: removeOps, Normalize: attrs.Apply}
+	if err := edit.ParseSet(sets); err != nil {
+		return err
+	}
+	if edit.Empty() {
+		return errors.New("nothing to change: use --add-op, --remove-op or --set")
+	}
+	if err := sess.Can(auth.PermNodesWrite); err != nil {
+		return err
+	}
+
+	// Only nodes the user may see can be selected
+	match := func(n *node.Node) bool {
+		return query.match(n) && sess.CanNode(auth.PermNodesRead, n.UNSAddress) == nil
+	}
+	// Run validation and authorization ahead of the preview so problems
+	// show up before confirmation; attributes are checked by the edit
+	check := func(changes []bulk.Change) error {
+		for _, c := range changes {
+			for _, uns := range []string{c.Before.UNSAddress, c.After.UNSAddress} {
+				if err := sess.CanNode(auth.PermNodesWrite, uns); err != nil {
+					return fmt.Errorf("node '%s': %w", c.Before.Title, err)
+				}
+			}
+			if err := c.After.Validate(); err != nil {
+				return fmt.Errorf("node '%s': %w", c.Before.Title, err)
+			}
+		}
+		return nil
+	}
+
+	current, err := store.Load()
+	if err != nil {
+		return err
+	}
+	_, changes, unchanged, err := bulk.Plan(current, match, edit)
+	if err != nil {
+		return err
+	}
+	if err := check(changes); err != nil {
+		return err
+	}
+
+	printBulkPreview(changes, unchanged)
+	if len(changes) == 0 || *dryRun {
+		return nil
+	}
+	if !*yes {
+		ok, err := askConfirm(p, fmt.Sprintf("Apply these changes to %d node(s)?", len(changes)))
+		if err != nil || !ok {
+			fmt.Println("Bulk update cancelled.")
+			return nil
+		}
+	}
+	if err := snapshotBefore(backups, fmt.Sprintf("before bulk-update of %d node(s)", len(changes))); err != nil {
+		return err
+	}
+
+	// Re-plan under the storage lock so the write is atomic and reflects
+	// exactly what was previewed
+	var applied []bulk.Change
+	err = store.Transaction(func(nodes []*node.Node) ([]*node.Node, error) {
+		result, final, _, err := bulk.Plan(nodes, match, edit)
+		if err != nil {
+			return nil, err
+		}
+		if !sameNodes(changes, final) {
+			return nil, errors.New("catalog changed since the preview; run bulk-update again")
+		}
+		if err := check(final); err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		for _, c := range final {
+			c.After.UpdatedAt = now
+		}
+		applied = final
+		return result, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	fmt.Println()
+	for _, c := range applied {
+		fmt.Printf("%s %s: %d change(s)\n", green("✓"), c.After.Title, len(c.Diff))
+	}
+	fmt.Printf("\n%s Updated %d node(s)\n", green("✓"), len(applied))
+	return nil
+}
+
+// sameNodes reports whether two plans change the same nodes the same way
+func sameNodes(a, b []bulk.Change) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Before.ID != b[i].Before.ID || strings.Join(a[i].Diff, "\n") != strings.Join(b[i].Diff, "\n") {
+			return false
+		}
+	}
+	return true
+}
+
+func printBulkPreview(changes []bulk.Change, unchanged int) {
+	yellow := color.New(color.FgYellow).SprintFunc()
+	for _, c := range changes {
+		fmt.Printf("\n%s %s (%s)\n", yellow("~"), c.Before.Title, c.Before.ID)
+		for _, d := range c.Diff {
+			fmt.Printf("    %s\n", d)
+		}
+	}
+	fmt.Printf("\n%d node(s) to update, %d already up to date\n", len(changes), unchanged)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"manu-node-cli/internal/auth"
+	"manu-node-cli/internal/maintenance"
+	"manu-node-cli/internal/node"
+	"manu-node-cli/internal/storage"
+)
+
+const calibrationUsage = "usage: calibration add <node> --instrument I --certificate C (--next-due date | --interval 365d) [--date date] [--result within|out] | " +
+	"list [<node>] | due [--within 30d]"
+
+// handleCalibration records and reports calibrations of node instruments
+func handleCalibration(store *storage.Storage, sess *auth.Session, args []string) error {
+	if len(args) == 0 {
+		return errors.New(calibrationUsage)
+	}
+
+	switch args[0] {
+	case "add":
+		fs := flag.NewFlagSet("calibration add", flag.ContinueOnError)
+		instrument := fs.String("instrument", "", "instrument that was calibrated, e.g. 'Spindle probe'")
+		certificate := fs.String("certificate", "", "calibration certificate number")
+		date := fs.String("date", "", "when the calibration was done (default: today)")
+		nextDue := fs.String("next-due", "", "when the next calibration is due")
+		interval := fs.String("interval", "", "time until the next calibration, e.g. 365d or 26w")
+		result := fs.String("result", node.CalibrationWithin, "result: within or out of tolerance")
+		ref, err := parseWithName(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		n, err := store.GetNodeByIDOrTitle(ref)
+		if err != nil {
+			return err
+		}
+		if err := sess.CanNode(auth.PermNodesWrite, n.UNSAddress); err != nil {
+			return err
+		}
+
+		c := node.Calibration{
+			Instrument:  strings.TrimSpace(*instrument),
+			Certificate: strings.TrimSpace(*certificate),
+			Result:      strings.ToLower(strings.TrimSpace(*result)),
+		}
+		now := time.Now()
+		c.Date = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+		if *date != "" {
+			if c.Date, err = maintenance.ParseTime(*date); err != nil {
+				return err
+			}
+		}
+		switch {
+		case *nextDue != "" && *interval != "":
+			return errors.New("give either --next-due or --interval, not both")
+		case *nextDue != "":
+			if c.NextDue, err = maintenance.ParseTime(*nextDue); err != nil {
+				return err
+			}
+		case *interval != "":
+			d, err := maintenance.ParseInterval(*interval)
+			if err != nil {
+				return err
+			}
+			c.NextDue = c.Date.Add(d)
+		default:
+			return errors.New("give the next due date with --next-due or --interval")
+		}
+		if err := c.Validate(); err != nil {
+			return err
+		}
+
+		if err := updateNodeIfUnchanged(store, n, func(n *node.Node) { n.Calibrations = append(n.Calibrations, c) }); err != nil {
+			return err
+		}
+		green := color.New(color.FgGreen).SprintFunc()
+		fmt.Printf("%s Recorded calibration of %s on '%s' (certificate %s, %s tolerance), next due %s\n", green("✓"),
+			c.Instrument, n.Title, c.Certificate, c.Result, c.NextDue.Format("2006-01-02"))
+		if c.Result == node.CalibrationOut {
+			fmt.Printf("'%s' cannot take work until %s passes a calibration.\n", n.Title, c.Instrument)
+		}
+		return nil
+
+	case "list":
+		if len(args) > 2 {
+			return errors.New("usage: calibration list [<node>]")
+		}
+		var rows []calibrationRow
+		if len(args) == 2 {
+			// The full history of one node, most recent first
+			n, err := store.GetNodeByIDOrTitle(args[1])
+			if err != nil {
+				return err
+			}
+			if err := sess.CanNode(auth.PermNodesRead, n.UNSAddress); err != nil {
+				return err
+			}
+			for _, c := range n.Calibrations {
+				rows = append(rows, calibrationRow{n, c})
+			}
+			sort.SliceStable(rows, func(i, j int) bool { return rows[i].c.Date.After(rows[j].c.Date) })
+		} else {
+			// The latest calibration of every instrument
+			nodes, err := store.Load()
+			if err != nil {
+				return err
+			}
+			if nodes, err = sess.Visible(nodes); err != nil {
+				return err
+			}
+			for _, n := range nodes {
+				for _, c := range n.CurrentCalibrations() {
+					rows = append(rows, calibrationRow{n, c})
+				}
+			}
+		}
+		if len(rows) == 0 {
+			fmt.Println("No calibrations recorded. Add one with 'calibration add'.")
+			return nil
+		}
+		printCalibrations(rows)
+		return nil
+
+	case "due":
+		fs := flag.NewFlagSet("calibration due", flag.ContinueOnError)
+		within := fs.String("within", "30d", "how far ahead to look, e.g. 30d or 2w")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() > 0 {
+			return fmt.Errorf("unexpected argument: %s", fs.Arg(0))
+		}
+		horizon, err := maintenance.ParseInterval(*within)
+		if err != nil {
+			return err
+		}
+		nodes, err := store.Load()
+		if err != nil {
+			return err
+		}
+		if nodes, err = sess.Visible(nodes); err != nil {
+			return err
+		}
+
+		// Expired and failed calibrations are listed too, as they block work
+		until := time.Now().Add(horizon)
+		var rows []calibrationRow
+		for _, n := range nodes {
+			for _, c := range n.CurrentCalibrations() {
+				if c.Result == node.CalibrationOut || c.NextDue.Before(until) {
+					rows = append(rows, calibrationRow{n, c})
+				}
+			}
+		}
+		if len(rows) == 0 {
+			fmt.Printf("No calibrations due within %s.\n", *within)
+			return nil
+		}
+		sort.SliceStable(rows, func(i, j int) bool { return rows[i].c.NextDue.Before(rows[j].c.NextDue) })
+		printCalibrations(rows)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown calibration command: %s", args[0])
+	}
+}
+
+// calibrationRow is a calibration with the node it belongs to
+type calibrationRow struct {
+	n *node.Node
+	c node.Calibration
+}
+
+func printCalibrations(rows []calibrationRow) {
+	red := color.New(color.FgRed).SprintFunc()
+	now := time.Now()
+	fmt.Printf("%-20s %-18s %-14s %-10s %-10s %s\n", "Node", "Instrument", "Certificate", "Date", "Next due", "Status")
+	fmt.Println(strings.Repeat("-", 90))
+	for _, r := range rows {
+		status := r.c.Status(now)
+		if status != "ok" {
+			status = red(status)
+		}
+		fmt.Printf("%-20s %-18s %-14s %-10s %-10s %s\n", truncate(r.n.Title, 20), truncate(r.c.Instrument, 18),
+			truncate(r.c.Certificate, 14), r.c.Date.Format("2006-01-02"), r.c.NextDue.Format("2006-01-02"), status)
+	}
+}
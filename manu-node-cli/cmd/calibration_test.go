@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"manu-node-cli/internal/node"
+)
+
+func TestCalibrationCommands(t *testing.T) {
+	store, _, cleanup := setupTestCommands(t)
+	defer cleanup()
+
+	cmm := node.NewNode("CMM", "", []string{"measure"}, "")
+	cmm.ID = "cmm"
+	if err := store.SaveNode(cmm); err != nil {
+		t.Fatalf("Failed to save node: %v", err)
+	}
+
+	if err := handleCalibration(store, nil, []string{"add", "CMM", "--instrument", "Probe", "--certificate", "C-1",
+		"--date", "2025-01-10", "--next-due", "2026-01-10"}); err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+	if err := handleCalibration(store, nil, []string{"add", "CMM", "--instrument", "Probe", "--certificate", "C-2"}); err == nil {
+		t.Error("Expected a record without next due date to be rejected")
+	}
+	if err := handleCalibration(store, nil, []string{"add", "CMM", "--instrument", "Probe", "--certificate", "C-2",
+		"--interval", "365d", "--result", "fine"}); err == nil {
+		t.Error("Expected an unknown result to be rejected")
+	}
+
+	// The expired probe keeps the CMM from taking work
+	n, _ := store.GetNode("cmm")
+	if err := n.CheckCalibration(time.Now()); !errors.Is(err, node.ErrNotCalibrated) {
+		t.Fatalf("Expected an expired calibration, got %v", err)
+	}
+	eligible, blocked := calibratedNodes([]*node.Node{n}, time.Now())
+	if len(eligible) != 0 || len(blocked) != 1 {
+		t.Errorf("Expected the CMM to be left out of matching, got %d eligible", len(eligible))
+	}
+
+	if err := handleCalibration(store, nil, []string{"add", "CMM", "--instrument", "probe", "--certificate", "C-2",
+		"--interval", "365d"}); err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+	n, _ = store.GetNode("cmm")
+	if len(n.Calibrations) != 2 {
+		t.Fatalf("Expected the history to be kept, got %+v", n.Calibrations)
+	}
+	if err := n.CheckCalibration(time.Now()); err != nil {
+		t.Errorf("Expected the new calibration to clear the block, got %v", err)
+	}
+	if err := handleCalibration(store, nil, []string{"due", "--within", "400d"}); err != nil {
+		t.Errorf("due failed: %v", err)
+	}
+}
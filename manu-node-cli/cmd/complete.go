@@ -0,0 +1,872 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/chzyer/readline"
+	"manu-node-cli/internal/attribute"
+	"manu-node-cli/internal/auth"
+	"manu-node-cli/internal/config"
+	"manu-node-cli/internal/graph"
+	"manu-node-cli/internal/labels"
+	"manu-node-cli/internal/node"
+)
+
+// source lists the candidates for an argument or flag value; word is the
+// partial text being completed
+type source func(c *completer, word string) []string
+
+// commandSpec describes a command for completion. A new command or flag
+// only needs an entry in commandSpecs to be completed in the REPL and in
+// the generated shell scripts.
+type commandSpec struct {
+	name string
+	// args lists the kind of each positional argument; the last one
+	// repeats. Nil means the command takes no arguments worth completing.
+	args []source
+	// flags maps flags to the source of their value; nil marks a boolean
+	flags map[string]source
+	// valueFlags take a free-text value that is not completed
+	valueFlags  []string
+	subcommands []commandSpec
+	replOnly    bool
+	cliOnly     bool
+}
+
+// takesValue reports whether flag is followed by a value
+func (s *commandSpec) takesValue(flag string) bool {
+	if src, ok := s.flags[flag]; ok {
+		return src != nil
+	}
+	for _, f := range s.valueFlags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *commandSpec) flagNames() []string {
+	names := make([]string, 0, len(s.flags)+len(s.valueFlags))
+	for f := range s.flags {
+		names = append(names, f)
+	}
+	names = append(names, s.valueFlags...)
+	sort.Strings(names)
+	return names
+}
+
+func withFlags(sets ...map[string]source) map[string]source {
+	out := map[string]source{}
+	for _, set := range sets {
+		for k, v := range set {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+var queryFlagSpecs = map[string]source{
+	"-l":           (*completer).labelSelectors,
+	"--selector":   (*completer).labelSelectors,
+	"--where":      (*completer).labelSelectors,
+	"--attr":       (*completer).attributeNames,
+	"--uns-prefix": (*completer).unsPaths,
+}
+
+var outputFlagSpecs = map[string]source{
+	"-o":       (*completer).outputFormats,
+	"--output": (*completer).outputFormats,
+}
+
+var commandSpecs = []commandSpec{
+	{name: "create", flags: map[string]source{"--from-template": (*completer).templateNames}, valueFlags: []string{"--var"}},
+	{name: "clone", args: []source{(*completer).nodes},
+		flags: map[string]source{"--uns": (*completer).unsPaths}, valueFlags: []string{"--title", "--description"}},
+	{name: "template", subcommands: []commandSpec{
+		{name: "save", args: []source{(*completer).nodes}, valueFlags: []string{"--title", "--uns"}},
+		{name: "list"},
+		{name: "show", args: []source{(*completer).templateNames}},
+		{name: "remove", args: []source{(*completer).templateNames}},
+	}},
+	{name: "list", flags: withFlags(queryFlagSpecs, outputFlagSpecs, map[string]source{"--all-contexts": nil})},
+	{name: "find", flags: withFlags(queryFlagSpecs, outputFlagSpecs)},
+	{name: "match", args: []source{(*completer).operations},
+		flags: withFlags(queryFlagSpecs, outputFlagSpecs, map[string]source{
+			"--same-line":     nil,
+			"--capacity-attr": (*completer).attributeNames,
+		}), valueFlags: []string{"--limit"}},
+	{name: "label", args: []source{(*completer).nodes, (*completer).labelPairs}},
+	{name: "link", args: []source{(*completer).nodes},
+		flags: map[string]source{"--kind": (*completer).linkKinds}, valueFlags: []string{"--capacity", "--transfer-time"}},
+	{name: "unlink", args: []source{(*completer).nodes}},
+	{name: "links", args: []source{(*completer).nodes}},
+	{name: "operator", subcommands: []commandSpec{
+		{name: "add", flags: map[string]source{"--shift": (*completer).shiftNames}},
+		{name: "update", args: []source{(*completer).operatorNames}, flags: map[string]source{"--shift": (*completer).shiftNames}},
+		{name: "remove", args: []source{(*completer).operatorNames}},
+		{name: "list"},
+		{name: "certify", args: []source{(*completer).operatorNames}, valueFlags: []string{"--skill", "--expires"}},
+		{name: "revoke", args: []source{(*completer).operatorNames}, valueFlags: []string{"--skill"}},
+		{name: "shift", args: []source{(*completer).shiftNames}, valueFlags: []string{"--start", "--end"}},
+		{name: "require", args: []source{(*completer).operations},
+			flags: map[string]source{"--node": (*completer).nodes}, valueFlags: []string{"--skill"}},
+		{name: "requirements"},
+		{name: "qualified", args: []source{(*completer).operations},
+			flags: map[string]source{"--node": (*completer).nodes, "--shift": (*completer).shiftNames}, valueFlags: []string{"--at"}},
+		{name: "check", args: []source{(*completer).operatorNames, (*completer).operations},
+			flags: map[string]source{"--node": (*completer).nodes}, valueFlags: []string{"--at"}},
+	}},
+	{name: "calibration", subcommands: []commandSpec{
+		{name: "add", args: []source{(*completer).nodes},
+			flags:      map[string]source{"--result": (*completer).calibrationResults},
+			valueFlags: []string{"--instrument", "--certificate", "--date", "--next-due", "--interval"}},
+		{name: "list", args: []source{(*completer).nodes}},
+		{name: "due", valueFlags: []string{"--within"}},
+	}},
+	{name: "maintenance", subcommands: []commandSpec{
+		{name: "plan", args: []source{(*completer).nodes},
+			valueFlags: []string{"--name", "--every", "--hours", "--cycles", "--duration", "--start"}},
+		{name: "plans", args: []source{(*completer).nodes}},
+		{name: "remove", args: []source{(*completer).maintenancePlanIDs}},
+		{name: "meter", args: []source{(*completer).nodes}, valueFlags: []string{"--hours", "--cycles"}},
+		{name: "due", args: []source{(*completer).nodes}, valueFlags: []string{"--within"}},
+		{name: "schedule", args: []source{(*completer).maintenanceTaskIDs}, valueFlags: []string{"--at", "--duration"}},
+		{name: "done", args: []source{(*completer).maintenanceTaskIDs}, valueFlags: []string{"--technician", "--notes", "--at"}},
+		{name: "history", args: []source{(*completer).nodes}},
+	}},
+	{name: "availability", args: []source{(*completer).nodes}, valueFlags: []string{"--within"}},
+	{name: "bulk-update", flags: withFlags(queryFlagSpecs, map[string]source{
+		"--add-op":    (*completer).operations,
+		"--remove-op": (*completer).operations,
+		"--set":       (*completer).settableFields,
+		"--dry-run":   nil,
+		"--yes":       nil,
+	})},
+	{name: "view", args: []source{(*completer).nodes}, replOnly: true},
+	{name: "update", args: []source{(*completer).nodes}, replOnly: true},
+	{name: "edit", args: []source{(*completer).nodes}},
+	{name: "delete", args: []source{(*completer).nodes}, replOnly: true},
+	{name: "undo", replOnly: true},
+	{name: "redo", replOnly: true},
+	{name: "import", args: []source{(*completer).files},
+		flags: map[string]source{"--dry-run": nil}, valueFlags: []string{"--map", "--ops-sep"}},
+	{name: "export", args: []source{(*completer).files},
+		flags: map[string]source{"--uns-prefix": (*completer).unsPaths}, valueFlags: []string{"--ops-sep"}},
+	{name: "export-graph", flags: map[string]source{
+		"--format":     (*completer).graphFormats,
+		"--uns-prefix": (*completer).unsPaths,
+		"--out":        (*completer).files,
+	}},
+	{name: "plan", flags: map[string]source{"-f": (*completer).files, "--prune": nil}},
+	{name: "apply", flags: map[string]source{"-f": (*completer).files, "--prune": nil}},
+	{name: "attr", subcommands: []commandSpec{
+		{name: "define", flags: map[string]source{"--type": (*completer).attributeTypes, "--required": nil},
+			valueFlags: []string{"--unit", "--default", "--description", "--value"}},
+		{name: "list"},
+		{name: "remove", args: []source{(*completer).attributeNames}},
+	}},
+	{name: "migrate", flags: map[string]source{"--dry-run": nil}},
+	{name: "backup", subcommands: []commandSpec{
+		{name: "create", valueFlags: []string{"--note"}},
+		{name: "list"},
+		{name: "restore", args: []source{(*completer).backupIDs}},
+	}},
+	{name: "user", subcommands: []commandSpec{
+		{name: "add", flags: map[string]source{"--role": (*completer).roles}, valueFlags: []string{"--scope"}},
+		{name: "list"},
+		{name: "remove", args: []source{(*completer).userNames}},
+	}},
+	{name: "token", subcommands: []commandSpec{
+		{name: "create", args: []source{(*completer).userNames}, valueFlags: []string{"--description"}},
+		{name: "revoke"},
+	}},
+	{name: "tui", cliOnly: true},
+	{name: "serve", valueFlags: []string{"--addr"}, cliOnly: true},
+	{name: "completion", args: []source{(*completer).shells}, cliOnly: true},
+	{name: "config", subcommands: []commandSpec{
+		{name: "view"},
+		{name: "set", args: []source{(*completer).configKeys}, flags: map[string]source{"--global": nil}},
+		{name: "unset", args: []source{(*completer).configKeys}, flags: map[string]source{"--global": nil}},
+	}},
+	{name: "context", subcommands: []commandSpec{
+		{name: "list"},
+		{name: "create", flags: map[string]source{"--data-dir": (*completer).files, "--use": nil}},
+		{name: "use", args: []source{(*completer).contextNames}},
+		{name: "remove", args: []source{(*completer).contextNames}},
+	}},
+	{name: "clear", replOnly: true},
+	{name: "cls", replOnly: true},
+	{name: "help"},
+	{name: "exit", replOnly: true},
+	{name: "quit", replOnly: true},
+}
+
+// completer computes completions for command lines and prompt fields
+type completer struct {
+	*workspace
+	repl bool
+}
+
+// complete returns the candidates for word, the argument being typed after
+// args, best matches first
+func (c *completer) complete(args []string, word string) []string {
+	specs := c.commands()
+	if len(args) == 0 {
+		names := make([]string, 0, len(specs))
+		for _, s := range specs {
+			names = append(names, s.name)
+		}
+		return rank(word, names)
+	}
+
+	spec := findSpec(specs, args[0])
+	rest := args[1:]
+	for spec != nil && len(spec.subcommands) > 0 {
+		if len(rest) == 0 {
+			names := make([]string, 0, len(spec.subcommands))
+			for _, s := range spec.subcommands {
+				names = append(names, s.name)
+			}
+			return rank(word, names)
+		}
+		spec = findSpec(spec.subcommands, rest[0])
+		rest = rest[1:]
+	}
+	if spec == nil {
+		return nil
+	}
+
+	// Count positional arguments, skipping flag values
+	positional := 0
+	expectValue := ""
+	for _, a := range rest {
+		switch {
+		case expectValue != "":
+			expectValue = ""
+		case strings.HasPrefix(a, "-") && !strings.Contains(a, "="):
+			if spec.takesValue(a) {
+				expectValue = a
+			}
+		default:
+			positional++
+		}
+	}
+
+	switch {
+	case expectValue != "":
+		return c.flagValue(spec, expectValue, "", word)
+	case strings.HasPrefix(word, "-"):
+		if flag, value, ok := strings.Cut(word, "="); ok {
+			return c.flagValue(spec, flag, flag+"=", value)
+		}
+		return rank(word, spec.flagNames())
+	case len(spec.args) == 0:
+		if word == "" {
+			return spec.flagNames()
+		}
+		return nil
+	}
+	i := positional
+	if i >= len(spec.args) {
+		i = len(spec.args) - 1
+	}
+	return rank(word, spec.args[i](c, word))
+}
+
+func (c *completer) flagValue(spec *commandSpec, flag, prefix, word string) []string {
+	src := spec.flags[flag]
+	if src == nil {
+		return nil
+	}
+	matches := rank(word, src(c, word))
+	for i := range matches {
+		matches[i] = prefix + matches[i]
+	}
+	return matches
+}
+
+// commands returns the specs available in the current mode
+func (c *completer) commands() []commandSpec {
+	var specs []commandSpec
+	for _, s := range commandSpecs {
+		if (s.replOnly && !c.repl) || (s.cliOnly && c.repl) {
+			continue
+		}
+		specs = append(specs, s)
+	}
+	return specs
+}
+
+func findSpec(specs []commandSpec, name string) *commandSpec {
+	for i := range specs {
+		if specs[i].name == name {
+			return &specs[i]
+		}
+	}
+	return nil
+}
+
+// visibleNodes loads the nodes the session may read
+func (c *completer) visibleNodes() []*node.Node {
+	nodes, err := c.store.Load()
+	if err != nil {
+		return nil
+	}
+	nodes, err = c.sess.Visible(nodes)
+	if err != nil {
+		return nil
+	}
+	return nodes
+}
+
+func (c *completer) nodes(string) []string {
+	var names []string
+	for _, n := range c.visibleNodes() {
+		names = append(names, n.ID)
+		if n.Title != "" {
+			names = append(names, n.Title)
+		}
+	}
+	return names
+}
+
+func (c *completer) unsPaths(word string) []string {
+	return unsCandidates(c.visibleNodes(), word)
+}
+
+func (c *completer) operations(string) []string {
+	return operationNames(c.visibleNodes())
+}
+
+func (c *completer) labelPairs(string) []string {
+	seen := map[string]bool{}
+	var pairs []string
+	for _, n := range c.visibleNodes() {
+		for _, k := range labels.Keys(n.Labels) {
+			for _, p := range []string{k + "=" + n.Labels[k], k + "-"} {
+				if !seen[p] {
+					seen[p] = true
+					pairs = append(pairs, p)
+				}
+			}
+		}
+	}
+	return pairs
+}
+
+// selectorTerm matches the last term of a label selector being typed
+var selectorTerm = regexp.MustCompile(`[^,]*$`)
+
+func (c *completer) labelSelectors(word string) []string {
+	// Complete the last comma-separated term, keeping the ones before it
+	head := word[:selectorTerm.FindStringIndex(word)[0]]
+	var out []string
+	for _, p := range c.labelPairs("") {
+		if !strings.HasSuffix(p, "-") {
+			out = append(out, head+p)
+		}
+	}
+	return out
+}
+
+func (c *completer) templateNames(string) []string {
+	all, err := c.tmpls.Load()
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(all))
+	for _, t := range all {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+func (c *completer) attributeNames(string) []string {
+	defs, err := c.attrs.Load()
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(defs))
+	for _, d := range defs {
+		names = append(names, d.Name)
+	}
+	return names
+}
+
+func (c *completer) settableFields(string) []string {
+	fields := []string{"description=", "uns_address="}
+	for _, p := range c.labelPairs("") {
+		if k, ok := strings.CutSuffix(p, "-"); ok {
+			fields = append(fields, "labels."+k+"=")
+		}
+	}
+	for _, name := range c.attributeNames("") {
+		fields = append(fields, "attributes."+name+"=")
+	}
+	return fields
+}
+
+func (c *completer) attributeTypes(string) []string {
+	types := make([]string, 0, len(attribute.Types))
+	for _, t := range attribute.Types {
+		types = append(types, string(t))
+	}
+	return types
+}
+
+func (c *completer) linkKinds(string) []string {
+	return node.LinkKinds
+}
+
+func (c *completer) calibrationResults(string) []string {
+	return node.CalibrationResults
+}
+
+func (c *completer) graphFormats(string) []string {
+	formats := make([]string, len(graph.Formats))
+	for i, f := range graph.Formats {
+		formats[i] = string(f)
+	}
+	return formats
+}
+
+func (c *completer) backupIDs(string) []string {
+	if c.sess.Can(auth.PermBackupsManage) != nil {
+		return nil
+	}
+	infos, err := c.backups.List()
+	if err != nil {
+		return nil
+	}
+	ids := make([]string, 0, len(infos))
+	for _, info := range infos {
+		ids = append(ids, info.ID)
+	}
+	return ids
+}
+
+func (c *completer) maintenancePlanIDs(string) []string {
+	if c.maint == nil {
+		return nil
+	}
+	s, err := c.maint.Load()
+	if err != nil {
+		return nil
+	}
+	ids := make([]string, 0, len(s.Plans))
+	for _, p := range s.Plans {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+// maintenanceTaskIDs lists the open tasks
+func (c *completer) maintenanceTaskIDs(string) []string {
+	if c.maint == nil {
+		return nil
+	}
+	s, err := c.maint.Load()
+	if err != nil {
+		return nil
+	}
+	var ids []string
+	for _, t := range s.Tasks {
+		if t.Open() {
+			ids = append(ids, t.ID)
+		}
+	}
+	return ids
+}
+
+func (c *completer) operatorNames(string) []string {
+	if c.ops == nil {
+		return nil
+	}
+	r, err := c.ops.Load()
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(r.Operators))
+	for _, o := range r.Operators {
+		names = append(names, o.Name)
+	}
+	return names
+}
+
+func (c *completer) shiftNames(string) []string {
+	if c.ops == nil {
+		return nil
+	}
+	r, err := c.ops.Load()
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(r.Shifts))
+	for _, s := range r.Shifts {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func (c *completer) userNames(string) []string {
+	if c.sess.Can(auth.PermUsersManage) != nil {
+		return nil
+	}
+	users, err := c.authz.Users().Load()
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(users))
+	for _, u := range users {
+		names = append(names, u.Name)
+	}
+	return names
+}
+
+func (c *completer) roles(string) []string {
+	return []string{string(auth.RoleAdmin), string(auth.RoleDeveloper), string(auth.RolePlanner), string(auth.RoleAnalyst)}
+}
+
+func (c *completer) shells(string) []string {
+	return []string{"bash", "zsh", "fish"}
+}
+
+func (c *completer) outputFormats(string) []string {
+	return []string{"table", "json", "yaml"}
+}
+
+func (c *completer) configKeys(string) []string {
+	return config.KeyNames()
+}
+
+func (c *completer) contextNames(string) []string {
+	if c.cfg == nil {
+		return nil
+	}
+	return c.cfg.ContextNames()
+}
+
+// files lists the entries of the directory word points into
+func (c *completer) files(word string) []string {
+	dir, _ := filepath.Split(word)
+	entries, err := os.ReadDir(filepath.Join(".", dir))
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, e := range entries {
+		name := dir + e.Name()
+		if e.IsDir() {
+			name += "/"
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// unsCandidates offers the next UNS path segment below what word already
+// spells out. Paths that continue deeper end in "/".
+func unsCandidates(nodes []*node.Node, word string) []string {
+	parent := ""
+	if i := strings.LastIndex(word, "/"); i >= 0 {
+		parent = word[:i+1]
+	}
+
+	seen := map[string]bool{}
+	var out []string
+	for _, n := range nodes {
+		rest, ok := strings.CutPrefix(n.UNSAddress, parent)
+		if !ok || rest == "" {
+			continue
+		}
+		candidate := parent + rest
+		if segment, _, deeper := strings.Cut(rest, "/"); deeper {
+			candidate = parent + segment + "/"
+		}
+		if !seen[candidate] {
+			seen[candidate] = true
+			out = append(out, candidate)
+		}
+	}
+	return out
+}
+
+// operationNames lists every operation used by nodes
+func operationNames(nodes []*node.Node) []string {
+	seen := map[string]bool{}
+	var ops []string
+	for _, n := range nodes {
+		for _, op := range n.Operations {
+			if key := strings.ToLower(op); !seen[key] {
+				seen[key] = true
+				ops = append(ops, op)
+			}
+		}
+	}
+	return ops
+}
+
+// rank keeps the candidates that fuzzily match word and orders them:
+// exact prefix, case-insensitive prefix, substring, then the letters of
+// word in order anywhere in the candidate. Very short words only match
+// as prefixes.
+func rank(word string, candidates []string) []string {
+	type scored struct {
+		s     string
+		score int
+	}
+	lower := strings.ToLower(word)
+	seen := map[string]bool{}
+	var matches []scored
+	for _, cand := range candidates {
+		if seen[cand] {
+			continue
+		}
+		seen[cand] = true
+		lc := strings.ToLower(cand)
+		score := -1
+		switch {
+		case strings.HasPrefix(cand, word):
+			score = 0
+		case strings.HasPrefix(lc, lower):
+			score = 1
+		case len(lower) >= 2 && strings.Contains(lc, lower):
+			score = 2
+		case len(lower) >= 3 && isSubsequence(lower, lc):
+			score = 3
+		}
+		if score >= 0 {
+			matches = append(matches, scored{cand, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score < matches[j].score
+		}
+		return matches[i].s < matches[j].s
+	})
+	out := make([]string, len(matches))
+	for i, m := range matches {
+		out[i] = m.s
+	}
+	return out
+}
+
+func isSubsequence(needle, haystack string) bool {
+	rs := []rune(needle)
+	i := 0
+	for _, r := range haystack {
+		if i < len(rs) && r == rs[i] {
+			i++
+		}
+	}
+	return i == len(rs)
+}
+
+// completionWords splits a partial command line into the finished
+// arguments and the word being typed
+func completionWords(text string) (args []string, word string) {
+	args = splitArgs(text)
+	if text == "" || strings.HasSuffix(text, " ") && !openQuote(text) {
+		return args, ""
+	}
+	if len(args) == 0 {
+		return nil, ""
+	}
+	return args[:len(args)-1], args[len(args)-1]
+}
+
+// openQuote reports whether text ends inside a quoted section
+func openQuote(text string) bool {
+	var quote rune
+	for _, r := range text {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+		}
+	}
+	return quote != 0
+}
+
+// lineCompleter adapts candidates to readline. Candidates that extend the
+// typed word are offered as usual; when none does, Tab replaces the word
+// with the best fuzzy match (see fuzzyTab).
+type lineCompleter struct {
+	// split returns the text before the word being completed and the word
+	split func(text string) (head string, word string)
+	// candidates returns ranked candidates for the word
+	candidates func(head, word string) []string
+}
+
+// Do implements readline.AutoCompleter
+func (l *lineCompleter) Do(line []rune, pos int) ([][]rune, int) {
+	head, word := l.split(string(line[:pos]))
+	var out [][]rune
+	for _, cand := range l.candidates(head, word) {
+		if rest, ok := strings.CutPrefix(cand, word); ok {
+			out = append(out, []rune(rest))
+		}
+	}
+	return out, len([]rune(word))
+}
+
+// fuzzyTab rewrites the word before the cursor to the best fuzzy match
+// when no candidate extends it
+func (l *lineCompleter) fuzzyTab(line []rune, pos int) ([]rune, int, bool) {
+	head, word := l.split(string(line[:pos]))
+	if word == "" {
+		return nil, 0, false
+	}
+	cands := l.candidates(head, word)
+	if len(cands) == 0 || strings.HasPrefix(cands[0], word) {
+		return nil, 0, false
+	}
+	best := cands[0]
+	if strings.ContainsAny(best, " \t") && !openQuote(head) {
+		best = `"` + best + `"`
+	}
+	start := []rune(string(line[:pos]))
+	prefix := start[:len(start)-len([]rune(rawWord(string(line[:pos]), word)))]
+	newLine := append(append(append([]rune{}, prefix...), []rune(best)...), line[pos:]...)
+	return newLine, len(prefix) + len([]rune(best)), true
+}
+
+// rawWord returns the word as typed at the end of text, including any
+// opening quote
+func rawWord(text, word string) string {
+	if openQuote(text) {
+		if i := strings.LastIndexAny(text, `"'`); i >= 0 {
+			return text[i:]
+		}
+	}
+	if strings.HasSuffix(text, word) {
+		return word
+	}
+	return ""
+}
+
+// commandLineCompleter completes REPL command lines
+func commandLineCompleter(c *completer) *lineCompleter {
+	return &lineCompleter{
+		split: func(text string) (string, string) {
+			_, word := completionWords(text)
+			return text[:len(text)-len(rawWord(text, word))], word
+		},
+		candidates: func(head, word string) []string {
+			args, _ := completionWords(head)
+			return c.complete(args, word)
+		},
+	}
+}
+
+// fieldCompleter completes a prompt field from a fixed candidate source.
+// With list set, each comma-separated item is completed on its own.
+func fieldCompleter(candidates func(word string) []string, list bool) *lineCompleter {
+	return &lineCompleter{
+		split: func(text string) (string, string) {
+			if !list {
+				return "", text
+			}
+			i := strings.LastIndex(text, ",") + 1
+			for i < len(text) && text[i] == ' ' {
+				i++
+			}
+			return text[:i], text[i:]
+		},
+		candidates: func(_, word string) []string {
+			return rank(word, candidates(word))
+		},
+	}
+}
+
+// noCompletion disables completion, e.g. while a title is typed
+var noCompletion = &lineCompleter{
+	split:      func(text string) (string, string) { return text, "" },
+	candidates: func(string, string) []string { return nil },
+}
+
+// installFuzzyTab makes Tab fall back to fuzzy matching for whichever
+// lineCompleter is active
+func installFuzzyTab(rl *readline.Instance) {
+	rl.Config.SetListener(func(line []rune, pos int, key rune) ([]rune, int, bool) {
+		if key != readline.CharTab {
+			return nil, 0, false
+		}
+		l, ok := rl.Config.AutoComplete.(*lineCompleter)
+		if !ok {
+			return nil, 0, false
+		}
+		return l.fuzzyTab(line, pos)
+	})
+}
+
+// handleCompletion prints a shell completion script: completion bash|zsh|fish
+func handleCompletion(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: completion bash|zsh|fish")
+	}
+	prog := filepath.Base(os.Args[0])
+	fn := "_" + regexp.MustCompile(`[^A-Za-z0-9_]`).ReplaceAllString(prog, "_") + "_complete"
+
+	switch args[0] {
+	case "bash":
+		fmt.Printf(`# bash completion for %[1]s; load with: source <(%[1]s completion bash)
+%[2]s() {
+    local IFS=$'\n'
+    COMPREPLY=($("${COMP_WORDS[0]}" __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F %[2]s %[1]s
+`, prog, fn)
+	case "zsh":
+		fmt.Printf(`#compdef %[1]s
+# zsh completion for %[1]s; load with: source <(%[1]s completion zsh)
+%[2]s() {
+    local -a candidates
+    candidates=("${(@f)$("${words[1]}" __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    if (( ${#candidates} )); then
+        compadd -U -- "${candidates[@]}"
+    else
+        _files
+    fi
+}
+compdef %[2]s %[1]s
+`, prog, fn)
+	case "fish":
+		fmt.Printf(`# fish completion for %[1]s; load with: %[1]s completion fish | source
+function %[2]s
+    set -l args (commandline -opc) (commandline -ct)
+    $args[1] __complete $args[2..-1] 2>/dev/null
+end
+complete -c %[1]s -f -a '(%[2]s)'
+`, prog, fn)
+	default:
+		return fmt.Errorf("unsupported shell '%s' (use bash, zsh or fish)", args[0])
+	}
+	return nil
+}
+
+// handleCompleteRequest answers the generated shell scripts: the last
+// argument is the word being completed
+func handleCompleteRequest(c *completer, args []string) error {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+	// Skip global flags such as --data-dir in front of the command
+	words, word := args[:len(args)-1], args[len(args)-1]
+	if _, rest, err := config.ParseArgs(words); err == nil {
+		words = rest
+	}
+	for _, cand := range c.complete(words, word) {
+		fmt.Println(cand)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"manu-node-cli/internal/node"
+	"manu-node-cli/internal/templates"
+)
+
+func setupTestCompleter(t *testing.T) (*completer, func()) {
+	store, attrs, cleanup := setupTestCommands(t)
+
+	seed := []*node.Node{
+		{ID: "n1", Title: "CNC-01", Operations: []string{"Milling", "Drilling"}, UNSAddress: "Plant/Line1/CNC-01",
+			Labels: map[string]string{"dept": "metal"}},
+		{ID: "n2", Title: "Lathe", Operations: []string{"Turning", "drilling"}, UNSAddress: "Plant/Line2/Lathe"},
+		{ID: "n3", Title: "Paint Booth", UNSAddress: "Plant/Finishing"},
+	}
+	for _, n := range seed {
+		if err := store.SaveNode(n); err != nil {
+			cleanup()
+			t.Fatalf("Failed to save node: %v", err)
+		}
+	}
+	tmpls, err := templates.NewStore(t.TempDir())
+	if err != nil {
+		cleanup()
+		t.Fatalf("Failed to create template store: %v", err)
+	}
+	if err := tmpls.Save(&templates.Template{Name: "cnc", Title: "{{title}}"}); err != nil {
+		cleanup()
+		t.Fatalf("Failed to save template: %v", err)
+	}
+
+	return &completer{workspace: &workspace{store: store, attrs: attrs, tmpls: tmpls}, repl: true}, cleanup
+}
+
+func TestRank(t *testing.T) {
+	candidates := []string{"Paint Booth", "CNC-01", "cnc-old", "Lathe", "Booth-CNC"}
+	got := rank("cnc", candidates)
+	want := []string{"cnc-old", "CNC-01", "Booth-CNC"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	// Letters in order anywhere in the candidate
+	if got := rank("pbt", candidates); !reflect.DeepEqual(got, []string{"Paint Booth"}) {
+		t.Errorf("Expected subsequence match, got %v", got)
+	}
+}
+
+func TestUNSCandidates(t *testing.T) {
+	c, cleanup := setupTestCompleter(t)
+	defer cleanup()
+	nodes := c.visibleNodes()
+
+	tests := []struct {
+		word string
+		want []string
+	}{
+		{"", []string{"Plant/"}},
+		{"Plant/", []string{"Plant/Line1/", "Plant/Line2/", "Plant/Finishing"}},
+		{"Plant/Line1/", []string{"Plant/Line1/CNC-01"}},
+	}
+	for _, tt := range tests {
+		if got := unsCandidates(nodes, tt.word); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("unsCandidates(%q): expected %v, got %v", tt.word, tt.want, got)
+		}
+	}
+}
+
+func TestComplete(t *testing.T) {
+	c, cleanup := setupTestCompleter(t)
+	defer cleanup()
+
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"vi", []string{"view"}},
+		{"template s", []string{"save", "show"}},
+		{"template show ", []string{"cnc"}},
+		{"view cnc", []string{"CNC-01"}},
+		{"bulk-update --add-op ", []string{"Drilling", "Milling", "Turning"}},
+		{"bulk-update --remove-", []string{"--remove-op"}},
+		{"list --uns-prefix=Plant/L", []string{"--uns-prefix=Plant/Line1/", "--uns-prefix=Plant/Line2/"}},
+		{"list -l ", []string{"dept=metal"}},
+		{"label Lathe ", []string{"dept-", "dept=metal"}},
+		{"create --from-template ", []string{"cnc"}},
+		{"clone Lathe --title X --uns Plant/", []string{"Plant/Finishing", "Plant/Line1/", "Plant/Line2/"}},
+		{"attr define power --type n", []string{"number"}},
+		{"attr define power --type ion", []string{"duration"}},
+		{"migrate ", []string{"--dry-run"}},
+	}
+	for _, tt := range tests {
+		args, word := completionWords(tt.line)
+		if got := c.complete(args, word); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: expected %v, got %v", tt.line, tt.want, got)
+		}
+	}
+}
+
+func TestCompleteModes(t *testing.T) {
+	c, cleanup := setupTestCompleter(t)
+	defer cleanup()
+
+	if got := c.complete(nil, "und"); !reflect.DeepEqual(got, []string{"undo"}) {
+		t.Errorf("Expected undo in the REPL, got %v", got)
+	}
+	if got := c.complete(nil, "ser"); !reflect.DeepEqual(got, []string{"user"}) {
+		t.Errorf("Expected serve to be hidden in the REPL, got %v", got)
+	}
+
+	c.repl = false
+	if got := c.complete(nil, "und"); len(got) != 0 {
+		t.Errorf("Expected undo to be hidden on the command line, got %v", got)
+	}
+	if got := c.complete(nil, "vi"); len(got) != 0 {
+		t.Errorf("Expected the REPL's view to be hidden on the command line, got %v", got)
+	}
+	if got := c.complete(nil, "ser"); !reflect.DeepEqual(got, []string{"serve", "user"}) {
+		t.Errorf("Expected serve on the command line, got %v", got)
+	}
+}
+
+func TestLineCompleter(t *testing.T) {
+	c, cleanup := setupTestCompleter(t)
+	defer cleanup()
+	l := commandLineCompleter(c)
+
+	line := []rune("view La")
+	got, length := l.Do(line, len(line))
+	if length != 2 || len(got) != 1 || string(got[0]) != "the" {
+		t.Errorf("Expected suffix 'the' for 2 runes, got %q, %d", got, length)
+	}
+
+	// No candidate starts with "booth", so Tab falls back to fuzzy matching
+	line = []rune("view booth")
+	if got, _ := l.Do(line, len(line)); len(got) != 0 {
+		t.Errorf("Expected no prefix candidates, got %q", got)
+	}
+	newLine, pos, ok := l.fuzzyTab(line, len(line))
+	if !ok || string(newLine) != `view "Paint Booth"` || pos != len(newLine) {
+		t.Errorf("Expected fuzzy rewrite to the quoted title, got %q at %d (%v)", string(newLine), pos, ok)
+	}
+
+	ops := fieldCompleter(func(string) []string { return operationNames(c.visibleNodes()) }, true)
+	line = []rune("Milling, Tu")
+	got, length = ops.Do(line, len(line))
+	if length != 2 || len(got) != 1 || string(got[0]) != "rning" {
+		t.Errorf("Expected the last list item to complete to Turning, got %q, %d", got, length)
+	}
+}
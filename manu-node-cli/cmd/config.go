@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"manu-node-cli/internal/config"
+	"manu-node-cli/internal/manifest"
+	"manu-node-cli/internal/node"
+)
+
+// applyColor switches colored output on or off; auto leaves the terminal
+// detection of the color package in charge
+func applyColor(mode string) {
+	switch mode {
+	case "always":
+		color.NoColor = false
+	case "never":
+		color.NoColor = true
+	}
+}
+
+// handleConfig shows or changes settings: config view | set [--global] <key> <value> | unset [--global] <key>
+func handleConfig(cfg *config.Loaded, args []string) error {
+	usage := errors.New("usage: config view | set [--global] <key> <value> | unset [--global] <key>")
+	if len(args) == 0 {
+		return usage
+	}
+	green := color.New(color.FgGreen).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+
+	switch args[0] {
+	case "view":
+		if len(args) != 1 {
+			return usage
+		}
+		fmt.Println("\n" + cyan("Configuration:"))
+		fmt.Println(strings.Repeat("-", 90))
+		fmt.Printf("%-20s %-35s %s\n", "Key", "Value", "Source")
+		fmt.Println(strings.Repeat("-", 90))
+		for _, k := range config.Keys {
+			fmt.Printf("%-20s %-35s %s\n", k.Name, truncate(k.Get(&cfg.Config), 33), cfg.Sources[k.Name])
+		}
+		if cfg.Context != "" {
+			fmt.Printf("\nContext: %s (from %s)\n", cfg.Context, cfg.ContextSource)
+		} else {
+			fmt.Printf("\nContext: %s\n", config.DefaultContext)
+		}
+		fmt.Printf("User config file: %s\n", cfg.UserFile)
+		fmt.Printf("System config file: %s\n\n", config.SystemFile)
+		return nil
+	case "set", "unset":
+		// Settings go to the current context unless --global is given
+		global := len(args) > 1 && args[1] == "--global"
+		if global {
+			args = append(args[:1:1], args[2:]...)
+		}
+		value := ""
+		if args[0] == "set" && len(args) == 3 {
+			value = args[2]
+		} else if args[0] == "set" || len(args) != 2 {
+			return usage
+		}
+		if cfg.UserFile == "" {
+			return errors.New("no user config file; pass --config <file>")
+		}
+		if args[0] == "set" && value == "" {
+			return fmt.Errorf("empty value for %s; use 'config unset %s'", args[1], args[1])
+		}
+		context, where := cfg.Context, cfg.UserFile
+		if global {
+			context = ""
+		}
+		if context != "" {
+			where = fmt.Sprintf("context '%s' in %s", context, cfg.UserFile)
+		}
+		if err := config.Set(cfg.UserFile, context, args[1], value); err != nil {
+			return err
+		}
+		if value == "" {
+			fmt.Printf("%s Removed %s from %s\n", green("✓"), args[1], where)
+		} else {
+			fmt.Printf("%s Set %s = %s in %s\n", green("✓"), args[1], value, where)
+		}
+		return nil
+	default:
+		return usage
+	}
+}
+
+// printNodesAs writes nodes as JSON or YAML and reports whether format was
+// one of those; the table format is left to the caller
+func printNodesAs(format string, nodes []*node.Node) (bool, error) {
+	switch format {
+	case "json":
+		if nodes == nil {
+			nodes = []*node.Node{}
+		}
+		data, err := json.MarshalIndent(nodes, "", "  ")
+		if err != nil {
+			return true, fmt.Errorf("failed to marshal nodes: %w", err)
+		}
+		fmt.Println(string(data))
+		return true, nil
+	case "yaml":
+		data, err := manifest.Marshal(nodes)
+		if err != nil {
+			return true, err
+		}
+		fmt.Print(string(data))
+		return true, nil
+	case "", "table":
+		return false, nil
+	default:
+		return true, fmt.Errorf("unknown output format '%s' (use table, json or yaml)", format)
+	}
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chzyer/readline"
+	"github.com/fatih/color"
+	"gopkg.in/yaml.v3"
+	"manu-node-cli/internal/attribute"
+	"manu-node-cli/internal/auth"
+	"manu-node-cli/internal/backup"
+	"manu-node-cli/internal/config"
+	"manu-node-cli/internal/labels"
+	"manu-node-cli/internal/maintenance"
+	"manu-node-cli/internal/manifest"
+	"manu-node-cli/internal/node"
+	"manu-node-cli/internal/operators"
+	"manu-node-cli/internal/storage"
+	"manu-node-cli/internal/templates"
+)
+
+// workspace is the set of stores behind one data directory
+type workspace struct {
+	cfg     *config.Loaded
+	store   *storage.Storage
+	backups *backup.Manager
+	attrs   *attribute.Store
+	tmpls   *templates.Store
+	maint   *maintenance.Store
+	ops     *operators.Store
+	authz   *auth.Authorizer
+	sess    *auth.Session
+}
+
+// openWorkspace opens the data directory of cfg and signs in with
+// MANU_NODE_TOKEN
+func openWorkspace(cfg *config.Loaded) (*workspace, error) {
+	ws := &workspace{cfg: cfg}
+	dataDir := cfg.DataDir
+
+	store, err := storage.NewStorage(dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	ws.store = store
+
+	// Snapshot the data directory before any node is deleted
+	if ws.backups, err = backup.NewManager(dataDir); err != nil {
+		return nil, fmt.Errorf("failed to initialize backups: %w", err)
+	}
+	store.SetSnapshotHook(func(reason string) error {
+		_, err := ws.backups.Snapshot(reason)
+		return err
+	})
+
+	// Check custom attribute values on every write
+	if ws.attrs, err = attribute.NewStore(dataDir); err != nil {
+		return nil, fmt.Errorf("failed to initialize attributes: %w", err)
+	}
+	store.SetValidator(ws.attrs.Apply)
+
+	if ws.tmpls, err = templates.NewStore(dataDir); err != nil {
+		return nil, fmt.Errorf("failed to initialize templates: %w", err)
+	}
+	if ws.maint, err = maintenance.NewStore(dataDir); err != nil {
+		return nil, fmt.Errorf("failed to initialize maintenance: %w", err)
+	}
+	if ws.ops, err = operators.NewStore(dataDir); err != nil {
+		return nil, fmt.Errorf("failed to initialize operators: %w", err)
+	}
+
+	// Refuse to touch a catalog written by a newer release
+	if _, err := store.Load(); errors.Is(err, storage.ErrNewerSchema) {
+		return nil, err
+	}
+
+	// Initialize access control; the token identifies the CLI user
+	users, err := auth.NewStore(dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize user store: %w", err)
+	}
+	ws.authz = auth.NewAuthorizer(users)
+	if ws.sess, err = ws.authz.Session(os.Getenv("MANU_NODE_TOKEN")); err != nil {
+		return nil, fmt.Errorf("MANU_NODE_TOKEN: %w", err)
+	}
+	return ws, nil
+}
+
+// replPrompt shows the current context in front of the usual prompt
+func replPrompt(cfg *config.Loaded) string {
+	if cfg.Context == "" {
+		return "\033[32m>>> \033[0m"
+	}
+	return "\033[36m[" + cfg.Context + "]\033[0m \033[32m>>> \033[0m"
+}
+
+// setPrompt changes the REPL prompt, including the copy prompts restore
+func setPrompt(rl *readline.Instance, prompt string) {
+	rl.Config.Prompt = prompt
+	rl.SetPrompt(prompt)
+}
+
+// handleContext manages contexts: context [list] | create <name> [--data-dir dir] [--use]
+// | use <name> | remove <name>. It returns the configuration to switch to
+// when the current context changed.
+func handleContext(cfg *config.Loaded, args []string) (*config.Loaded, error) {
+	usage := errors.New("usage: context [list] | create <name> [--data-dir dir] [--use] | use <name> | remove <name>")
+	green := color.New(color.FgGreen).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	if cfg.UserFile == "" && args[0] != "list" {
+		return nil, errors.New("no user config file; pass --config <file>")
+	}
+
+	switch args[0] {
+	case "list":
+		if len(args) != 1 {
+			return nil, usage
+		}
+		fmt.Println("\n" + cyan("Contexts:"))
+		fmt.Println(strings.Repeat("-", 90))
+		fmt.Printf("  %-25s %s\n", "Name", "Data Directory")
+		fmt.Println(strings.Repeat("-", 90))
+		current := cfg.Context
+		if current == "" {
+			current = config.DefaultContext
+		}
+		for _, name := range cfg.ContextNames() {
+			resolved, err := cfg.WithContext(name)
+			dataDir := ""
+			if err != nil {
+				dataDir = err.Error()
+			} else {
+				dataDir = resolved.DataDir
+			}
+			marker := " "
+			if name == current {
+				marker = green("*")
+			}
+			fmt.Printf("%s %-25s %s\n", marker, name, dataDir)
+		}
+		fmt.Println()
+		return nil, nil
+
+	case "create":
+		fs := flag.NewFlagSet("context create", flag.ContinueOnError)
+		dataDir := fs.String("data-dir", "", "data directory of the new context")
+		use := fs.Bool("use", false, "switch to the new context")
+		name, err := parseWithName(fs, args[1:])
+		if err != nil {
+			return nil, err
+		}
+		if err := config.ValidateContextName(name); err != nil {
+			return nil, err
+		}
+		if _, ok := cfg.Contexts[name]; ok {
+			return nil, fmt.Errorf("context '%s' already exists", name)
+		}
+		dir := *dataDir
+		if dir == "" {
+			dir = config.ContextDataDir(name)
+		}
+		// Contexts are used from any directory, so store an absolute path
+		if dir, err = filepath.Abs(dir); err != nil {
+			return nil, fmt.Errorf("failed to resolve data directory: %w", err)
+		}
+		if err := config.AddContext(cfg.UserFile, name, config.Config{DataDir: dir}); err != nil {
+			return nil, err
+		}
+		fmt.Printf("%s Created context '%s' with data directory %s\n", green("✓"), name, dir)
+		if !*use {
+			return nil, nil
+		}
+		return useContext(cfg, name)
+
+	case "use":
+		if len(args) != 2 {
+			return nil, usage
+		}
+		return useContext(cfg, args[1])
+
+	case "remove":
+		if len(args) != 2 {
+			return nil, usage
+		}
+		if err := config.RemoveContext(cfg.UserFile, args[1]); err != nil {
+			return nil, err
+		}
+		fmt.Printf("%s Removed context '%s'; its data directory was kept\n", green("✓"), args[1])
+		if cfg.Context == args[1] {
+			return cfg.WithContext(config.DefaultContext)
+		}
+		return nil, nil
+
+	default:
+		return nil, usage
+	}
+}
+
+// useContext makes name the current context and returns its configuration
+func useContext(cfg *config.Loaded, name string) (*config.Loaded, error) {
+	next, err := cfg.WithContext(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.UseContext(cfg.UserFile, name); err != nil {
+		return nil, err
+	}
+	next.ContextSource = cfg.UserFile
+	green := color.New(color.FgGreen).SprintFunc()
+	fmt.Printf("%s Switched to context '%s' (%s)\n", green("✓"), name, next.DataDir)
+	return next, nil
+}
+
+// contextNodes holds the matching nodes of one context
+type contextNodes struct {
+	name  string
+	nodes []*node.Node
+}
+
+// collectAllContexts runs the query of qf against every context's catalog.
+// Contexts that cannot be opened are reported and skipped.
+func collectAllContexts(cfg *config.Loaded, qf *queryFlags) []contextNodes {
+	yellow := color.New(color.FgYellow).SprintFunc()
+	warn := func(name string, err error) {
+		fmt.Fprintf(os.Stderr, "%s context '%s' skipped: %v\n", yellow("Warning:"), name, err)
+	}
+
+	var results []contextNodes
+	seen := map[string]string{}
+	for _, name := range cfg.ContextNames() {
+		resolved, err := cfg.WithContext(name)
+		if err != nil {
+			warn(name, err)
+			continue
+		}
+		// Two contexts sharing a data directory would list the same nodes twice
+		dir, err := filepath.Abs(resolved.DataDir)
+		if err != nil {
+			warn(name, err)
+			continue
+		}
+		if other, ok := seen[dir]; ok {
+			warn(name, fmt.Errorf("same data directory as '%s'", other))
+			continue
+		}
+		seen[dir] = name
+
+		ws, err := openWorkspace(resolved)
+		if err != nil {
+			warn(name, err)
+			continue
+		}
+		q, err := qf.build(ws.attrs, "")
+		if err != nil {
+			warn(name, err)
+			continue
+		}
+		nodes, err := ws.store.Load()
+		if err == nil {
+			nodes, err = ws.sess.Visible(nodes)
+		}
+		if err != nil {
+			warn(name, err)
+			continue
+		}
+		if !q.empty() {
+			nodes = q.filter(nodes)
+		}
+		results = append(results, contextNodes{name: name, nodes: nodes})
+	}
+	return results
+}
+
+// listAllContexts prints the nodes of every context
+func listAllContexts(cfg *config.Loaded, qf *queryFlags, output string) error {
+	results := collectAllContexts(cfg, qf)
+
+	switch output {
+	case "json":
+		out := map[string][]*node.Node{}
+		for _, r := range results {
+			out[r.name] = append([]*node.Node{}, r.nodes...)
+		}
+		data, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal nodes: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	case "yaml":
+		out := map[string][]manifest.Spec{}
+		for _, r := range results {
+			specs := []manifest.Spec{}
+			for _, n := range r.nodes {
+				specs = append(specs, manifest.FromNode(n))
+			}
+			out[r.name] = specs
+		}
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		if err := enc.Encode(map[string]interface{}{"contexts": out}); err != nil {
+			return fmt.Errorf("failed to marshal nodes: %w", err)
+		}
+		return enc.Close()
+	case "", "table":
+	default:
+		return fmt.Errorf("unknown output format '%s' (use table, json or yaml)", output)
+	}
+
+	total := 0
+	for _, r := range results {
+		total += len(r.nodes)
+	}
+	if total == 0 {
+		fmt.Println("\nNo nodes found in any context.")
+		fmt.Println()
+		return nil
+	}
+
+	cyan := color.New(color.FgCyan).SprintFunc()
+	fmt.Println("\n" + cyan(fmt.Sprintf("Manufacturing Nodes in %d context(s):", len(results))))
+	fmt.Println(strings.Repeat("-", 100))
+	fmt.Printf("%-18s %-20s %-25s %-25s %s\n", "Context", "ID", "Title", "UNS Address", "Labels")
+	fmt.Println(strings.Repeat("-", 100))
+	for _, r := range results {
+		for _, n := range r.nodes {
+			fmt.Printf("%-18s %-20s %-25s %-25s %s\n", truncate(r.name, 16), n.ID, truncate(n.Title, 23),
+				truncate(n.UNSAddress, 23), truncate(labels.Format(n.Labels), 20))
+		}
+	}
+	fmt.Println()
+	return nil
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"gopkg.in/yaml.v3"
+	"manu-node-cli/internal/attribute"
+	"manu-node-cli/internal/auth"
+	"manu-node-cli/internal/manifest"
+	"manu-node-cli/internal/node"
+	"manu-node-cli/internal/storage"
+)
+
+// runEditor opens path in the user's editor and waits for it to exit.
+// Tests replace it with a scripted edit.
+var runEditor = func(path string) error {
+	editor := strings.Fields(os.Getenv("EDITOR"))
+	if len(editor) == 0 {
+		editor = []string{"vi"}
+	}
+	cmd := exec.Command(editor[0], append(editor[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run editor %s: %w", editor[0], err)
+	}
+	return nil
+}
+
+// handleEdit opens a node as YAML in $EDITOR and saves it once it
+// validates: edit <node-id or title>
+func handleEdit(store *storage.Storage, attrs *attribute.Store, sess *auth.Session, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: edit <node-id or title>")
+	}
+	existing, err := store.GetNodeByIDOrTitle(strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+	if err := sess.CanNode(auth.PermNodesWrite, existing.UNSAddress); err != nil {
+		return err
+	}
+
+	updated, err := editNode(existing, func(spec manifest.Spec) (*node.Node, error) {
+		return nodeFromSpec(store, attrs, sess, existing, spec)
+	})
+	if err != nil {
+		return err
+	}
+	if updated == nil {
+		fmt.Println("No changes.")
+		return nil
+	}
+
+	diff := node.Diff(existing, updated)
+	if err := store.UpdateNode(existing.ID, updated); err != nil {
+		return fmt.Errorf("failed to update node: %w", err)
+	}
+	green := color.New(color.FgGreen).SprintFunc()
+	fmt.Printf("%s Node '%s' updated\n", green("✓"), updated.Title)
+	for _, d := range diff {
+		fmt.Printf("    %s\n", d)
+	}
+	return nil
+}
+
+// editNode lets the user edit n as YAML until build accepts the result.
+// Problems are shown as comments at the top of the reopened file. It
+// returns nil if the file was saved unchanged, and an error if the user
+// emptied it to cancel.
+func editNode(n *node.Node, build func(manifest.Spec) (*node.Node, error)) (*node.Node, error) {
+	spec := manifest.FromNode(n)
+	spec.ID = ""
+	data, err := yaml.Marshal(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal node: %w", err)
+	}
+
+	f, err := os.CreateTemp("", "node-*.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	path := f.Name()
+	f.Close()
+	defer os.Remove(path)
+
+	original := string(data)
+	content := original
+	var problem error
+	for {
+		if err := os.WriteFile(path, []byte(editHeader(n, problem)+content), 0600); err != nil {
+			return nil, fmt.Errorf("failed to write temp file: %w", err)
+		}
+		if err := runEditor(path); err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read temp file: %w", err)
+		}
+
+		content = stripHeader(string(data))
+		if strings.TrimSpace(content) == "" {
+			return nil, errors.New("edit cancelled")
+		}
+		if content == original {
+			return nil, nil
+		}
+
+		specs, err := manifest.Parse([]byte(content), "edited node")
+		switch {
+		case err != nil:
+			problem = err
+			continue
+		case len(specs) != 1:
+			problem = fmt.Errorf("expected exactly one node, found %d", len(specs))
+			continue
+		}
+		updated, err := build(specs[0])
+		if err != nil {
+			problem = err
+			continue
+		}
+		return updated, nil
+	}
+}
+
+// editHeader is the comment block shown above the YAML
+func editHeader(n *node.Node, problem error) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Editing node %s (%s).\n", n.ID, n.Title)
+	b.WriteString("# Save and quit to apply the changes; save an empty file to cancel.\n")
+	if problem != nil {
+		b.WriteString("#\n")
+		for _, line := range strings.Split(problem.Error(), "\n") {
+			fmt.Fprintf(&b, "# ERROR: %s\n", line)
+		}
+	}
+	b.WriteString("#\n")
+	return b.String()
+}
+
+// stripHeader drops the leading comment lines written by editHeader
+func stripHeader(content string) string {
+	for strings.HasPrefix(content, "#") {
+		i := strings.IndexByte(content, '\n')
+		if i < 0 {
+			return ""
+		}
+		content = content[i+1:]
+	}
+	return content
+}
+
+// nodeFromSpec applies an edited spec to existing and runs the checks an
+// update has to pass
+func nodeFromSpec(store *storage.Storage, attrs *attribute.Store, sess *auth.Session,
+	existing *node.Node, spec manifest.Spec) (*node.Node, error) {
+	if spec.ID != "" && spec.ID != existing.ID {
+		return nil, errors.New("the node ID cannot be changed")
+	}
+
+	var operations []string
+	for _, op := range spec.Operations {
+		if op = strings.TrimSpace(op); op != "" {
+			operations = append(operations, op)
+		}
+	}
+	updated := &node.Node{
+		ID:           existing.ID,
+		Title:        strings.TrimSpace(spec.Title),
+		Description:  strings.TrimRight(spec.Description, "\n"),
+		Operations:   operations,
+		UNSAddress:   strings.TrimSpace(spec.UNSAddress),
+		CreatedAt:    existing.CreatedAt,
+		UpdatedAt:    time.Now(),
+		Attributes:   spec.Attributes,
+		Labels:       spec.Labels,
+		Calibrations: existing.Calibrations,
+	}
+	if len(spec.Links) > 0 {
+		nodes, err := store.Load()
+		if err != nil {
+			return nil, err
+		}
+		if updated.Links, err = manifest.ResolveLinks(spec.Links, nodes); err != nil {
+			return nil, err
+		}
+	}
+	if err := updated.Validate(); err != nil {
+		return nil, err
+	}
+	if err := attrs.Apply(updated); err != nil {
+		return nil, err
+	}
+	if err := sess.CanNode(auth.PermNodesWrite, updated.UNSAddress); err != nil {
+		return nil, err
+	}
+	unique, err := store.IsTitleUnique(updated.Title, existing.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check title uniqueness: %w", err)
+	}
+	if !unique {
+		return nil, fmt.Errorf("a node with title '%s' already exists", updated.Title)
+	}
+	return updated, nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"manu-node-cli/internal/attribute"
+	"manu-node-cli/internal/node"
+)
+
+// scriptEditor replaces runEditor with edits that each rewrite the file.
+// It returns the file contents the editor was opened with.
+func scriptEditor(t *testing.T, edits ...func(string) string) *[]string {
+	t.Helper()
+	var opened []string
+	orig := runEditor
+	t.Cleanup(func() { runEditor = orig })
+
+	runEditor = func(path string) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		opened = append(opened, string(data))
+		if len(edits) == 0 {
+			t.Fatalf("Editor opened more often than expected")
+		}
+		edit := edits[0]
+		edits = edits[1:]
+		return os.WriteFile(path, []byte(edit(string(data))), 0600)
+	}
+	return &opened
+}
+
+func TestHandleEdit(t *testing.T) {
+	store, attrs, cleanup := setupTestCommands(t)
+	defer cleanup()
+
+	n := node.NewNode("CNC", "Mill", []string{"Cut"}, "Plant/Line1/CNC")
+	if err := store.SaveNode(n); err != nil {
+		t.Fatalf("Failed to save node: %v", err)
+	}
+
+	scriptEditor(t, func(content string) string {
+		content = strings.Replace(content, "description: Mill", "description: |\n    Five-axis mill.\n    Coolant: oil", 1)
+		return strings.Replace(content, "    - Cut", "    - Cut\n    - Drill\n    - Tap", 1)
+	})
+	if err := handleEdit(store, attrs, nil, []string{"CNC"}); err != nil {
+		t.Fatalf("handleEdit failed: %v", err)
+	}
+
+	got, err := store.GetNode(n.ID)
+	if err != nil {
+		t.Fatalf("Failed to get node: %v", err)
+	}
+	if got.Description != "Five-axis mill.\nCoolant: oil" {
+		t.Errorf("Expected multi-line description, got %q", got.Description)
+	}
+	if !reflect.DeepEqual(got.Operations, []string{"Cut", "Drill", "Tap"}) {
+		t.Errorf("Expected operations [Cut Drill Tap], got %v", got.Operations)
+	}
+}
+
+func TestHandleEditReopensOnInvalidInput(t *testing.T) {
+	store, attrs, cleanup := setupTestCommands(t)
+	defer cleanup()
+
+	if _, err := attrs.Define(attribute.Definition{Name: "power", Type: attribute.TypeNumber}); err != nil {
+		t.Fatalf("Failed to define attribute: %v", err)
+	}
+	n := node.NewNode("CNC", "Mill", nil, "")
+	if err := store.SaveNode(n); err != nil {
+		t.Fatalf("Failed to save node: %v", err)
+	}
+
+	opened := scriptEditor(t,
+		func(content string) string { return content + "attributes:\n    power: lots\n" },
+		func(content string) string { return strings.Replace(content, "power: lots", "power: 7.50", 1) },
+	)
+	if err := handleEdit(store, attrs, nil, []string{"CNC"}); err != nil {
+		t.Fatalf("handleEdit failed: %v", err)
+	}
+
+	if len(*opened) != 2 {
+		t.Fatalf("Expected the editor to reopen once, opened %d times", len(*opened))
+	}
+	if !strings.Contains((*opened)[1], "# ERROR:") || !strings.Contains((*opened)[1], "power") {
+		t.Errorf("Expected an error comment about power, got:\n%s", (*opened)[1])
+	}
+	if strings.Count((*opened)[1], "# Editing node") != 1 {
+		t.Errorf("Expected a single header after reopening, got:\n%s", (*opened)[1])
+	}
+
+	got, err := store.GetNode(n.ID)
+	if err != nil {
+		t.Fatalf("Failed to get node: %v", err)
+	}
+	if got.Attributes["power"] != "7.5" {
+		t.Errorf("Expected normalized power 7.5, got %v", got.Attributes)
+	}
+}
+
+func TestHandleEditUnchangedOrCancelled(t *testing.T) {
+	store, attrs, cleanup := setupTestCommands(t)
+	defer cleanup()
+
+	n := node.NewNode("CNC", "Mill", nil, "")
+	if err := store.SaveNode(n); err != nil {
+		t.Fatalf("Failed to save node: %v", err)
+	}
+	before, _ := store.GetNode(n.ID)
+
+	scriptEditor(t, func(content string) string { return content })
+	if err := handleEdit(store, attrs, nil, []string{"CNC"}); err != nil {
+		t.Errorf("Expected no error for an unchanged file, got %v", err)
+	}
+
+	scriptEditor(t, func(string) string { return "" })
+	if err := handleEdit(store, attrs, nil, []string{"CNC"}); err == nil {
+		t.Error("Expected an error when the file is emptied")
+	}
+
+	after, _ := store.GetNode(n.ID)
+	if !after.UpdatedAt.Equal(before.UpdatedAt) {
+		t.Error("Expected the node to be left untouched")
+	}
+}
+
+func TestHandleEditRejectsDuplicateTitle(t *testing.T) {
+	store, attrs, cleanup := setupTestCommands(t)
+	defer cleanup()
+
+	for _, title := range []string{"CNC", "Lathe"} {
+		n := node.NewNode(title, "", nil, "")
+		n.ID = title
+		if err := store.SaveNode(n); err != nil {
+			t.Fatalf("Failed to save node: %v", err)
+		}
+	}
+
+	opened := scriptEditor(t,
+		func(content string) string { return strings.Replace(content, "title: CNC", "title: Lathe", 1) },
+		func(string) string { return "" },
+	)
+	if err := handleEdit(store, attrs, nil, []string{"CNC"}); err == nil {
+		t.Error("Expected the edit to end cancelled")
+	}
+	if !strings.Contains((*opened)[1], "already exists") {
+		t.Errorf("Expected a duplicate title error, got:\n%s", (*opened)[1])
+	}
+}
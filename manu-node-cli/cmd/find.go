@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"manu-node-cli/internal/attribute"
+	"manu-node-cli/internal/auth"
+	"manu-node-cli/internal/labels"
+	"manu-node-cli/internal/node"
+	"manu-node-cli/internal/storage"
+)
+
+// nodeQuery selects nodes by label selector, attribute filters, UNS
+// prefix and text
+type nodeQuery struct {
+	selector  labels.Selector
+	attrs     []*attribute.Filter
+	unsPrefix string
+	text      string
+}
+
+// queryFlags registers the selection flags shared by list, find and
+// bulk commands
+type queryFlags struct {
+	selector  string
+	attrs     stringList
+	unsPrefix string
+}
+
+func (q *queryFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&q.selector, "l", "", "label selector, e.g. 'dept=woodshop,criticality in (high,medium),!retired'")
+	fs.StringVar(&q.selector, "selector", "", "same as -l")
+	fs.StringVar(&q.selector, "where", "", "same as -l")
+	fs.Var(&q.attrs, "attr", "attribute filter such as 'power>=5' or 'plc=S7' (repeatable)")
+	fs.StringVar(&q.unsPrefix, "uns-prefix", "", "only nodes whose UNS address lies under this path")
+}
+
+// registerOutput adds the -o/--output flag; output holds the configured
+// format and is overwritten when the flag is given
+func registerOutput(fs *flag.FlagSet, output *string) {
+	fs.StringVar(output, "o", *output, "output format: table, json or yaml")
+	fs.StringVar(output, "output", *output, "same as -o")
+}
+
+// build parses the flag values into a query
+func (q *queryFlags) build(attrs *attribute.Store, text string) (*nodeQuery, error) {
+	sel, err := labels.ParseSelector(q.selector)
+	if err != nil {
+		return nil, err
+	}
+	filters, err := attrs.Filters(q.attrs)
+	if err != nil {
+		return nil, err
+	}
+	return &nodeQuery{
+		selector:  sel,
+		attrs:     filters,
+		unsPrefix: strings.TrimSpace(q.unsPrefix),
+		text:      strings.ToLower(strings.TrimSpace(text)),
+	}, nil
+}
+
+// empty reports whether the query selects every node
+func (q *nodeQuery) empty() bool {
+	return q.selector.Empty() && len(q.attrs) == 0 && q.unsPrefix == "" && q.text == ""
+}
+
+func (q *nodeQuery) match(n *node.Node) bool {
+	if !q.selector.Matches(n.Labels) || !attribute.MatchAll(q.attrs, n.Attributes) {
+		return false
+	}
+	if q.unsPrefix != "" && !n.HasUNSPrefix(q.unsPrefix) {
+		return false
+	}
+	if q.text == "" {
+		return true
+	}
+	for _, field := range append([]string{n.Title, n.Description, n.UNSAddress}, n.Operations...) {
+		if strings.Contains(strings.ToLower(field), q.text) {
+			return true
+		}
+	}
+	return false
+}
+
+// filter returns the nodes matching the query
+func (q *nodeQuery) filter(nodes []*node.Node) []*node.Node {
+	var matched []*node.Node
+	for _, n := range nodes {
+		if q.match(n) {
+			matched = append(matched, n)
+		}
+	}
+	return matched
+}
+
+// handleFind searches nodes: find [text] [-l selector] [--attr expr] [-o table|json|yaml]
+func handleFind(store *storage.Storage, attrs *attribute.Store, sess *auth.Session, output string, args []string) error {
+	fs := flag.NewFlagSet("find", flag.ContinueOnError)
+	var qf queryFlags
+	qf.register(fs)
+	registerOutput(fs, &output)
+	words, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	q, err := qf.build(attrs, strings.Join(words, " "))
+	if err != nil {
+		return err
+	}
+	if q.empty() {
+		return fmt.Errorf("usage: find [text] [-l selector] [--attr expr]")
+	}
+
+	nodes, err := store.Load()
+	if err != nil {
+		return err
+	}
+	if nodes, err = sess.Visible(nodes); err != nil {
+		return err
+	}
+	matched := q.filter(nodes)
+	if handled, err := printNodesAs(output, matched); handled {
+		return err
+	}
+	if len(matched) == 0 {
+		fmt.Println("No matching nodes.")
+		return nil
+	}
+
+	cyan := color.New(color.FgCyan).SprintFunc()
+	fmt.Println("\n" + cyan(fmt.Sprintf("%d matching node(s):", len(matched))))
+	fmt.Println(strings.Repeat("-", 90))
+	fmt.Printf("%-20s %-25s %-25s %s\n", "ID", "Title", "UNS Address", "Labels")
+	fmt.Println(strings.Repeat("-", 90))
+	for _, n := range matched {
+		fmt.Printf("%-20s %-25s %-25s %s\n", n.ID, truncate(n.Title, 23),
+			truncate(n.UNSAddress, 23), truncate(labels.Format(n.Labels), 30))
+	}
+	fmt.Println()
+	return nil
+}
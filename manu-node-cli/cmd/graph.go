@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"manu-node-cli/internal/auth"
+	"manu-node-cli/internal/graph"
+	"manu-node-cli/internal/node"
+	"manu-node-cli/internal/storage"
+)
+
+// handleExportGraph draws the plant layout for design reviews and wikis:
+// export-graph --format dot|mermaid [--uns-prefix path] [--out file]
+func handleExportGraph(store *storage.Storage, sess *auth.Session, args []string) error {
+	fs := flag.NewFlagSet("export-graph", flag.ContinueOnError)
+	format := fs.String("format", string(graph.FormatDOT), "diagram format: dot or mermaid")
+	unsPrefix := fs.String("uns-prefix", "", "only draw nodes under this UNS path")
+	out := fs.String("out", "", "write to this file instead of standard output")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return errors.New("usage: export-graph --format dot|mermaid [--uns-prefix path] [--out file]")
+	}
+
+	nodes, err := store.Load()
+	if err != nil {
+		return err
+	}
+	if nodes, err = sess.Visible(nodes); err != nil {
+		return err
+	}
+	var selected []*node.Node
+	for _, n := range nodes {
+		if n.HasUNSPrefix(*unsPrefix) {
+			selected = append(selected, n)
+		}
+	}
+
+	if *out == "" {
+		return graph.Write(os.Stdout, selected, graph.Format(*format))
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	err = graph.Write(f, selected, graph.Format(*format))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(*out)
+		return err
+	}
+	fmt.Printf("Exported %d node(s) to %s\n", len(selected), *out)
+	return nil
+}
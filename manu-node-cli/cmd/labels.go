@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fatih/color"
+	"manu-node-cli/internal/auth"
+	"manu-node-cli/internal/labels"
+	"manu-node-cli/internal/storage"
+)
+
+// handleLabel edits a node's labels: label <node> key=value ... key-
+func handleLabel(store *storage.Storage, sess *auth.Session, args []string) error {
+	if len(args) < 2 {
+		return errors.New("usage: label <node-id or title> key=value ... [key-]")
+	}
+
+	existing, err := store.GetNodeByIDOrTitle(args[0])
+	if err != nil {
+		return err
+	}
+	if err := sess.CanNode(auth.PermNodesWrite, existing.UNSAddress); err != nil {
+		return err
+	}
+
+	updated := existing.Clone()
+	if updated.Labels, err = labels.Edit(existing.Labels, args[1:]); err != nil {
+		return err
+	}
+	updated.UpdatedAt = time.Now()
+	if err := store.UpdateNodeIfMatch(existing.ID, existing.ETag(), updated); err != nil {
+		return err
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	fmt.Printf("%s Labels of '%s': %s\n", green("✓"), updated.Title, labels.Format(updated.Labels))
+	return nil
+}
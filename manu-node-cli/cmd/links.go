@@ -0,0 +1,178 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"manu-node-cli/internal/auth"
+	"manu-node-cli/internal/node"
+	"manu-node-cli/internal/storage"
+)
+
+// handleLink adds or changes a material flow link:
+// link <from> <to> [--kind conveyor|buffer|manual] [--capacity N] [--transfer-time 90s]
+func handleLink(store *storage.Storage, sess *auth.Session, args []string) error {
+	fs := flag.NewFlagSet("link", flag.ContinueOnError)
+	kind := fs.String("kind", node.LinkConveyor, "how material moves: "+strings.Join(node.LinkKinds, ", "))
+	capacity := fs.Int("capacity", 0, "units the connection can hold")
+	transfer := fs.String("transfer-time", "", "time to move material, e.g. 90s or 5m")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return errors.New("usage: link <from> <to> [--kind conveyor|buffer|manual] [--capacity N] [--transfer-time 90s]")
+	}
+	from, to, err := linkEnds(store, sess, positional[0], positional[1])
+	if err != nil {
+		return err
+	}
+
+	// Changing an existing link keeps the values whose flags were not given
+	existing, exists := from.LinkTo(to.ID)
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if exists {
+		if !set["kind"] {
+			*kind = existing.Kind
+		}
+		if !set["capacity"] {
+			*capacity = existing.Capacity
+		}
+		if !set["transfer-time"] {
+			*transfer = existing.TransferTime
+		}
+	}
+	link, err := node.NewLink(to.ID, *kind, *capacity, *transfer)
+	if err != nil {
+		return err
+	}
+
+	err = updateNodeIfUnchanged(store, from, func(n *node.Node) {
+		for i, l := range n.Links {
+			if l.To == to.ID {
+				n.Links[i] = link
+				return
+			}
+		}
+		n.Links = append(n.Links, link)
+	})
+	if err != nil {
+		return err
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	verb := "Linked"
+	if exists {
+		verb = "Updated link"
+	}
+	fmt.Printf("%s %s '%s' → '%s' (%s)\n", green("✓"), verb, from.Title, to.Title, link)
+	return nil
+}
+
+// handleUnlink removes a material flow link: unlink <from> <to>
+func handleUnlink(store *storage.Storage, sess *auth.Session, args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: unlink <from> <to>")
+	}
+	from, to, err := linkEnds(store, sess, args[0], args[1])
+	if err != nil {
+		return err
+	}
+	if _, ok := from.LinkTo(to.ID); !ok {
+		return fmt.Errorf("'%s' does not link to '%s'", from.Title, to.Title)
+	}
+
+	err = updateNodeIfUnchanged(store, from, func(n *node.Node) {
+		var kept []node.Link
+		for _, l := range n.Links {
+			if l.To != to.ID {
+				kept = append(kept, l)
+			}
+		}
+		n.Links = kept
+	})
+	if err != nil {
+		return err
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	fmt.Printf("%s Unlinked '%s' → '%s'\n", green("✓"), from.Title, to.Title)
+	return nil
+}
+
+// linkEnds looks up both ends of a link. Linking changes the source node,
+// so it needs write access there, but only read access to the target.
+func linkEnds(store *storage.Storage, sess *auth.Session, fromRef, toRef string) (*node.Node, *node.Node, error) {
+	from, err := store.GetNodeByIDOrTitle(fromRef)
+	if err != nil {
+		return nil, nil, err
+	}
+	to, err := store.GetNodeByIDOrTitle(toRef)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := sess.CanNode(auth.PermNodesWrite, from.UNSAddress); err != nil {
+		return nil, nil, err
+	}
+	if err := sess.CanNode(auth.PermNodesRead, to.UNSAddress); err != nil {
+		return nil, nil, err
+	}
+	if from.ID == to.ID {
+		return nil, nil, errors.New("a node cannot link to itself")
+	}
+	return from, to, nil
+}
+
+// handleLinks shows the nodes a node receives material from and sends it
+// to: links <node-id or title>
+func handleLinks(store *storage.Storage, sess *auth.Session, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: links <node-id or title>")
+	}
+	n, err := store.GetNodeByIDOrTitle(strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+	if err := sess.CanNode(auth.PermNodesRead, n.UNSAddress); err != nil {
+		return err
+	}
+	nodes, err := store.Load()
+	if err != nil {
+		return err
+	}
+	visible, err := sess.Visible(nodes)
+	if err != nil {
+		return err
+	}
+
+	cyan := color.New(color.FgCyan).SprintFunc()
+	fmt.Printf("\n%s\n", cyan(fmt.Sprintf("Material flow of '%s':", n.Title)))
+	printNeighbors("Upstream", "←", node.Upstream(visible, n.ID))
+	printNeighbors("Downstream", "→", node.Downstream(visible, n))
+	fmt.Println()
+	return nil
+}
+
+func printNeighbors(heading, arrow string, neighbors []node.Neighbor) {
+	fmt.Printf("  %s:\n", heading)
+	if len(neighbors) == 0 {
+		fmt.Println("    (none)")
+		return
+	}
+	for _, nb := range neighbors {
+		fmt.Printf("    %s %s (%s)  %s\n", arrow, nb.Node.Title, nb.Link, nb.Node.UNSAddress)
+	}
+}
+
+// neighborTitles lists neighbors as "Title (kind)" for one-line summaries
+func neighborTitles(neighbors []node.Neighbor) string {
+	titles := make([]string, len(neighbors))
+	for i, nb := range neighbors {
+		titles[i] = fmt.Sprintf("%s (%s)", nb.Node.Title, nb.Link.Kind)
+	}
+	return strings.Join(titles, ", ")
+}
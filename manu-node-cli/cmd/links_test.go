@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+
+	"manu-node-cli/internal/node"
+)
+
+func TestLinkAndUnlink(t *testing.T) {
+	store, _, cleanup := setupTestCommands(t)
+	defer cleanup()
+
+	saw := node.NewNode("Saw", "", nil, "")
+	saw.ID = "saw"
+	cnc := node.NewNode("CNC", "", nil, "")
+	cnc.ID = "cnc"
+	for _, n := range []*node.Node{saw, cnc} {
+		if err := store.SaveNode(n); err != nil {
+			t.Fatalf("Failed to save node: %v", err)
+		}
+	}
+
+	if err := handleLink(store, nil, []string{"Saw", "CNC", "--kind", "buffer", "--capacity", "12"}); err != nil {
+		t.Fatalf("link failed: %v", err)
+	}
+	// Relinking changes only the given values
+	if err := handleLink(store, nil, []string{"Saw", "--transfer-time", "45s", "CNC"}); err != nil {
+		t.Fatalf("link failed: %v", err)
+	}
+	got, _ := store.GetNode("saw")
+	want := node.Link{To: "cnc", Kind: node.LinkBuffer, Capacity: 12, TransferTime: "45s"}
+	if len(got.Links) != 1 || got.Links[0] != want {
+		t.Fatalf("Expected %+v, got %+v", want, got.Links)
+	}
+
+	if err := handleLink(store, nil, []string{"Saw", "Saw"}); err == nil {
+		t.Error("Expected a link to itself to be rejected")
+	}
+	if err := handleLink(store, nil, []string{"Saw", "CNC", "--kind", "belt"}); err == nil {
+		t.Error("Expected an unknown kind to be rejected")
+	}
+	if err := store.DeleteNode("cnc"); err == nil {
+		t.Error("Expected a linked node not to be deletable")
+	}
+
+	if err := handleUnlink(store, nil, []string{"Saw", "CNC"}); err != nil {
+		t.Fatalf("unlink failed: %v", err)
+	}
+	if err := handleUnlink(store, nil, []string{"Saw", "CNC"}); err == nil {
+		t.Error("Expected unlinking a missing link to fail")
+	}
+	if got, _ := store.GetNode("saw"); len(got.Links) != 0 {
+		t.Errorf("Expected no links, got %+v", got.Links)
+	}
+}
@@ -1,7 +1,8 @@
 package main
 
 import (
-	"bufio"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,69 +11,91 @@ import (
 
 	"github.com/chzyer/readline"
 	"github.com/fatih/color"
+	"manu-node-cli/internal/attribute"
+	"manu-node-cli/internal/auth"
+	"manu-node-cli/internal/config"
+	"manu-node-cli/internal/labels"
 	"manu-node-cli/internal/node"
 	"manu-node-cli/internal/storage"
 )
 
-// nodeCompleter generates completion items for node IDs and titles
-func createNodeCompleter(store *storage.Storage) func(string) []string {
-	return func(line string) []string {
-		nodes, err := store.Load()
-		if err != nil {
-			return nil
-		}
-		
-		var suggestions []string
-		for _, n := range nodes {
-			// Add ID
-			suggestions = append(suggestions, n.ID)
-			// Add Title (if not empty)
-			if n.Title != "" {
-				suggestions = append(suggestions, n.Title)
-			}
-		}
-		return suggestions
-	}
-}
-
 func main() {
 	// Create color printers for nice output
 	cyan := color.New(color.FgCyan).SprintFunc()
 	yellow := color.New(color.FgYellow).SprintFunc()
 	red := color.New(color.FgRed).SprintFunc()
 
-	fmt.Println(cyan("=== Manufacturing Node Manager CLI ==="))
-	fmt.Println("Type 'help' for available commands")
-	fmt.Println()
+	// Resolve settings from config files, environment and global flags
+	overrides, args, err := config.ParseArgs(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", red("Error"), err)
+		os.Exit(2)
+	}
+	userFile, err := config.UserFile()
+	if err != nil && overrides.File == "" {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", yellow("Warning"), err)
+	}
+	cfg, err := config.Load(config.Options{
+		SystemFile: config.SystemFile,
+		UserFile:   userFile,
+		Getenv:     os.Getenv,
+		Overrides:  overrides,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", red("Error"), err)
+		os.Exit(1)
+	}
+	applyColor(cfg.Color)
 
-	// Initialize storage
-	dataDir := filepath.Join(".", "data")
-	store, err := storage.NewStorage(dataDir)
+	// Settings and contexts can be inspected and fixed without touching
+	// the catalog
+	if len(args) > 0 && (args[0] == "config" || args[0] == "context") {
+		if args[0] == "config" {
+			err = handleConfig(cfg, args[1:])
+		} else {
+			_, err = handleContext(cfg, args[1:])
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", red("Error"), err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Open the data directory of the selected context
+	ws, err := openWorkspace(cfg)
 	if err != nil {
-		fmt.Printf("%s: Failed to initialize storage: %v\n", red("Error"), err)
+		fmt.Printf("%s: %v\n", red("Error"), err)
 		os.Exit(1)
 	}
 
-	// Create completer
-	nodeCompleter := createNodeCompleter(store)
-	completer := readline.NewPrefixCompleter(
-		readline.PcItem("create"),
-		readline.PcItem("list"),
-		readline.PcItem("view", readline.PcItemDynamic(nodeCompleter)),
-		readline.PcItem("update", readline.PcItemDynamic(nodeCompleter)),
-		readline.PcItem("delete", readline.PcItemDynamic(nodeCompleter)),
-		readline.PcItem("clear"),
-		readline.PcItem("cls"),
-		readline.PcItem("help"),
-		readline.PcItem("exit"),
-		readline.PcItem("quit"),
-	)
+	// Run a single command non-interactively when arguments are given
+	if len(args) > 0 {
+		if err := runCommand(ws, args); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", red("Error"), err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Println(cyan("=== Manufacturing Node Manager CLI ==="))
+	fmt.Println("Type 'help' for available commands")
+	if cfg.Context != "" {
+		fmt.Printf("Context: %s\n", cfg.Context)
+	}
+	if ws.sess.User != nil {
+		fmt.Printf("Signed in as %s\n", ws.sess.Name())
+	}
+	fmt.Println()
+
+	// Complete commands, flags and values from the catalog
+	comp := &completer{workspace: ws, repl: true}
 
 	// Configure readline
 	rl, err := readline.NewEx(&readline.Config{
-		Prompt:          "\033[32m>>> \033[0m",
-		HistoryFile:     filepath.Join(dataDir, ".history"),
-		AutoComplete:    completer,
+		Prompt:          replPrompt(cfg),
+		HistoryFile:     filepath.Join(cfg.DataDir, ".history"),
+		AutoComplete:    commandLineCompleter(comp),
 		InterruptPrompt: "^C",
 		EOFPrompt:       "exit",
 	})
@@ -81,9 +104,15 @@ func main() {
 		os.Exit(1)
 	}
 	defer rl.Close()
+	installFuzzyTab(rl)
+	prompter := &readlinePrompter{rl: rl}
+	recorder := newSessionRecorder(ws.store)
 
 	// Main loop
 	for {
+		// Record what the previous command changed so it can be undone
+		recorder.end()
+
 		// Read user input
 		line, err := rl.Readline()
 		if err != nil { // io.EOF or user pressed Ctrl+C
@@ -93,39 +122,176 @@ func main() {
 		input := strings.TrimSpace(line)
 		
 		// Split input into command and arguments
-		parts := strings.Fields(input)
+		parts := splitArgs(input)
 		if len(parts) == 0 {
 			continue
 		}
 		
 		command := parts[0]
+		if command != "undo" && command != "redo" {
+			recorder.begin(input)
+		}
 		
 		// Handle commands
 		switch command {
 		case "help":
 			showHelp()
 		case "create":
-			handleCreate(store, rl)
+			if len(parts) > 1 {
+				if err := handleCreateFromTemplate(ws.store, ws.tmpls, ws.sess, parts[1:], prompter); err != nil {
+					fmt.Printf("%s: %v\n", red("Error"), err)
+				}
+				continue
+			}
+			handleCreate(ws.store, ws.attrs, ws.sess, prompter)
+		case "clone":
+			if err := handleClone(ws.store, ws.sess, parts[1:]); err != nil {
+				fmt.Printf("%s: %v\n", red("Error"), err)
+			}
+		case "template":
+			if err := handleTemplate(ws.store, ws.tmpls, ws.sess, parts[1:]); err != nil {
+				fmt.Printf("%s: %v\n", red("Error"), err)
+			}
 		case "list":
-			handleList(store)
+			if err := handleList(ws.cfg, ws.store, ws.attrs, ws.sess, parts[1:]); err != nil {
+				fmt.Printf("%s: %v\n", red("Error"), err)
+			}
 		case "view":
 			if len(parts) < 2 {
 				fmt.Println(red("Usage: view <node-id or title>"))
 				continue
 			}
-			handleView(store, strings.Join(parts[1:], " "))
+			handleView(ws.store, ws.attrs, ws.sess, strings.Join(parts[1:], " "))
 		case "update":
 			if len(parts) < 2 {
 				fmt.Println(red("Usage: update <node-id or title>"))
 				continue
 			}
-			handleUpdate(store, strings.Join(parts[1:], " "))
+			handleUpdate(ws.store, ws.attrs, ws.sess, strings.Join(parts[1:], " "), prompter)
+		case "edit":
+			if err := handleEdit(ws.store, ws.attrs, ws.sess, parts[1:]); err != nil {
+				fmt.Printf("%s: %v\n", red("Error"), err)
+			}
+		case "undo", "redo":
+			if err := handleUndo(ws.store, ws.backups, recorder, ws.sess, prompter, command == "redo"); err != nil {
+				fmt.Printf("%s: %v\n", red("Error"), err)
+			}
 		case "delete":
 			if len(parts) < 2 {
 				fmt.Println(red("Usage: delete <node-id or title>"))
 				continue
 			}
-			handleDelete(store, strings.Join(parts[1:], " "))
+			handleDelete(ws.store, ws.sess, strings.Join(parts[1:], " "), prompter)
+		case "find":
+			if err := handleFind(ws.store, ws.attrs, ws.sess, ws.cfg.Output, parts[1:]); err != nil {
+				fmt.Printf("%s: %v\n", red("Error"), err)
+			}
+		case "match":
+			if err := handleMatch(ws.store, ws.attrs, ws.sess, ws.cfg.Output, parts[1:]); err != nil {
+				fmt.Printf("%s: %v\n", red("Error"), err)
+			}
+		case "label":
+			if err := handleLabel(ws.store, ws.sess, parts[1:]); err != nil {
+				fmt.Printf("%s: %v\n", red("Error"), err)
+			}
+		case "link":
+			if err := handleLink(ws.store, ws.sess, parts[1:]); err != nil {
+				fmt.Printf("%s: %v\n", red("Error"), err)
+			}
+		case "unlink":
+			if err := handleUnlink(ws.store, ws.sess, parts[1:]); err != nil {
+				fmt.Printf("%s: %v\n", red("Error"), err)
+			}
+		case "links":
+			if err := handleLinks(ws.store, ws.sess, parts[1:]); err != nil {
+				fmt.Printf("%s: %v\n", red("Error"), err)
+			}
+		case "operator":
+			if err := handleOperator(ws.store, ws.ops, ws.sess, parts[1:]); err != nil {
+				fmt.Printf("%s: %v\n", red("Error"), err)
+			}
+		case "calibration":
+			if err := handleCalibration(ws.store, ws.sess, parts[1:]); err != nil {
+				fmt.Printf("%s: %v\n", red("Error"), err)
+			}
+		case "maintenance":
+			if err := handleMaintenance(ws.store, ws.maint, ws.sess, parts[1:]); err != nil {
+				fmt.Printf("%s: %v\n", red("Error"), err)
+			}
+		case "availability":
+			if err := handleAvailability(ws.store, ws.maint, ws.sess, parts[1:]); err != nil {
+				fmt.Printf("%s: %v\n", red("Error"), err)
+			}
+		case "bulk-update":
+			if err := handleBulkUpdate(ws.store, ws.attrs, ws.backups, ws.sess, parts[1:], prompter); err != nil {
+				fmt.Printf("%s: %v\n", red("Error"), err)
+			}
+		case "import":
+			if err := handleImport(ws.store, ws.backups, ws.sess, parts[1:]); err != nil {
+				fmt.Printf("%s: %v\n", red("Error"), err)
+			}
+		case "export":
+			if err := handleExport(ws.store, ws.sess, parts[1:]); err != nil {
+				fmt.Printf("%s: %v\n", red("Error"), err)
+			}
+		case "export-graph":
+			if err := handleExportGraph(ws.store, ws.sess, parts[1:]); err != nil {
+				fmt.Printf("%s: %v\n", red("Error"), err)
+			}
+		case "plan":
+			if err := handlePlan(ws.store, ws.attrs, ws.sess, parts[1:]); err != nil {
+				fmt.Printf("%s: %v\n", red("Error"), err)
+			}
+		case "apply":
+			if err := handleApply(ws.store, ws.attrs, ws.backups, ws.sess, parts[1:]); err != nil {
+				fmt.Printf("%s: %v\n", red("Error"), err)
+			}
+		case "attr":
+			if err := handleAttr(ws.store, ws.attrs, ws.sess, parts[1:]); err != nil {
+				fmt.Printf("%s: %v\n", red("Error"), err)
+			}
+		case "migrate":
+			if err := handleMigrate(ws.store, ws.sess, parts[1:]); err != nil {
+				fmt.Printf("%s: %v\n", red("Error"), err)
+			}
+		case "backup":
+			if err := handleBackup(ws.backups, ws.sess, parts[1:]); err != nil {
+				fmt.Printf("%s: %v\n", red("Error"), err)
+			}
+		case "user":
+			if err := handleUser(ws.authz, ws.sess, parts[1:]); err != nil {
+				fmt.Printf("%s: %v\n", red("Error"), err)
+			}
+		case "token":
+			if err := handleToken(ws.authz, ws.sess, parts[1:]); err != nil {
+				fmt.Printf("%s: %v\n", red("Error"), err)
+			}
+		case "context":
+			next, err := handleContext(ws.cfg, parts[1:])
+			if err != nil {
+				fmt.Printf("%s: %v\n", red("Error"), err)
+				continue
+			}
+			if next == nil {
+				continue
+			}
+			opened, err := openWorkspace(next)
+			if err != nil {
+				fmt.Printf("%s: %v; staying in the current workspace\n", red("Error"), err)
+				continue
+			}
+			ws = opened
+			comp.workspace = ws
+			// Undo steps belong to the catalog they were recorded on
+			recorder = newSessionRecorder(ws.store)
+			applyColor(ws.cfg.Color)
+			setPrompt(rl, replPrompt(ws.cfg))
+		case "config":
+			if err := handleConfig(ws.cfg, parts[1:]); err != nil {
+				fmt.Printf("%s: %v\n", red("Error"), err)
+			} else if len(parts) > 1 && parts[1] != "view" {
+				fmt.Println("Changes take effect the next time the CLI starts.")
+			}
 		case "clear", "cls":
 			handleClear()
 		case "exit", "quit":
@@ -137,16 +303,125 @@ func main() {
 	}
 }
 
+// runCommand executes a command given on the command line
+func runCommand(ws *workspace, args []string) error {
+	switch args[0] {
+	case "serve":
+		return handleServe(ws.store, ws.attrs, ws.authz, ws.cfg.API.Addr, args[1:])
+	case "create":
+		return handleCreateFromTemplate(ws.store, ws.tmpls, ws.sess, args[1:], newLinePrompter(os.Stdin, os.Stdout))
+	case "clone":
+		return handleClone(ws.store, ws.sess, args[1:])
+	case "template":
+		return handleTemplate(ws.store, ws.tmpls, ws.sess, args[1:])
+	case "edit":
+		return handleEdit(ws.store, ws.attrs, ws.sess, args[1:])
+	case "find":
+		return handleFind(ws.store, ws.attrs, ws.sess, ws.cfg.Output, args[1:])
+	case "match":
+		return handleMatch(ws.store, ws.attrs, ws.sess, ws.cfg.Output, args[1:])
+	case "label":
+		return handleLabel(ws.store, ws.sess, args[1:])
+	case "link":
+		return handleLink(ws.store, ws.sess, args[1:])
+	case "unlink":
+		return handleUnlink(ws.store, ws.sess, args[1:])
+	case "links":
+		return handleLinks(ws.store, ws.sess, args[1:])
+	case "operator":
+		return handleOperator(ws.store, ws.ops, ws.sess, args[1:])
+	case "calibration":
+		return handleCalibration(ws.store, ws.sess, args[1:])
+	case "maintenance":
+		return handleMaintenance(ws.store, ws.maint, ws.sess, args[1:])
+	case "availability":
+		return handleAvailability(ws.store, ws.maint, ws.sess, args[1:])
+	case "bulk-update":
+		return handleBulkUpdate(ws.store, ws.attrs, ws.backups, ws.sess, args[1:], newLinePrompter(os.Stdin, os.Stdout))
+	case "import":
+		return handleImport(ws.store, ws.backups, ws.sess, args[1:])
+	case "export":
+		return handleExport(ws.store, ws.sess, args[1:])
+	case "export-graph":
+		return handleExportGraph(ws.store, ws.sess, args[1:])
+	case "plan":
+		return handlePlan(ws.store, ws.attrs, ws.sess, args[1:])
+	case "apply":
+		return handleApply(ws.store, ws.attrs, ws.backups, ws.sess, args[1:])
+	case "attr":
+		return handleAttr(ws.store, ws.attrs, ws.sess, args[1:])
+	case "migrate":
+		return handleMigrate(ws.store, ws.sess, args[1:])
+	case "backup":
+		return handleBackup(ws.backups, ws.sess, args[1:])
+	case "user":
+		return handleUser(ws.authz, ws.sess, args[1:])
+	case "token":
+		return handleToken(ws.authz, ws.sess, args[1:])
+	case "list":
+		return handleList(ws.cfg, ws.store, ws.attrs, ws.sess, args[1:])
+	case "tui":
+		return handleTUI(ws.cfg, ws.store, ws.attrs, ws.sess, args[1:])
+	case "completion":
+		return handleCompletion(args[1:])
+	case "__complete":
+		comp := &completer{workspace: ws}
+		return handleCompleteRequest(comp, args[1:])
+	case "help", "-h", "--help":
+		showHelp()
+		return nil
+	default:
+		return fmt.Errorf("unknown command: %s", args[0])
+	}
+}
+
 func showHelp() {
 	fmt.Println("\nAvailable commands:")
-	fmt.Println("  create  - Create a new manufacturing node")
-	fmt.Println("  list    - List all nodes")
+	fmt.Println("  create  - Create a new manufacturing node: create [--from-template <name> --var key=value ...]")
+	fmt.Println("  clone   - Copy a node: clone <node> --title T [--uns path]")
+	fmt.Println("  template - Node templates: template save <node> <name> [--title pattern] [--uns pattern] | list | show <name> | remove <name>")
+	fmt.Println("  list    - List all nodes: list [-l 'dept=woodshop,!retired'] [--attr 'power>=5' ...] [-o table|json|yaml] [--all-contexts]")
+	fmt.Println("  find    - Search nodes: find [text] [-l selector] [--attr expr] [-o table|json|yaml]")
+	fmt.Println("  match   - Find nodes or node combinations that can run operations in order: match 'cut, drill, edge-band' [--uns-prefix line] [--same-line] [--capacity-attr name] [--limit N]")
 	fmt.Println("  view    - View details of a specific node")
 	fmt.Println("  update  - Update a node")
+	fmt.Println("  edit    - Edit a node as YAML in $EDITOR: edit <node-id or title>")
 	fmt.Println("  delete  - Delete a node")
+	fmt.Println("  undo    - Revert the last change made in this session")
+	fmt.Println("  redo    - Reapply the last undone change")
+	fmt.Println("  label   - Set or remove labels: label <node> key=value ... key-")
+	fmt.Println("  link    - Connect nodes by material flow: link <from> <to> [--kind conveyor|buffer|manual] [--capacity N] [--transfer-time 90s]")
+	fmt.Println("  unlink  - Remove a material flow link: unlink <from> <to>")
+	fmt.Println("  links   - Show the upstream and downstream neighbors of a node: links <node-id or title>")
+	fmt.Println("  operator - Operators, certifications and required skills: operator add <name> [--shift S] | update <name> --shift S | remove <name> | list | certify <name> --skill S [--expires date] | revoke <name> --skill S | shift <name> --start HH:MM --end HH:MM | require <operation> --skill S ... [--node N] | requirements | qualified <operation> --node N [--shift S] | check <name> <operation> --node N")
+	fmt.Println("  calibration - Instrument calibrations: calibration add <node> --instrument I --certificate C (--next-due date | --interval 365d) [--date date] [--result within|out] | list [<node>] | due [--within 30d]")
+	fmt.Println("  maintenance - Preventive maintenance: maintenance plan <node> --name N (--every 30d | --hours N | --cycles N) [--duration 2h] | plans | remove <plan-id> | meter <node> --hours N | due [--within 7d] [<node>] | schedule <task-id> --at 'YYYY-MM-DD HH:MM' | done <task-id> --technician T [--notes text] | history <node>")
+	fmt.Println("  availability - Show a node's time left after planned maintenance: availability <node> [--within 7d]")
+	fmt.Println("  bulk-update - Edit many nodes: bulk-update --where <selector> [--uns-prefix path] [--add-op X] [--remove-op Y] [--set field=value] [--dry-run] [--yes]")
+	fmt.Println("  import  - Import nodes from CSV/XLSX/B2MML: import <file> [--dry-run] [--map title=Machine,...]")
+	fmt.Println("  export  - Export nodes to CSV/XLSX/YAML/B2MML: export <file> [--uns-prefix path]")
+	fmt.Println("  export-graph - Draw the plant layout with material flow: export-graph --format dot|mermaid [--uns-prefix path] [--out file]")
+	fmt.Println("  plan    - Show changes needed to match YAML/JSON manifests: plan -f <dir> [--prune]")
+	fmt.Println("  apply   - Apply YAML/JSON manifests to storage: apply -f <dir> [--prune]")
+	fmt.Println("  attr    - Manage custom attributes: attr define <name> --type T [--unit U] [--required] [--default V] [--value V ...] | list | remove <name>")
+	fmt.Println("  migrate - Upgrade nodes.json to the current schema: migrate [--dry-run]")
+	fmt.Println("  backup  - Snapshot the data directory: backup create [--note text] | list | restore <id> (keeps users)")
+	fmt.Println("  user    - Manage users: user add <name> --role R [--scope 'Site/Area/#'] | list | remove <name>")
+	fmt.Println("  token   - Manage API tokens: token create <user> | revoke <token-id>")
+	fmt.Println("  context - Switch between plants/workspaces: context [list] | create <name> [--data-dir dir] [--use] | use <name> | remove <name>")
+	fmt.Println("  config  - Show or change settings of the current context: config view | set [--global] <key> <value> | unset [--global] <key>")
 	fmt.Println("  clear   - Clear the screen")
+	fmt.Println("  Tab completes commands, flags, nodes, UNS paths and operations; unmatched words are fuzzy-matched")
 	fmt.Println("  help    - Show this help message")
 	fmt.Println("  exit    - Exit the program")
+	fmt.Println("\nCommand-line usage:")
+	fmt.Println("  manu-node-cli [--config file] [--context name] [--data-dir dir] [--output format] [--no-color] ... <command>  - Override settings for one run")
+	fmt.Println("  manu-node-cli tui  - Browse and edit nodes in a full-screen UNS tree (press ? for keys)")
+	fmt.Println("  manu-node-cli serve [--addr :8080]  - Serve the REST API (/api/v1/nodes)")
+	fmt.Println("  manu-node-cli completion bash|zsh|fish  - Print a shell completion script")
+	fmt.Println("\nSettings are read from /etc/manu-node/config.yaml, then $XDG_CONFIG_HOME/manu-node/config.yaml,")
+	fmt.Println("then the current context, then MANU_NODE_* environment variables (e.g. MANU_NODE_DATA_DIR), then global flags.")
+	fmt.Println("\nOnce users exist, set MANU_NODE_TOKEN to your API token to use the CLI.")
 	fmt.Println()
 }
 
@@ -156,109 +431,43 @@ func handleClear() {
 	fmt.Print("\033[H")
 }
 
-func handleCreate(store *storage.Storage, rl *readline.Instance) {
+func handleCreate(store *storage.Storage, attrs *attribute.Store, sess *auth.Session, p Prompter) {
 	red := color.New(color.FgRed).SprintFunc()
 	green := color.New(color.FgGreen).SprintFunc()
 	yellow := color.New(color.FgYellow).SprintFunc()
 	
-	fmt.Println("\n" + yellow("Creating new node (Press Ctrl+C to cancel)"))
-	
-	// Temporarily change prompt for input
-	oldPrompt := rl.Config.Prompt
-	defer func() { rl.SetPrompt(oldPrompt) }()
-	
-	// Get title
-	rl.SetPrompt("Node title: ")
-	title, err := rl.Readline()
-	if err != nil { // User pressed Ctrl+C
-		fmt.Println(red("\nCancelled"))
-		return
-	}
-	title = strings.TrimSpace(title)
-	
-	// Validate title
-	if title == "" {
-		fmt.Printf("%s: Title cannot be empty\n", red("Error"))
-		return
-	}
-	
-	// Check for control characters or non-printable characters
-	if !isValidInput(title) {
-		fmt.Printf("%s: Title contains invalid characters\n", red("Error"))
-		return
-	}
-	
-	// Check if title is unique
-	unique, err := store.IsTitleUnique(title, "")
-	if err != nil {
-		fmt.Printf("%s: Failed to check title uniqueness: %v\n", red("Error"), err)
-		return
-	}
-	if !unique {
-		fmt.Printf("%s: A node with title '%s' already exists\n", red("Error"), title)
+	// Fail early instead of after all prompts were answered
+	if err := sess.Can(auth.PermNodesWrite); err != nil {
+		fmt.Printf("%s: %v\n", red("Error"), err)
 		return
 	}
-	
-	// Get description
-	rl.SetPrompt("Description: ")
-	description, err := rl.Readline()
+	defs, err := attrs.Load()
 	if err != nil {
-		fmt.Println(red("\nCancelled"))
-		return
-	}
-	description = strings.TrimSpace(description)
-	
-	// Validate description if not empty
-	if description != "" && !isValidInput(description) {
-		fmt.Printf("%s: Description contains invalid characters\n", red("Error"))
+		fmt.Printf("%s: %v\n", red("Error"), err)
 		return
 	}
+
+	fmt.Println("\n" + yellow("Creating new node (Press Ctrl+C to cancel)"))
 	
-	// Get operations
-	rl.SetPrompt("Operations (comma-separated): ")
-	opsInput, err := rl.Readline()
+	form, err := askNodeFields(store, sess, p, &node.Node{})
 	if err != nil {
 		fmt.Println(red("\nCancelled"))
 		return
 	}
-	opsInput = strings.TrimSpace(opsInput)
-	var operations []string
-	if opsInput != "" {
-		operations = strings.Split(opsInput, ",")
-		for i := range operations {
-			operations[i] = strings.TrimSpace(operations[i])
-		}
-		// Remove any empty strings from the operations and validate
-		var filtered []string
-		for _, op := range operations {
-			if op != "" {
-				if !isValidInput(op) {
-					fmt.Printf("%s: Operation '%s' contains invalid characters\n", red("Error"), op)
-					return
-				}
-				filtered = append(filtered, op)
-			}
-		}
-		operations = filtered
-	}
-	
-	// Get UNS address
-	rl.SetPrompt("UNS Address (e.g., Site/Area/Line/Cell): ")
-	unsAddress, err := rl.Readline()
+	values, err := promptAttributes(defs, nil, p)
 	if err != nil {
 		fmt.Println(red("\nCancelled"))
 		return
 	}
-	unsAddress = strings.TrimSpace(unsAddress)
-	
-	// Validate UNS address if not empty
-	if unsAddress != "" && !isValidInput(unsAddress) {
-		fmt.Printf("%s: UNS Address contains invalid characters\n", red("Error"))
-		return
-	}
-	
+
 	// Create the node
-	newNode := node.NewNode(title, description, operations, unsAddress)
+	newNode := node.NewNode(form.Title, form.Description, form.Operations, form.UNSAddress)
+	newNode.ID = node.UniqueID(func(id string) bool {
+		_, err := store.GetNode(id)
+		return err == nil
+	})
+	newNode.Attributes = values
+	newNode.Labels = form.Labels
 	
 	// Save to storage
 	if err := store.SaveNode(newNode); err != nil {
@@ -271,20 +480,114 @@ func handleCreate(store *storage.Storage, rl *readline.Instance) {
 	fmt.Printf("Title: %s\n\n", newNode.Title)
 }
 
-func handleList(store *storage.Storage) {
-	red := color.New(color.FgRed).SprintFunc()
+// askNodeFields asks for the editable fields of a node, offering the
+// values of current for in-place editing. Each answer is validated before
+// moving on, and only the title, description, operations, UNS address and
+// labels of the returned node are set.
+func askNodeFields(store *storage.Storage, sess *auth.Session, p Prompter, current *node.Node) (*node.Node, error) {
+	title, err := askText(p, "Title", current.Title, func(title string) error {
+		if title == "" {
+			return errors.New("title cannot be empty")
+		}
+		unique, err := store.IsTitleUnique(title, current.ID)
+		if err != nil {
+			return fmt.Errorf("failed to check title uniqueness: %w", err)
+		}
+		if !unique {
+			return fmt.Errorf("a node with title '%s' already exists", title)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	description, err := askText(p, "Description", current.Description, nil)
+	if err != nil {
+		return nil, err
+	}
+	// Offer the operations and UNS paths already in use
+	known, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	if known, err = sess.Visible(known); err != nil {
+		return nil, err
+	}
+	opsPrompt := withCompletion(p, fieldCompleter(func(string) []string { return operationNames(known) }, true))
+	unsPrompt := withCompletion(p, fieldCompleter(func(word string) []string { return unsCandidates(known, word) }, false))
+
+	operations, err := askList(opsPrompt, "Operations (comma-separated)", current.Operations)
+	if err != nil {
+		return nil, err
+	}
+	unsAddress, err := askText(unsPrompt, "UNS Address (e.g., Site/Area/Line/Cell)", current.UNSAddress, func(uns string) error {
+		return sess.CanNode(auth.PermNodesWrite, uns)
+	})
+	if err != nil {
+		return nil, err
+	}
+	var nodeLabels map[string]string
+	_, err = askText(p, "Labels (key=value, comma-separated)", labels.Format(current.Labels), func(input string) error {
+		var err error
+		nodeLabels, err = labels.Parse(input)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &node.Node{
+		Title:       title,
+		Description: description,
+		Operations:  operations,
+		UNSAddress:  unsAddress,
+		Labels:      nodeLabels,
+	}, nil
+}
+
+func handleList(cfg *config.Loaded, store *storage.Storage, attrs *attribute.Store, sess *auth.Session, args []string) error {
 	cyan := color.New(color.FgCyan).SprintFunc()
+
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	var qf queryFlags
+	qf.register(fs)
+	output := cfg.Output
+	registerOutput(fs, &output)
+	allContexts := fs.Bool("all-contexts", false, "list the nodes of every context")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *allContexts {
+		return listAllContexts(cfg, &qf, output)
+	}
+	query, err := qf.build(attrs, "")
+	if err != nil {
+		return err
+	}
 	
 	nodes, err := store.Load()
 	if err != nil {
-		fmt.Printf("%s: Failed to load nodes: %v\n", red("Error"), err)
-		return
+		return fmt.Errorf("failed to load nodes: %w", err)
+	}
+	if nodes, err = sess.Visible(nodes); err != nil {
+		return err
+	}
+	if !query.empty() {
+		nodes = query.filter(nodes)
+	}
+	if handled, err := printNodesAs(output, nodes); handled {
+		return err
+	}
+	if len(nodes) == 0 && !query.empty() {
+		fmt.Println("\nNo nodes match the filter.")
+		fmt.Println()
+		return nil
 	}
 	
 	if len(nodes) == 0 {
 		fmt.Println("\nNo nodes found. Create some nodes first!")
 		fmt.Println()
-		return
+		return nil
 	}
 	
 	// Display header
@@ -301,9 +604,10 @@ func handleList(store *storage.Storage) {
 			truncate(n.UNSAddress, 33))
 	}
 	fmt.Println()
+	return nil
 }
 
-func handleView(store *storage.Storage, identifier string) {
+func handleView(store *storage.Storage, attrs *attribute.Store, sess *auth.Session, identifier string) {
 	red := color.New(color.FgRed).SprintFunc()
 	cyan := color.New(color.FgCyan).SprintFunc()
 	
@@ -312,6 +616,10 @@ func handleView(store *storage.Storage, identifier string) {
 		fmt.Printf("%s: %v\n", red("Error"), err)
 		return
 	}
+	if err := sess.CanNode(auth.PermNodesRead, n.UNSAddress); err != nil {
+		fmt.Printf("%s: %v\n", red("Error"), err)
+		return
+	}
 	
 	fmt.Println("\n" + cyan("Node Details:"))
 	fmt.Println(strings.Repeat("-", 60))
@@ -320,12 +628,35 @@ func handleView(store *storage.Storage, identifier string) {
 	fmt.Printf("Description: %s\n", n.Description)
 	fmt.Printf("UNS Address: %s\n", n.UNSAddress)
 	fmt.Printf("Operations:  %s\n", strings.Join(n.Operations, ", "))
+	if len(n.Labels) > 0 {
+		fmt.Printf("Labels:      %s\n", labels.Format(n.Labels))
+	}
+	if defs, err := attrs.Load(); err == nil {
+		printAttributes(defs, n.Attributes)
+	}
+	if nodes, err := store.Load(); err == nil {
+		if visible, err := sess.Visible(nodes); err == nil {
+			if up := node.Upstream(visible, n.ID); len(up) > 0 {
+				fmt.Printf("Upstream:    %s\n", neighborTitles(up))
+			}
+			if down := node.Downstream(visible, n); len(down) > 0 {
+				fmt.Printf("Downstream:  %s\n", neighborTitles(down))
+			}
+		}
+	}
+	for _, c := range n.CurrentCalibrations() {
+		status := c.Status(time.Now())
+		if status != "ok" {
+			status = red(status)
+		}
+		fmt.Printf("Calibration: %s, next due %s (%s)\n", c.Instrument, c.NextDue.Format("2006-01-02"), status)
+	}
 	fmt.Printf("Created:     %s\n", n.CreatedAt.Format(time.RFC3339))
 	fmt.Printf("Updated:     %s\n", n.UpdatedAt.Format(time.RFC3339))
 	fmt.Println()
 }
 
-func handleUpdate(store *storage.Storage, identifier string) {
+func handleUpdate(store *storage.Storage, attrs *attribute.Store, sess *auth.Session, identifier string, p Prompter) {
 	red := color.New(color.FgRed).SprintFunc()
 	green := color.New(color.FgGreen).SprintFunc()
 	yellow := color.New(color.FgYellow).SprintFunc()
@@ -336,80 +667,43 @@ func handleUpdate(store *storage.Storage, identifier string) {
 		fmt.Printf("%s: %v\n", red("Error"), err)
 		return
 	}
-	
-	scanner := bufio.NewScanner(os.Stdin)
-	
-	fmt.Printf("\nUpdating node: %s\n", existing.Title)
-	fmt.Println(yellow("Press Enter to keep current value"))
-	
-	// Update title
-	fmt.Printf("Title [%s]: ", existing.Title)
-	scanner.Scan()
-	title := strings.TrimSpace(scanner.Text())
-	if title == "" {
-		title = existing.Title
-	} else if title != existing.Title {
-		// Check if new title is unique
-		unique, err := store.IsTitleUnique(title, existing.ID)
-		if err != nil {
-			fmt.Printf("%s: Failed to check title uniqueness: %v\n", red("Error"), err)
-			return
-		}
-		if !unique {
-			fmt.Printf("%s: A node with title '%s' already exists\n", red("Error"), title)
-			return
-		}
+	if err := sess.CanNode(auth.PermNodesWrite, existing.UNSAddress); err != nil {
+		fmt.Printf("%s: %v\n", red("Error"), err)
+		return
 	}
-	
-	// Update description
-	fmt.Printf("Description [%s]: ", existing.Description)
-	scanner.Scan()
-	description := scanner.Text()
-	if description == "" {
-		description = existing.Description
+	defs, err := attrs.Load()
+	if err != nil {
+		fmt.Printf("%s: %v\n", red("Error"), err)
+		return
 	}
 	
-	// Update operations
-	fmt.Printf("Operations [%s]: ", strings.Join(existing.Operations, ", "))
-	scanner.Scan()
-	opsInput := strings.TrimSpace(scanner.Text())
-	operations := existing.Operations
-	if opsInput != "" {
-		operations = strings.Split(opsInput, ",")
-		for i := range operations {
-			operations[i] = strings.TrimSpace(operations[i])
-		}
-		// Remove any empty strings from the operations and validate
-		var filtered []string
-		for _, op := range operations {
-			if op != "" {
-				if !isValidInput(op) {
-					fmt.Printf("%s: Operation '%s' contains invalid characters\n", red("Error"), op)
-					return
-				}
-				filtered = append(filtered, op)
-			}
-		}
-		operations = filtered
-	}
+	fmt.Printf("\nUpdating node: %s\n", existing.Title)
+	fmt.Println(yellow("Edit the current values (Press Ctrl+C to cancel)"))
 	
-	// Update UNS address
-	fmt.Printf("UNS Address [%s]: ", existing.UNSAddress)
-	scanner.Scan()
-	unsAddress := scanner.Text()
-	if unsAddress == "" {
-		unsAddress = existing.UNSAddress
+	form, err := askNodeFields(store, sess, p, existing)
+	if err != nil {
+		fmt.Println(red("\nCancelled"))
+		return
+	}
+	values, err := promptAttributes(defs, existing.Attributes, p)
+	if err != nil {
+		fmt.Println(red("\nCancelled"))
+		return
 	}
 	
 	// Create updated node
 	updated := &node.Node{
-		ID:          existing.ID,
-		Title:       title,
-		Description: description,
-		Operations:  operations,
-		UNSAddress:  unsAddress,
-		CreatedAt:   existing.CreatedAt,
-		UpdatedAt:   time.Now(),
+		ID:           existing.ID,
+		Title:        form.Title,
+		Description:  form.Description,
+		Operations:   form.Operations,
+		UNSAddress:   form.UNSAddress,
+		CreatedAt:    existing.CreatedAt,
+		UpdatedAt:    time.Now(),
+		Attributes:   values,
+		Labels:       form.Labels,
+		Links:        existing.Links,
+		Calibrations: existing.Calibrations,
 	}
 	
 	// Save updated node
@@ -421,7 +715,7 @@ func handleUpdate(store *storage.Storage, identifier string) {
 	fmt.Printf("\n%s Node updated successfully!\n", green("✓"))
 }
 
-func handleDelete(store *storage.Storage, identifier string) {
+func handleDelete(store *storage.Storage, sess *auth.Session, identifier string, p Prompter) {
 	red := color.New(color.FgRed).SprintFunc()
 	green := color.New(color.FgGreen).SprintFunc()
 	yellow := color.New(color.FgYellow).SprintFunc()
@@ -432,15 +726,15 @@ func handleDelete(store *storage.Storage, identifier string) {
 		fmt.Printf("%s: %v\n", red("Error"), err)
 		return
 	}
+	if err := sess.CanNode(auth.PermNodesWrite, n.UNSAddress); err != nil {
+		fmt.Printf("%s: %v\n", red("Error"), err)
+		return
+	}
 	
 	// Confirm deletion
-	scanner := bufio.NewScanner(os.Stdin)
-	fmt.Printf("\n%s Delete node '%s' (ID: %s)? [y/N]: ", 
-		yellow("Warning:"), n.Title, n.ID)
-	scanner.Scan()
-	confirm := strings.ToLower(strings.TrimSpace(scanner.Text()))
-	
-	if confirm != "y" && confirm != "yes" {
+	fmt.Println()
+	ok, err := askConfirm(p, fmt.Sprintf("%s Delete node '%s' (ID: %s)?", yellow("Warning:"), n.Title, n.ID))
+	if err != nil || !ok {
 		fmt.Println("Deletion cancelled.")
 		return
 	}
@@ -455,15 +749,61 @@ func handleDelete(store *storage.Storage, identifier string) {
 }
 
 // Helper functions
+
+// updateNodeIfUnchanged applies edit to the stored copy of n, refusing if
+// the node changed since it was read
+func updateNodeIfUnchanged(store *storage.Storage, n *node.Node, edit func(*node.Node)) error {
+	etag := n.ETag()
+	return store.Transaction(func(nodes []*node.Node) ([]*node.Node, error) {
+		for _, other := range nodes {
+			if other.ID != n.ID {
+				continue
+			}
+			if other.ETag() != etag {
+				return nil, storage.ErrPreconditionFailed
+			}
+			edit(other)
+			other.UpdatedAt = time.Now()
+			return nodes, nil
+		}
+		return nil, fmt.Errorf("node with ID %s not found", n.ID)
+	})
+}
+
 func isValidInput(s string) bool {
 	// Check if string contains only printable characters
-	for _, r := range s {
-		// Allow printable ASCII and common Unicode characters
-		if r < 32 || r == 127 { // Control characters
-			return false
+	return node.IsValidText(s)
+}
+
+// splitArgs splits a command line on whitespace, keeping single- or
+// double-quoted sections together
+func splitArgs(input string) []string {
+	var args []string
+	var current strings.Builder
+	var quote rune
+	inArg := false
+	for _, r := range input {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+			inArg = true
+		case quote == 0 && (r == ' ' || r == '\t'):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
 		}
 	}
-	return true
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args
 }
 
 func truncate(s string, length int) string {
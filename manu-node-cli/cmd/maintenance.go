@@ -0,0 +1,457 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"manu-node-cli/internal/auth"
+	"manu-node-cli/internal/maintenance"
+	"manu-node-cli/internal/node"
+	"manu-node-cli/internal/storage"
+)
+
+const maintenanceUsage = "usage: maintenance plan <node> --name N (--every 30d | --hours N | --cycles N) | plans [<node>] | remove <plan-id> | " +
+	"meter <node> (--hours N | --cycles N) | due [--within 7d] [<node>] | schedule <task-id> --at 'YYYY-MM-DD HH:MM' | " +
+	"done <task-id> --technician T [--notes text] | history <node>"
+
+// handleMaintenance manages preventive maintenance plans and their tasks
+func handleMaintenance(store *storage.Storage, maint *maintenance.Store, sess *auth.Session, args []string) error {
+	if len(args) == 0 {
+		return errors.New(maintenanceUsage)
+	}
+	green := color.New(color.FgGreen).SprintFunc()
+
+	switch args[0] {
+	case "plan":
+		fs := flag.NewFlagSet("maintenance plan", flag.ContinueOnError)
+		name := fs.String("name", "", "what the maintenance is, e.g. 'Lubricate spindle'")
+		every := fs.String("every", "", "calendar interval, e.g. 30d or 2w")
+		hours := fs.Float64("hours", 0, "runtime hours between services")
+		cycles := fs.Float64("cycles", 0, "cycles between services")
+		duration := fs.String("duration", "", "how long the node is out of service (default 2h)")
+		start := fs.String("start", "", "first due date of a calendar plan (default: one interval from now)")
+		ref, err := parseWithName(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		n, err := maintenanceNode(store, sess, auth.PermWorkOrdersWrite, ref)
+		if err != nil {
+			return err
+		}
+
+		p := &maintenance.Plan{NodeID: n.ID, Name: *name, Duration: *duration}
+		triggers := 0
+		if *every != "" {
+			p.Trigger, p.Every = maintenance.TriggerCalendar, *every
+			triggers++
+		}
+		if *hours != 0 {
+			p.Trigger, p.Interval = maintenance.TriggerRuntime, *hours
+			triggers++
+		}
+		if *cycles != 0 {
+			p.Trigger, p.Interval = maintenance.TriggerCycles, *cycles
+			triggers++
+		}
+		if triggers != 1 {
+			return errors.New("give exactly one trigger: --every, --hours or --cycles")
+		}
+		var first time.Time
+		if *start != "" {
+			if p.Trigger != maintenance.TriggerCalendar {
+				return errors.New("--start only applies to calendar plans")
+			}
+			if first, err = maintenance.ParseTime(*start); err != nil {
+				return err
+			}
+		}
+
+		var task *maintenance.Task
+		var due string
+		err = maint.Update(func(s *maintenance.Schedule) error {
+			task, err = s.AddPlan(p, time.Now(), first)
+			due = describeDue(s, task)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s Plan %s '%s' on '%s' (%s); task %s due %s\n", green("✓"), p.ID, p.Name, n.Title, p.Describe(), task.ID, due)
+		return nil
+
+	case "plans":
+		if len(args) > 2 {
+			return errors.New("usage: maintenance plans [<node>]")
+		}
+		nodeID := ""
+		if len(args) == 2 {
+			n, err := maintenanceNode(store, sess, auth.PermNodesRead, args[1])
+			if err != nil {
+				return err
+			}
+			nodeID = n.ID
+		}
+		s, nodes, err := loadMaintenance(store, maint, sess)
+		if err != nil {
+			return err
+		}
+		var plans []*maintenance.Plan
+		for _, p := range s.NodePlans(nodeID) {
+			if nodes[p.NodeID] != nil {
+				plans = append(plans, p)
+			}
+		}
+		if len(plans) == 0 {
+			fmt.Println("No maintenance plans. Add one with 'maintenance plan'.")
+			return nil
+		}
+		fmt.Printf("%-6s %-20s %-26s %-18s %-9s %s\n", "ID", "Node", "Name", "Trigger", "Duration", "Next due")
+		fmt.Println(strings.Repeat("-", 90))
+		for _, p := range plans {
+			fmt.Printf("%-6s %-20s %-26s %-18s %-9s %s\n", p.ID, truncate(nodes[p.NodeID].Title, 20), truncate(p.Name, 26),
+				p.Describe(), p.Duration, describeDue(s, s.OpenTask(p.ID)))
+		}
+		return nil
+
+	case "remove":
+		if len(args) != 2 {
+			return errors.New("usage: maintenance remove <plan-id>")
+		}
+		var removed *maintenance.Plan
+		err := maint.Update(func(s *maintenance.Schedule) error {
+			p, err := s.Plan(args[1])
+			if err != nil {
+				return err
+			}
+			if err := canMaintain(store, sess, p.NodeID); err != nil {
+				return err
+			}
+			removed, err = s.RemovePlan(p.ID)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s Removed plan %s '%s'; completed tasks stay in the history\n", green("✓"), removed.ID, removed.Name)
+		return nil
+
+	case "meter":
+		fs := flag.NewFlagSet("maintenance meter", flag.ContinueOnError)
+		hours := fs.Float64("hours", -1, "runtime hours counter")
+		cycles := fs.Float64("cycles", -1, "cycle counter")
+		ref, err := parseWithName(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		n, err := maintenanceNode(store, sess, auth.PermWorkOrdersWrite, ref)
+		if err != nil {
+			return err
+		}
+		var readings []maintenance.Reading
+		now := time.Now()
+		if *hours >= 0 {
+			readings = append(readings, maintenance.Reading{NodeID: n.ID, Meter: maintenance.TriggerRuntime, Value: *hours, At: now})
+		}
+		if *cycles >= 0 {
+			readings = append(readings, maintenance.Reading{NodeID: n.ID, Meter: maintenance.TriggerCycles, Value: *cycles, At: now})
+		}
+		if len(readings) == 0 {
+			return errors.New("usage: maintenance meter <node> (--hours N | --cycles N)")
+		}
+		err = maint.Update(func(s *maintenance.Schedule) error {
+			for _, r := range readings {
+				if err := s.Record(r); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s Recorded meter reading for '%s'\n", green("✓"), n.Title)
+		return nil
+
+	case "due":
+		fs := flag.NewFlagSet("maintenance due", flag.ContinueOnError)
+		within := fs.String("within", "7d", "how far ahead to look, e.g. 7d or 12h")
+		positional, err := parseInterspersed(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		if len(positional) > 1 {
+			return errors.New("usage: maintenance due [--within 7d] [<node>]")
+		}
+		horizon, err := maintenance.ParseInterval(*within)
+		if err != nil {
+			return err
+		}
+		nodeID := ""
+		if len(positional) == 1 {
+			n, err := maintenanceNode(store, sess, auth.PermNodesRead, positional[0])
+			if err != nil {
+				return err
+			}
+			nodeID = n.ID
+		}
+		s, nodes, err := loadMaintenance(store, maint, sess)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		due := s.Due(now, now.Add(horizon), func(id string) bool {
+			return nodes[id] != nil && (nodeID == "" || id == nodeID)
+		})
+		if len(due) == 0 {
+			fmt.Printf("No maintenance due within %s.\n", *within)
+			return nil
+		}
+		red := color.New(color.FgRed).SprintFunc()
+		fmt.Printf("%-6s %-17s %-20s %-26s %s\n", "Task", "Due", "Node", "Name", "Window")
+		fmt.Println(strings.Repeat("-", 90))
+		for _, d := range due {
+			when := fmt.Sprintf("%-17s", d.Due.Format("2006-01-02 15:04"))
+			if d.Overdue {
+				when = red(when)
+			}
+			window := ""
+			if w, ok := s.Window(d.Task); ok {
+				window = formatWindow(w)
+			}
+			fmt.Printf("%-6s %s %-20s %-26s %s\n", d.Task.ID, when, truncate(nodes[d.Task.NodeID].Title, 20),
+				truncate(d.Task.Name, 26), window)
+		}
+		return nil
+
+	case "schedule":
+		fs := flag.NewFlagSet("maintenance schedule", flag.ContinueOnError)
+		at := fs.String("at", "", "start of the maintenance window, e.g. '2026-10-20 06:00'")
+		duration := fs.String("duration", "", "length of the window (default: the plan's)")
+		id, err := parseWithName(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		if *at == "" {
+			return errors.New("usage: maintenance schedule <task-id> --at 'YYYY-MM-DD HH:MM' [--duration 3h]")
+		}
+		start, err := maintenance.ParseTime(*at)
+		if err != nil {
+			return err
+		}
+		var window maintenance.Window
+		err = maint.Update(func(s *maintenance.Schedule) error {
+			t, err := s.Task(id)
+			if err != nil {
+				return err
+			}
+			if err := canMaintain(store, sess, t.NodeID); err != nil {
+				return err
+			}
+			if t, err = s.Reschedule(t.ID, start, *duration); err != nil {
+				return err
+			}
+			window, _ = s.Window(t)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s Task %s scheduled %s\n", green("✓"), window.Task.ID, formatWindow(window))
+		return nil
+
+	case "done":
+		fs := flag.NewFlagSet("maintenance done", flag.ContinueOnError)
+		technician := fs.String("technician", "", "who did the work")
+		notes := fs.String("notes", "", "findings, parts replaced, etc.")
+		at := fs.String("at", "", "when the work was done (default: now)")
+		id, err := parseWithName(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		c := maintenance.Completion{At: time.Now(), Technician: *technician, Notes: *notes}
+		if *at != "" {
+			if c.At, err = maintenance.ParseTime(*at); err != nil {
+				return err
+			}
+		}
+		var task, next *maintenance.Task
+		var due string
+		err = maint.Update(func(s *maintenance.Schedule) error {
+			t, err := s.Task(id)
+			if err != nil {
+				return err
+			}
+			if err := canMaintain(store, sess, t.NodeID); err != nil {
+				return err
+			}
+			if task, next, err = s.Complete(t.ID, c); err != nil {
+				return err
+			}
+			due = describeDue(s, next)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s Task %s '%s' completed by %s\n", green("✓"), task.ID, task.Name, task.Completed.Technician)
+		if next != nil {
+			fmt.Printf("Next: task %s due %s\n", next.ID, due)
+		}
+		return nil
+
+	case "history":
+		if len(args) != 2 {
+			return errors.New("usage: maintenance history <node>")
+		}
+		n, err := maintenanceNode(store, sess, auth.PermNodesRead, args[1])
+		if err != nil {
+			return err
+		}
+		s, err := maint.Load()
+		if err != nil {
+			return err
+		}
+		tasks := s.History(n.ID)
+		if len(tasks) == 0 {
+			fmt.Printf("No maintenance recorded for '%s'.\n", n.Title)
+			return nil
+		}
+		fmt.Printf("%-6s %-17s %-26s %-16s %s\n", "Task", "Completed", "Name", "Technician", "Notes")
+		fmt.Println(strings.Repeat("-", 90))
+		for _, t := range tasks {
+			fmt.Printf("%-6s %-17s %-26s %-16s %s\n", t.ID, t.Completed.At.Format("2006-01-02 15:04"), truncate(t.Name, 26),
+				truncate(t.Completed.Technician, 16), truncate(strings.ReplaceAll(t.Completed.Notes, "\n", " "), 40))
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown maintenance command: %s", args[0])
+	}
+}
+
+// handleAvailability shows how much of the coming period a node is free
+// once planned maintenance is taken out: availability <node> [--within 7d]
+func handleAvailability(store *storage.Storage, maint *maintenance.Store, sess *auth.Session, args []string) error {
+	fs := flag.NewFlagSet("availability", flag.ContinueOnError)
+	within := fs.String("within", "7d", "length of the period from now, e.g. 7d or 24h")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("usage: availability <node> [--within 7d]")
+	}
+	period, err := maintenance.ParseInterval(*within)
+	if err != nil {
+		return err
+	}
+	if period <= 0 {
+		return errors.New("--within must be positive")
+	}
+	n, err := maintenanceNode(store, sess, auth.PermNodesRead, positional[0])
+	if err != nil {
+		return err
+	}
+	s, err := maint.Load()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	a := s.Availability(n.ID, now, now.Add(period))
+	cyan := color.New(color.FgCyan).SprintFunc()
+	fmt.Println("\n" + cyan(fmt.Sprintf("Availability of '%s' over the next %s:", n.Title, *within)))
+	fmt.Println(strings.Repeat("-", 90))
+	if len(a.Blocked) == 0 {
+		fmt.Println("No planned maintenance.")
+	}
+	for _, w := range a.Blocked {
+		if w.Projected {
+			fmt.Printf("Blocked %s  next %s (projected)\n", formatWindow(w), w.Task.Name)
+			continue
+		}
+		fmt.Printf("Blocked %s  %s %s\n", formatWindow(w), w.Task.ID, w.Task.Name)
+	}
+	fmt.Println(strings.Repeat("-", 90))
+	fmt.Printf("Available: %s of %s (%.1f%%)\n\n", formatHours(a.Available), formatHours(a.Total()), a.Ratio()*100)
+	return nil
+}
+
+// maintenanceNode looks up a node and checks the session may act on it
+func maintenanceNode(store *storage.Storage, sess *auth.Session, perm auth.Permission, ref string) (*node.Node, error) {
+	n, err := store.GetNodeByIDOrTitle(ref)
+	if err != nil {
+		return nil, err
+	}
+	if err := sess.CanNode(perm, n.UNSAddress); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// canMaintain checks the session may plan maintenance on a node. Tasks of
+// deleted nodes can still be handled by anyone allowed to plan everywhere.
+func canMaintain(store *storage.Storage, sess *auth.Session, nodeID string) error {
+	n, err := store.GetNode(nodeID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return sess.Can(auth.PermWorkOrdersWrite)
+	}
+	if err != nil {
+		return err
+	}
+	return sess.CanNode(auth.PermWorkOrdersWrite, n.UNSAddress)
+}
+
+// loadMaintenance reads the schedule and the nodes the session may see,
+// by ID
+func loadMaintenance(store *storage.Storage, maint *maintenance.Store, sess *auth.Session) (*maintenance.Schedule, map[string]*node.Node, error) {
+	s, err := maint.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+	nodes, err := store.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+	if nodes, err = sess.Visible(nodes); err != nil {
+		return nil, nil, err
+	}
+	byID := make(map[string]*node.Node, len(nodes))
+	for _, n := range nodes {
+		byID[n.ID] = n
+	}
+	return s, byID, nil
+}
+
+// describeDue says when a task falls due, or at what meter reading if
+// there are too few readings to estimate a date
+func describeDue(s *maintenance.Schedule, t *maintenance.Task) string {
+	if t == nil {
+		return "-"
+	}
+	if due, ok := s.DueDate(t); ok {
+		return due.Format("2006-01-02 15:04")
+	}
+	unit := " h"
+	if p, err := s.Plan(t.PlanID); err == nil && p.Trigger == maintenance.TriggerCycles {
+		unit = " cycles"
+	}
+	return "at " + strconv.FormatFloat(t.DueMeter, 'f', -1, 64) + unit
+}
+
+func formatWindow(w maintenance.Window) string {
+	end := w.End.Format("15:04")
+	if w.End.YearDay() != w.Start.YearDay() || w.End.Year() != w.Start.Year() {
+		end = w.End.Format("2006-01-02 15:04")
+	}
+	return w.Start.Format("2006-01-02 15:04") + " – " + end
+}
+
+func formatHours(d time.Duration) string {
+	return strconv.FormatFloat(d.Hours(), 'f', 1, 64) + " h"
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+
+	"manu-node-cli/internal/maintenance"
+	"manu-node-cli/internal/node"
+)
+
+func TestMaintenanceCommands(t *testing.T) {
+	store, _, cleanup := setupTestCommands(t)
+	defer cleanup()
+	maint, err := maintenance.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create maintenance store: %v", err)
+	}
+
+	cnc := node.NewNode("CNC", "", nil, "")
+	cnc.ID = "cnc"
+	if err := store.SaveNode(cnc); err != nil {
+		t.Fatalf("Failed to save node: %v", err)
+	}
+
+	if err := handleMaintenance(store, maint, nil, []string{"plan", "CNC", "--name", "Lubricate", "--every", "7d", "--duration", "3h"}); err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+	if err := handleMaintenance(store, maint, nil, []string{"plan", "CNC", "--name", "Oil", "--every", "7d", "--cycles", "100"}); err == nil {
+		t.Error("Expected two triggers to be rejected")
+	}
+	if err := handleMaintenance(store, maint, nil, []string{"plan", "Lathe", "--name", "Oil", "--every", "7d"}); err == nil {
+		t.Error("Expected an unknown node to be rejected")
+	}
+	if err := handleMaintenance(store, maint, nil, []string{"schedule", "T1", "--at", "2026-10-20 06:00"}); err != nil {
+		t.Fatalf("schedule failed: %v", err)
+	}
+	if err := handleMaintenance(store, maint, nil, []string{"done", "T1"}); err == nil {
+		t.Error("Expected a completion without technician to be rejected")
+	}
+	if err := handleMaintenance(store, maint, nil, []string{"done", "T1", "--technician", "Ana", "--at", "2026-10-20 09:00"}); err != nil {
+		t.Fatalf("done failed: %v", err)
+	}
+
+	s, err := maint.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := s.History("cnc"); len(h) != 1 || h[0].Completed.Technician != "Ana" || h[0].Duration != "3h" {
+		t.Errorf("Expected the completed task in the history, got %+v", h)
+	}
+	next, err := s.Task("T2")
+	if err != nil {
+		t.Fatalf("Expected a follow-up task: %v", err)
+	}
+	if want, _ := maintenance.ParseTime("2026-10-27 09:00"); !next.Due.Equal(want) {
+		t.Errorf("Expected the next task due %v, got %v", want, next.Due)
+	}
+
+	if err := handleAvailability(store, maint, nil, []string{"CNC", "--within", "0d"}); err == nil {
+		t.Error("Expected an empty period to be rejected")
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/fatih/color"
+	"manu-node-cli/internal/attribute"
+	"manu-node-cli/internal/auth"
+	"manu-node-cli/internal/backup"
+	"manu-node-cli/internal/manifest"
+	"manu-node-cli/internal/node"
+	"manu-node-cli/internal/storage"
+)
+
+// handlePlan shows the storage calls needed to match the manifests
+func handlePlan(store *storage.Storage, attrs *attribute.Store, sess *auth.Session, args []string) error {
+	return reconcile(store, attrs, nil, sess, "plan", args)
+}
+
+// handleApply reconciles storage with the manifests
+func handleApply(store *storage.Storage, attrs *attribute.Store, backups *backup.Manager, sess *auth.Session, args []string) error {
+	return reconcile(store, attrs, backups, sess, "apply", args)
+}
+
+func reconcile(store *storage.Storage, attrs *attribute.Store, backups *backup.Manager, sess *auth.Session, command string, args []string) error {
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	path := fs.String("f", "", "manifest file or directory")
+	prune := fs.Bool("prune", false, "delete nodes that no manifest declares")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *path == "" || fs.NArg() > 0 {
+		return fmt.Errorf("usage: %s -f <file|dir> [--prune]", command)
+	}
+	if err := sess.Can(auth.PermNodesWrite); err != nil {
+		return err
+	}
+
+	specs, err := manifest.Load(*path)
+	if err != nil {
+		return err
+	}
+	opts := manifest.Options{
+		Prune: *prune,
+		Authorize: func(n *node.Node) error {
+			return sess.CanNode(auth.PermNodesWrite, n.UNSAddress)
+		},
+		Normalize: attrs.Apply,
+	}
+
+	current, err := store.Load()
+	if err != nil {
+		return err
+	}
+	plan, err := manifest.BuildPlan(current, specs, opts)
+	if err != nil {
+		return err
+	}
+	printManifestPlan(plan)
+
+	if command == "plan" || len(plan.Changes) == 0 {
+		return nil
+	}
+	if err := snapshotBefore(backups, "before applying "+*path); err != nil {
+		return err
+	}
+
+	// Plan again under the storage lock and write everything at once
+	err = store.Transaction(func(nodes []*node.Node) ([]*node.Node, error) {
+		final, err := manifest.BuildPlan(nodes, specs, opts)
+		if err != nil {
+			return nil, err
+		}
+		if len(final.Changes) != len(plan.Changes) {
+			return nil, errors.New("catalog changed since the plan was computed; run apply again")
+		}
+		return final.Nodes, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	fmt.Printf("%s Applied %d change(s)\n", green("✓"), len(plan.Changes))
+	return nil
+}
+
+// printManifestPlan lists each storage call with its field diff
+func printManifestPlan(plan *manifest.Plan) {
+	green := color.New(color.FgGreen).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+
+	fmt.Println()
+	for _, c := range plan.Changes {
+		switch c.Action {
+		case manifest.ActionCreate:
+			fmt.Printf("  %s %s  (%s)\n", green("+ SaveNode"), c.Node.Title, c.Source)
+			fmt.Printf("        uns_address: %q\n", c.Node.UNSAddress)
+			if len(c.Node.Operations) > 0 {
+				fmt.Printf("        operations: %v\n", c.Node.Operations)
+			}
+		case manifest.ActionUpdate:
+			fmt.Printf("  %s %s [%s]  (%s)\n", yellow("~ UpdateNode"), c.Existing.Title, c.Existing.ID, c.Source)
+			for _, d := range c.Diff {
+				fmt.Printf("        %s\n", d)
+			}
+		case manifest.ActionDelete:
+			fmt.Printf("  %s %s [%s]\n", red("- DeleteNode"), c.Existing.Title, c.Existing.ID)
+		}
+	}
+	if len(plan.Changes) == 0 {
+		fmt.Println("  No changes. Storage matches the manifests.")
+	}
+	fmt.Printf("\nPlan: %d to create, %d to update, %d to delete, %d unchanged\n",
+		plan.Count(manifest.ActionCreate), plan.Count(manifest.ActionUpdate),
+		plan.Count(manifest.ActionDelete), plan.Unchanged)
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"gopkg.in/yaml.v3"
+	"manu-node-cli/internal/attribute"
+	"manu-node-cli/internal/auth"
+	"manu-node-cli/internal/capability"
+	"manu-node-cli/internal/node"
+	"manu-node-cli/internal/storage"
+)
+
+// defaultCapacityAttr is used for ranking when it is defined as a number
+const defaultCapacityAttr = "capacity"
+
+// handleMatch finds the nodes, alone or combined, that can run a sequence
+// of operations: match <op, op, ...> [--uns-prefix line] [--same-line]
+// [--capacity-attr name] [--limit N] [-l selector] [--attr expr] [-o format]
+func handleMatch(store *storage.Storage, attrs *attribute.Store, sess *auth.Session, output string, args []string) error {
+	fs := flag.NewFlagSet("match", flag.ContinueOnError)
+	var qf queryFlags
+	qf.register(fs)
+	registerOutput(fs, &output)
+	sameLine := fs.Bool("same-line", false, "only combine nodes on the same UNS line")
+	capacityAttr := fs.String("capacity-attr", "", "number attribute holding each node's capacity (default: capacity, if defined)")
+	limit := fs.Int("limit", 10, "show at most this many routes; 0 shows all")
+	words, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	ops := capability.ParseOperations(words)
+	if len(ops) == 0 {
+		return errors.New("usage: match <op, op, ...> [--uns-prefix line] [--same-line] [--capacity-attr name] [--limit N]")
+	}
+	q, err := qf.build(attrs, "")
+	if err != nil {
+		return err
+	}
+	capacity, err := capacityFunc(attrs, *capacityAttr)
+	if err != nil {
+		return err
+	}
+
+	nodes, err := store.Load()
+	if err != nil {
+		return err
+	}
+	if nodes, err = sess.Visible(nodes); err != nil {
+		return err
+	}
+	// Work must not go to nodes whose calibration expired or failed
+	eligible, blocked := calibratedNodes(q.filter(nodes), time.Now())
+	res := capability.Match(eligible, ops, capability.Options{SameLine: *sameLine, Capacity: capacity})
+	routes := res.Routes
+	if *limit > 0 && len(routes) > *limit {
+		routes = routes[:*limit]
+	}
+
+	if handled, err := printRoutesAs(output, routes); handled {
+		return err
+	}
+	sequence := strings.Join(ops, " → ")
+	if len(routes) == 0 {
+		fmt.Printf("No node or combination of nodes can run %s.\n", sequence)
+		printBlocked(blocked)
+		return nil
+	}
+
+	cyan := color.New(color.FgCyan).SprintFunc()
+	fmt.Println("\n" + cyan(fmt.Sprintf("Routes for %s:", sequence)))
+	fmt.Println(strings.Repeat("-", 90))
+	fmt.Printf("%-4s %-10s %-10s %s\n", "#", "Transfers", "Capacity", "Route")
+	fmt.Println(strings.Repeat("-", 90))
+	for i, r := range routes {
+		fmt.Printf("%-4d %-10d %-10s %s\n", i+1, r.Transfers, formatCapacity(r), formatRoute(r))
+	}
+	if shown := len(routes); shown < len(res.Routes) || res.Truncated {
+		more := fmt.Sprintf("%d", len(res.Routes))
+		if res.Truncated {
+			more = "more than " + more
+		}
+		fmt.Printf("\nShowing %d of %s routes; use --limit or narrow with --uns-prefix.\n", shown, more)
+	}
+	printBlocked(blocked)
+	fmt.Println()
+	return nil
+}
+
+// calibratedNodes splits nodes into those that may take work at now and
+// the calibration errors of those that may not
+func calibratedNodes(nodes []*node.Node, now time.Time) ([]*node.Node, []error) {
+	var eligible []*node.Node
+	var blocked []error
+	for _, n := range nodes {
+		if err := n.CheckCalibration(now); err != nil {
+			blocked = append(blocked, err)
+			continue
+		}
+		eligible = append(eligible, n)
+	}
+	return eligible, blocked
+}
+
+// printBlocked lists the nodes left out of matching for calibration
+func printBlocked(blocked []error) {
+	if len(blocked) == 0 {
+		return
+	}
+	yellow := color.New(color.FgYellow).SprintFunc()
+	fmt.Printf("\n%s\n", yellow(fmt.Sprintf("%d node(s) left out for calibration:", len(blocked))))
+	for _, err := range blocked {
+		fmt.Printf("  %v\n", err)
+	}
+}
+
+// capacityFunc reads capacities from the named number attribute. With no
+// name it uses defaultCapacityAttr if defined, and otherwise none.
+func capacityFunc(attrs *attribute.Store, name string) (func(*node.Node) (float64, bool), error) {
+	defs, err := attrs.Load()
+	if err != nil {
+		return nil, err
+	}
+	explicit := name != ""
+	if !explicit {
+		name = defaultCapacityAttr
+	}
+	d, ok := attribute.Find(defs, name)
+	switch {
+	case !ok && explicit:
+		return nil, fmt.Errorf("unknown attribute '%s'", name)
+	case !ok:
+		return nil, nil
+	case d.Type != attribute.TypeNumber:
+		if explicit {
+			return nil, fmt.Errorf("attribute '%s' is a %s, not a number", name, d.Type)
+		}
+		return nil, nil
+	}
+	return func(n *node.Node) (float64, bool) {
+		v, err := strconv.ParseFloat(n.Attributes[d.Name], 64)
+		return v, err == nil
+	}, nil
+}
+
+func formatCapacity(r capability.Route) string {
+	if !r.HasCapacity {
+		return "-"
+	}
+	return strconv.FormatFloat(r.Capacity, 'f', -1, 64)
+}
+
+// formatRoute shows each step as "Title [ops]", joined by the link used
+// for the transfer
+func formatRoute(r capability.Route) string {
+	var b strings.Builder
+	for i, s := range r.Steps {
+		if i > 0 {
+			b.WriteString(" → ")
+			if s.Link != nil {
+				fmt.Fprintf(&b, "(%s) ", s.Link.Kind)
+			}
+		}
+		fmt.Fprintf(&b, "%s [%s]", s.Node.Title, strings.Join(s.Operations, ", "))
+	}
+	return b.String()
+}
+
+// routeOutput is the JSON and YAML form of a route
+type routeOutput struct {
+	Transfers int          `json:"transfers" yaml:"transfers"`
+	Capacity  *float64     `json:"capacity,omitempty" yaml:"capacity,omitempty"`
+	Steps     []stepOutput `json:"steps" yaml:"steps"`
+}
+
+type stepOutput struct {
+	NodeID     string   `json:"node_id" yaml:"node_id"`
+	Title      string   `json:"title" yaml:"title"`
+	UNSAddress string   `json:"uns_address,omitempty" yaml:"uns_address,omitempty"`
+	Operations []string `json:"operations" yaml:"operations"`
+	Link       string   `json:"link,omitempty" yaml:"link,omitempty"`
+}
+
+// printRoutesAs writes routes as JSON or YAML and reports whether format
+// was one of those; the table format is left to the caller
+func printRoutesAs(format string, routes []capability.Route) (bool, error) {
+	if format == "" || format == "table" {
+		return false, nil
+	}
+	out := make([]routeOutput, len(routes))
+	for i, r := range routes {
+		out[i].Transfers = r.Transfers
+		if r.HasCapacity {
+			c := r.Capacity
+			out[i].Capacity = &c
+		}
+		for _, s := range r.Steps {
+			step := stepOutput{NodeID: s.Node.ID, Title: s.Node.Title, UNSAddress: s.Node.UNSAddress, Operations: s.Operations}
+			if s.Link != nil {
+				step.Link = s.Link.Kind
+			}
+			out[i].Steps = append(out[i].Steps, step)
+		}
+	}
+
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return true, fmt.Errorf("failed to marshal routes: %w", err)
+		}
+		fmt.Println(string(data))
+	case "yaml":
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(map[string][]routeOutput{"routes": out}); err != nil {
+			return true, fmt.Errorf("failed to marshal routes: %w", err)
+		}
+		fmt.Print(buf.String())
+	default:
+		return true, fmt.Errorf("unknown output format '%s' (use table, json or yaml)", format)
+	}
+	return true, nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/fatih/color"
+	"manu-node-cli/internal/auth"
+	"manu-node-cli/internal/storage"
+)
+
+// handleMigrate upgrades nodes.json to the schema version of this build:
+// migrate [--dry-run]
+func handleMigrate(store *storage.Storage, sess *auth.Session, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "show pending migrations without changing the file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := sess.Can(auth.PermNodesWrite); err != nil {
+		return err
+	}
+
+	report, err := store.Migrate(*dryRun)
+	if err != nil {
+		return err
+	}
+	if !report.Pending() {
+		fmt.Printf("nodes.json is up to date (schema version %d).\n", report.To)
+		return nil
+	}
+
+	fmt.Printf("nodes.json schema version %d -> %d (%d node(s))\n", report.From, report.To, report.Nodes)
+	for _, m := range report.Applied {
+		fmt.Printf("  %d -> %d: %s\n", m.From, m.From+1, m.Description)
+	}
+	if *dryRun {
+		fmt.Println("\nDry run: nothing was changed.")
+		return nil
+	}
+	green := color.New(color.FgGreen).SprintFunc()
+	fmt.Printf("%s Migrated to schema version %d\n", green("✓"), report.To)
+	return nil
+}
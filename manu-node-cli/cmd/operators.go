@@ -0,0 +1,380 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"manu-node-cli/internal/auth"
+	"manu-node-cli/internal/maintenance"
+	"manu-node-cli/internal/node"
+	"manu-node-cli/internal/operators"
+	"manu-node-cli/internal/storage"
+)
+
+const operatorUsage = "usage: operator add <name> [--shift S] | update <name> --shift S | remove <name> | list | " +
+	"certify <name> --skill S [--expires date] | revoke <name> --skill S | shift <name> --start HH:MM --end HH:MM | " +
+	"require <operation> [--skill S ...] [--node N] | requirements | qualified <operation> --node N [--shift S] [--at time] | " +
+	"check <name> <operation> --node N [--at time]"
+
+// handleOperator manages the operators registry and answers who may run
+// an operation on a node
+func handleOperator(store *storage.Storage, ops *operators.Store, sess *auth.Session, args []string) error {
+	if len(args) == 0 {
+		return errors.New(operatorUsage)
+	}
+	green := color.New(color.FgGreen).SprintFunc()
+
+	// Everything but the queries changes the registry
+	switch args[0] {
+	case "list", "requirements", "qualified", "check":
+		if err := sess.Can(auth.PermNodesRead); err != nil {
+			return err
+		}
+	default:
+		if err := sess.Can(auth.PermWorkOrdersWrite); err != nil {
+			return err
+		}
+	}
+
+	switch args[0] {
+	case "add", "update":
+		fs := flag.NewFlagSet("operator "+args[0], flag.ContinueOnError)
+		shift := fs.String("shift", "", "the operator's usual shift; empty for any shift")
+		name, err := parseWithName(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		var o *operators.Operator
+		err = ops.Update(func(r *operators.Registry) error {
+			if args[0] == "add" {
+				o, err = r.AddOperator(name, *shift)
+				return err
+			}
+			if o, err = r.Operator(name); err != nil {
+				return err
+			}
+			return r.SetShift(o, *shift)
+		})
+		if err != nil {
+			return err
+		}
+		verb := "Added"
+		if args[0] == "update" {
+			verb = "Updated"
+		}
+		fmt.Printf("%s %s operator '%s' (shift: %s)\n", green("✓"), verb, o.Name, orAny(o.Shift))
+		return nil
+
+	case "remove":
+		if len(args) != 2 {
+			return errors.New("usage: operator remove <name>")
+		}
+		var o *operators.Operator
+		err := ops.Update(func(r *operators.Registry) (err error) {
+			o, err = r.RemoveOperator(args[1])
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s Removed operator '%s'\n", green("✓"), o.Name)
+		return nil
+
+	case "list":
+		r, err := ops.Load()
+		if err != nil {
+			return err
+		}
+		if len(r.Shifts) > 0 {
+			fmt.Println("Shifts:")
+			for _, s := range r.Shifts {
+				fmt.Printf("  %-12s %s–%s\n", s.Name, s.Start, s.End)
+			}
+			fmt.Println()
+		}
+		if len(r.Operators) == 0 {
+			fmt.Println("No operators. Add one with 'operator add'.")
+			return nil
+		}
+		red := color.New(color.FgRed).SprintFunc()
+		now := time.Now()
+		fmt.Printf("%-20s %-12s %s\n", "Operator", "Shift", "Certifications")
+		fmt.Println(strings.Repeat("-", 90))
+		for _, o := range r.Operators {
+			var certs []string
+			for _, c := range o.Certifications {
+				cert := c.Skill
+				if !c.Expires.IsZero() {
+					cert += " (until " + c.Expires.Format("2006-01-02") + ")"
+				}
+				if !c.ValidAt(now) {
+					cert = red(c.Skill + " (expired " + c.Expires.Format("2006-01-02") + ")")
+				}
+				certs = append(certs, cert)
+			}
+			fmt.Printf("%-20s %-12s %s\n", truncate(o.Name, 20), truncate(orAny(o.Shift), 12), strings.Join(certs, ", "))
+		}
+		return nil
+
+	case "certify", "revoke":
+		fs := flag.NewFlagSet("operator "+args[0], flag.ContinueOnError)
+		skill := fs.String("skill", "", "certified skill, e.g. '5-axis milling'")
+		expires := fs.String("expires", "", "when the certification lapses (default: never)")
+		name, err := parseWithName(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		if *skill == "" {
+			return fmt.Errorf("usage: operator %s <name> --skill S", args[0])
+		}
+		var until time.Time
+		if *expires != "" {
+			if args[0] == "revoke" {
+				return errors.New("--expires only applies to certify")
+			}
+			if until, err = maintenance.ParseTime(*expires); err != nil {
+				return err
+			}
+		}
+		var o *operators.Operator
+		err = ops.Update(func(r *operators.Registry) error {
+			if args[0] == "revoke" {
+				o, err = r.Revoke(name, *skill)
+			} else {
+				o, err = r.Certify(name, *skill, until)
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if args[0] == "revoke" {
+			fmt.Printf("%s Revoked %s from '%s'\n", green("✓"), *skill, o.Name)
+		} else if until.IsZero() {
+			fmt.Printf("%s Certified '%s' for %s\n", green("✓"), o.Name, *skill)
+		} else {
+			fmt.Printf("%s Certified '%s' for %s until %s\n", green("✓"), o.Name, *skill, until.Format("2006-01-02"))
+		}
+		return nil
+
+	case "shift":
+		fs := flag.NewFlagSet("operator shift", flag.ContinueOnError)
+		start := fs.String("start", "", "when the shift starts, HH:MM")
+		end := fs.String("end", "", "when the shift ends, HH:MM; before the start for night shifts")
+		name, err := parseWithName(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		if *start == "" || *end == "" {
+			return errors.New("usage: operator shift <name> --start HH:MM --end HH:MM")
+		}
+		var s *operators.Shift
+		err = ops.Update(func(r *operators.Registry) error {
+			s, err = r.DefineShift(name, *start, *end)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s Shift '%s' runs %s–%s\n", green("✓"), s.Name, s.Start, s.End)
+		return nil
+
+	case "require":
+		fs := flag.NewFlagSet("operator require", flag.ContinueOnError)
+		var skills stringList
+		fs.Var(&skills, "skill", "skill the operation needs (repeatable); none removes the requirement")
+		nodeRef := fs.String("node", "", "require the skills on this node only")
+		positional, err := parseInterspersed(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		if len(positional) != 1 {
+			return errors.New("usage: operator require <operation> [--skill S ...] [--node N]")
+		}
+		operation := positional[0]
+		nodeID, where := "", "every node"
+		if *nodeRef != "" {
+			n, err := store.GetNodeByIDOrTitle(*nodeRef)
+			if err != nil {
+				return err
+			}
+			if err := sess.CanNode(auth.PermWorkOrdersWrite, n.UNSAddress); err != nil {
+				return err
+			}
+			nodeID, where = n.ID, "'"+n.Title+"'"
+		}
+		if err := ops.Update(func(r *operators.Registry) error { return r.Require(operation, nodeID, skills) }); err != nil {
+			return err
+		}
+		if len(skills) == 0 {
+			fmt.Printf("%s %s on %s no longer requires skills\n", green("✓"), operation, where)
+		} else {
+			fmt.Printf("%s %s on %s requires %s\n", green("✓"), operation, where, strings.Join(skills, ", "))
+		}
+		return nil
+
+	case "requirements":
+		r, err := ops.Load()
+		if err != nil {
+			return err
+		}
+		if len(r.Requirements) == 0 {
+			fmt.Println("No skill requirements. Add one with 'operator require'.")
+			return nil
+		}
+		fmt.Printf("%-24s %-24s %s\n", "Operation", "Node", "Skills")
+		fmt.Println(strings.Repeat("-", 90))
+		for _, req := range r.Requirements {
+			where := "(all)"
+			if req.NodeID != "" {
+				where = req.NodeID
+				if n, err := store.GetNode(req.NodeID); err == nil {
+					where = n.Title
+				}
+			}
+			fmt.Printf("%-24s %-24s %s\n", truncate(req.Operation, 24), truncate(where, 24), strings.Join(req.Skills, ", "))
+		}
+		return nil
+
+	case "qualified":
+		fs := flag.NewFlagSet("operator qualified", flag.ContinueOnError)
+		nodeRef := fs.String("node", "", "node the operation runs on")
+		shiftName := fs.String("shift", "", "shift to staff (default: the current shift)")
+		at := fs.String("at", "", "time to check instead of now")
+		positional, err := parseInterspersed(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		if len(positional) != 1 || *nodeRef == "" {
+			return errors.New("usage: operator qualified <operation> --node N [--shift S] [--at time]")
+		}
+		operation := positional[0]
+		n, err := operatorNode(store, sess, *nodeRef, operation)
+		if err != nil {
+			return err
+		}
+		when, err := parseAt(*at)
+		if err != nil {
+			return err
+		}
+		r, err := ops.Load()
+		if err != nil {
+			return err
+		}
+
+		// Certifications must hold until the end of the shift
+		var shift *operators.Shift
+		until := when
+		if *shiftName != "" {
+			if shift, err = r.Shift(*shiftName); err != nil {
+				return err
+			}
+		} else {
+			shift, _ = r.CurrentShift(when)
+		}
+		heading := fmt.Sprintf("Operators for %s on '%s'", operation, n.Title)
+		if shift != nil {
+			var from time.Time
+			from, until = shift.Next(when)
+			heading += fmt.Sprintf(", %s shift %s–%s", shift.Name, from.Format("2006-01-02 15:04"), until.Format("15:04"))
+		}
+		if err := n.CheckCalibration(until); err != nil {
+			return err
+		}
+
+		skills := r.RequiredSkills(operation, n.ID)
+		candidates := r.Qualified(operation, n.ID, shift, until)
+		cyan := color.New(color.FgCyan).SprintFunc()
+		red := color.New(color.FgRed).SprintFunc()
+		fmt.Println("\n" + cyan(heading+":"))
+		if len(skills) > 0 {
+			fmt.Printf("Required skills: %s\n", strings.Join(skills, ", "))
+		} else {
+			fmt.Println("Required skills: none")
+		}
+		fmt.Println(strings.Repeat("-", 90))
+		if len(candidates) == 0 {
+			fmt.Println("No operators on this shift.")
+		}
+		for _, c := range candidates {
+			if len(c.Problems) == 0 {
+				fmt.Printf("  %s %s\n", green("✓"), c.Operator.Name)
+				continue
+			}
+			problems := make([]string, len(c.Problems))
+			for i, p := range c.Problems {
+				problems[i] = p.String()
+			}
+			fmt.Printf("  %s %s (%s)\n", red("✗"), c.Operator.Name, strings.Join(problems, ", "))
+		}
+		fmt.Println()
+		return nil
+
+	case "check":
+		fs := flag.NewFlagSet("operator check", flag.ContinueOnError)
+		nodeRef := fs.String("node", "", "node the operation runs on")
+		at := fs.String("at", "", "time the work ends (default: now)")
+		positional, err := parseInterspersed(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		if len(positional) != 2 || *nodeRef == "" {
+			return errors.New("usage: operator check <name> <operation> --node N [--at time]")
+		}
+		n, err := operatorNode(store, sess, *nodeRef, positional[1])
+		if err != nil {
+			return err
+		}
+		when, err := parseAt(*at)
+		if err != nil {
+			return err
+		}
+		r, err := ops.Load()
+		if err != nil {
+			return err
+		}
+		if err := r.CheckAssignment(positional[0], positional[1], n, when); err != nil {
+			return err
+		}
+		o, _ := r.Operator(positional[0])
+		fmt.Printf("%s '%s' is qualified to run %s on '%s'\n", green("✓"), o.Name, positional[1], n.Title)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown operator command: %s", args[0])
+	}
+}
+
+// operatorNode looks up the node an operation is staffed on
+func operatorNode(store *storage.Storage, sess *auth.Session, ref, operation string) (*node.Node, error) {
+	n, err := store.GetNodeByIDOrTitle(ref)
+	if err != nil {
+		return nil, err
+	}
+	if err := sess.CanNode(auth.PermNodesRead, n.UNSAddress); err != nil {
+		return nil, err
+	}
+	if !n.HasOperation(operation) {
+		return nil, fmt.Errorf("'%s' does not support operation '%s'", n.Title, operation)
+	}
+	return n, nil
+}
+
+// parseAt reads an optional --at time, defaulting to now
+func parseAt(s string) (time.Time, error) {
+	if s == "" {
+		return time.Now(), nil
+	}
+	return maintenance.ParseTime(s)
+}
+
+func orAny(shift string) string {
+	if shift == "" {
+		return "any"
+	}
+	return shift
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"manu-node-cli/internal/node"
+	"manu-node-cli/internal/operators"
+)
+
+func TestOperatorCommands(t *testing.T) {
+	store, _, cleanup := setupTestCommands(t)
+	defer cleanup()
+	ops, err := operators.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create operators store: %v", err)
+	}
+
+	dmu := node.NewNode("DMU 50", "", []string{"mill"}, "")
+	dmu.ID = "dmu"
+	if err := store.SaveNode(dmu); err != nil {
+		t.Fatalf("Failed to save node: %v", err)
+	}
+
+	steps := [][]string{
+		{"shift", "Morning", "--start", "06:00", "--end", "14:00"},
+		{"add", "Ana", "--shift", "Morning"},
+		{"add", "Ben"},
+		{"certify", "Ana", "--skill", "5-axis", "--expires", "2099-01-01"},
+		{"certify", "Ben", "--skill", "5-axis", "--expires", "2020-01-01"},
+		{"require", "mill", "--skill", "5-axis", "--node", "DMU 50"},
+	}
+	for _, args := range steps {
+		if err := handleOperator(store, ops, nil, args); err != nil {
+			t.Fatalf("operator %v failed: %v", args, err)
+		}
+	}
+	if err := handleOperator(store, ops, nil, []string{"add", "Cyril", "--shift", "Weekend"}); err == nil {
+		t.Error("Expected an unknown shift to be rejected")
+	}
+
+	if err := handleOperator(store, ops, nil, []string{"check", "Ana", "mill", "--node", "DMU 50"}); err != nil {
+		t.Errorf("Expected Ana to be qualified, got %v", err)
+	}
+	err = handleOperator(store, ops, nil, []string{"check", "Ben", "mill", "--node", "DMU 50"})
+	if !errors.Is(err, operators.ErrNotQualified) {
+		t.Errorf("Expected Ben's expired certification to be flagged, got %v", err)
+	}
+	if err := handleOperator(store, ops, nil, []string{"check", "Ana", "weld", "--node", "DMU 50"}); err == nil {
+		t.Error("Expected an operation the node does not support to be rejected")
+	}
+	if err := handleOperator(store, ops, nil, []string{"qualified", "mill", "--node", "dmu", "--shift", "morning"}); err != nil {
+		t.Errorf("qualified failed: %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/chzyer/readline"
+	"github.com/fatih/color"
+)
+
+// errCancelled is returned by prompts when the user presses Ctrl+C or
+// closes the input
+var errCancelled = errors.New("cancelled")
+
+// Prompter asks the user for one line of input at a time. Every
+// interactive command goes through it, so the REPL's readline instance
+// stays the only reader of the terminal.
+type Prompter interface {
+	// Ask shows label and returns the entered line. current is offered
+	// as the starting value: pre-filled for in-place editing on a
+	// terminal, or kept when Enter is pressed on a plain input stream.
+	Ask(label, current string) (string, error)
+}
+
+// readlinePrompter prompts through the REPL's readline instance
+type readlinePrompter struct {
+	rl *readline.Instance
+	// complete offers completions for the field; command completion is
+	// switched off while a prompt is open
+	complete *lineCompleter
+}
+
+func (p *readlinePrompter) Ask(label, current string) (string, error) {
+	oldPrompt, oldComplete := p.rl.Config.Prompt, p.rl.Config.AutoComplete
+	defer func() {
+		p.rl.SetPrompt(oldPrompt)
+		p.rl.Config.AutoComplete = oldComplete
+	}()
+
+	p.rl.Config.AutoComplete = noCompletion
+	if p.complete != nil {
+		p.rl.Config.AutoComplete = p.complete
+	}
+	p.rl.SetPrompt(label + ": ")
+	line, err := p.rl.ReadlineWithDefault(current)
+	if err != nil { // Ctrl+C or Ctrl+D
+		return "", errCancelled
+	}
+	return line, nil
+}
+
+// withCompletion returns p set up to complete the next answers with l.
+// Prompters without a terminal are returned unchanged.
+func withCompletion(p Prompter, l *lineCompleter) Prompter {
+	if rp, ok := p.(*readlinePrompter); ok {
+		return &readlinePrompter{rl: rp.rl, complete: l}
+	}
+	return p
+}
+
+// linePrompter reads plain lines, for piped input, one-shot commands and
+// tests. An empty line keeps the current value and "-" clears it.
+type linePrompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func newLinePrompter(r io.Reader, w io.Writer) *linePrompter {
+	return &linePrompter{in: bufio.NewReader(r), out: w}
+}
+
+func (p *linePrompter) Ask(label, current string) (string, error) {
+	if current != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", label, current)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", label)
+	}
+
+	line, err := p.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", errCancelled
+	}
+	line = strings.TrimRight(line, "\r\n")
+	switch {
+	case line == "":
+		return current, nil
+	case strings.TrimSpace(line) == "-":
+		return "", nil
+	}
+	return line, nil
+}
+
+// askText asks for a text field until the answer is free of control
+// characters and passes check, if given. The answer is trimmed.
+func askText(p Prompter, label, current string, check func(string) error) (string, error) {
+	red := color.New(color.FgRed).SprintFunc()
+	for {
+		answer, err := p.Ask(label, current)
+		if err != nil {
+			return "", err
+		}
+		answer = strings.TrimSpace(answer)
+
+		if !isValidInput(answer) {
+			fmt.Printf("%s: %s contains invalid characters\n", red("Error"), fieldName(label))
+			continue
+		}
+		if check != nil {
+			if err := check(answer); err != nil {
+				fmt.Printf("%s: %v\n", red("Error"), err)
+				continue
+			}
+		}
+		return answer, nil
+	}
+}
+
+// askList asks for a comma-separated list, dropping empty entries
+func askList(p Prompter, label string, current []string) ([]string, error) {
+	red := color.New(color.FgRed).SprintFunc()
+	for {
+		answer, err := p.Ask(label, strings.Join(current, ", "))
+		if err != nil {
+			return nil, err
+		}
+
+		var items []string
+		valid := true
+		for _, item := range strings.Split(answer, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			if !isValidInput(item) {
+				fmt.Printf("%s: '%s' contains invalid characters\n", red("Error"), item)
+				valid = false
+				break
+			}
+			items = append(items, item)
+		}
+		if valid {
+			return items, nil
+		}
+	}
+}
+
+// askConfirm asks a yes/no question, defaulting to no
+func askConfirm(p Prompter, question string) (bool, error) {
+	answer, err := p.Ask(question+" [y/N]", "")
+	if err != nil {
+		return false, err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// fieldName turns a prompt label like "UNS Address (e.g., ...)" into the
+// field name used in messages
+func fieldName(label string) string {
+	if i := strings.Index(label, " ("); i >= 0 {
+		return label[:i]
+	}
+	return label
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"manu-node-cli/internal/attribute"
+	"manu-node-cli/internal/node"
+	"manu-node-cli/internal/storage"
+)
+
+// keep makes fakeTerminal accept the pre-filled value unchanged
+const keep = "\x00keep"
+
+// fakeTerminal answers prompts from a script, the way a user editing the
+// pre-filled line would, and records what was offered
+type fakeTerminal struct {
+	answers []string
+	offered []string
+}
+
+func (f *fakeTerminal) Ask(label, current string) (string, error) {
+	f.offered = append(f.offered, fieldName(label)+"="+current)
+	if len(f.answers) == 0 {
+		return "", errCancelled
+	}
+	answer := f.answers[0]
+	f.answers = f.answers[1:]
+	if answer == keep {
+		return current, nil
+	}
+	return answer, nil
+}
+
+func setupTestCommands(t *testing.T) (*storage.Storage, *attribute.Store, func()) {
+	tempDir, err := os.MkdirTemp("", "cmd_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+
+	store, err := storage.NewStorage(tempDir)
+	if err != nil {
+		os.RemoveAll(tempDir)
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	attrs, err := attribute.NewStore(tempDir)
+	if err != nil {
+		os.RemoveAll(tempDir)
+		t.Fatalf("Failed to create attribute store: %v", err)
+	}
+	store.SetValidator(attrs.Apply)
+
+	cleanup := func() {
+		os.RemoveAll(tempDir)
+	}
+
+	return store, attrs, cleanup
+}
+
+func TestLinePrompter(t *testing.T) {
+	var out bytes.Buffer
+	p := newLinePrompter(strings.NewReader("\nnew value\n-\n"), &out)
+
+	tests := []struct {
+		current string
+		want    string
+	}{
+		{"old", "old"}, // Enter keeps the current value
+		{"old", "new value"},
+		{"old", ""}, // "-" clears it
+	}
+	for _, tt := range tests {
+		got, err := p.Ask("Field", tt.current)
+		if err != nil {
+			t.Fatalf("Ask failed: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("Expected '%s', got '%s'", tt.want, got)
+		}
+	}
+	if !strings.Contains(out.String(), "Field [old]: ") {
+		t.Errorf("Expected the current value in the prompt, got %q", out.String())
+	}
+
+	if _, err := p.Ask("Field", "old"); !errors.Is(err, errCancelled) {
+		t.Errorf("Expected errCancelled at end of input, got %v", err)
+	}
+}
+
+func TestAskTextRetriesInvalidInput(t *testing.T) {
+	term := &fakeTerminal{answers: []string{"bad\x07bell", "", "  Good  "}}
+	got, err := askText(term, "Title", "", func(s string) error {
+		if s == "" {
+			return errors.New("title cannot be empty")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("askText failed: %v", err)
+	}
+	if got != "Good" {
+		t.Errorf("Expected 'Good', got '%s'", got)
+	}
+	if len(term.offered) != 3 {
+		t.Errorf("Expected 3 attempts, got %d", len(term.offered))
+	}
+}
+
+func TestAskListAndConfirm(t *testing.T) {
+	term := &fakeTerminal{answers: []string{"Drill, , Mill ", "y", ""}}
+
+	ops, err := askList(term, "Operations", []string{"Saw"})
+	if err != nil {
+		t.Fatalf("askList failed: %v", err)
+	}
+	if !reflect.DeepEqual(ops, []string{"Drill", "Mill"}) {
+		t.Errorf("Expected [Drill Mill], got %v", ops)
+	}
+	if term.offered[0] != "Operations=Saw" {
+		t.Errorf("Expected current operations to be offered, got %s", term.offered[0])
+	}
+
+	if ok, err := askConfirm(term, "Sure?"); err != nil || !ok {
+		t.Errorf("Expected yes, got %v, %v", ok, err)
+	}
+	if ok, err := askConfirm(term, "Sure?"); err != nil || ok {
+		t.Errorf("Expected no by default, got %v, %v", ok, err)
+	}
+	if _, err := askConfirm(term, "Sure?"); !errors.Is(err, errCancelled) {
+		t.Errorf("Expected errCancelled, got %v", err)
+	}
+}
+
+func TestHandleUpdatePrefillsCurrentValues(t *testing.T) {
+	store, attrs, cleanup := setupTestCommands(t)
+	defer cleanup()
+
+	if _, err := attrs.Define(attribute.Definition{Name: "power", Type: attribute.TypeNumber}); err != nil {
+		t.Fatalf("Failed to define attribute: %v", err)
+	}
+	n := node.NewNode("CNC", "Mill", []string{"Cut"}, "Plant/Line1/CNC")
+	n.Labels = map[string]string{"dept": "metal"}
+	n.Attributes = map[string]string{"power": "5"}
+	if err := store.SaveNode(n); err != nil {
+		t.Fatalf("Failed to save node: %v", err)
+	}
+
+	term := &fakeTerminal{answers: []string{
+		keep,                 // title
+		"Five-axis mill",     // description
+		"Cut, Drill",         // operations
+		keep,                 // UNS address
+		"dept=metal,shift=a", // labels
+		"",                   // power: cleared
+	}}
+	handleUpdate(store, attrs, nil, "CNC", term)
+
+	wantOffered := []string{
+		"Title=CNC",
+		"Description=Mill",
+		"Operations=Cut",
+		"UNS Address=Plant/Line1/CNC",
+		"Labels=dept=metal",
+		"power <number>=5",
+	}
+	if !reflect.DeepEqual(term.offered, wantOffered) {
+		t.Errorf("Expected prompts %v, got %v", wantOffered, term.offered)
+	}
+
+	got, err := store.GetNode(n.ID)
+	if err != nil {
+		t.Fatalf("Failed to get node: %v", err)
+	}
+	if got.Title != "CNC" || got.Description != "Five-axis mill" || got.UNSAddress != "Plant/Line1/CNC" {
+		t.Errorf("Unexpected fields after update: %+v", got)
+	}
+	if !reflect.DeepEqual(got.Operations, []string{"Cut", "Drill"}) {
+		t.Errorf("Expected operations [Cut Drill], got %v", got.Operations)
+	}
+	if got.Labels["shift"] != "a" || len(got.Labels) != 2 {
+		t.Errorf("Expected labels dept and shift, got %v", got.Labels)
+	}
+	if len(got.Attributes) != 0 {
+		t.Errorf("Expected power to be cleared, got %v", got.Attributes)
+	}
+}
+
+func TestHandleUpdateCancel(t *testing.T) {
+	store, attrs, cleanup := setupTestCommands(t)
+	defer cleanup()
+
+	n := node.NewNode("CNC", "Mill", nil, "")
+	if err := store.SaveNode(n); err != nil {
+		t.Fatalf("Failed to save node: %v", err)
+	}
+
+	// Cancel halfway through: the script runs out after the description
+	term := &fakeTerminal{answers: []string{"Lathe", "Turning"}}
+	handleUpdate(store, attrs, nil, "CNC", term)
+
+	got, err := store.GetNode(n.ID)
+	if err != nil {
+		t.Fatalf("Failed to get node: %v", err)
+	}
+	if got.Title != "CNC" || got.Description != "Mill" {
+		t.Errorf("Expected a cancelled update to change nothing, got %+v", got)
+	}
+}
+
+func TestHandleDelete(t *testing.T) {
+	store, _, cleanup := setupTestCommands(t)
+	defer cleanup()
+
+	n := node.NewNode("CNC", "", nil, "")
+	if err := store.SaveNode(n); err != nil {
+		t.Fatalf("Failed to save node: %v", err)
+	}
+
+	handleDelete(store, nil, "CNC", &fakeTerminal{answers: []string{"n"}})
+	if _, err := store.GetNode(n.ID); err != nil {
+		t.Errorf("Expected node to survive a declined delete: %v", err)
+	}
+
+	handleDelete(store, nil, "CNC", &fakeTerminal{answers: []string{"yes"}})
+	if _, err := store.GetNode(n.ID); err == nil {
+		t.Error("Expected node to be deleted after confirmation")
+	}
+}
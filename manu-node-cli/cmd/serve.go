@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"manu-node-cli/internal/api"
+	"manu-node-cli/internal/attribute"
+	"manu-node-cli/internal/auth"
+	"manu-node-cli/internal/storage"
+)
+
+// pollInterval is how often the server looks for changes that other
+// processes made to the nodes file, so event streams include CLI edits
+const pollInterval = time.Second
+
+// handleServe runs the REST API until interrupted
+func handleServe(store *storage.Storage, attrs *attribute.Store, authz *auth.Authorizer, defaultAddr string, args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := fs.String("addr", defaultAddr, "address to listen on")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// Shut down gracefully on Ctrl+C / SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Start from the catalog as it is now; later edits become events
+	if err := store.Poll(); err != nil {
+		return err
+	}
+	go watchNodes(ctx, store)
+
+	handler := api.NewServer(store, authz)
+	handler.SetAttributes(attrs)
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		// Request contexts end on shutdown so event streams close promptly
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		fmt.Printf("Serving API on %s (OpenAPI spec at /openapi.json)\n", *addr)
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+		fmt.Println("\nShutting down...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	}
+}
+
+// watchNodes publishes edits made outside the server until ctx is done
+func watchNodes(ctx context.Context, store *storage.Storage) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := store.Poll(); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to check nodes for changes: %v\n", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"manu-node-cli/internal/auth"
+	"manu-node-cli/internal/labels"
+	"manu-node-cli/internal/node"
+	"manu-node-cli/internal/storage"
+	"manu-node-cli/internal/templates"
+)
+
+// handleClone copies a node under a new title:
+// clone <node> --title T [--uns path] [--description text]
+func handleClone(store *storage.Storage, sess *auth.Session, args []string) error {
+	fs := flag.NewFlagSet("clone", flag.ContinueOnError)
+	title := fs.String("title", "", "title of the new node (required)")
+	uns := fs.String("uns", "", "UNS address of the new node (default: same as the source)")
+	description := fs.String("description", "", "description of the new node (default: same as the source)")
+	identifier, err := parseWithName(fs, args)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(*title) == "" {
+		return errors.New("usage: clone <node-id or title> --title T [--uns path] [--description text]")
+	}
+
+	source, err := store.GetNodeByIDOrTitle(identifier)
+	if err != nil {
+		return err
+	}
+	if err := sess.CanNode(auth.PermNodesRead, source.UNSAddress); err != nil {
+		return err
+	}
+
+	clone := source.Clone()
+	clone.Title = strings.TrimSpace(*title)
+	// The copy is a separate machine, so it starts without material flow
+	clone.Links = nil
+	clone.Calibrations = nil
+	if *uns != "" {
+		clone.UNSAddress = strings.TrimSpace(*uns)
+	}
+	if *description != "" {
+		clone.Description = strings.TrimSpace(*description)
+	}
+	clone.CreatedAt = time.Now()
+	clone.UpdatedAt = clone.CreatedAt
+
+	if err := saveNewNode(store, sess, clone); err != nil {
+		return err
+	}
+	green := color.New(color.FgGreen).SprintFunc()
+	fmt.Printf("%s Cloned '%s' as '%s' (ID: %s)\n", green("✓"), source.Title, clone.Title, clone.ID)
+	return nil
+}
+
+// handleTemplate manages node templates: template save|list|show|remove
+func handleTemplate(store *storage.Storage, tmpls *templates.Store, sess *auth.Session, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: template save <node> <name> [--title pattern] [--uns pattern] | list | show <name> | remove <name>")
+	}
+	green := color.New(color.FgGreen).SprintFunc()
+
+	switch args[0] {
+	case "save":
+		fs := flag.NewFlagSet("template save", flag.ContinueOnError)
+		titlePattern := fs.String("title", "", "title pattern (default: {{title}})")
+		unsPattern := fs.String("uns", "", "UNS address pattern (default: the node's parent path + /{{title}})")
+		positional, err := parseInterspersed(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		if len(positional) != 2 {
+			return errors.New("usage: template save <node> <name> [--title pattern] [--uns pattern]")
+		}
+		if err := sess.Can(auth.PermNodesWrite); err != nil {
+			return err
+		}
+		n, err := store.GetNodeByIDOrTitle(positional[0])
+		if err != nil {
+			return err
+		}
+		if err := sess.CanNode(auth.PermNodesRead, n.UNSAddress); err != nil {
+			return err
+		}
+
+		t := templates.FromNode(positional[1], n, *titlePattern, *unsPattern)
+		if err := tmpls.Save(t); err != nil {
+			return err
+		}
+		fmt.Printf("%s Template '%s' saved from '%s'\n", green("✓"), t.Name, n.Title)
+		if vars := t.Variables(); len(vars) > 0 {
+			fmt.Printf("Variables: %s\n", strings.Join(vars, ", "))
+		}
+		return nil
+
+	case "list":
+		if err := sess.Can(auth.PermNodesRead); err != nil {
+			return err
+		}
+		all, err := tmpls.Load()
+		if err != nil {
+			return err
+		}
+		if len(all) == 0 {
+			fmt.Println("No templates yet. Save one with 'template save <node> <name>'.")
+			return nil
+		}
+		fmt.Printf("%-20s %-25s %-35s %s\n", "Name", "Title", "UNS Address", "Operations")
+		fmt.Println(strings.Repeat("-", 100))
+		for _, t := range all {
+			fmt.Printf("%-20s %-25s %-35s %s\n", t.Name, truncate(t.Title, 23),
+				truncate(t.UNSAddress, 33), strings.Join(t.Operations, ", "))
+		}
+		return nil
+
+	case "show":
+		if len(args) != 2 {
+			return errors.New("usage: template show <name>")
+		}
+		if err := sess.Can(auth.PermNodesRead); err != nil {
+			return err
+		}
+		t, err := tmpls.Get(args[1])
+		if err != nil {
+			return err
+		}
+		cyan := color.New(color.FgCyan).SprintFunc()
+		fmt.Println("\n" + cyan("Template Details:"))
+		fmt.Println(strings.Repeat("-", 60))
+		fmt.Printf("Name:        %s\n", t.Name)
+		fmt.Printf("Title:       %s\n", t.Title)
+		fmt.Printf("Description: %s\n", t.Description)
+		fmt.Printf("UNS Address: %s\n", t.UNSAddress)
+		fmt.Printf("Operations:  %s\n", strings.Join(t.Operations, ", "))
+		if len(t.Labels) > 0 {
+			fmt.Printf("Labels:      %s\n", labels.Format(t.Labels))
+		}
+		if len(t.Attributes) > 0 {
+			fmt.Printf("Attributes:  %s\n", labels.Format(t.Attributes))
+		}
+		fmt.Printf("Variables:   %s\n", strings.Join(t.Variables(), ", "))
+		fmt.Println()
+		return nil
+
+	case "remove":
+		if len(args) != 2 {
+			return errors.New("usage: template remove <name>")
+		}
+		if err := sess.Can(auth.PermNodesWrite); err != nil {
+			return err
+		}
+		if err := tmpls.Remove(args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("%s Template '%s' removed\n", green("✓"), args[1])
+		return nil
+
+	default:
+		return fmt.Errorf("unknown template command: %s", args[0])
+	}
+}
+
+// handleCreateFromTemplate creates a node from a template:
+// create --from-template <name> [--var key=value ...]
+// Variables that were not given are prompted for.
+func handleCreateFromTemplate(store *storage.Storage, tmpls *templates.Store, sess *auth.Session,
+	args []string, p Prompter) error {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	name := fs.String("from-template", "", "template to create the node from")
+	var assignments stringList
+	fs.Var(&assignments, "var", "template variable as key=value (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *name == "" || fs.NArg() > 0 {
+		return errors.New("usage: create --from-template <name> [--var key=value ...]")
+	}
+	if err := sess.Can(auth.PermNodesWrite); err != nil {
+		return err
+	}
+
+	t, err := tmpls.Get(*name)
+	if err != nil {
+		return err
+	}
+	vars := map[string]string{}
+	for _, a := range assignments {
+		k, v, ok := strings.Cut(a, "=")
+		if !ok {
+			return fmt.Errorf("invalid --var '%s': expected key=value", a)
+		}
+		vars[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	for _, v := range t.Variables() {
+		if _, ok := vars[v]; ok {
+			continue
+		}
+		value, err := askText(p, v, "", nil)
+		if err != nil {
+			return fmt.Errorf("missing template variable '%s' (use --var %s=...)", v, v)
+		}
+		vars[v] = value
+	}
+
+	n, err := t.Instantiate(vars)
+	if err != nil {
+		return err
+	}
+	if err := saveNewNode(store, sess, n); err != nil {
+		return err
+	}
+	green := color.New(color.FgGreen).SprintFunc()
+	fmt.Printf("%s Node '%s' created from template '%s' (ID: %s)\n", green("✓"), n.Title, t.Name, n.ID)
+	if n.UNSAddress != "" {
+		fmt.Printf("UNS Address: %s\n", n.UNSAddress)
+	}
+	return nil
+}
+
+// saveNewNode assigns n a fresh ID and stores it after the same checks
+// handleCreate performs
+func saveNewNode(store *storage.Storage, sess *auth.Session, n *node.Node) error {
+	if err := n.Validate(); err != nil {
+		return err
+	}
+	if err := sess.CanNode(auth.PermNodesWrite, n.UNSAddress); err != nil {
+		return err
+	}
+	unique, err := store.IsTitleUnique(n.Title, "")
+	if err != nil {
+		return fmt.Errorf("failed to check title uniqueness: %w", err)
+	}
+	if !unique {
+		return fmt.Errorf("a node with title '%s' already exists", n.Title)
+	}
+	n.ID = node.UniqueID(func(id string) bool {
+		_, err := store.GetNode(id)
+		return err == nil
+	})
+	return store.SaveNode(n)
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"manu-node-cli/internal/auth"
+	"manu-node-cli/internal/b2mml"
+	"manu-node-cli/internal/backup"
+	"manu-node-cli/internal/manifest"
+	"manu-node-cli/internal/node"
+	"manu-node-cli/internal/storage"
+	"manu-node-cli/internal/tabular"
+)
+
+// handleImport imports nodes from a CSV, XLSX or B2MML file
+func handleImport(store *storage.Storage, backups *backup.Manager, sess *auth.Session, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "show what would change without writing")
+	mapSpec := fs.String("map", "", "column mapping, e.g. 'title=Machine,uns_address=Location'")
+	opsSep := fs.String("ops-sep", tabular.DefaultOpsSeparator, "separator between operations in one cell")
+	file, err := parseWithName(fs, args)
+	if err != nil {
+		return errors.New("usage: import <file.csv|file.xlsx|file.xml> [--dry-run] [--map field=Column,...] [--ops-sep ;]")
+	}
+
+	if err := sess.Can(auth.PermNodesWrite); err != nil {
+		return err
+	}
+	if strings.EqualFold(filepath.Ext(file), ".xml") {
+		return importB2MML(store, backups, sess, file, *dryRun)
+	}
+	mapping, err := tabular.ParseMapping(*mapSpec)
+	if err != nil {
+		return err
+	}
+	table, err := tabular.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	opts := tabular.ImportOptions{
+		Mapping:      mapping,
+		OpsSeparator: *opsSep,
+		Authorize: func(n *node.Node) error {
+			return sess.CanNode(auth.PermNodesWrite, n.UNSAddress)
+		},
+	}
+
+	current, err := store.Load()
+	if err != nil {
+		return err
+	}
+	plan, err := tabular.PlanImport(current, table, opts)
+	if err != nil {
+		return err
+	}
+	printImportPlan(plan)
+
+	if plan.HasConflicts() {
+		return fmt.Errorf("%d conflicting row(s); nothing was imported", plan.Count(tabular.ActionConflict))
+	}
+	if *dryRun {
+		fmt.Println("Dry run: no changes were written.")
+		return nil
+	}
+	if plan.Count(tabular.ActionCreate)+plan.Count(tabular.ActionUpdate) == 0 {
+		fmt.Println("Nothing to import.")
+		return nil
+	}
+	if err := snapshotBefore(backups, "before importing "+filepath.Base(file)); err != nil {
+		return err
+	}
+
+	// Re-plan inside the transaction so edits made since the preview are
+	// taken into account; all rows are written at once or not at all
+	err = store.Transaction(func(nodes []*node.Node) ([]*node.Node, error) {
+		final, err := tabular.PlanImport(nodes, table, opts)
+		if err != nil {
+			return nil, err
+		}
+		if final.HasConflicts() {
+			return nil, errors.New("catalog changed during import and rows now conflict; run the import again")
+		}
+		plan = final
+		return final.Nodes, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	fmt.Printf("%s Imported: %d created, %d updated\n", green("✓"),
+		plan.Count(tabular.ActionCreate), plan.Count(tabular.ActionUpdate))
+	return nil
+}
+
+// importB2MML maps ISA-95 equipment onto nodes and applies them like a
+// manifest, without pruning nodes the document does not mention
+func importB2MML(store *storage.Storage, backups *backup.Manager, sess *auth.Session, file string, dryRun bool) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	doc, err := b2mml.Decode(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	specs, err := doc.Specs()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Read %d equipment node(s) and %d equipment class(es) from %s\n",
+		len(specs), len(doc.Classes), file)
+
+	opts := manifest.Options{
+		Authorize: func(n *node.Node) error {
+			return sess.CanNode(auth.PermNodesWrite, n.UNSAddress)
+		},
+		// B2MML has no place for attributes, labels or links
+		Merge: true,
+	}
+	current, err := store.Load()
+	if err != nil {
+		return err
+	}
+	plan, err := manifest.BuildPlan(current, specs, opts)
+	if err != nil {
+		return err
+	}
+	printManifestPlan(plan)
+	if dryRun {
+		fmt.Println("Dry run: no changes were written.")
+		return nil
+	}
+	if len(plan.Changes) == 0 {
+		return nil
+	}
+	if err := snapshotBefore(backups, "before importing "+filepath.Base(file)); err != nil {
+		return err
+	}
+
+	err = store.Transaction(func(nodes []*node.Node) ([]*node.Node, error) {
+		final, err := manifest.BuildPlan(nodes, specs, opts)
+		if err != nil {
+			return nil, err
+		}
+		plan = final
+		return final.Nodes, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	fmt.Printf("%s Imported: %d created, %d updated\n", green("✓"),
+		plan.Count(manifest.ActionCreate), plan.Count(manifest.ActionUpdate))
+	return nil
+}
+
+// printImportPlan shows one line per row plus field-level changes
+func printImportPlan(plan *tabular.ImportPlan) {
+	green := color.New(color.FgGreen).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+
+	fmt.Println()
+	for _, r := range plan.Rows {
+		switch r.Action {
+		case tabular.ActionCreate:
+			fmt.Printf("  %s row %d: %s (%s)\n", green("+ create"), r.Row, r.Node.Title, r.Node.UNSAddress)
+		case tabular.ActionUpdate:
+			fmt.Printf("  %s row %d: %s\n", yellow("~ update"), r.Row, r.Existing.Title)
+			for _, c := range r.Changes {
+				fmt.Printf("        %s\n", c)
+			}
+		case tabular.ActionUnchanged:
+			fmt.Printf("  = same   row %d: %s\n", r.Row, r.Node.Title)
+		case tabular.ActionConflict:
+			fmt.Printf("  %s row %d: %s\n", red("! conflict"), r.Row, r.Reason)
+		}
+	}
+	fmt.Printf("\n%d to create, %d to update, %d unchanged, %d conflicts\n",
+		plan.Count(tabular.ActionCreate), plan.Count(tabular.ActionUpdate),
+		plan.Count(tabular.ActionUnchanged), plan.Count(tabular.ActionConflict))
+}
+
+// handleExport writes the visible nodes to a CSV, XLSX, YAML manifest or
+// B2MML file
+func handleExport(store *storage.Storage, sess *auth.Session, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	opsSep := fs.String("ops-sep", tabular.DefaultOpsSeparator, "separator between operations in one cell")
+	unsPrefix := fs.String("uns-prefix", "", "only export nodes under this UNS path")
+	file, err := parseWithName(fs, args)
+	if err != nil {
+		return errors.New("usage: export <file.csv|file.xlsx|file.yaml|file.xml> [--uns-prefix path] [--ops-sep ;]")
+	}
+
+	nodes, err := store.Load()
+	if err != nil {
+		return err
+	}
+	if nodes, err = sess.Visible(nodes); err != nil {
+		return err
+	}
+	var selected []*node.Node
+	for _, n := range nodes {
+		if n.HasUNSPrefix(*unsPrefix) {
+			selected = append(selected, n)
+		}
+	}
+
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		// A manifest suitable for 'plan -f' / 'apply -f'
+		data, err := manifest.Marshal(selected)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, data, 0644); err != nil {
+			return err
+		}
+	case ".xml":
+		// ISA-95 B2MML EquipmentInformation
+		f, err := os.Create(file)
+		if err != nil {
+			return err
+		}
+		err = b2mml.Encode(f, selected)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	default:
+		if err := tabular.WriteFile(file, tabular.NodesToTable(selected, *opsSep)); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Exported %d node(s) to %s\n", len(selected), strings.TrimSpace(file))
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"manu-node-cli/internal/attribute"
+	"manu-node-cli/internal/node"
+)
+
+func TestB2MMLRoundTripKeepsNodes(t *testing.T) {
+	store, attrs, cleanup := setupTestCommands(t)
+	defer cleanup()
+	if _, err := attrs.Define(attribute.Definition{Name: "power", Type: attribute.TypeNumber}); err != nil {
+		t.Fatalf("Failed to define attribute: %v", err)
+	}
+
+	a := node.NewNode("Saw", "Panel saw", []string{"cut"}, "Plant/Wood/Saw")
+	a.ID = "a"
+	a.Attributes = map[string]string{"power": "5"}
+	a.Labels = map[string]string{"dept": "wood"}
+	a.Links = []node.Link{{To: "b", Kind: node.LinkConveyor}}
+	b := node.NewNode("CNC", "", []string{"mill"}, "Plant/Wood/CNC")
+	b.ID = "b"
+	if err := store.Save([]*node.Node{a, b}); err != nil {
+		t.Fatalf("Failed to save nodes: %v", err)
+	}
+	before, _ := store.Load()
+
+	// B2MML carries neither attributes, labels nor links
+	file := filepath.Join(t.TempDir(), "plant.xml")
+	if err := handleExport(store, nil, []string{file}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if err := handleImport(store, nil, nil, []string{file}); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+
+	after, _ := store.Load()
+	if len(after) != len(before) {
+		t.Fatalf("Expected %d nodes, got %d", len(before), len(after))
+	}
+	for i := range before {
+		if diff := node.Diff(before[i], after[i]); len(diff) > 0 {
+			t.Errorf("Expected '%s' to be unchanged, got %v", before[i].Title, diff)
+		}
+	}
+}
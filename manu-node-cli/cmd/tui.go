@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"manu-node-cli/internal/attribute"
+	"manu-node-cli/internal/auth"
+	"manu-node-cli/internal/config"
+	"manu-node-cli/internal/labels"
+	"manu-node-cli/internal/manifest"
+	"manu-node-cli/internal/node"
+	"manu-node-cli/internal/storage"
+	"manu-node-cli/internal/tui"
+)
+
+// handleTUI opens the full-screen browser: tui
+func handleTUI(cfg *config.Loaded, store *storage.Storage, attrs *attribute.Store, sess *auth.Session, args []string) error {
+	if len(args) != 0 {
+		return errors.New("usage: tui")
+	}
+	app, err := tui.New(&tuiBackend{store: store, attrs: attrs, sess: sess}, cfg.Context)
+	if err != nil {
+		return err
+	}
+	return tui.Run(app, os.Stdin, os.Stdout)
+}
+
+// tuiBackend gives the TUI the same checks as the create, update, edit and
+// delete commands
+type tuiBackend struct {
+	store *storage.Storage
+	attrs *attribute.Store
+	sess  *auth.Session
+}
+
+// Form field names outside the attributes
+const (
+	fieldTitle       = "title"
+	fieldDescription = "description"
+	fieldOperations  = "operations"
+	fieldUNS         = "uns"
+	fieldLabels      = "labels"
+	attrFieldPrefix  = "attr:"
+)
+
+func (b *tuiBackend) Nodes() ([]*node.Node, error) {
+	nodes, err := b.store.Load()
+	if err != nil {
+		return nil, err
+	}
+	return b.sess.Visible(nodes)
+}
+
+func (b *tuiBackend) Fields(n *node.Node) ([]tui.Field, error) {
+	if err := b.sess.Can(auth.PermNodesWrite); err != nil {
+		return nil, err
+	}
+	defs, err := b.attrs.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := []tui.Field{
+		{Name: fieldTitle, Label: "Title", Value: n.Title},
+		{Name: fieldDescription, Label: "Description", Value: n.Description},
+		{Name: fieldOperations, Label: "Operations", Hint: "comma-separated", Value: strings.Join(n.Operations, ", ")},
+		{Name: fieldUNS, Label: "UNS Address", Hint: "Site/Area/Line/Cell", Value: n.UNSAddress},
+		{Name: fieldLabels, Label: "Labels", Hint: "key=value, comma-separated", Value: labels.Format(n.Labels)},
+	}
+	for _, d := range defs {
+		value := n.Attributes[d.Name]
+		if value == "" && (n.ID == "" || d.Required) {
+			value = d.Default
+		}
+		fields = append(fields, tui.Field{Name: attrFieldPrefix + d.Name, Label: d.Label(), Hint: d.Hint(), Value: value})
+	}
+	return fields, nil
+}
+
+func (b *tuiBackend) Save(existing *node.Node, fields []tui.Field) (*node.Node, error) {
+	spec := manifest.Spec{Attributes: map[string]string{}}
+	for _, f := range fields {
+		value := strings.TrimSpace(f.Value)
+		switch f.Name {
+		case fieldTitle:
+			spec.Title = value
+		case fieldDescription:
+			spec.Description = value
+		case fieldOperations:
+			spec.Operations = strings.Split(value, ",")
+		case fieldUNS:
+			spec.UNSAddress = strings.TrimSuffix(value, "/")
+		case fieldLabels:
+			parsed, err := labels.Parse(value)
+			if err != nil {
+				return nil, err
+			}
+			spec.Labels = parsed
+		default:
+			if name, ok := strings.CutPrefix(f.Name, attrFieldPrefix); ok && value != "" {
+				spec.Attributes[name] = value
+			}
+		}
+	}
+	if spec.Title == "" {
+		return nil, errors.New("title cannot be empty")
+	}
+	if existing != nil {
+		// The form has no link fields, so links stay as they are
+		spec.Links = manifest.FromNode(existing).Links
+	}
+
+	if existing == nil {
+		if err := b.sess.Can(auth.PermNodesWrite); err != nil {
+			return nil, err
+		}
+		blank := &node.Node{CreatedAt: time.Now()}
+		blank.ID = node.UniqueID(func(id string) bool {
+			_, err := b.store.GetNode(id)
+			return err == nil
+		})
+		created, err := nodeFromSpec(b.store, b.attrs, b.sess, blank, spec)
+		if err != nil {
+			return nil, err
+		}
+		if err := b.store.SaveNode(created); err != nil {
+			return nil, fmt.Errorf("failed to save node: %w", err)
+		}
+		return created, nil
+	}
+
+	if err := b.sess.CanNode(auth.PermNodesWrite, existing.UNSAddress); err != nil {
+		return nil, err
+	}
+	updated, err := nodeFromSpec(b.store, b.attrs, b.sess, existing, spec)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.store.UpdateNodeIfMatch(existing.ID, existing.ETag(), updated); err != nil {
+		if errors.Is(err, storage.ErrPreconditionFailed) {
+			return nil, errors.New("the node changed since it was loaded; press Esc and r to reload")
+		}
+		return nil, fmt.Errorf("failed to update node: %w", err)
+	}
+	return updated, nil
+}
+
+func (b *tuiBackend) Delete(n *node.Node) error {
+	if err := b.sess.CanNode(auth.PermNodesWrite, n.UNSAddress); err != nil {
+		return err
+	}
+	if err := b.store.DeleteNodeIfMatch(n.ID, n.ETag()); err != nil {
+		if errors.Is(err, storage.ErrPreconditionFailed) {
+			return errors.New("the node changed since it was loaded; press r to reload")
+		}
+		return fmt.Errorf("failed to delete node: %w", err)
+	}
+	return nil
+}
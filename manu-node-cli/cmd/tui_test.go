@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+
+	"manu-node-cli/internal/attribute"
+	"manu-node-cli/internal/node"
+	"manu-node-cli/internal/tui"
+)
+
+// setField sets the form field called name
+func setField(fields []tui.Field, name, value string) {
+	for i := range fields {
+		if fields[i].Name == name {
+			fields[i].Value = value
+		}
+	}
+}
+
+func TestTUIBackendSave(t *testing.T) {
+	store, attrs, cleanup := setupTestCommands(t)
+	defer cleanup()
+	if _, err := attrs.Define(attribute.Definition{Name: "power", Type: attribute.TypeNumber, Default: "5"}); err != nil {
+		t.Fatalf("Failed to define attribute: %v", err)
+	}
+	b := &tuiBackend{store: store, attrs: attrs}
+
+	fields, err := b.Fields(&node.Node{UNSAddress: "Plant/Line1/"})
+	if err != nil {
+		t.Fatalf("Fields failed: %v", err)
+	}
+	if len(fields) != 6 || fields[5].Value != "5" {
+		t.Fatalf("Expected the attribute with its default, got %+v", fields)
+	}
+	setField(fields, fieldTitle, "CNC-01")
+	setField(fields, fieldOperations, "Milling, , Drilling")
+	setField(fields, fieldLabels, "dept=metal")
+
+	created, err := b.Save(nil, fields)
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if created.UNSAddress != "Plant/Line1" || len(created.Operations) != 2 || created.Attributes["power"] != "5" {
+		t.Errorf("Unexpected node: %+v", created)
+	}
+
+	// A second node cannot take the same title
+	if _, err := b.Save(nil, fields); err == nil {
+		t.Error("Expected an error for a duplicate title")
+	}
+
+	// Editing a stale copy is refused
+	stale := created.Clone()
+	fields, _ = b.Fields(created)
+	setField(fields, fieldDescription, "first")
+	if _, err := b.Save(created, fields); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	setField(fields, fieldDescription, "second")
+	if _, err := b.Save(stale, fields); err == nil {
+		t.Error("Expected a conflict when saving over a newer version")
+	}
+	if got, _ := store.GetNode(created.ID); got.Description != "first" {
+		t.Errorf("Expected 'first', got '%s'", got.Description)
+	}
+
+	current, _ := store.GetNode(created.ID)
+	if err := b.Delete(stale); err == nil {
+		t.Error("Expected a stale node not to be deleted")
+	}
+	if err := b.Delete(current); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := store.GetNode(created.ID); err == nil {
+		t.Error("Expected the node to be deleted")
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fatih/color"
+	"manu-node-cli/internal/auth"
+	"manu-node-cli/internal/backup"
+	"manu-node-cli/internal/node"
+	"manu-node-cli/internal/storage"
+	"manu-node-cli/internal/undo"
+)
+
+// sessionRecorder turns the catalog changes made by each REPL command
+// into an undo step
+type sessionRecorder struct {
+	store   *storage.Storage
+	history *undo.History
+
+	command string
+	seq     uint64
+	before  []*node.Node
+}
+
+func newSessionRecorder(store *storage.Storage) *sessionRecorder {
+	return &sessionRecorder{store: store, history: undo.NewHistory(undo.DefaultLimit)}
+}
+
+// begin snapshots the catalog before command runs
+func (r *sessionRecorder) begin(command string) {
+	nodes, err := r.store.Load()
+	if err != nil {
+		r.before = nil
+		return
+	}
+	r.command = command
+	r.seq = r.store.Events().LastSeq()
+	r.before = nodes
+}
+
+// end records what the command changed. Only writes made by this process
+// count, so another process editing the file is never undone by accident.
+func (r *sessionRecorder) end() {
+	if r.before == nil {
+		return
+	}
+	before := r.before
+	r.before = nil
+	if r.store.Events().LastSeq() == r.seq {
+		return
+	}
+	after, err := r.store.Load()
+	if err != nil {
+		return
+	}
+	r.history.Record(undo.Step{Command: r.command, Time: time.Now(), Changes: undo.Diff(before, after)})
+}
+
+// handleUndo reverts the most recent recorded step, or reapplies the most
+// recently undone one when redo is set
+func handleUndo(store *storage.Storage, backups *backup.Manager, rec *sessionRecorder, sess *auth.Session,
+	p Prompter, redo bool) error {
+	verb, done := "undo", "Undid"
+	recorded, ok := rec.history.NextUndo()
+	step := recorded.Inverse()
+	if redo {
+		verb, done = "redo", "Redid"
+		recorded, ok = rec.history.NextRedo()
+		step = recorded
+	}
+	if !ok {
+		fmt.Printf("Nothing to %s.\n", verb)
+		return nil
+	}
+
+	for _, c := range step.Changes {
+		for _, n := range []*node.Node{c.Before, c.After} {
+			if n == nil {
+				continue
+			}
+			if err := sess.CanNode(auth.PermNodesWrite, n.UNSAddress); err != nil {
+				return err
+			}
+		}
+	}
+
+	nodes, err := store.Load()
+	if err != nil {
+		return err
+	}
+	conflicts := undo.Conflicts(nodes, step)
+	if len(conflicts) > 0 {
+		yellow := color.New(color.FgYellow).SprintFunc()
+		fmt.Printf("%s these nodes changed after '%s':\n", yellow("Warning:"), step.Command)
+		for _, c := range conflicts {
+			fmt.Printf("    %s (%s)\n", c.Title(), c.ID())
+		}
+		ok, err := askConfirm(p, fmt.Sprintf("Overwrite these changes and %s anyway?", verb))
+		if err != nil || !ok {
+			fmt.Printf("%s cancelled.\n", verb)
+			return nil
+		}
+	}
+
+	for _, c := range step.Changes {
+		if c.After == nil {
+			if err := snapshotBefore(backups, fmt.Sprintf("before %s of '%s'", verb, step.Command)); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	err = store.Transaction(func(nodes []*node.Node) ([]*node.Node, error) {
+		if !sameConflicts(conflicts, undo.Conflicts(nodes, step)) {
+			return nil, fmt.Errorf("catalog changed while confirming; run %s again", verb)
+		}
+		return undo.Apply(nodes, step)
+	})
+	if err != nil {
+		return err
+	}
+
+	if redo {
+		rec.history.Redone()
+	} else {
+		rec.history.Undone()
+	}
+	green := color.New(color.FgGreen).SprintFunc()
+	fmt.Printf("%s %s: %s\n", green("✓"), done, recorded.Summary())
+	return nil
+}
+
+// sameConflicts reports whether two conflict lists name the same nodes
+func sameConflicts(a, b []undo.Change) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].ID() != b[i].ID() {
+			return false
+		}
+	}
+	return true
+}
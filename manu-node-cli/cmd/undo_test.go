@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+
+	"manu-node-cli/internal/node"
+)
+
+func TestUndoRedoDelete(t *testing.T) {
+	store, _, cleanup := setupTestCommands(t)
+	defer cleanup()
+
+	n := node.NewNode("x", "junk", nil, "")
+	if err := store.SaveNode(n); err != nil {
+		t.Fatalf("Failed to save node: %v", err)
+	}
+
+	rec := newSessionRecorder(store)
+	rec.begin("delete x")
+	handleDelete(store, nil, "x", &fakeTerminal{answers: []string{"y"}})
+	rec.end()
+
+	if err := handleUndo(store, nil, rec, nil, &fakeTerminal{}, false); err != nil {
+		t.Fatalf("undo failed: %v", err)
+	}
+	if _, err := store.GetNode(n.ID); err != nil {
+		t.Errorf("Expected undo to restore the node: %v", err)
+	}
+
+	if err := handleUndo(store, nil, rec, nil, &fakeTerminal{}, true); err != nil {
+		t.Fatalf("redo failed: %v", err)
+	}
+	if _, err := store.GetNode(n.ID); err == nil {
+		t.Error("Expected redo to delete the node again")
+	}
+}
+
+func TestUndoAsksWhenNodeChangedSince(t *testing.T) {
+	store, attrs, cleanup := setupTestCommands(t)
+	defer cleanup()
+
+	n := node.NewNode("CNC", "Mill", nil, "")
+	if err := store.SaveNode(n); err != nil {
+		t.Fatalf("Failed to save node: %v", err)
+	}
+
+	rec := newSessionRecorder(store)
+	rec.begin("update CNC")
+	handleUpdate(store, attrs, nil, "CNC", &fakeTerminal{answers: []string{keep, "Lathe", "", keep, ""}})
+	rec.end()
+
+	// A change the session did not record
+	changed, _ := store.GetNode(n.ID)
+	changed.Description = "Edited elsewhere"
+	if err := store.UpdateNode(n.ID, changed); err != nil {
+		t.Fatalf("Failed to update node: %v", err)
+	}
+
+	// Declining leaves the node alone and keeps the step
+	term := &fakeTerminal{answers: []string{"n"}}
+	if err := handleUndo(store, nil, rec, nil, term, false); err != nil {
+		t.Fatalf("undo failed: %v", err)
+	}
+	if len(term.offered) != 1 {
+		t.Errorf("Expected a confirmation prompt, got %v", term.offered)
+	}
+	if got, _ := store.GetNode(n.ID); got.Description != "Edited elsewhere" {
+		t.Errorf("Expected the node to be untouched, got '%s'", got.Description)
+	}
+
+	if err := handleUndo(store, nil, rec, nil, &fakeTerminal{answers: []string{"y"}}, false); err != nil {
+		t.Fatalf("undo failed: %v", err)
+	}
+	if got, _ := store.GetNode(n.ID); got.Description != "Mill" {
+		t.Errorf("Expected the original description, got '%s'", got.Description)
+	}
+}
+
+func TestRecorderIgnoresReadOnlyCommands(t *testing.T) {
+	store, _, cleanup := setupTestCommands(t)
+	defer cleanup()
+
+	rec := newSessionRecorder(store)
+	rec.begin("list")
+	rec.end()
+	if _, ok := rec.history.NextUndo(); ok {
+		t.Error("Expected no undo step for a command that changed nothing")
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"manu-node-cli/internal/auth"
+)
+
+// stringList collects a repeatable command-line flag
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ",") }
+func (l *stringList) Set(v string) error { *l = append(*l, v); return nil }
+
+// handleUser manages users: user add|list|remove
+func handleUser(authz *auth.Authorizer, sess *auth.Session, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: user add|list|remove")
+	}
+	if err := sess.Can(auth.PermUsersManage); err != nil {
+		return err
+	}
+	users := authz.Users()
+
+	switch args[0] {
+	case "add":
+		fs := flag.NewFlagSet("user add", flag.ContinueOnError)
+		var roleNames, scopes stringList
+		fs.Var(&roleNames, "role", "role to grant (repeatable): admin, developer, planner, analyst")
+		fs.Var(&scopes, "scope", "restrict node access to a UNS filter, e.g. 'Site/Area/#' (repeatable)")
+		name, err := parseWithName(fs, args[1:])
+		if err != nil {
+			return err
+		}
+
+		var roles []auth.Role
+		for _, rn := range roleNames {
+			r, err := auth.ParseRole(rn)
+			if err != nil {
+				return err
+			}
+			roles = append(roles, r)
+		}
+
+		u, err := users.AddUser(name, roles, scopes)
+		if err != nil {
+			return err
+		}
+		token, _, err := users.CreateToken(u.Name, "initial token")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("User '%s' created.\n", u.Name)
+		fmt.Printf("API token (shown only once): %s\n", token)
+		fmt.Println("Use it with MANU_NODE_TOKEN for the CLI or 'Authorization: Bearer <token>' for the API.")
+		return nil
+
+	case "list":
+		list, err := users.Load()
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			fmt.Println("No users defined; access control is disabled.")
+			return nil
+		}
+		fmt.Printf("%-20s %-25s %-30s %s\n", "Name", "Roles", "Scopes", "Tokens")
+		fmt.Println(strings.Repeat("-", 90))
+		for _, u := range list {
+			var roles []string
+			for _, r := range u.Roles {
+				roles = append(roles, string(r))
+			}
+			var tokenIDs []string
+			for _, t := range u.Tokens {
+				tokenIDs = append(tokenIDs, t.ID)
+			}
+			scopes := strings.Join(u.Scopes, ", ")
+			if scopes == "" {
+				scopes = "(all)"
+			}
+			fmt.Printf("%-20s %-25s %-30s %s\n", truncate(u.Name, 18), strings.Join(roles, ", "),
+				truncate(scopes, 28), strings.Join(tokenIDs, ", "))
+		}
+		return nil
+
+	case "remove":
+		if len(args) < 2 {
+			return errors.New("usage: user remove <name>")
+		}
+		if err := users.RemoveUser(args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("User '%s' removed.\n", args[1])
+		return nil
+
+	default:
+		return fmt.Errorf("unknown user command: %s", args[0])
+	}
+}
+
+// handleToken manages API tokens: token create|revoke
+func handleToken(authz *auth.Authorizer, sess *auth.Session, args []string) error {
+	if len(args) < 2 {
+		return errors.New("usage: token create <user> [--description text] | token revoke <token-id>")
+	}
+	if err := sess.Can(auth.PermUsersManage); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("token create", flag.ContinueOnError)
+		description := fs.String("description", "", "what the token is used for")
+		name, err := parseWithName(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		token, t, err := authz.Users().CreateToken(name, *description)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Token %s created for '%s'.\n", t.ID, name)
+		fmt.Printf("API token (shown only once): %s\n", token)
+		return nil
+
+	case "revoke":
+		if err := authz.Users().RevokeToken(args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("Token %s revoked.\n", args[1])
+		return nil
+
+	default:
+		return fmt.Errorf("unknown token command: %s", args[0])
+	}
+}
+
+// parseWithName parses flags around a single positional name argument
+func parseWithName(fs *flag.FlagSet, args []string) (string, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return "", fmt.Errorf("usage: %s <name> [flags]", fs.Name())
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return "", err
+	}
+	if fs.NArg() > 0 {
+		return "", fmt.Errorf("unexpected argument: %s", fs.Arg(0))
+	}
+	return args[0], nil
+}
+
+// parseInterspersed parses flags that may appear before, between or
+// after positional arguments and returns the positional ones
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
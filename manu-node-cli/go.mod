@@ -2,10 +2,14 @@ module manu-node-cli
 
 go 1.21
 
-require github.com/fatih/color v1.16.0
+require (
+	github.com/chzyer/readline v1.5.1
+	github.com/fatih/color v1.16.0
+	github.com/gorilla/websocket v1.5.3
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
-	github.com/chzyer/readline v1.5.1 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	golang.org/x/sys v0.14.0 // indirect
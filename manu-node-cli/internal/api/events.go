@@ -0,0 +1,194 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"manu-node-cli/internal/auth"
+	"manu-node-cli/internal/storage"
+)
+
+const eventsPath = "/api/v1/events"
+
+// heartbeatInterval keeps idle streams from being closed by proxies
+const heartbeatInterval = 15 * time.Second
+
+var upgrader = websocket.Upgrader{
+	// The API carries no cookies, so cross-origin dashboards are allowed
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// subscribe parses the resume cursor and subscribes to the storage bus,
+// writing an error response on failure. The cursor comes from the
+// "cursor" query parameter or, for SSE reconnects, the Last-Event-ID header.
+func (s *Server) subscribe(w http.ResponseWriter, r *http.Request) ([]storage.Event, <-chan storage.Event, func(), bool) {
+	if err := session(r).Can(auth.PermNodesRead); err != nil {
+		s.writeAuthError(w, err)
+		return nil, nil, nil, false
+	}
+
+	raw := r.URL.Query().Get("cursor")
+	if raw == "" {
+		raw = r.Header.Get("Last-Event-ID")
+	}
+
+	var cursor uint64
+	if raw != "" {
+		var err error
+		cursor, err = strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "cursor must be an event sequence number")
+			return nil, nil, nil, false
+		}
+	}
+
+	replay, ch, cancel, err := s.store.Events().Subscribe(cursor)
+	if errors.Is(err, storage.ErrCursorExpired) {
+		writeError(w, http.StatusGone, "%v; reload /api/v1/nodes and resume from cursor %d",
+			err, s.store.Events().LastSeq())
+		return nil, nil, nil, false
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return nil, nil, nil, false
+	}
+	return replay, ch, cancel, true
+}
+
+// handleSSE streams node changes as Server-Sent Events
+func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	replay, ch, cancel, ok := s.subscribe(w, r)
+	if !ok {
+		return
+	}
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: 3000\n\n")
+
+	sess := session(r)
+	for _, ev := range replay {
+		if canSee(sess, ev) {
+			writeSSE(w, ev)
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case ev, open := <-ch:
+			if !open {
+				// Fell behind; the client reconnects with Last-Event-ID
+				return
+			}
+			if canSee(sess, ev) {
+				writeSSE(w, ev)
+				flusher.Flush()
+			}
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// canSee applies the session's UNS scopes to a streamed event
+func canSee(sess *auth.Session, ev storage.Event) bool {
+	return sess.CanNode(auth.PermNodesRead, ev.Node.UNSAddress) == nil
+}
+
+func writeSSE(w http.ResponseWriter, ev storage.Event) {
+	data, _ := json.Marshal(ev)
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.Seq, ev.Type, data)
+}
+
+// handleWebSocket streams node changes as JSON text messages
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	replay, ch, cancel, ok := s.subscribe(w, r)
+	if !ok {
+		return
+	}
+	defer cancel()
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade already wrote an HTTP error
+		return
+	}
+	defer conn.Close()
+
+	// Read in the background so control frames (close, pong) are handled
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	sess := session(r)
+	for _, ev := range replay {
+		if !canSee(sess, ev) {
+			continue
+		}
+		if err := conn.WriteJSON(ev); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case ev, open := <-ch:
+			if !open {
+				conn.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "subscriber fell behind"))
+				return
+			}
+			if !canSee(sess, ev) {
+				continue
+			}
+			if err := conn.WriteJSON(ev); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(5*time.Second)); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		case <-r.Context().Done():
+			conn.WriteMessage(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"))
+			return
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"manu-node-cli/internal/node"
+	"manu-node-cli/internal/storage"
+)
+
+// readSSEEvent reads lines until a complete event with data is received
+func readSSEEvent(t *testing.T, r *bufio.Reader) (id string, ev storage.Event) {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Stream ended: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ev); err != nil {
+				t.Fatalf("Invalid event data: %v", err)
+			}
+		case line == "" && id != "":
+			return id, ev
+		}
+	}
+}
+
+func TestSSEStreamAndResume(t *testing.T) {
+	srv, store, cleanup := setupTestServer(t)
+	defer cleanup()
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	store.SaveNode(node.NewNode("First", "", nil, ""))
+
+	// Resuming from 0 receives only live events; from a cursor, the replay
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/v1/events", nil)
+	req.Header.Set("Last-Event-ID", "0")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected text/event-stream, got %s", ct)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	second := node.NewNode("Second", "", nil, "")
+	second.ID = "second"
+	store.SaveNode(second)
+
+	id, ev := readSSEEvent(t, reader)
+	if id != "2" || ev.Type != storage.EventCreated || ev.Node.Title != "Second" {
+		t.Errorf("Unexpected live event id=%s %+v", id, ev)
+	}
+
+	// Resume after the first event replays the second
+	resp2, err := http.Get(ts.URL + "/api/v1/events?cursor=1")
+	if err != nil {
+		t.Fatalf("Failed to resume stream: %v", err)
+	}
+	defer resp2.Body.Close()
+	id, ev = readSSEEvent(t, bufio.NewReader(resp2.Body))
+	if id != "2" || ev.Node.ID != "second" {
+		t.Errorf("Expected replay of event 2, got id=%s %+v", id, ev)
+	}
+}
+
+func TestSSEExpiredCursor(t *testing.T) {
+	srv, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	rec := doRequest(srv, http.MethodGet, "/api/v1/events?cursor=42", "", nil)
+	if rec.Code != http.StatusGone {
+		t.Errorf("Expected 410 for unknown cursor, got %d", rec.Code)
+	}
+	rec = doRequest(srv, http.MethodGet, "/api/v1/events?cursor=abc", "", nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for malformed cursor, got %d", rec.Code)
+	}
+}
+
+func TestWebSocketStream(t *testing.T) {
+	srv, store, cleanup := setupTestServer(t)
+	defer cleanup()
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	store.SaveNode(&node.Node{ID: "ws1", Title: "WS"})
+
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http") + "/api/v1/events/ws?cursor=0"
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	// Wait until the handler has subscribed before writing
+	time.Sleep(50 * time.Millisecond)
+	store.DeleteNode("ws1")
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var ev storage.Event
+	if err := conn.ReadJSON(&ev); err != nil {
+		t.Fatalf("Failed to read event: %v", err)
+	}
+	if ev.Type != storage.EventDeleted || ev.Node.ID != "ws1" || ev.Seq != 2 {
+		t.Errorf("Unexpected event %+v", ev)
+	}
+}
@@ -0,0 +1,537 @@
+package api
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"manu-node-cli/internal/attribute"
+	"manu-node-cli/internal/auth"
+	"manu-node-cli/internal/labels"
+	"manu-node-cli/internal/node"
+	"manu-node-cli/internal/storage"
+)
+
+const (
+	nodesPath = "/api/v1/nodes"
+
+	defaultLimit = 50
+	maxLimit     = 500
+)
+
+//go:embed openapi.json
+var openAPISpec []byte
+
+// sessionKey stores the request's *auth.Session in its context
+type sessionKey struct{}
+
+// Server exposes manufacturing nodes over a versioned REST API
+type Server struct {
+	store *storage.Storage
+	authz *auth.Authorizer
+	attrs *attribute.Store
+	mux   *http.ServeMux
+}
+
+// NewServer creates an HTTP handler backed by the given storage. Requests
+// are checked by authz; a nil authorizer leaves the API open.
+func NewServer(store *storage.Storage, authz *auth.Authorizer) *Server {
+	s := &Server{
+		store: store,
+		authz: authz,
+		mux:   http.NewServeMux(),
+	}
+	s.mux.HandleFunc(nodesPath, s.handleNodes)
+	s.mux.HandleFunc(nodesPath+"/", s.handleNode)
+	s.mux.HandleFunc(eventsPath, s.handleSSE)
+	s.mux.HandleFunc(eventsPath+"/ws", s.handleWebSocket)
+	s.mux.HandleFunc("/openapi.json", s.handleOpenAPI)
+	return s
+}
+
+// SetAttributes enables filtering nodes by custom attributes with the
+// attr query parameter
+func (s *Server) SetAttributes(attrs *attribute.Store) {
+	s.attrs = attrs
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// The spec is public so clients can discover how to authenticate
+	if r.URL.Path == "/openapi.json" || s.authz == nil {
+		s.mux.ServeHTTP(w, r)
+		return
+	}
+
+	sess, err := s.authz.Session(bearerToken(r))
+	if err != nil {
+		s.writeAuthError(w, err)
+		return
+	}
+	s.mux.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), sessionKey{}, sess)))
+}
+
+// session returns the authenticated session for a request, or nil when
+// access control is not configured
+func session(r *http.Request) *auth.Session {
+	sess, _ := r.Context().Value(sessionKey{}).(*auth.Session)
+	return sess
+}
+
+// bearerToken extracts the API token from the Authorization header. The
+// access_token query parameter is accepted for WebSocket and EventSource
+// clients, which cannot set headers.
+func bearerToken(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(h, "Bearer "))
+	}
+	return r.URL.Query().Get("access_token")
+}
+
+// nodeInput is the request body for creating a node
+type nodeInput struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Operations  []string `json:"operations"`
+	UNSAddress  string   `json:"uns_address"`
+
+	Attributes map[string]string `json:"attributes"`
+	Labels     map[string]string `json:"labels"`
+}
+
+// nodePatch is the request body for PATCH; absent fields are left unchanged
+type nodePatch struct {
+	Title       *string   `json:"title"`
+	Description *string   `json:"description"`
+	Operations  *[]string `json:"operations"`
+	UNSAddress  *string   `json:"uns_address"`
+
+	// Attributes are merged into the node's values; an empty string
+	// removes an attribute
+	Attributes map[string]string `json:"attributes"`
+	// Labels are merged the same way as attributes
+	Labels map[string]string `json:"labels"`
+}
+
+// nodeList is the paginated response body for GET /api/v1/nodes
+type nodeList struct {
+	Items  []*node.Node `json:"items"`
+	Total  int          `json:"total"`
+	Limit  int          `json:"limit"`
+	Offset int          `json:"offset"`
+}
+
+// errorBody is the JSON shape of every error response
+type errorBody struct {
+	Error struct {
+		Status  int    `json:"status"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (s *Server) handleNodes(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.listNodes(w, r)
+	case http.MethodPost:
+		s.createNode(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+}
+
+func (s *Server) handleNode(w http.ResponseWriter, r *http.Request) {
+	// Use the escaped path so titles containing '/' survive as one segment
+	raw := strings.TrimPrefix(r.URL.EscapedPath(), nodesPath+"/")
+	identifier, err := url.PathUnescape(raw)
+	if err != nil || identifier == "" {
+		writeError(w, http.StatusNotFound, "invalid node reference")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.getNode(w, r, identifier)
+	case http.MethodPatch:
+		s.patchNode(w, r, identifier)
+	case http.MethodDelete:
+		s.deleteNode(w, r, identifier)
+	default:
+		w.Header().Set("Allow", "GET, PATCH, DELETE")
+		writeError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+}
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
+
+func (s *Server) listNodes(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	limit, err := queryInt(q, "limit", defaultLimit)
+	if err != nil || limit < 1 || limit > maxLimit {
+		writeError(w, http.StatusBadRequest, "limit must be between 1 and %d", maxLimit)
+		return
+	}
+	offset, err := queryInt(q, "offset", 0)
+	if err != nil || offset < 0 {
+		writeError(w, http.StatusBadRequest, "offset must be a non-negative integer")
+		return
+	}
+
+	selector, err := labels.ParseSelector(q.Get("selector"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	var filters []*attribute.Filter
+	if exprs := q["attr"]; len(exprs) > 0 {
+		if s.attrs == nil {
+			writeError(w, http.StatusBadRequest, "attribute filters are not available")
+			return
+		}
+		if filters, err = s.attrs.Filters(exprs); err != nil {
+			writeError(w, http.StatusBadRequest, "%v", err)
+			return
+		}
+	}
+
+	nodes, err := s.store.Load()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load nodes: %v", err)
+		return
+	}
+	if nodes, err = session(r).Visible(nodes); err != nil {
+		s.writeAuthError(w, err)
+		return
+	}
+
+	// Apply filters
+	prefix := q.Get("uns_prefix")
+	operation := q.Get("operation")
+	filtered := []*node.Node{}
+	for _, n := range nodes {
+		if prefix != "" && !n.HasUNSPrefix(prefix) {
+			continue
+		}
+		if operation != "" && !n.HasOperation(operation) {
+			continue
+		}
+		if !selector.Matches(n.Labels) || !attribute.MatchAll(filters, n.Attributes) {
+			continue
+		}
+		filtered = append(filtered, n)
+	}
+
+	// Apply pagination
+	page := []*node.Node{}
+	if offset < len(filtered) {
+		end := offset + limit
+		if end > len(filtered) {
+			end = len(filtered)
+		}
+		page = filtered[offset:end]
+	}
+
+	writeJSON(w, http.StatusOK, nodeList{
+		Items:  page,
+		Total:  len(filtered),
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+func (s *Server) createNode(w http.ResponseWriter, r *http.Request) {
+	var in nodeInput
+	if err := decodeBody(r, &in); err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	n := node.NewNode(strings.TrimSpace(in.Title), strings.TrimSpace(in.Description),
+		cleanOperations(in.Operations), strings.TrimSpace(in.UNSAddress))
+	// IDs have one-second resolution, so API clients can easily collide
+	n.ID = node.UniqueID(func(id string) bool {
+		_, err := s.store.GetNode(id)
+		return err == nil
+	})
+	if len(in.Attributes) > 0 {
+		n.Attributes = in.Attributes
+	}
+	if len(in.Labels) > 0 {
+		n.Labels = in.Labels
+	}
+	if err := n.Validate(); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "%v", err)
+		return
+	}
+	if err := session(r).CanNode(auth.PermNodesWrite, n.UNSAddress); err != nil {
+		s.writeAuthError(w, err)
+		return
+	}
+
+	unique, err := s.store.IsTitleUnique(n.Title, "")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to check title uniqueness: %v", err)
+		return
+	}
+	if !unique {
+		writeError(w, http.StatusConflict, "a node with title '%s' already exists", n.Title)
+		return
+	}
+
+	if err := s.store.SaveNode(n); err != nil {
+		s.writeStoreError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", nodesPath+"/"+url.PathEscape(n.ID))
+	w.Header().Set("ETag", n.ETag())
+	writeJSON(w, http.StatusCreated, n)
+}
+
+func (s *Server) getNode(w http.ResponseWriter, r *http.Request, identifier string) {
+	n, ok := s.lookup(w, identifier)
+	if !ok {
+		return
+	}
+	if err := session(r).CanNode(auth.PermNodesRead, n.UNSAddress); err != nil {
+		s.writeAuthError(w, err)
+		return
+	}
+
+	etag := n.ETag()
+	w.Header().Set("ETag", etag)
+	if match := r.Header.Get("If-None-Match"); match != "" && etagMatches(match, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeJSON(w, http.StatusOK, n)
+}
+
+func (s *Server) patchNode(w http.ResponseWriter, r *http.Request, identifier string) {
+	existing, ok := s.lookup(w, identifier)
+	if !ok {
+		return
+	}
+	if err := session(r).CanNode(auth.PermNodesWrite, existing.UNSAddress); err != nil {
+		s.writeAuthError(w, err)
+		return
+	}
+
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch != "" && !etagMatches(ifMatch, existing.ETag()) {
+		writeError(w, http.StatusPreconditionFailed, "node has been modified; fetch it again and retry")
+		return
+	}
+
+	var patch nodePatch
+	if err := decodeBody(r, &patch); err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	updated := *existing.Clone()
+	if patch.Title != nil {
+		updated.Title = strings.TrimSpace(*patch.Title)
+	}
+	if patch.Description != nil {
+		updated.Description = strings.TrimSpace(*patch.Description)
+	}
+	if patch.Operations != nil {
+		updated.Operations = cleanOperations(*patch.Operations)
+	}
+	if patch.UNSAddress != nil {
+		updated.UNSAddress = strings.TrimSpace(*patch.UNSAddress)
+	}
+	updated.Attributes = mergeValues(updated.Attributes, patch.Attributes)
+	updated.Labels = mergeValues(updated.Labels, patch.Labels)
+	if err := updated.Validate(); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "%v", err)
+		return
+	}
+	// Moving a node requires write access at the destination as well
+	if err := session(r).CanNode(auth.PermNodesWrite, updated.UNSAddress); err != nil {
+		s.writeAuthError(w, err)
+		return
+	}
+
+	if !strings.EqualFold(updated.Title, existing.Title) {
+		unique, err := s.store.IsTitleUnique(updated.Title, existing.ID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to check title uniqueness: %v", err)
+			return
+		}
+		if !unique {
+			writeError(w, http.StatusConflict, "a node with title '%s' already exists", updated.Title)
+			return
+		}
+	}
+
+	updated.UpdatedAt = time.Now()
+	// Re-check the tag under the storage lock so concurrent writers
+	// between lookup and save are detected as well
+	if err := s.store.UpdateNodeIfMatch(existing.ID, existing.ETag(), &updated); err != nil {
+		s.writeStoreError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", updated.ETag())
+	writeJSON(w, http.StatusOK, &updated)
+}
+
+func (s *Server) deleteNode(w http.ResponseWriter, r *http.Request, identifier string) {
+	existing, ok := s.lookup(w, identifier)
+	if !ok {
+		return
+	}
+	if err := session(r).CanNode(auth.PermNodesWrite, existing.UNSAddress); err != nil {
+		s.writeAuthError(w, err)
+		return
+	}
+
+	etag := existing.ETag()
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && !etagMatches(ifMatch, etag) {
+		writeError(w, http.StatusPreconditionFailed, "node has been modified; fetch it again and retry")
+		return
+	}
+
+	if err := s.store.DeleteNodeIfMatch(existing.ID, etag); err != nil {
+		s.writeStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// lookup resolves an ID or title, writing an error response on failure
+func (s *Server) lookup(w http.ResponseWriter, identifier string) (*node.Node, bool) {
+	n, err := s.store.GetNodeByIDOrTitle(identifier)
+	if err != nil {
+		s.writeStoreError(w, err)
+		return nil, false
+	}
+	return n, true
+}
+
+// writeStoreError maps storage errors onto HTTP status codes
+func (s *Server) writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		writeError(w, http.StatusNotFound, "%v", err)
+	case errors.Is(err, storage.ErrAmbiguous), errors.Is(err, storage.ErrLinked):
+		writeError(w, http.StatusConflict, "%v", err)
+	case errors.Is(err, storage.ErrPreconditionFailed):
+		writeError(w, http.StatusPreconditionFailed, "%v", err)
+	case errors.Is(err, attribute.ErrInvalid):
+		writeError(w, http.StatusUnprocessableEntity, "%v", err)
+	default:
+		writeError(w, http.StatusInternalServerError, "%v", err)
+	}
+}
+
+// writeAuthError maps authentication and authorization errors
+func (s *Server) writeAuthError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrUnauthenticated), errors.Is(err, auth.ErrInvalidToken):
+		w.Header().Set("WWW-Authenticate", `Bearer realm="manu-node"`)
+		writeError(w, http.StatusUnauthorized, "%v", err)
+	case errors.Is(err, auth.ErrForbidden):
+		writeError(w, http.StatusForbidden, "%v", err)
+	default:
+		writeError(w, http.StatusInternalServerError, "%v", err)
+	}
+}
+
+// Helper functions
+
+// mergeValues applies a PATCH map onto current; empty values remove keys
+func mergeValues(current, patch map[string]string) map[string]string {
+	if len(patch) == 0 {
+		return current
+	}
+	merged := map[string]string{}
+	for k, v := range current {
+		merged[k] = v
+	}
+	for k, v := range patch {
+		if strings.TrimSpace(v) == "" {
+			delete(merged, k)
+		} else {
+			merged[k] = v
+		}
+	}
+	if len(merged) == 0 {
+		return nil
+	}
+	return merged
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	var body errorBody
+	body.Error.Status = status
+	body.Error.Message = fmt.Sprintf(format, args...)
+	writeJSON(w, status, body)
+}
+
+func decodeBody(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid JSON body: %v", err)
+	}
+	return nil
+}
+
+func queryInt(q url.Values, key string, def int) (int, error) {
+	v := q.Get(key)
+	if v == "" {
+		return def, nil
+	}
+	return strconv.Atoi(v)
+}
+
+// cleanOperations trims operation names and drops empty entries
+func cleanOperations(ops []string) []string {
+	var cleaned []string
+	for _, op := range ops {
+		if op = strings.TrimSpace(op); op != "" {
+			cleaned = append(cleaned, op)
+		}
+	}
+	return cleaned
+}
+
+// etagMatches checks a comma-separated If-Match / If-None-Match header
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
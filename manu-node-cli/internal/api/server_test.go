@@ -0,0 +1,390 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"manu-node-cli/internal/attribute"
+	"manu-node-cli/internal/auth"
+	"manu-node-cli/internal/node"
+	"manu-node-cli/internal/storage"
+)
+
+func setupTestServer(t *testing.T) (*Server, *storage.Storage, func()) {
+	tempDir, err := os.MkdirTemp("", "api_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+
+	store, err := storage.NewStorage(tempDir)
+	if err != nil {
+		os.RemoveAll(tempDir)
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+
+	cleanup := func() {
+		os.RemoveAll(tempDir)
+	}
+
+	return NewServer(store, nil), store, cleanup
+}
+
+func seedNodes(t *testing.T, store *storage.Storage) {
+	nodes := []*node.Node{
+		{ID: "n1", Title: "CNC", Operations: []string{"BigShelf", "SmallShelf"},
+			UNSAddress: "StribrneHory/Dilna/NovaBudova/CNC", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ID: "n2", Title: "Saw", Operations: []string{"cut"},
+			UNSAddress: "StribrneHory/Dilna/StaraBudova/Saw", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ID: "n3", Title: "Press", Operations: []string{"press"},
+			UNSAddress: "Other/Area/Press", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+	}
+	if err := store.Save(nodes); err != nil {
+		t.Fatalf("Failed to seed nodes: %v", err)
+	}
+}
+
+func doRequest(srv http.Handler, method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+	var req *http.Request
+	if body != "" {
+		req = httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+	} else {
+		req = httptest.NewRequest(method, path, nil)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestListNodesFilteringAndPagination(t *testing.T) {
+	srv, store, cleanup := setupTestServer(t)
+	defer cleanup()
+	seedNodes(t, store)
+
+	rec := doRequest(srv, http.MethodGet, "/api/v1/nodes?uns_prefix=StribrneHory/Dilna", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var list nodeList
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatalf("Failed to decode list: %v", err)
+	}
+	if list.Total != 2 {
+		t.Errorf("Expected 2 nodes under prefix, got %d", list.Total)
+	}
+
+	rec = doRequest(srv, http.MethodGet, "/api/v1/nodes?operation=bigshelf", "", nil)
+	json.Unmarshal(rec.Body.Bytes(), &list)
+	if list.Total != 1 || list.Items[0].ID != "n1" {
+		t.Errorf("Expected only n1 to support BigShelf, got %+v", list.Items)
+	}
+
+	rec = doRequest(srv, http.MethodGet, "/api/v1/nodes?limit=2&offset=2", "", nil)
+	json.Unmarshal(rec.Body.Bytes(), &list)
+	if list.Total != 3 || len(list.Items) != 1 || list.Items[0].ID != "n3" {
+		t.Errorf("Expected last page with n3, got total=%d items=%d", list.Total, len(list.Items))
+	}
+
+	rec = doRequest(srv, http.MethodGet, "/api/v1/nodes?limit=0", "", nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid limit, got %d", rec.Code)
+	}
+}
+
+func TestCreateNode(t *testing.T) {
+	srv, store, cleanup := setupTestServer(t)
+	defer cleanup()
+	seedNodes(t, store)
+
+	body := `{"title": "Edge Bander", "operations": ["edge-band", " "], "uns_address": "StribrneHory/Dilna/EB"}`
+	rec := doRequest(srv, http.MethodPost, "/api/v1/nodes", body, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("ETag") == "" || rec.Header().Get("Location") == "" {
+		t.Error("Expected ETag and Location headers")
+	}
+
+	created, err := store.GetNodeByTitle("Edge Bander")
+	if err != nil {
+		t.Fatalf("Expected node to be stored: %v", err)
+	}
+	if len(created.Operations) != 1 {
+		t.Errorf("Expected empty operations to be dropped, got %v", created.Operations)
+	}
+
+	// Duplicate title (case-insensitive)
+	rec = doRequest(srv, http.MethodPost, "/api/v1/nodes", `{"title": "cnc"}`, nil)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for duplicate title, got %d", rec.Code)
+	}
+
+	// Validation failure
+	rec = doRequest(srv, http.MethodPost, "/api/v1/nodes", `{"title": ""}`, nil)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for empty title, got %d", rec.Code)
+	}
+
+	// Malformed body returns a JSON error
+	rec = doRequest(srv, http.MethodPost, "/api/v1/nodes", `{"title":`, nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for bad JSON, got %d", rec.Code)
+	}
+	var errResp errorBody
+	if err := json.Unmarshal(rec.Body.Bytes(), &errResp); err != nil || errResp.Error.Message == "" {
+		t.Errorf("Expected JSON error body, got %s", rec.Body.String())
+	}
+}
+
+func TestGetNodeByIDOrTitle(t *testing.T) {
+	srv, store, cleanup := setupTestServer(t)
+	defer cleanup()
+	seedNodes(t, store)
+
+	rec := doRequest(srv, http.MethodGet, "/api/v1/nodes/n2", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	etag := rec.Header().Get("ETag")
+
+	rec = doRequest(srv, http.MethodGet, "/api/v1/nodes/saw", "", map[string]string{"If-None-Match": etag})
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 for matching If-None-Match, got %d", rec.Code)
+	}
+
+	rec = doRequest(srv, http.MethodGet, "/api/v1/nodes/missing", "", nil)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", rec.Code)
+	}
+}
+
+func TestPatchNodeOptimisticConcurrency(t *testing.T) {
+	srv, store, cleanup := setupTestServer(t)
+	defer cleanup()
+	seedNodes(t, store)
+
+	rec := doRequest(srv, http.MethodGet, "/api/v1/nodes/n1", "", nil)
+	etag := rec.Header().Get("ETag")
+
+	rec = doRequest(srv, http.MethodPatch, "/api/v1/nodes/n1", `{"description": "updated"}`,
+		map[string]string{"If-Match": etag})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("ETag") == etag {
+		t.Error("Expected a new ETag after update")
+	}
+
+	n, _ := store.GetNode("n1")
+	if n.Description != "updated" || n.Title != "CNC" {
+		t.Errorf("Expected only description to change, got %+v", n)
+	}
+
+	// Stale ETag is rejected
+	rec = doRequest(srv, http.MethodPatch, "/api/v1/nodes/n1", `{"description": "stale"}`,
+		map[string]string{"If-Match": etag})
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for stale ETag, got %d", rec.Code)
+	}
+
+	// Renaming onto an existing title is rejected
+	rec = doRequest(srv, http.MethodPatch, "/api/v1/nodes/n1", `{"title": "Saw"}`, nil)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for duplicate title, got %d", rec.Code)
+	}
+}
+
+func TestDeleteNode(t *testing.T) {
+	srv, store, cleanup := setupTestServer(t)
+	defer cleanup()
+	seedNodes(t, store)
+
+	rec := doRequest(srv, http.MethodDelete, "/api/v1/nodes/Press", "", map[string]string{"If-Match": `"stale"`})
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for stale ETag, got %d", rec.Code)
+	}
+
+	rec = doRequest(srv, http.MethodDelete, "/api/v1/nodes/Press", "", nil)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d", rec.Code)
+	}
+	if _, err := store.GetNode("n3"); err == nil {
+		t.Error("Expected node to be deleted")
+	}
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	srv, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	rec := doRequest(srv, http.MethodGet, "/openapi.json", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	var spec map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("Spec is not valid JSON: %v", err)
+	}
+	if spec["openapi"] != "3.0.3" {
+		t.Errorf("Expected OpenAPI 3 document, got %v", spec["openapi"])
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "api_auth_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	store, _ := storage.NewStorage(tempDir)
+	users, _ := auth.NewStore(tempDir)
+	seedNodes(t, store)
+	users.AddUser("root", []auth.Role{auth.RoleAdmin}, nil)
+	users.AddUser("dilna", []auth.Role{auth.RoleDeveloper}, []string{"StribrneHory/Dilna/#"})
+	token, _, _ := users.CreateToken("dilna", "")
+	srv := NewServer(store, auth.NewAuthorizer(users))
+	bearer := map[string]string{"Authorization": "Bearer " + token}
+
+	rec := doRequest(srv, http.MethodGet, "/api/v1/nodes", "", nil)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", rec.Code)
+	}
+	rec = doRequest(srv, http.MethodGet, "/api/v1/nodes", "", map[string]string{"Authorization": "Bearer nope"})
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for invalid token, got %d", rec.Code)
+	}
+
+	rec = doRequest(srv, http.MethodGet, "/api/v1/nodes", "", bearer)
+	var list nodeList
+	json.Unmarshal(rec.Body.Bytes(), &list)
+	if rec.Code != http.StatusOK || list.Total != 2 {
+		t.Errorf("Expected 2 in-scope nodes, got %d (status %d)", list.Total, rec.Code)
+	}
+
+	rec = doRequest(srv, http.MethodDelete, "/api/v1/nodes/n3", "", bearer)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 outside scope, got %d", rec.Code)
+	}
+
+	// Moving a node out of scope is forbidden too
+	rec = doRequest(srv, http.MethodPatch, "/api/v1/nodes/n1", `{"uns_address": "Other/Area"}`, bearer)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 when moving out of scope, got %d", rec.Code)
+	}
+
+	rec = doRequest(srv, http.MethodPatch, "/api/v1/nodes/n1", `{"description": "ok"}`, bearer)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 inside scope, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = doRequest(srv, http.MethodGet, "/openapi.json", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected spec to be public, got %d", rec.Code)
+	}
+}
+
+func TestAttributes(t *testing.T) {
+	srv, store, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	attrDir, err := os.MkdirTemp("", "api_attr_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(attrDir)
+	attrs, err := attribute.NewStore(attrDir)
+	if err != nil {
+		t.Fatalf("Failed to create attribute store: %v", err)
+	}
+	if _, err := attrs.Define(attribute.Definition{Name: "power", Type: attribute.TypeNumber, Unit: "kW"}); err != nil {
+		t.Fatalf("Failed to define attribute: %v", err)
+	}
+	store.SetValidator(attrs.Apply)
+	srv.SetAttributes(attrs)
+
+	rec := doRequest(srv, http.MethodPost, "/api/v1/nodes",
+		`{"title":"Mill","attributes":{"power":"7.50"}}`, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created node.Node
+	json.Unmarshal(rec.Body.Bytes(), &created)
+	if created.Attributes["power"] != "7.5" {
+		t.Errorf("Expected canonical power 7.5, got %v", created.Attributes)
+	}
+
+	rec = doRequest(srv, http.MethodPost, "/api/v1/nodes",
+		`{"title":"Lathe","attributes":{"power":"lots"}}`, nil)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for invalid attribute, got %d", rec.Code)
+	}
+
+	rec = doRequest(srv, http.MethodPost, "/api/v1/nodes", `{"title":"Drill","attributes":{"power":"2"}}`, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = doRequest(srv, http.MethodGet, "/api/v1/nodes?attr=power%3E%3D5", "", nil)
+	var list nodeList
+	json.Unmarshal(rec.Body.Bytes(), &list)
+	if list.Total != 1 || list.Items[0].Title != "Mill" {
+		t.Errorf("Expected only Mill to match power>=5, got %+v", list.Items)
+	}
+
+	rec = doRequest(srv, http.MethodGet, "/api/v1/nodes?attr=torque%3D1", "", nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for unknown attribute filter, got %d", rec.Code)
+	}
+
+	rec = doRequest(srv, http.MethodPatch, "/api/v1/nodes/Mill", `{"attributes":{"power":""}}`, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	n, _ := store.GetNodeByTitle("Mill")
+	if len(n.Attributes) != 0 {
+		t.Errorf("Expected power to be removed, got %v", n.Attributes)
+	}
+}
+
+func TestLabelSelector(t *testing.T) {
+	srv, store, cleanup := setupTestServer(t)
+	defer cleanup()
+	seedNodes(t, store)
+
+	rec := doRequest(srv, http.MethodPatch, "/api/v1/nodes/CNC",
+		`{"labels":{"dept":"woodshop","criticality":"high"}}`, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = doRequest(srv, http.MethodPatch, "/api/v1/nodes/Saw", `{"labels":{"dept":"woodshop"}}`, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = doRequest(srv, http.MethodPatch, "/api/v1/nodes/Press", `{"labels":{"dept":"wood shop"}}`, nil)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for an invalid label, got %d", rec.Code)
+	}
+
+	query := url.Values{"selector": {"dept=woodshop,criticality notin (high)"}}
+	rec = doRequest(srv, http.MethodGet, "/api/v1/nodes?"+query.Encode(), "", nil)
+	var list nodeList
+	json.Unmarshal(rec.Body.Bytes(), &list)
+	if list.Total != 1 || list.Items[0].Title != "Saw" {
+		t.Errorf("Expected only Saw to match, got %+v", list.Items)
+	}
+
+	rec = doRequest(srv, http.MethodGet, "/api/v1/nodes?selector=a+in+(b", "", nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid selector, got %d", rec.Code)
+	}
+}
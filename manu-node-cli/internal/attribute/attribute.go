@@ -0,0 +1,255 @@
+// Package attribute lets administrators extend nodes with typed fields
+// that differ from plant to plant, such as power rating or PLC type
+package attribute
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Type is the value type of an attribute
+type Type string
+
+const (
+	TypeString   Type = "string"
+	TypeNumber   Type = "number"
+	TypeEnum     Type = "enum"
+	TypeBool     Type = "bool"
+	TypeDate     Type = "date"
+	TypeDuration Type = "duration"
+)
+
+// Types lists every supported type
+var Types = []Type{TypeString, TypeNumber, TypeEnum, TypeBool, TypeDate, TypeDuration}
+
+// dateLayout is the canonical form of date values
+const dateLayout = "2006-01-02"
+
+// ErrInvalid is returned when node attribute values fail validation
+var ErrInvalid = errors.New("invalid attributes")
+
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// ParseType converts a type name into a Type
+func ParseType(s string) (Type, error) {
+	for _, t := range Types {
+		if strings.EqualFold(s, string(t)) {
+			return t, nil
+		}
+	}
+	names := make([]string, len(Types))
+	for i, t := range Types {
+		names[i] = string(t)
+	}
+	return "", fmt.Errorf("unknown attribute type '%s' (use %s)", s, strings.Join(names, ", "))
+}
+
+// Definition describes an attribute that nodes may carry
+type Definition struct {
+	Name        string   `json:"name"`
+	Type        Type     `json:"type"`
+	Unit        string   `json:"unit,omitempty"`
+	Required    bool     `json:"required,omitempty"`
+	Default     string   `json:"default,omitempty"`
+	Values      []string `json:"values,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+// Check validates the definition itself and canonicalizes its default
+func (d *Definition) Check() error {
+	if !namePattern.MatchString(d.Name) {
+		return fmt.Errorf("attribute name '%s' must start with a letter and contain only lowercase letters, digits and '_'", d.Name)
+	}
+	if _, err := ParseType(string(d.Type)); err != nil {
+		return err
+	}
+	if d.Type == TypeEnum {
+		if len(d.Values) == 0 {
+			return fmt.Errorf("enum attribute '%s' needs at least one value", d.Name)
+		}
+		seen := map[string]bool{}
+		for _, v := range d.Values {
+			if strings.TrimSpace(v) == "" || seen[strings.ToLower(v)] {
+				return fmt.Errorf("enum attribute '%s' has an empty or duplicate value", d.Name)
+			}
+			seen[strings.ToLower(v)] = true
+		}
+	} else if len(d.Values) > 0 {
+		return fmt.Errorf("only enum attributes take a list of values")
+	}
+	if d.Default != "" {
+		v, err := d.Normalize(d.Default)
+		if err != nil {
+			return fmt.Errorf("invalid default: %w", err)
+		}
+		d.Default = v
+	}
+	return nil
+}
+
+// Label returns the name followed by the unit, if any
+func (d *Definition) Label() string {
+	if d.Unit != "" {
+		return fmt.Sprintf("%s (%s)", d.Name, d.Unit)
+	}
+	return d.Name
+}
+
+// Hint describes the accepted input for prompts and help output
+func (d *Definition) Hint() string {
+	switch d.Type {
+	case TypeEnum:
+		return strings.Join(d.Values, "|")
+	case TypeBool:
+		return "yes|no"
+	case TypeDate:
+		return "YYYY-MM-DD"
+	case TypeDuration:
+		return "e.g. 90m, 8h"
+	default:
+		return string(d.Type)
+	}
+}
+
+// Normalize parses raw according to the attribute type and returns its
+// canonical string form
+func (d *Definition) Normalize(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", fmt.Errorf("%s cannot be empty", d.Name)
+	}
+
+	switch d.Type {
+	case TypeNumber:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return "", fmt.Errorf("%s must be a number, got '%s'", d.Name, raw)
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	case TypeEnum:
+		for _, v := range d.Values {
+			if strings.EqualFold(v, raw) {
+				return v, nil
+			}
+		}
+		return "", fmt.Errorf("%s must be one of %s, got '%s'", d.Name, strings.Join(d.Values, ", "), raw)
+	case TypeBool:
+		switch strings.ToLower(raw) {
+		case "true", "yes", "y", "1":
+			return "true", nil
+		case "false", "no", "n", "0":
+			return "false", nil
+		}
+		return "", fmt.Errorf("%s must be yes or no, got '%s'", d.Name, raw)
+	case TypeDate:
+		t, err := time.Parse(dateLayout, raw)
+		if err != nil {
+			return "", fmt.Errorf("%s must be a date (YYYY-MM-DD), got '%s'", d.Name, raw)
+		}
+		return t.Format(dateLayout), nil
+	case TypeDuration:
+		dur, err := time.ParseDuration(raw)
+		if err != nil || dur < 0 {
+			return "", fmt.Errorf("%s must be a duration such as 90m or 8h, got '%s'", d.Name, raw)
+		}
+		return dur.String(), nil
+	default:
+		return raw, nil
+	}
+}
+
+// compare orders two canonical values of this attribute
+func (d *Definition) compare(a, b string) int {
+	switch d.Type {
+	case TypeNumber:
+		x, _ := strconv.ParseFloat(a, 64)
+		y, _ := strconv.ParseFloat(b, 64)
+		return compareOrdered(x, y)
+	case TypeDuration:
+		x, _ := time.ParseDuration(a)
+		y, _ := time.ParseDuration(b)
+		return compareOrdered(x, y)
+	case TypeString:
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	default:
+		// Dates, enums and booleans compare correctly in canonical form
+		return strings.Compare(a, b)
+	}
+}
+
+func compareOrdered[T float64 | time.Duration](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Find returns the definition with the given name
+func Find(defs []Definition, name string) (*Definition, bool) {
+	for i := range defs {
+		if defs[i].Name == name {
+			return &defs[i], true
+		}
+	}
+	return nil, false
+}
+
+// Validate checks a node's attribute values against defs and returns them
+// in canonical form. Missing required attributes take their default;
+// empty values are dropped.
+func Validate(defs []Definition, values map[string]string) (map[string]string, error) {
+	var problems []string
+	out := map[string]string{}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		raw := values[name]
+		d, ok := Find(defs, name)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("unknown attribute '%s'", name))
+			continue
+		}
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		v, err := d.Normalize(raw)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		out[name] = v
+	}
+
+	for _, d := range defs {
+		if _, ok := out[d.Name]; ok || !d.Required {
+			continue
+		}
+		if d.Default != "" {
+			out[d.Name] = d.Default
+		} else if strings.TrimSpace(values[d.Name]) == "" {
+			problems = append(problems, fmt.Sprintf("%s is required", d.Name))
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalid, strings.Join(problems, "; "))
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return out, nil
+}
@@ -0,0 +1,160 @@
+package attribute
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"manu-node-cli/internal/node"
+)
+
+func setupTestStore(t *testing.T) (*Store, func()) {
+	tempDir, err := os.MkdirTemp("", "attribute_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	store, err := NewStore(tempDir)
+	if err != nil {
+		os.RemoveAll(tempDir)
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	return store, func() { os.RemoveAll(tempDir) }
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		def     Definition
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{Definition{Name: "power", Type: TypeNumber}, " 7.50 ", "7.5", false},
+		{Definition{Name: "power", Type: TypeNumber}, "lots", "", true},
+		{Definition{Name: "plc", Type: TypeEnum, Values: []string{"S7", "Logix"}}, "s7", "S7", false},
+		{Definition{Name: "plc", Type: TypeEnum, Values: []string{"S7", "Logix"}}, "Beckhoff", "", true},
+		{Definition{Name: "mobile", Type: TypeBool}, "Yes", "true", false},
+		{Definition{Name: "mobile", Type: TypeBool}, "maybe", "", true},
+		{Definition{Name: "installed", Type: TypeDate}, "2024-03-01", "2024-03-01", false},
+		{Definition{Name: "installed", Type: TypeDate}, "01.03.2024", "", true},
+		{Definition{Name: "setup", Type: TypeDuration}, "90m", "1h30m0s", false},
+		{Definition{Name: "setup", Type: TypeDuration}, "-5m", "", true},
+		{Definition{Name: "vendor", Type: TypeString}, " Haas ", "Haas", false},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.def.Normalize(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s(%q): expected error %v, got %v", tt.def.Type, tt.input, tt.wantErr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s(%q): expected %q, got %q", tt.def.Type, tt.input, tt.want, got)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	defs := []Definition{
+		{Name: "power", Type: TypeNumber, Required: true},
+		{Name: "plc", Type: TypeEnum, Values: []string{"S7"}, Required: true, Default: "S7"},
+		{Name: "note", Type: TypeString},
+	}
+
+	got, err := Validate(defs, map[string]string{"power": "5.0", "note": ""})
+	if err != nil {
+		t.Fatalf("Expected valid values, got %v", err)
+	}
+	if got["power"] != "5" || got["plc"] != "S7" {
+		t.Errorf("Expected canonical value and default, got %v", got)
+	}
+	if _, ok := got["note"]; ok {
+		t.Errorf("Expected empty value to be dropped, got %v", got)
+	}
+
+	if _, err := Validate(defs, nil); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected missing required attribute to fail, got %v", err)
+	}
+	if _, err := Validate(defs, map[string]string{"power": "1", "torque": "3"}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected unknown attribute to fail, got %v", err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	defs := []Definition{
+		{Name: "power", Type: TypeNumber},
+		{Name: "setup", Type: TypeDuration},
+		{Name: "plc", Type: TypeEnum, Values: []string{"S7", "Logix"}},
+	}
+	values := map[string]string{"power": "10", "setup": "1h30m0s", "plc": "S7"}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"power>=10", true},
+		{"power>10", false},
+		{"power<9.5", false},
+		{"setup<2h", true},
+		{"plc=s7", true},
+		{"plc!=S7", false},
+		{"power", true},
+	}
+	for _, tt := range tests {
+		f, err := ParseFilter(defs, tt.expr)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.expr, err)
+			continue
+		}
+		if got := f.Match(values); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.expr, tt.want, got)
+		}
+	}
+
+	f, _ := ParseFilter(defs, "power!=3")
+	if !f.Match(nil) {
+		t.Errorf("Expected != to match nodes without the attribute")
+	}
+	for _, bad := range []string{"torque=1", "power>=abc", "plc>S7", "power=>1"} {
+		if _, err := ParseFilter(defs, bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestStore(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	if _, err := store.Define(Definition{Name: "Power", Type: TypeNumber}); err == nil {
+		t.Errorf("Expected uppercase name to be rejected")
+	}
+	if _, err := store.Define(Definition{Name: "plc", Type: TypeEnum}); err == nil {
+		t.Errorf("Expected enum without values to be rejected")
+	}
+	if _, err := store.Define(Definition{Name: "power", Type: TypeNumber, Default: "x"}); err == nil {
+		t.Errorf("Expected invalid default to be rejected")
+	}
+	if _, err := store.Define(Definition{Name: "power", Type: TypeNumber, Unit: "kW"}); err != nil {
+		t.Fatalf("Failed to define attribute: %v", err)
+	}
+	if _, err := store.Define(Definition{Name: "power", Type: TypeNumber, Unit: "W"}); err != nil {
+		t.Fatalf("Failed to redefine attribute: %v", err)
+	}
+
+	defs, _ := store.Load()
+	if len(defs) != 1 || defs[0].Unit != "W" {
+		t.Errorf("Expected redefinition to replace the attribute, got %+v", defs)
+	}
+
+	n := &node.Node{Title: "Mill", Attributes: map[string]string{"power": "1e3"}}
+	if err := store.Apply(n); err != nil || n.Attributes["power"] != "1000" {
+		t.Errorf("Expected Apply to canonicalize values, got %v, %v", n.Attributes, err)
+	}
+
+	if err := store.Remove("power"); err != nil {
+		t.Fatalf("Failed to remove attribute: %v", err)
+	}
+	if err := store.Remove("power"); err == nil {
+		t.Errorf("Expected removing a missing attribute to fail")
+	}
+}
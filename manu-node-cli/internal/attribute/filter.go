@@ -0,0 +1,83 @@
+package attribute
+
+import (
+	"fmt"
+	"strings"
+)
+
+// operators are tried longest first so that ">=" is not read as ">"
+var operators = []string{">=", "<=", "!=", "=", ">", "<"}
+
+// Filter selects nodes by one attribute, e.g. "power>=5" or "plc=S7".
+// A bare name matches every node that has the attribute set.
+type Filter struct {
+	def   *Definition
+	op    string
+	value string
+}
+
+// ParseFilter parses expr against the known definitions
+func ParseFilter(defs []Definition, expr string) (*Filter, error) {
+	name, op, value := strings.TrimSpace(expr), "", ""
+	if i := strings.IndexAny(expr, "=!<>"); i >= 0 {
+		name = strings.TrimSpace(expr[:i])
+		rest := expr[i:]
+		for _, candidate := range operators {
+			if strings.HasPrefix(rest, candidate) {
+				op = candidate
+				value = rest[len(candidate):]
+				break
+			}
+		}
+		if op == "" {
+			return nil, fmt.Errorf("invalid attribute filter '%s'", expr)
+		}
+	}
+
+	d, ok := Find(defs, name)
+	if !ok {
+		return nil, fmt.Errorf("unknown attribute '%s'", name)
+	}
+	f := &Filter{def: d, op: op}
+	if op == "" {
+		return f, nil
+	}
+
+	v, err := d.Normalize(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attribute filter '%s': %w", expr, err)
+	}
+	if op != "=" && op != "!=" && (d.Type == TypeBool || d.Type == TypeEnum) {
+		return nil, fmt.Errorf("%s attributes only support = and !=", d.Type)
+	}
+	f.value = v
+	return f, nil
+}
+
+// Match reports whether a node's attribute values satisfy the filter.
+// Nodes without the attribute only match "!=".
+func (f *Filter) Match(values map[string]string) bool {
+	v, ok := values[f.def.Name]
+	if !ok {
+		return f.op == "!="
+	}
+
+	c := f.def.compare(v, f.value)
+	switch f.op {
+	case "":
+		return true
+	case "=":
+		return c == 0
+	case "!=":
+		return c != 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package attribute
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"manu-node-cli/internal/node"
+)
+
+// Store handles persistence of attribute definitions
+type Store struct {
+	filePath string
+	mu       sync.RWMutex
+}
+
+// NewStore creates a definition store in the data directory
+func NewStore(dataDir string) (*Store, error) {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	return &Store{filePath: filepath.Join(dataDir, "attributes.json")}, nil
+}
+
+// Load reads all definitions in the order they were defined
+func (s *Store) Load() ([]Definition, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.load()
+}
+
+func (s *Store) load() ([]Definition, error) {
+	data, err := os.ReadFile(s.filePath)
+	if os.IsNotExist(err) {
+		return []Definition{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attributes file: %w", err)
+	}
+	if len(data) == 0 {
+		return []Definition{}, nil
+	}
+
+	var defs []Definition
+	if err := json.Unmarshal(data, &defs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal attributes: %w", err)
+	}
+	return defs, nil
+}
+
+func (s *Store) save(defs []Definition) error {
+	data, err := json.MarshalIndent(defs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal attributes: %w", err)
+	}
+	tmp := s.filePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write attributes file: %w", err)
+	}
+	if err := os.Rename(tmp, s.filePath); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write attributes file: %w", err)
+	}
+	return nil
+}
+
+// Define adds a definition or replaces the one with the same name. Values
+// already stored on nodes are not checked; callers should check them
+// against the new definition.
+func (s *Store) Define(d Definition) (*Definition, error) {
+	if err := d.Check(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	defs, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	if existing, ok := Find(defs, d.Name); ok {
+		*existing = d
+	} else {
+		defs = append(defs, d)
+	}
+	if err := s.save(defs); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// Remove deletes a definition. Values already stored on nodes are not
+// touched; callers should strip them as well.
+func (s *Store) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	defs, err := s.load()
+	if err != nil {
+		return err
+	}
+	var kept []Definition
+	for _, d := range defs {
+		if d.Name != name {
+			kept = append(kept, d)
+		}
+	}
+	if len(kept) == len(defs) {
+		return fmt.Errorf("attribute '%s' not found", name)
+	}
+	return s.save(kept)
+}
+
+// Apply validates n's attribute values against the current definitions
+// and replaces them with their canonical form. It is meant to be
+// registered as a storage validator.
+func (s *Store) Apply(n *node.Node) error {
+	defs, err := s.Load()
+	if err != nil {
+		return err
+	}
+	values, err := Validate(defs, n.Attributes)
+	if err != nil {
+		return fmt.Errorf("node '%s': %w", n.Title, err)
+	}
+	n.Attributes = values
+	return nil
+}
+
+// Filters parses a list of filter expressions
+func (s *Store) Filters(exprs []string) ([]*Filter, error) {
+	if len(exprs) == 0 {
+		return nil, nil
+	}
+	defs, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+	filters := make([]*Filter, 0, len(exprs))
+	for _, expr := range exprs {
+		f, err := ParseFilter(defs, expr)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	return filters, nil
+}
+
+// MatchAll reports whether values satisfy every filter
+func MatchAll(filters []*Filter, values map[string]string) bool {
+	for _, f := range filters {
+		if !f.Match(values) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"manu-node-cli/internal/node"
+)
+
+// Permission is a single action a user may perform
+type Permission string
+
+const (
+	PermNodesRead        Permission = "nodes:read"
+	PermNodesWrite       Permission = "nodes:write"
+	PermRoutingsWrite    Permission = "routings:write"
+	PermWorkOrdersWrite  Permission = "workorders:write"
+	PermKPIsRead         Permission = "kpis:read"
+	PermUsersManage      Permission = "users:manage"
+	PermBackupsManage    Permission = "backups:manage"
+	PermAttributesManage Permission = "attributes:manage"
+)
+
+// Role groups permissions along the modules described in PROJECT.md
+type Role string
+
+const (
+	RoleAdmin     Role = "admin"
+	RoleDeveloper Role = "developer"
+	RolePlanner   Role = "planner"
+	RoleAnalyst   Role = "analyst"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleAdmin: {PermNodesRead, PermNodesWrite, PermRoutingsWrite, PermWorkOrdersWrite,
+		PermKPIsRead, PermUsersManage, PermBackupsManage, PermAttributesManage},
+	RoleDeveloper: {PermNodesRead, PermNodesWrite, PermKPIsRead},
+	RolePlanner:   {PermNodesRead, PermRoutingsWrite, PermWorkOrdersWrite, PermKPIsRead},
+	RoleAnalyst:   {PermNodesRead, PermKPIsRead},
+}
+
+// ErrUnauthenticated is returned when access control is enabled but no
+// valid credentials were presented
+var ErrUnauthenticated = errors.New("authentication required")
+
+// ErrForbidden is returned when the user lacks a permission
+var ErrForbidden = errors.New("permission denied")
+
+// ParseRole validates a role name
+func ParseRole(s string) (Role, error) {
+	r := Role(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := rolePermissions[r]; !ok {
+		return "", fmt.Errorf("unknown role '%s' (valid: admin, developer, planner, analyst)", s)
+	}
+	return r, nil
+}
+
+// ValidateScope checks an MQTT-style UNS filter such as "Site/Area/#"
+func ValidateScope(scope string) error {
+	if strings.TrimSpace(scope) == "" {
+		return errors.New("scope cannot be empty")
+	}
+	levels := strings.Split(scope, "/")
+	for i, level := range levels {
+		if strings.Contains(level, "#") && (level != "#" || i != len(levels)-1) {
+			return fmt.Errorf("invalid scope '%s': '#' must be the last level", scope)
+		}
+		if strings.Contains(level, "+") && level != "+" {
+			return fmt.Errorf("invalid scope '%s': '+' must occupy a whole level", scope)
+		}
+	}
+	return nil
+}
+
+// MatchScope reports whether a UNS address matches an MQTT-style filter.
+// "+" matches exactly one level and a trailing "#" matches the parent
+// level and everything below it.
+func MatchScope(scope, address string) bool {
+	filter := strings.Split(strings.Trim(scope, "/"), "/")
+	topic := strings.Split(strings.Trim(address, "/"), "/")
+	if address == "" {
+		topic = nil
+	}
+
+	for i, level := range filter {
+		if level == "#" {
+			return true
+		}
+		if i >= len(topic) {
+			return false
+		}
+		if level != "+" && level != topic[i] {
+			return false
+		}
+	}
+	return len(filter) == len(topic)
+}
+
+// Authorizer decides what users may do. Access control is only enforced
+// once at least one user has been registered.
+type Authorizer struct {
+	users *Store
+}
+
+// NewAuthorizer creates an authorizer backed by the given user store
+func NewAuthorizer(users *Store) *Authorizer {
+	return &Authorizer{users: users}
+}
+
+// Users returns the underlying user store
+func (a *Authorizer) Users() *Store {
+	return a.users
+}
+
+// Enabled reports whether any users exist and access control is enforced
+func (a *Authorizer) Enabled() (bool, error) {
+	users, err := a.users.Load()
+	if err != nil {
+		return false, err
+	}
+	return len(users) > 0, nil
+}
+
+// Session authenticates a token and returns the resulting session. An
+// empty token yields an anonymous session, which is only allowed to act
+// while access control is disabled.
+func (a *Authorizer) Session(token string) (*Session, error) {
+	if token == "" {
+		return &Session{authz: a}, nil
+	}
+	u, err := a.users.Authenticate(token)
+	if err != nil {
+		return nil, err
+	}
+	return &Session{authz: a, User: u}, nil
+}
+
+// Session binds the authorizer to one authenticated (or anonymous) user.
+// Both the CLI and the HTTP server perform every check through a Session.
+type Session struct {
+	authz *Authorizer
+	User  *User
+}
+
+// Name returns a display name for the session's user
+func (s *Session) Name() string {
+	if s == nil || s.User == nil {
+		return "anonymous"
+	}
+	return s.User.Name
+}
+
+// Can checks a permission that is not tied to a particular node
+func (s *Session) Can(perm Permission) error {
+	if s == nil || s.authz == nil {
+		return nil
+	}
+	enabled, err := s.authz.Enabled()
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return nil
+	}
+	if s.User == nil {
+		return ErrUnauthenticated
+	}
+	if !s.User.HasPermission(perm) {
+		return fmt.Errorf("%w: %s requires %s", ErrForbidden, s.User.Name, perm)
+	}
+	return nil
+}
+
+// CanNode checks a permission against a node's UNS address, applying the
+// user's scopes on top of their roles
+func (s *Session) CanNode(perm Permission, unsAddress string) error {
+	if err := s.Can(perm); err != nil {
+		return err
+	}
+	if s == nil || s.User == nil || len(s.User.Scopes) == 0 {
+		return nil
+	}
+	for _, scope := range s.User.Scopes {
+		if MatchScope(scope, unsAddress) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: '%s' is outside the scopes of %s", ErrForbidden, unsAddress, s.User.Name)
+}
+
+// Visible returns the nodes the session may read
+func (s *Session) Visible(nodes []*node.Node) ([]*node.Node, error) {
+	if err := s.Can(PermNodesRead); err != nil {
+		return nil, err
+	}
+	visible := []*node.Node{}
+	for _, n := range nodes {
+		if s.CanNode(PermNodesRead, n.UNSAddress) == nil {
+			visible = append(visible, n)
+		}
+	}
+	return visible, nil
+}
@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"manu-node-cli/internal/node"
+)
+
+func setupTestStore(t *testing.T) (*Store, func()) {
+	tempDir, err := os.MkdirTemp("", "auth_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+
+	store, err := NewStore(tempDir)
+	if err != nil {
+		os.RemoveAll(tempDir)
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	return store, func() { os.RemoveAll(tempDir) }
+}
+
+func TestMatchScope(t *testing.T) {
+	tests := []struct {
+		scope, address string
+		want           bool
+	}{
+		{"StribrneHory/Dilna/#", "StribrneHory/Dilna/NovaBudova/CNC", true},
+		{"StribrneHory/Dilna/#", "StribrneHory/Dilna", true},
+		{"StribrneHory/Dilna/#", "StribrneHory/Sklad/Rack", false},
+		{"StribrneHory/+/NovaBudova/#", "StribrneHory/Dilna/NovaBudova/CNC", true},
+		{"StribrneHory/+", "StribrneHory/Dilna/NovaBudova", false},
+		{"StribrneHory/Dilna", "StribrneHory/Dilna", true},
+		{"#", "", true},
+		{"Site/#", "", false},
+	}
+	for _, tt := range tests {
+		if got := MatchScope(tt.scope, tt.address); got != tt.want {
+			t.Errorf("MatchScope(%q, %q) = %v, want %v", tt.scope, tt.address, got, tt.want)
+		}
+	}
+
+	if err := ValidateScope("Site/#/Line"); err == nil {
+		t.Error("Expected '#' in the middle to be rejected")
+	}
+	if err := ValidateScope("Site/Ar+ea"); err == nil {
+		t.Error("Expected partial '+' to be rejected")
+	}
+}
+
+func TestUserStoreAndTokens(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	if _, err := store.AddUser("dev", []Role{RoleDeveloper}, nil); err == nil {
+		t.Error("Expected first user without admin role to be rejected")
+	}
+	if _, err := store.AddUser("root", []Role{RoleAdmin}, nil); err != nil {
+		t.Fatalf("Failed to add admin: %v", err)
+	}
+	if _, err := store.AddUser("ROOT", []Role{RoleAnalyst}, nil); err == nil {
+		t.Error("Expected duplicate (case-insensitive) user name to be rejected")
+	}
+
+	token, tok, err := store.CreateToken("root", "test")
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	u, err := store.Authenticate(token)
+	if err != nil || u.Name != "root" {
+		t.Fatalf("Expected token to authenticate root, got %v, %v", u, err)
+	}
+
+	// Only the hash is persisted
+	data, _ := os.ReadFile(store.filePath)
+	if len(data) == 0 || strings.Contains(string(data), token) {
+		t.Error("Expected plain token not to be stored")
+	}
+	if strings.Contains(token, tok.ID) {
+		t.Errorf("Expected the token ID %s not to reveal part of the secret", tok.ID)
+	}
+
+	if err := store.RevokeToken(tok.ID); err != nil {
+		t.Fatalf("Failed to revoke token: %v", err)
+	}
+	if _, err := store.Authenticate(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected revoked token to be invalid, got %v", err)
+	}
+
+	if err := store.RemoveUser("root"); err == nil {
+		t.Error("Expected removing the only user to fail, as it would disable access control")
+	}
+}
+
+func TestRemoveLastAdmin(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	store.AddUser("root", []Role{RoleAdmin}, nil)
+	store.AddUser("ana", []Role{RoleAnalyst}, nil)
+	if err := store.RemoveUser("root"); err == nil {
+		t.Error("Expected removing the last admin to fail")
+	}
+	if err := store.RemoveUser("ana"); err != nil {
+		t.Errorf("Expected removing a non-admin to succeed: %v", err)
+	}
+}
+
+func TestSessionPermissions(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+	authz := NewAuthorizer(store)
+
+	// Without users, access control is disabled
+	anon, _ := authz.Session("")
+	if err := anon.CanNode(PermNodesWrite, "anything"); err != nil {
+		t.Errorf("Expected open access without users, got %v", err)
+	}
+
+	store.AddUser("root", []Role{RoleAdmin}, nil)
+	store.AddUser("dilna", []Role{RoleDeveloper}, []string{"StribrneHory/Dilna/#"})
+	store.AddUser("ana", []Role{RoleAnalyst}, nil)
+	devToken, _, _ := store.CreateToken("dilna", "")
+	anaToken, _, _ := store.CreateToken("ana", "")
+
+	if err := anon.Can(PermNodesRead); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Expected ErrUnauthenticated once users exist, got %v", err)
+	}
+
+	dev, err := authz.Session(devToken)
+	if err != nil {
+		t.Fatalf("Failed to open session: %v", err)
+	}
+	if err := dev.CanNode(PermNodesWrite, "StribrneHory/Dilna/NovaBudova/CNC"); err != nil {
+		t.Errorf("Expected developer to edit inside scope, got %v", err)
+	}
+	if err := dev.CanNode(PermNodesWrite, "StribrneHory/Sklad"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Expected ErrForbidden outside scope, got %v", err)
+	}
+	if err := dev.Can(PermUsersManage); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Expected developer not to manage users, got %v", err)
+	}
+
+	ana, _ := authz.Session(anaToken)
+	if err := ana.CanNode(PermNodesWrite, "StribrneHory/Dilna"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Expected analyst to be read-only, got %v", err)
+	}
+	if err := ana.Can(PermKPIsRead); err != nil {
+		t.Errorf("Expected analyst to read KPIs, got %v", err)
+	}
+
+	nodes := []*node.Node{
+		{ID: "1", UNSAddress: "StribrneHory/Dilna/CNC"},
+		{ID: "2", UNSAddress: "Other/Site"},
+	}
+	visible, err := dev.Visible(nodes)
+	if err != nil || len(visible) != 1 || visible[0].ID != "1" {
+		t.Errorf("Expected only in-scope node to be visible, got %v, %v", visible, err)
+	}
+}
@@ -0,0 +1,284 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenPrefix makes API tokens recognisable in configs and logs
+const tokenPrefix = "mnt_"
+
+// ErrInvalidToken is returned when a token matches no user
+var ErrInvalidToken = errors.New("invalid API token")
+
+// User is a person or service allowed to use the CLI or API
+type User struct {
+	Name      string    `json:"name"`
+	Roles     []Role    `json:"roles"`
+	Scopes    []string  `json:"scopes,omitempty"`
+	Tokens    []Token   `json:"tokens,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Token is a stored API token; only its hash is persisted
+type Token struct {
+	ID          string    `json:"id"`
+	Hash        string    `json:"hash"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// HasPermission reports whether any of the user's roles grants perm
+func (u *User) HasPermission(perm Permission) bool {
+	for _, r := range u.Roles {
+		for _, p := range rolePermissions[r] {
+			if p == perm {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Store handles persistence of users and their tokens
+type Store struct {
+	filePath string
+	mu       sync.RWMutex
+}
+
+// NewStore creates a user store in the data directory
+func NewStore(dataDir string) (*Store, error) {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	return &Store{filePath: filepath.Join(dataDir, "users.json")}, nil
+}
+
+// Load reads all users
+func (s *Store) Load() ([]*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.load()
+}
+
+func (s *Store) load() ([]*User, error) {
+	data, err := os.ReadFile(s.filePath)
+	if os.IsNotExist(err) {
+		return []*User{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read users file: %w", err)
+	}
+	if len(data) == 0 {
+		return []*User{}, nil
+	}
+
+	var users []*User
+	if err := json.Unmarshal(data, &users); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal users: %w", err)
+	}
+	return users, nil
+}
+
+func (s *Store) save(users []*User) error {
+	data, err := json.MarshalIndent(users, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal users: %w", err)
+	}
+	// Token hashes are sensitive, keep the file private
+	if err := os.WriteFile(s.filePath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write users file: %w", err)
+	}
+	return nil
+}
+
+// AddUser registers a new user. The first user must be an admin so that
+// someone can manage access once it is enforced.
+func (s *Store) AddUser(name string, roles []Role, scopes []string) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("user name cannot be empty")
+	}
+	if len(roles) == 0 {
+		return nil, errors.New("at least one role is required")
+	}
+	for _, scope := range scopes {
+		if err := ValidateScope(scope); err != nil {
+			return nil, err
+		}
+	}
+
+	users, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		if strings.EqualFold(u.Name, name) {
+			return nil, fmt.Errorf("user '%s' already exists", name)
+		}
+	}
+	if len(users) == 0 && !containsRole(roles, RoleAdmin) {
+		return nil, errors.New("the first user must have the admin role")
+	}
+
+	u := &User{Name: name, Roles: roles, Scopes: scopes, CreatedAt: time.Now()}
+	users = append(users, u)
+	if err := s.save(users); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// RemoveUser deletes a user and all of their tokens. The last admin cannot
+// be removed: without users access control would silently turn off.
+func (s *Store) RemoveUser(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	var remaining []*User
+	found := false
+	for _, u := range users {
+		if strings.EqualFold(u.Name, name) {
+			found = true
+			continue
+		}
+		remaining = append(remaining, u)
+	}
+	if !found {
+		return fmt.Errorf("user '%s' not found", name)
+	}
+	if !anyAdmin(remaining) {
+		return errors.New("cannot remove the last admin")
+	}
+	return s.save(remaining)
+}
+
+// CreateToken issues a new API token for a user. The plain token is
+// returned once and cannot be recovered afterwards.
+func (s *Store) CreateToken(userName, description string) (string, *Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users, err := s.load()
+	if err != nil {
+		return "", nil, err
+	}
+	u := findUser(users, userName)
+	if u == nil {
+		return "", nil, fmt.Errorf("user '%s' not found", userName)
+	}
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	plain := tokenPrefix + hex.EncodeToString(secret)
+
+	// The ID is listed and stored in clear, so it comes from its own random
+	// bytes rather than from the secret
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	t := Token{
+		ID:          hex.EncodeToString(id),
+		Hash:        hashToken(plain),
+		Description: description,
+		CreatedAt:   time.Now(),
+	}
+	u.Tokens = append(u.Tokens, t)
+	if err := s.save(users); err != nil {
+		return "", nil, err
+	}
+	return plain, &t, nil
+}
+
+// RevokeToken removes a token by its ID
+func (s *Store) RevokeToken(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users, err := s.load()
+	if err != nil {
+		return err
+	}
+	for _, u := range users {
+		for i, t := range u.Tokens {
+			if t.ID == id {
+				u.Tokens = append(u.Tokens[:i], u.Tokens[i+1:]...)
+				return s.save(users)
+			}
+		}
+	}
+	return fmt.Errorf("token '%s' not found", id)
+}
+
+// Authenticate returns the user owning the token
+func (s *Store) Authenticate(token string) (*User, error) {
+	users, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	hash := []byte(hashToken(strings.TrimSpace(token)))
+	for _, u := range users {
+		for _, t := range u.Tokens {
+			if subtle.ConstantTimeCompare(hash, []byte(t.Hash)) == 1 {
+				return u, nil
+			}
+		}
+	}
+	return nil, ErrInvalidToken
+}
+
+// Helper functions
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func findUser(users []*User, name string) *User {
+	for _, u := range users {
+		if strings.EqualFold(u.Name, name) {
+			return u
+		}
+	}
+	return nil
+}
+
+func containsRole(roles []Role, role Role) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func anyAdmin(users []*User) bool {
+	for _, u := range users {
+		if containsRole(u.Roles, RoleAdmin) {
+			return true
+		}
+	}
+	return false
+}
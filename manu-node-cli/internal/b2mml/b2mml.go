@@ -0,0 +1,341 @@
+package b2mml
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"manu-node-cli/internal/manifest"
+	"manu-node-cli/internal/node"
+)
+
+// Namespace is the B2MML (ISA-95) XML namespace
+const Namespace = "http://www.mesa.org/xml/B2MML"
+
+// Property IDs with special meaning when mapping equipment to nodes
+const (
+	// PropertyCapability values become node operations; "Operation" is
+	// accepted as an alias on import
+	PropertyCapability = "Capability"
+	// PropertyTitle carries a node title that differs from the equipment ID
+	PropertyTitle = "Title"
+	// PropertyNodeID carries the node ID so exports can be re-imported
+	// onto the same nodes
+	PropertyNodeID = "NodeID"
+	// PropertyUNSAddress overrides the address derived from the hierarchy;
+	// export uses it for nodes without a UNS address
+	PropertyUNSAddress = "UNSAddress"
+)
+
+// levelsByDepth names intermediate hierarchy levels, following the
+// Site/Area/Line/Cell convention used for UNS addresses
+var levelsByDepth = []string{"Site", "Area", "ProductionLine", "WorkCell"}
+
+// Value is a B2MML property value
+type Value struct {
+	ValueString   string `xml:"ValueString"`
+	DataType      string `xml:"DataType,omitempty"`
+	UnitOfMeasure string `xml:"UnitOfMeasure,omitempty"`
+}
+
+// Property is an EquipmentProperty or EquipmentClassProperty
+type Property struct {
+	ID          string  `xml:"ID"`
+	Description string  `xml:"Description,omitempty"`
+	Values      []Value `xml:"Value"`
+}
+
+// Equipment is a B2MML Equipment element with its nested children
+type Equipment struct {
+	ID               string      `xml:"ID"`
+	Descriptions     []string    `xml:"Description"`
+	EquipmentLevel   string      `xml:"EquipmentLevel,omitempty"`
+	Properties       []Property  `xml:"EquipmentProperty"`
+	Children         []Equipment `xml:"Equipment"`
+	EquipmentClassID []string    `xml:"EquipmentClassID"`
+}
+
+// EquipmentClass is a B2MML EquipmentClass element
+type EquipmentClass struct {
+	ID           string     `xml:"ID"`
+	Descriptions []string   `xml:"Description"`
+	Properties   []Property `xml:"EquipmentClassProperty"`
+}
+
+// Document holds the equipment and classes found in a B2MML file
+type Document struct {
+	Equipment []Equipment
+	Classes   []EquipmentClass
+}
+
+// Decode reads Equipment and EquipmentClass elements from any B2MML
+// document: a bare element, an EquipmentInformation, or a verb/noun
+// message such as SyncEquipmentInformation with a DataArea
+func Decode(r io.Reader) (*Document, error) {
+	dec := xml.NewDecoder(r)
+	doc := &Document{}
+
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid B2MML: %w", err)
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "Equipment":
+			var e Equipment
+			if err := dec.DecodeElement(&e, &start); err != nil {
+				return nil, fmt.Errorf("invalid Equipment element: %w", err)
+			}
+			doc.Equipment = append(doc.Equipment, e)
+		case "EquipmentClass":
+			var c EquipmentClass
+			if err := dec.DecodeElement(&c, &start); err != nil {
+				return nil, fmt.Errorf("invalid EquipmentClass element: %w", err)
+			}
+			doc.Classes = append(doc.Classes, c)
+		}
+	}
+
+	if len(doc.Equipment) == 0 && len(doc.Classes) == 0 {
+		return nil, errors.New("no Equipment or EquipmentClass elements found")
+	}
+	return doc, nil
+}
+
+// Specs maps the equipment hierarchy onto node specs. Every leaf
+// equipment becomes a node whose UNS address is the path of equipment IDs
+// from the root; capabilities of the equipment and its classes become
+// operations.
+func (d *Document) Specs() ([]manifest.Spec, error) {
+	classes := make(map[string]EquipmentClass, len(d.Classes))
+	for _, c := range d.Classes {
+		classes[c.ID] = c
+	}
+
+	var specs []manifest.Spec
+	var walk func(e Equipment, path []string) error
+	walk = func(e Equipment, path []string) error {
+		id := strings.TrimSpace(e.ID)
+		if id == "" {
+			return fmt.Errorf("equipment under '%s' has no ID", strings.Join(path, "/"))
+		}
+		path = append(path, id)
+
+		if len(e.Children) > 0 {
+			for _, child := range e.Children {
+				if err := walk(child, path); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		spec := manifest.Spec{
+			Title:      id,
+			UNSAddress: strings.Join(path, "/"),
+			Source:     "Equipment " + strings.Join(path, "/"),
+		}
+		if len(e.Descriptions) > 0 {
+			spec.Description = strings.TrimSpace(e.Descriptions[0])
+		}
+
+		var ops []string
+		for _, p := range e.Properties {
+			switch {
+			case strings.EqualFold(p.ID, PropertyTitle) && len(p.Values) > 0:
+				spec.Title = strings.TrimSpace(p.Values[0].ValueString)
+			case strings.EqualFold(p.ID, PropertyNodeID) && len(p.Values) > 0:
+				spec.ID = strings.TrimSpace(p.Values[0].ValueString)
+			case strings.EqualFold(p.ID, PropertyUNSAddress):
+				spec.UNSAddress = ""
+				if len(p.Values) > 0 {
+					spec.UNSAddress = strings.TrimSpace(p.Values[0].ValueString)
+				}
+			case isCapability(p.ID):
+				ops = appendValues(ops, p.Values)
+			}
+		}
+		for _, classID := range e.EquipmentClassID {
+			class, ok := classes[strings.TrimSpace(classID)]
+			if !ok {
+				return fmt.Errorf("equipment '%s' references unknown EquipmentClass '%s'", spec.UNSAddress, classID)
+			}
+			for _, p := range class.Properties {
+				if isCapability(p.ID) {
+					ops = appendValues(ops, p.Values)
+				}
+			}
+		}
+		spec.Operations = ops
+
+		specs = append(specs, spec)
+		return nil
+	}
+
+	for _, e := range d.Equipment {
+		if err := walk(e, nil); err != nil {
+			return nil, err
+		}
+	}
+	return specs, nil
+}
+
+// equipmentInformation is the root element written by Encode
+type equipmentInformation struct {
+	XMLName   xml.Name    `xml:"EquipmentInformation"`
+	Xmlns     string      `xml:"xmlns,attr"`
+	Equipment []Equipment `xml:"Equipment"`
+}
+
+// Encode writes nodes as an EquipmentInformation document. UNS address
+// segments become nested Equipment elements and operations become
+// Capability property values.
+func Encode(w io.Writer, nodes []*node.Node) error {
+	var roots []*treeNode
+	for _, n := range nodes {
+		segments := splitUNS(n.UNSAddress)
+		if len(segments) == 0 {
+			segments = []string{n.Title}
+		}
+
+		// Walk or create the intermediate equipment
+		level := &roots
+		var leaf *treeNode
+		for i, seg := range segments {
+			var found *treeNode
+			isLeaf := i == len(segments)-1
+			for _, t := range *level {
+				// Two nodes at the same address stay separate leaves
+				if t.id == seg && t.node == nil && !isLeaf {
+					found = t
+					break
+				}
+			}
+			if found == nil {
+				found = &treeNode{id: seg, depth: i}
+				*level = append(*level, found)
+			}
+			level = &found.children
+			leaf = found
+		}
+		leaf.node = n
+	}
+
+	info := equipmentInformation{Xmlns: Namespace}
+	for _, r := range roots {
+		info.Equipment = append(info.Equipment, r.toEquipment())
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(info); err != nil {
+		return fmt.Errorf("failed to encode B2MML: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// treeNode builds the equipment hierarchy during export
+type treeNode struct {
+	id       string
+	depth    int
+	node     *node.Node
+	children []*treeNode
+}
+
+func (t *treeNode) toEquipment() Equipment {
+	e := Equipment{ID: t.id, EquipmentLevel: levelName(t.depth, t.node != nil)}
+	for _, c := range t.children {
+		e.Children = append(e.Children, c.toEquipment())
+	}
+	if t.node == nil {
+		return e
+	}
+
+	n := t.node
+	if n.Description != "" {
+		e.Descriptions = []string{n.Description}
+	}
+	e.Properties = append(e.Properties, stringProperty(PropertyNodeID, n.ID))
+	if n.Title != t.id {
+		e.Properties = append(e.Properties, stringProperty(PropertyTitle, n.Title))
+	}
+	if len(splitUNS(n.UNSAddress)) == 0 {
+		e.Properties = append(e.Properties, stringProperty(PropertyUNSAddress, n.UNSAddress))
+	}
+	if len(n.Operations) > 0 {
+		p := Property{ID: PropertyCapability}
+		for _, op := range n.Operations {
+			p.Values = append(p.Values, Value{ValueString: op, DataType: "string"})
+		}
+		e.Properties = append(e.Properties, p)
+	}
+	return e
+}
+
+// levelName picks an ISA-95 EquipmentLevel for a hierarchy depth
+func levelName(depth int, isNode bool) string {
+	if isNode && depth >= len(levelsByDepth)-1 {
+		if depth == len(levelsByDepth)-1 {
+			return "WorkCell"
+		}
+		return "WorkUnit"
+	}
+	if depth < len(levelsByDepth) {
+		return levelsByDepth[depth]
+	}
+	return "Other"
+}
+
+// Helper functions
+func stringProperty(id, value string) Property {
+	return Property{ID: id, Values: []Value{{ValueString: value, DataType: "string"}}}
+}
+
+func isCapability(id string) bool {
+	return strings.EqualFold(id, PropertyCapability) || strings.EqualFold(id, "Operation")
+}
+
+// appendValues adds property values to ops, skipping duplicates
+func appendValues(ops []string, values []Value) []string {
+	for _, v := range values {
+		op := strings.TrimSpace(v.ValueString)
+		if op == "" {
+			continue
+		}
+		dup := false
+		for _, existing := range ops {
+			if strings.EqualFold(existing, op) {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+func splitUNS(addr string) []string {
+	var segments []string
+	for _, s := range strings.Split(addr, "/") {
+		if s = strings.TrimSpace(s); s != "" {
+			segments = append(segments, s)
+		}
+	}
+	return segments
+}
@@ -0,0 +1,153 @@
+package b2mml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"manu-node-cli/internal/manifest"
+	"manu-node-cli/internal/node"
+)
+
+func decodeFile(t *testing.T, path string) *Document {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", path, err)
+	}
+	defer f.Close()
+
+	doc, err := Decode(f)
+	if err != nil {
+		t.Fatalf("Failed to decode %s: %v", path, err)
+	}
+	return doc
+}
+
+func TestDecodeSyncEquipmentInformation(t *testing.T) {
+	doc := decodeFile(t, "testdata/sync_equipment.xml")
+	if len(doc.Classes) != 2 || len(doc.Equipment) != 1 {
+		t.Fatalf("Expected 2 classes and 1 root equipment, got %d and %d", len(doc.Classes), len(doc.Equipment))
+	}
+
+	specs, err := doc.Specs()
+	if err != nil {
+		t.Fatalf("Failed to map equipment: %v", err)
+	}
+	if len(specs) != 2 {
+		t.Fatalf("Expected 2 leaf equipment, got %d", len(specs))
+	}
+
+	cnc := specs[0]
+	if cnc.Title != "CNC" || cnc.UNSAddress != "StribrneHory/Dilna/NovaBudova/CNC" {
+		t.Errorf("Unexpected CNC mapping: %+v", cnc)
+	}
+	if got := strings.Join(cnc.Operations, ","); got != "Drawer,bigshelf,SmallShelf" {
+		t.Errorf("Expected own and class capabilities without duplicates, got %s", got)
+	}
+	if cnc.Description != "Wardrobe / shelf making equipment" {
+		t.Errorf("Unexpected description %q", cnc.Description)
+	}
+
+	saw := specs[1]
+	if saw.UNSAddress != "StribrneHory/Dilna/StaraBudova/PanelSaw" || strings.Join(saw.Operations, ",") != "cut" {
+		t.Errorf("Unexpected saw mapping: %+v", saw)
+	}
+}
+
+func TestDecodeBareEquipment(t *testing.T) {
+	specs, err := decodeFile(t, "testdata/equipment.xml").Specs()
+	if err != nil {
+		t.Fatalf("Failed to map equipment: %v", err)
+	}
+	if len(specs) != 1 || specs[0].Title != "High Bay Rack 1" || specs[0].UNSAddress != "Warehouse/Rack1" {
+		t.Errorf("Unexpected mapping: %+v", specs)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	if _, err := Decode(strings.NewReader(`<Other/>`)); err == nil {
+		t.Error("Expected error for document without equipment")
+	}
+
+	doc, err := Decode(strings.NewReader(`<Equipment><ID>A</ID><EquipmentClassID>Missing</EquipmentClassID></Equipment>`))
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if _, err := doc.Specs(); err == nil {
+		t.Error("Expected error for unknown EquipmentClassID")
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	// Import the sample document into a catalog
+	specs, err := decodeFile(t, "testdata/sync_equipment.xml").Specs()
+	if err != nil {
+		t.Fatalf("Failed to map equipment: %v", err)
+	}
+	more, _ := decodeFile(t, "testdata/equipment.xml").Specs()
+	specs = append(specs, more...)
+	specs = append(specs, manifest.Spec{Title: "Loose", Operations: []string{"misc"}})
+
+	plan, err := manifest.BuildPlan(nil, specs, manifest.Options{})
+	if err != nil {
+		t.Fatalf("Failed to plan import: %v", err)
+	}
+	catalog := plan.Nodes
+
+	// Export and import again: nothing should change
+	var buf bytes.Buffer
+	if err := Encode(&buf, catalog); err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	if !strings.Contains(buf.String(), `xmlns="`+Namespace+`"`) {
+		t.Error("Expected B2MML namespace on the root element")
+	}
+
+	doc, err := Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Failed to decode export: %v", err)
+	}
+	reimported, err := doc.Specs()
+	if err != nil {
+		t.Fatalf("Failed to map export: %v", err)
+	}
+	again, err := manifest.BuildPlan(catalog, reimported, manifest.Options{Prune: true})
+	if err != nil {
+		t.Fatalf("Failed to plan re-import: %v", err)
+	}
+	if len(again.Changes) != 0 {
+		t.Errorf("Expected lossless round trip, got changes: %+v", again.Changes)
+	}
+}
+
+func TestEncodeHierarchy(t *testing.T) {
+	now := time.Now()
+	nodes := []*node.Node{
+		{ID: "1", Title: "CNC", UNSAddress: "S/A/L/CNC", Operations: []string{"cut"}, CreatedAt: now},
+		{ID: "2", Title: "Saw", UNSAddress: "S/A/L/Saw", CreatedAt: now},
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, nodes); err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+
+	var info equipmentInformation
+	if err := xml.Unmarshal(buf.Bytes(), &info); err != nil {
+		t.Fatalf("Export is not well-formed XML: %v", err)
+	}
+	if len(info.Equipment) != 1 {
+		t.Fatalf("Expected shared Site element, got %d roots", len(info.Equipment))
+	}
+	site := info.Equipment[0]
+	line := site.Children[0].Children[0]
+	if site.EquipmentLevel != "Site" || line.EquipmentLevel != "ProductionLine" || len(line.Children) != 2 {
+		t.Errorf("Unexpected hierarchy: %+v", site)
+	}
+	if line.Children[0].EquipmentLevel != "WorkCell" {
+		t.Errorf("Expected machines at WorkCell level, got %s", line.Children[0].EquipmentLevel)
+	}
+}
@@ -0,0 +1,460 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DirName is the backup directory inside the data directory
+	DirName = "backups"
+	// DefaultKeep is how many automatic snapshots rotation keeps
+	DefaultKeep = 20
+
+	metadataName = "metadata.json"
+	archiveExt   = ".tar.gz"
+	checksumExt  = ".sha256"
+	formatV1     = 1
+)
+
+// ErrNotFound is returned when no backup has the requested ID
+var ErrNotFound = errors.New("backup not found")
+
+// ErrCorrupt is returned when an archive fails checksum verification
+var ErrCorrupt = errors.New("backup is corrupt")
+
+// FileInfo describes one file inside a backup
+type FileInfo struct {
+	Name   string      `json:"name"`
+	Size   int64       `json:"size"`
+	Mode   os.FileMode `json:"mode"`
+	SHA256 string      `json:"sha256"`
+}
+
+// Info is the metadata stored with every backup
+type Info struct {
+	Format    int        `json:"format"`
+	ID        string     `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Reason    string     `json:"reason"`
+	Files     []FileInfo `json:"files"`
+	// Automatic snapshots are taken before destructive operations and
+	// rotated; manual backups are never deleted by rotation
+	Automatic bool `json:"automatic,omitempty"`
+
+	// Size is the compressed archive size; not stored in the archive
+	Size int64 `json:"-"`
+}
+
+// Manager creates, lists and restores snapshots of a data directory
+type Manager struct {
+	dataDir   string
+	backupDir string
+	// Keep is how many automatic snapshots Rotate keeps; zero or less
+	// keeps all
+	Keep int
+	// Preserve lists data files that Restore leaves as they are
+	Preserve []string
+	mu       sync.Mutex
+}
+
+// NewManager creates a backup manager storing archives in dataDir/backups
+func NewManager(dataDir string) (*Manager, error) {
+	backupDir := filepath.Join(dataDir, DirName)
+	if err := os.MkdirAll(backupDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	return &Manager{
+		dataDir:   dataDir,
+		backupDir: backupDir,
+		Keep:      DefaultKeep,
+		// Restoring users could bring back removed users and revoked tokens
+		Preserve: []string{"users.json"},
+	}, nil
+}
+
+// dataFiles lists the store files to snapshot: every regular file in the
+// data directory except history, temporary files and the backups themselves
+func (m *Manager) dataFiles() ([]string, error) {
+	entries, err := os.ReadDir(m.dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data directory: %w", err)
+	}
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		if !e.Type().IsRegular() || strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".tmp") {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Create takes a manual backup of the data directory
+func (m *Manager) Create(reason string) (*Info, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.create(reason, false)
+}
+
+// Snapshot takes an automatic backup ahead of a destructive operation and
+// rotates old snapshots
+func (m *Manager) Snapshot(reason string) (*Info, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	info, err := m.create(reason, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.rotate(); err != nil {
+		return info, err
+	}
+	return info, nil
+}
+
+func (m *Manager) create(reason string, automatic bool) (*Info, error) {
+	names, err := m.dataFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	info := &Info{Format: formatV1, ID: m.newID(now), CreatedAt: now, Reason: reason, Automatic: automatic}
+	contents := make(map[string][]byte, len(names))
+	for _, name := range names {
+		path := filepath.Join(m.dataDir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		mode := os.FileMode(0600)
+		if st, err := os.Stat(path); err == nil {
+			mode = st.Mode().Perm()
+		}
+		contents[name] = data
+		info.Files = append(info.Files, FileInfo{Name: name, Size: int64(len(data)), Mode: mode, SHA256: sum(data)})
+	}
+
+	// Build the archive in memory: metadata first so List can stop early
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	meta, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeTarFile(tw, metadataName, meta, now); err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if err := writeTarFile(tw, name, contents[name], now); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	archive := m.archivePath(info.ID)
+	if err := writeFileAtomic(archive, buf.Bytes(), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write backup: %w", err)
+	}
+	checksum := fmt.Sprintf("%s  %s\n", sum(buf.Bytes()), filepath.Base(archive))
+	if err := writeFileAtomic(archive+checksumExt, []byte(checksum), 0600); err != nil {
+		os.Remove(archive)
+		return nil, fmt.Errorf("failed to write backup checksum: %w", err)
+	}
+
+	info.Size = int64(buf.Len())
+	return info, nil
+}
+
+// List returns all backups, newest first
+func (m *Manager) List() ([]*Info, error) {
+	entries, err := os.ReadDir(m.backupDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	var infos []*Info
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), archiveExt) {
+			continue
+		}
+		id := strings.TrimSuffix(e.Name(), archiveExt)
+		info, err := m.readMetadata(id)
+		if err != nil {
+			// Show unreadable archives instead of hiding them
+			info = &Info{ID: id, Reason: fmt.Sprintf("unreadable: %v", err)}
+		}
+		if st, err := e.Info(); err == nil {
+			info.Size = st.Size()
+		}
+		infos = append(infos, info)
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		if !infos[i].CreatedAt.Equal(infos[j].CreatedAt) {
+			return infos[i].CreatedAt.After(infos[j].CreatedAt)
+		}
+		return infos[i].ID > infos[j].ID
+	})
+	return infos, nil
+}
+
+// Verify checks the archive checksum and every file checksum, returning
+// the metadata and file contents of a sound backup
+func (m *Manager) Verify(id string) (*Info, map[string][]byte, error) {
+	archive := m.archivePath(id)
+	data, err := os.ReadFile(archive)
+	if os.IsNotExist(err) {
+		return nil, nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	expected, err := os.ReadFile(archive + checksumExt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: missing checksum file", ErrCorrupt)
+	}
+	if fields := strings.Fields(string(expected)); len(fields) == 0 || fields[0] != sum(data) {
+		return nil, nil, fmt.Errorf("%w: archive checksum mismatch", ErrCorrupt)
+	}
+
+	files, err := readArchive(bytes.NewReader(data), false)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	var info Info
+	if err := json.Unmarshal(files[metadataName], &info); err != nil {
+		return nil, nil, fmt.Errorf("%w: invalid metadata: %v", ErrCorrupt, err)
+	}
+	if info.Format > formatV1 {
+		return nil, nil, fmt.Errorf("backup %s uses format %d; upgrade manu-node-cli to restore it", id, info.Format)
+	}
+	delete(files, metadataName)
+
+	for _, f := range info.Files {
+		content, ok := files[f.Name]
+		if !ok || sum(content) != f.SHA256 {
+			return nil, nil, fmt.Errorf("%w: checksum mismatch for %s", ErrCorrupt, f.Name)
+		}
+	}
+	info.Size = int64(len(data))
+	return &info, files, nil
+}
+
+// Restore verifies a backup and replaces the data files with its
+// contents, except those in Preserve. The current state is snapshotted
+// first so a restore can be undone; data files that did not exist at
+// backup time are removed.
+func (m *Manager) Restore(id string) (*Info, *Info, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	info, files, err := m.Verify(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	safety, err := m.create("before restoring "+id, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to snapshot current data: %w", err)
+	}
+
+	current, err := m.dataFiles()
+	if err != nil {
+		return nil, safety, err
+	}
+	for _, f := range info.Files {
+		if m.preserved(f.Name) {
+			continue
+		}
+		mode := f.Mode
+		if mode == 0 {
+			mode = 0600
+		}
+		if err := writeFileAtomic(filepath.Join(m.dataDir, f.Name), files[f.Name], mode); err != nil {
+			return nil, safety, fmt.Errorf("failed to restore %s (pre-restore backup %s is intact): %w",
+				f.Name, safety.ID, err)
+		}
+	}
+	for _, name := range current {
+		if !info.hasFile(name) && !m.preserved(name) {
+			os.Remove(filepath.Join(m.dataDir, name))
+		}
+	}
+
+	if err := m.rotate(); err != nil {
+		return info, safety, err
+	}
+	return info, safety, nil
+}
+
+// Rotate deletes the oldest automatic snapshots beyond Keep
+func (m *Manager) Rotate() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.rotate()
+}
+
+func (m *Manager) rotate() error {
+	if m.Keep <= 0 {
+		return nil
+	}
+	infos, err := m.List()
+	if err != nil {
+		return err
+	}
+	kept := 0
+	for _, info := range infos {
+		if !info.Automatic {
+			continue
+		}
+		if kept++; kept <= m.Keep {
+			continue
+		}
+		archive := m.archivePath(info.ID)
+		if err := os.Remove(archive); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate backup %s: %w", info.ID, err)
+		}
+		os.Remove(archive + checksumExt)
+	}
+	return nil
+}
+
+func (m *Manager) readMetadata(id string) (*Info, error) {
+	f, err := os.Open(m.archivePath(id))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	files, err := readArchive(f, true)
+	if err != nil {
+		return nil, err
+	}
+	var info Info
+	if err := json.Unmarshal(files[metadataName], &info); err != nil {
+		return nil, fmt.Errorf("invalid metadata: %w", err)
+	}
+	return &info, nil
+}
+
+// newID returns a sortable, unused backup ID
+func (m *Manager) newID(t time.Time) string {
+	base := t.Format("20060102-150405.000")
+	id := base
+	for i := 2; ; i++ {
+		if _, err := os.Stat(m.archivePath(id)); os.IsNotExist(err) {
+			return id
+		}
+		id = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+func (m *Manager) archivePath(id string) string {
+	return filepath.Join(m.backupDir, filepath.Base(id)+archiveExt)
+}
+
+func (m *Manager) preserved(name string) bool {
+	for _, p := range m.Preserve {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (i *Info) hasFile(name string) bool {
+	for _, f := range i.Files {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Helper functions
+func sum(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	hdr := &tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), ModTime: modTime}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// readArchive extracts the files of a backup; with metadataOnly it stops
+// after the metadata entry
+func readArchive(r io.Reader, metadataOnly bool) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	files := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if filepath.Base(hdr.Name) != hdr.Name {
+			return nil, fmt.Errorf("unexpected path %q in archive", hdr.Name)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[hdr.Name] = data
+		if metadataOnly && hdr.Name == metadataName {
+			break
+		}
+	}
+	if _, ok := files[metadataName]; !ok {
+		return nil, errors.New("archive has no metadata")
+	}
+	return files, nil
+}
+
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package backup
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func setupTestManager(t *testing.T) (*Manager, string, func()) {
+	tempDir, err := os.MkdirTemp("", "backup_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+
+	m, err := NewManager(tempDir)
+	if err != nil {
+		os.RemoveAll(tempDir)
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	return m, tempDir, func() { os.RemoveAll(tempDir) }
+}
+
+func writeData(t *testing.T, dir, name, content string) {
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+}
+
+func TestCreateListRestore(t *testing.T) {
+	m, dir, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	writeData(t, dir, "nodes.json", `[{"id":"1"}]`)
+	writeData(t, dir, "users.json", `[]`)
+	writeData(t, dir, ".history", "list")
+
+	info, err := m.Create("manual")
+	if err != nil {
+		t.Fatalf("Failed to create backup: %v", err)
+	}
+	if len(info.Files) != 2 {
+		t.Errorf("Expected nodes.json and users.json only, got %+v", info.Files)
+	}
+
+	// Change the data after the snapshot
+	writeData(t, dir, "nodes.json", `[]`)
+	writeData(t, dir, "extra.json", `{}`)
+	writeData(t, dir, "users.json", `[{"name":"root"}]`)
+
+	list, err := m.List()
+	if err != nil || len(list) != 1 || list[0].Reason != "manual" || list[0].Size == 0 {
+		t.Fatalf("Unexpected backup list %+v (err %v)", list, err)
+	}
+
+	restored, safety, err := m.Restore(info.ID)
+	if err != nil {
+		t.Fatalf("Failed to restore: %v", err)
+	}
+	if restored.ID != info.ID || safety == nil {
+		t.Error("Expected restored info and a pre-restore snapshot")
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "nodes.json"))
+	if string(data) != `[{"id":"1"}]` {
+		t.Errorf("Expected nodes.json to be restored, got %s", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "extra.json")); !os.IsNotExist(err) {
+		t.Error("Expected files created after the backup to be removed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".history")); err != nil {
+		t.Error("Expected history to be left alone")
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "users.json")); string(data) != `[{"name":"root"}]` {
+		t.Errorf("Expected users.json to be left alone, got %s", data)
+	}
+
+	// The pre-restore snapshot holds the state we overwrote
+	_, files, err := m.Verify(safety.ID)
+	if err != nil || string(files["nodes.json"]) != `[]` {
+		t.Errorf("Expected safety snapshot of overwritten data, got %v", err)
+	}
+}
+
+func TestRestoreDetectsCorruption(t *testing.T) {
+	m, dir, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	writeData(t, dir, "nodes.json", `[]`)
+	info, _ := m.Create("manual")
+
+	archive := m.archivePath(info.ID)
+	data, _ := os.ReadFile(archive)
+	data[len(data)/2] ^= 0xff
+	os.WriteFile(archive, data, 0600)
+
+	if _, _, err := m.Restore(info.ID); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("Expected ErrCorrupt, got %v", err)
+	}
+	if _, _, err := m.Restore("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	m, dir, cleanup := setupTestManager(t)
+	defer cleanup()
+	m.Keep = 3
+
+	writeData(t, dir, "nodes.json", `[]`)
+	manual, err := m.Create("manual")
+	if err != nil {
+		t.Fatalf("Failed to create backup: %v", err)
+	}
+	var ids []string
+	for i := 0; i < 5; i++ {
+		info, err := m.Snapshot("auto")
+		if err != nil {
+			t.Fatalf("Failed to create backup: %v", err)
+		}
+		ids = append(ids, info.ID)
+	}
+
+	list, _ := m.List()
+	if len(list) != 4 {
+		t.Fatalf("Expected 3 snapshots and the manual backup after rotation, got %d", len(list))
+	}
+	if list[3].ID != manual.ID || list[3].Automatic {
+		t.Errorf("Expected the manual backup to survive rotation, got %s", list[3].ID)
+	}
+	if list[0].ID != ids[4] || list[2].ID != ids[2] {
+		t.Errorf("Expected the newest backups to be kept, got %s..%s", list[0].ID, list[2].ID)
+	}
+	if _, err := os.Stat(m.archivePath(ids[0]) + checksumExt); !os.IsNotExist(err) {
+		t.Error("Expected checksum file of rotated backup to be removed")
+	}
+}
@@ -0,0 +1,163 @@
+// Package bulk applies the same edit to many nodes at once
+package bulk
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"manu-node-cli/internal/labels"
+	"manu-node-cli/internal/node"
+)
+
+// Settable fields for --set; labels and attributes use a "labels." or
+// "attributes." prefix followed by the key
+const (
+	FieldDescription = "description"
+	FieldUNSAddress  = "uns_address"
+
+	labelPrefix     = "labels."
+	attributePrefix = "attributes."
+)
+
+// Edit describes the changes made to every selected node
+type Edit struct {
+	AddOps    []string
+	RemoveOps []string
+	// Set maps a field to its new value; an empty value clears a label
+	// or attribute
+	Set map[string]string
+	// Normalize, if set, validates custom attributes of edited nodes and
+	// rewrites them in canonical form, so the preview shows only the
+	// changes that will be written
+	Normalize func(n *node.Node) error
+}
+
+// ParseSet reads field=value assignments into e.Set
+func (e *Edit) ParseSet(assignments []string) error {
+	for _, a := range assignments {
+		field, value, ok := strings.Cut(a, "=")
+		if !ok {
+			return fmt.Errorf("invalid --set '%s': expected field=value", a)
+		}
+		field, value = strings.TrimSpace(field), strings.TrimSpace(value)
+
+		switch {
+		case field == FieldDescription, field == FieldUNSAddress:
+		case field == "title":
+			return errors.New("titles must be unique and cannot be bulk-updated")
+		case strings.HasPrefix(field, labelPrefix):
+			key := strings.TrimPrefix(field, labelPrefix)
+			if err := labels.ValidateKey(key); err != nil {
+				return err
+			}
+			if value != "" {
+				if err := labels.ValidateValue(value); err != nil {
+					return err
+				}
+			}
+		case strings.HasPrefix(field, attributePrefix):
+			if strings.TrimPrefix(field, attributePrefix) == "" {
+				return fmt.Errorf("invalid --set '%s': missing attribute name", a)
+			}
+		default:
+			return fmt.Errorf("cannot set '%s' (use %s, %s, %s<key> or %s<name>)",
+				field, FieldDescription, FieldUNSAddress, labelPrefix, attributePrefix)
+		}
+
+		if e.Set == nil {
+			e.Set = map[string]string{}
+		}
+		e.Set[field] = value
+	}
+	return nil
+}
+
+// Empty reports whether the edit would change nothing
+func (e *Edit) Empty() bool {
+	return len(e.AddOps) == 0 && len(e.RemoveOps) == 0 && len(e.Set) == 0
+}
+
+// Apply returns a copy of n with the edit applied
+func (e *Edit) Apply(n *node.Node) *node.Node {
+	out := n.Clone()
+
+	for _, op := range e.RemoveOps {
+		kept := out.Operations[:0]
+		for _, existing := range out.Operations {
+			if !strings.EqualFold(existing, op) {
+				kept = append(kept, existing)
+			}
+		}
+		out.Operations = kept
+	}
+	for _, op := range e.AddOps {
+		if op = strings.TrimSpace(op); op != "" && !out.HasOperation(op) {
+			out.Operations = append(out.Operations, op)
+		}
+	}
+
+	for field, value := range e.Set {
+		switch {
+		case field == FieldDescription:
+			out.Description = value
+		case field == FieldUNSAddress:
+			out.UNSAddress = value
+		case strings.HasPrefix(field, labelPrefix):
+			out.Labels = setValue(out.Labels, strings.TrimPrefix(field, labelPrefix), value)
+		case strings.HasPrefix(field, attributePrefix):
+			out.Attributes = setValue(out.Attributes, strings.TrimPrefix(field, attributePrefix), value)
+		}
+	}
+	return out
+}
+
+func setValue(m map[string]string, key, value string) map[string]string {
+	if value == "" {
+		delete(m, key)
+		if len(m) == 0 {
+			return nil
+		}
+		return m
+	}
+	if m == nil {
+		m = map[string]string{}
+	}
+	m[key] = value
+	return m
+}
+
+// Change is the effect of an edit on one node
+type Change struct {
+	Before *node.Node
+	After  *node.Node
+	Diff   []string
+}
+
+// Plan applies edit to every node accepted by match. It returns the
+// resulting catalog and the nodes that actually change; matched nodes
+// the edit leaves untouched are counted in unchanged. It fails if
+// Normalize rejects an edited node.
+func Plan(nodes []*node.Node, match func(*node.Node) bool, edit *Edit) (result []*node.Node, changes []Change, unchanged int, err error) {
+	result = make([]*node.Node, len(nodes))
+	for i, n := range nodes {
+		result[i] = n
+		if !match(n) {
+			continue
+		}
+		after := edit.Apply(n)
+		if edit.Normalize != nil {
+			if err := edit.Normalize(after); err != nil {
+				return nil, nil, 0, err
+			}
+		}
+		diff := node.Diff(n, after)
+		if len(diff) == 0 {
+			unchanged++
+			continue
+		}
+		result[i] = after
+		changes = append(changes, Change{Before: n, After: after, Diff: diff})
+	}
+	return result, changes, unchanged, nil
+}
@@ -0,0 +1,98 @@
+package bulk
+
+import (
+	"errors"
+	"testing"
+
+	"manu-node-cli/internal/node"
+)
+
+func testNodes() []*node.Node {
+	return []*node.Node{
+		{ID: "1", Title: "Saw", Operations: []string{"cut"}, Labels: map[string]string{"line": "a"}},
+		{ID: "2", Title: "Drill", Operations: []string{"drill", "Deburr"}, Labels: map[string]string{"line": "a"}},
+		{ID: "3", Title: "Press", Operations: []string{"press"}, Labels: map[string]string{"line": "b"}},
+	}
+}
+
+func TestParseSet(t *testing.T) {
+	var e Edit
+	if err := e.ParseSet([]string{"description=Line A", "labels.owner=jan", "attributes.power=5"}); err != nil {
+		t.Fatalf("Failed to parse assignments: %v", err)
+	}
+	if e.Set["description"] != "Line A" || e.Set["labels.owner"] != "jan" || e.Set["attributes.power"] != "5" {
+		t.Errorf("Unexpected assignments: %v", e.Set)
+	}
+
+	for _, bad := range []string{"title=X", "operations=cut", "description", "labels.bad key=x", "labels.owner=a b", "attributes.=1"} {
+		var e Edit
+		if err := e.ParseSet([]string{bad}); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestPlan(t *testing.T) {
+	nodes := testNodes()
+	edit := &Edit{AddOps: []string{"deburr"}, RemoveOps: []string{"CUT"}}
+	if err := edit.ParseSet([]string{"labels.line=", "description=Line A"}); err != nil {
+		t.Fatalf("Failed to parse assignments: %v", err)
+	}
+
+	result, changes, unchanged, err := Plan(nodes, func(n *node.Node) bool { return n.Labels["line"] == "a" }, edit)
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+	if len(changes) != 2 || unchanged != 0 {
+		t.Fatalf("Expected 2 changes, got %d (unchanged %d)", len(changes), unchanged)
+	}
+
+	saw := result[0]
+	if len(saw.Operations) != 1 || saw.Operations[0] != "deburr" {
+		t.Errorf("Expected cut replaced by deburr, got %v", saw.Operations)
+	}
+	if saw.Labels != nil || saw.Description != "Line A" {
+		t.Errorf("Expected label cleared and description set, got %+v", saw)
+	}
+	drill := result[1]
+	if len(drill.Operations) != 2 {
+		t.Errorf("Expected existing Deburr to be kept once, got %v", drill.Operations)
+	}
+	if result[2] != nodes[2] {
+		t.Errorf("Expected unmatched node to be returned as is")
+	}
+	if nodes[0].Operations[0] != "cut" || nodes[0].Labels["line"] != "a" {
+		t.Errorf("Expected Plan to leave the input untouched, got %+v", nodes[0])
+	}
+
+	// Applying the same edit again changes nothing
+	_, changes, unchanged, _ = Plan(result, func(n *node.Node) bool { return n.ID != "3" }, edit)
+	if len(changes) != 0 || unchanged != 2 {
+		t.Errorf("Expected edit to be idempotent, got %d changes", len(changes))
+	}
+}
+
+func TestPlanNormalizes(t *testing.T) {
+	nodes := testNodes()
+	nodes[0].Attributes = map[string]string{"power": "5"}
+	edit := &Edit{Normalize: func(n *node.Node) error {
+		if n.Attributes["power"] == "5.0" {
+			n.Attributes["power"] = "5"
+		}
+		return nil
+	}}
+	if err := edit.ParseSet([]string{"attributes.power=5.0"}); err != nil {
+		t.Fatalf("Failed to parse assignments: %v", err)
+	}
+
+	// 5.0 is stored as 5, so the preview must not show a change
+	_, changes, unchanged, err := Plan(nodes, func(n *node.Node) bool { return n.ID == "1" }, edit)
+	if err != nil || len(changes) != 0 || unchanged != 1 {
+		t.Errorf("Expected the canonical value to be unchanged, got %+v (err %v)", changes, err)
+	}
+
+	edit.Normalize = func(n *node.Node) error { return errors.New("power must be a number") }
+	if _, _, _, err := Plan(nodes, func(n *node.Node) bool { return n.ID == "1" }, edit); err == nil {
+		t.Error("Expected a rejected value to fail the plan")
+	}
+}
@@ -0,0 +1,205 @@
+// Package capability finds the nodes that can run a sequence of operations.
+// A route splits the sequence into consecutive runs, each performed by one
+// node; every change of node is a transfer.
+package capability
+
+import (
+	"sort"
+	"strings"
+
+	"manu-node-cli/internal/node"
+)
+
+// maxRoutes bounds the search so long sequences over large catalogs stay
+// fast; Result.Truncated reports when it was reached
+const maxRoutes = 5000
+
+// Step is a run of consecutive operations performed on one node
+type Step struct {
+	Node       *node.Node
+	Operations []string
+	// Link is the material flow link from the previous step's node, if any
+	Link *node.Link
+}
+
+// Route assigns every operation of the sequence to a node
+type Route struct {
+	Steps     []Step
+	Transfers int
+	// Capacity is the smallest capacity along the route; HasCapacity is
+	// false if any node on it has none declared
+	Capacity    float64
+	HasCapacity bool
+}
+
+// Options controls matching
+type Options struct {
+	// SameLine only returns routes whose nodes share one UNS line
+	SameLine bool
+	// Capacity, if set, returns a node's declared capacity
+	Capacity func(n *node.Node) (float64, bool)
+}
+
+// Result lists the routes found, best first
+type Result struct {
+	Routes    []Route
+	Truncated bool
+}
+
+// Match returns every route through nodes that performs ops in order,
+// ranked by fewest transfers, then highest capacity, then fewest transfers
+// without a material flow link, then title
+func Match(nodes []*node.Node, ops []string, opts Options) *Result {
+	res := &Result{}
+	if len(ops) == 0 {
+		return res
+	}
+
+	// candidates[i][j] lists the nodes that can run ops[i..j]
+	candidates := make([][][]*node.Node, len(ops))
+	for i := range ops {
+		candidates[i] = make([][]*node.Node, len(ops))
+		for _, n := range nodes {
+			for j := i; j < len(ops) && n.HasOperation(ops[j]); j++ {
+				candidates[i][j] = append(candidates[i][j], n)
+			}
+		}
+	}
+
+	// Routes are searched by increasing number of steps, so when the limit
+	// is reached every route with fewer transfers has already been found
+	var steps []Step
+	var walk func(start, size int)
+	walk = func(start, size int) {
+		if res.Truncated {
+			return
+		}
+		if start == len(ops) {
+			if len(res.Routes) == maxRoutes {
+				res.Truncated = true
+				return
+			}
+			res.Routes = append(res.Routes, newRoute(steps, opts))
+			return
+		}
+		// Every remaining step needs at least one operation
+		left := size - len(steps)
+		if left == 0 {
+			return
+		}
+		for end := start; end <= len(ops)-left; end++ {
+			// The last step has to run everything that is left
+			if left == 1 && end != len(ops)-1 {
+				continue
+			}
+			for _, n := range candidates[start][end] {
+				// A node repeating the previous step would be a longer
+				// run of that step, which is enumerated separately
+				if len(steps) > 0 && steps[len(steps)-1].Node.ID == n.ID {
+					continue
+				}
+				if opts.SameLine && len(steps) > 0 && Line(steps[0].Node) != Line(n) {
+					continue
+				}
+				steps = append(steps, Step{Node: n, Operations: ops[start : end+1]})
+				walk(end+1, size)
+				steps = steps[:len(steps)-1]
+			}
+		}
+	}
+	for size := 1; size <= len(ops) && !res.Truncated; size++ {
+		walk(0, size)
+	}
+
+	sort.SliceStable(res.Routes, func(i, j int) bool {
+		a, b := res.Routes[i], res.Routes[j]
+		if a.Transfers != b.Transfers {
+			return a.Transfers < b.Transfers
+		}
+		if a.HasCapacity != b.HasCapacity {
+			return a.HasCapacity
+		}
+		if a.Capacity != b.Capacity {
+			return a.Capacity > b.Capacity
+		}
+		// Transfers along existing material flow links come first
+		if ua, ub := a.unlinked(), b.unlinked(); ua != ub {
+			return ua < ub
+		}
+		return a.titles() < b.titles()
+	})
+	return res
+}
+
+func newRoute(steps []Step, opts Options) Route {
+	r := Route{Steps: make([]Step, len(steps)), Transfers: len(steps) - 1, HasCapacity: opts.Capacity != nil}
+	copy(r.Steps, steps)
+	for i := range r.Steps {
+		if i > 0 {
+			if l, ok := r.Steps[i-1].Node.LinkTo(r.Steps[i].Node.ID); ok {
+				r.Steps[i].Link = &l
+			}
+		}
+		if !r.HasCapacity {
+			continue
+		}
+		c, ok := opts.Capacity(r.Steps[i].Node)
+		switch {
+		case !ok:
+			r.HasCapacity = false
+		case i == 0 || c < r.Capacity:
+			r.Capacity = c
+		}
+	}
+	if !r.HasCapacity {
+		r.Capacity = 0
+	}
+	return r
+}
+
+// unlinked counts the transfers that no material flow link covers
+func (r Route) unlinked() int {
+	count := 0
+	for _, s := range r.Steps[1:] {
+		if s.Link == nil {
+			count++
+		}
+	}
+	return count
+}
+
+func (r Route) titles() string {
+	titles := make([]string, len(r.Steps))
+	for i, s := range r.Steps {
+		titles[i] = s.Node.Title
+	}
+	return strings.Join(titles, "\x00")
+}
+
+// Line returns the Site/Area/Line part of a node's UNS address: its parent
+// path cut to three levels
+func Line(n *node.Node) string {
+	segments := strings.Split(strings.Trim(n.UNSAddress, "/"), "/")
+	segments = segments[:len(segments)-1]
+	if len(segments) > 3 {
+		segments = segments[:3]
+	}
+	return strings.Join(segments, "/")
+}
+
+// ParseOperations reads an operation sequence given as arguments. Commas
+// separate operations when present, so "cut, drill" and cut drill both
+// give two operations while "Edge banding" stays one when quoted.
+func ParseOperations(args []string) []string {
+	parts := args
+	if joined := strings.Join(args, " "); strings.Contains(joined, ",") {
+		parts = strings.Split(joined, ",")
+	}
+	var ops []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			ops = append(ops, p)
+		}
+	}
+	return ops
+}
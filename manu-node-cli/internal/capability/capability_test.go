@@ -0,0 +1,105 @@
+package capability
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"manu-node-cli/internal/node"
+)
+
+func shop() []*node.Node {
+	return []*node.Node{
+		{ID: "saw", Title: "Saw", Operations: []string{"Cut"}, UNSAddress: "Site/Hall/Line1/Saw",
+			Attributes: map[string]string{"capacity": "8"},
+			Links:      []node.Link{{To: "drill", Kind: node.LinkConveyor}}},
+		{ID: "drill", Title: "Drill", Operations: []string{"drill", "edge-band"}, UNSAddress: "Site/Hall/Line1/Drill",
+			Attributes: map[string]string{"capacity": "5"}},
+		{ID: "cnc", Title: "CNC", Operations: []string{"cut", "drill", "edge-band"}, UNSAddress: "Site/Hall/Line2/CNC",
+			Attributes: map[string]string{"capacity": "3"}},
+		{ID: "bander", Title: "Bander", Operations: []string{"edge-band"}, UNSAddress: "Site/Hall/Line2/Bander"},
+	}
+}
+
+func capacity(n *node.Node) (float64, bool) {
+	v, err := strconv.ParseFloat(n.Attributes["capacity"], 64)
+	return v, err == nil
+}
+
+// describe renders routes as "Node[ops] > Node[ops]" for comparison
+func describe(routes []Route) []string {
+	var out []string
+	for _, r := range routes {
+		s := ""
+		for i, st := range r.Steps {
+			if i > 0 {
+				s += " > "
+			}
+			s += st.Node.ID + strconv.Itoa(len(st.Operations))
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func TestMatch(t *testing.T) {
+	res := Match(shop(), []string{"cut", "drill", "edge-band"}, Options{Capacity: capacity})
+	want := []string{
+		"cnc3",           // no transfer
+		"saw1 > drill2",  // one transfer, capacity 5
+		"cnc1 > drill2",  // one transfer, capacity 3
+		"cnc2 > drill1",  // same nodes and capacity, in search order
+		"saw1 > cnc2",    // capacity 3, ordered by title
+		"cnc2 > bander1", // one transfer, capacity unknown
+	}
+	got := describe(res.Routes)
+	if len(got) < len(want) || !reflect.DeepEqual(got[:len(want)], want) {
+		t.Fatalf("Expected routes to start with %v, got %v", want, got)
+	}
+	if res.Routes[1].Capacity != 5 || res.Routes[1].Steps[1].Link == nil {
+		t.Errorf("Expected capacity 5 over the saw's conveyor, got %+v", res.Routes[1])
+	}
+	if res.Routes[5].HasCapacity {
+		t.Error("Expected unknown capacity when a node declares none")
+	}
+
+	sameLine := Match(shop(), []string{"cut", "edge-band"}, Options{SameLine: true})
+	if got := describe(sameLine.Routes); !reflect.DeepEqual(got, []string{"cnc2", "saw1 > drill1", "cnc1 > bander1"}) {
+		t.Errorf("Unexpected same-line routes %v", got)
+	}
+
+	if res := Match(shop(), []string{"paint"}, Options{}); len(res.Routes) != 0 {
+		t.Errorf("Expected no routes for an unknown operation, got %v", describe(res.Routes))
+	}
+}
+
+func TestMatchTruncatedKeepsBestRoutes(t *testing.T) {
+	ops := []string{"a", "b", "c", "d", "e"}
+	var nodes []*node.Node
+	for i := 0; i < 12; i++ {
+		id := "n" + strconv.Itoa(i)
+		nodes = append(nodes, &node.Node{ID: id, Title: id, Operations: ops})
+	}
+
+	res := Match(nodes, ops, Options{})
+	if !res.Truncated || len(res.Routes) != maxRoutes {
+		t.Fatalf("Expected the search to stop at %d routes, got %d (truncated %v)", maxRoutes, len(res.Routes), res.Truncated)
+	}
+	for i, r := range res.Routes[:12] {
+		if r.Transfers != 0 {
+			t.Fatalf("Expected the single-node routes first, route %d has %d transfers", i, r.Transfers)
+		}
+	}
+	if res.Routes[12].Transfers != 1 {
+		t.Errorf("Expected one-transfer routes after them, got %d transfers", res.Routes[12].Transfers)
+	}
+}
+
+func TestParseOperations(t *testing.T) {
+	if got := ParseOperations([]string{"cut,", "drill, Edge", "banding"}); !reflect.DeepEqual(got, []string{"cut", "drill", "Edge banding"}) {
+		t.Errorf("Unexpected operations %v", got)
+	}
+	if got := ParseOperations([]string{"cut", "drill"}); !reflect.DeepEqual(got, []string{"cut", "drill"}) {
+		t.Errorf("Unexpected operations %v", got)
+	}
+}
@@ -0,0 +1,543 @@
+// Package config resolves the CLI settings. Each value comes from the first
+// layer that sets it, in order of precedence: command-line flags,
+// MANU_NODE_* environment variables, the selected context, the user config
+// file, the system config file and finally the built-in defaults.
+//
+// A context names a workspace, typically one plant, with its own data
+// directory and settings.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SystemFile is the machine-wide config file
+const SystemFile = "/etc/manu-node/config.yaml"
+
+// DefaultContext names the workspace configured outside any context
+const DefaultContext = "default"
+
+var contextNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Config holds every setting; empty fields are unset
+type Config struct {
+	DataDir string `yaml:"data_dir,omitempty"`
+	Storage string `yaml:"storage,omitempty"`
+	Output  string `yaml:"output,omitempty"`
+	Color   string `yaml:"color,omitempty"`
+	MQTT    MQTT   `yaml:"mqtt,omitempty"`
+	API     API    `yaml:"api,omitempty"`
+
+	// Only valid at the top level of a config file
+	CurrentContext string            `yaml:"current_context,omitempty"`
+	Contexts       map[string]Config `yaml:"contexts,omitempty"`
+}
+
+// MQTT configures the connection to the message broker
+type MQTT struct {
+	Broker      string `yaml:"broker,omitempty"`
+	ClientID    string `yaml:"client_id,omitempty"`
+	TopicPrefix string `yaml:"topic_prefix,omitempty"`
+}
+
+// API configures the REST server started by 'serve'
+type API struct {
+	Addr string `yaml:"addr,omitempty"`
+}
+
+// Key describes one setting and where it can be given
+type Key struct {
+	Name        string // dotted name used in files and 'config set'
+	Env         string
+	Flag        string
+	Description string
+	Allowed     []string // nil accepts any value that passes check
+	field       func(*Config) *string
+	check       func(string) error
+}
+
+// Keys lists every setting in display order
+var Keys = []Key{
+	{Name: "data_dir", Env: "MANU_NODE_DATA_DIR", Flag: "data-dir",
+		Description: "directory holding the catalog, users, templates and backups",
+		field:       func(c *Config) *string { return &c.DataDir }},
+	{Name: "storage", Env: "MANU_NODE_STORAGE", Flag: "storage",
+		Description: "storage backend", Allowed: []string{"json"},
+		field: func(c *Config) *string { return &c.Storage }},
+	{Name: "output", Env: "MANU_NODE_OUTPUT", Flag: "output",
+		Description: "output format of list and find", Allowed: []string{"table", "json", "yaml"},
+		field: func(c *Config) *string { return &c.Output }},
+	{Name: "color", Env: "MANU_NODE_COLOR", Flag: "color",
+		Description: "colored output", Allowed: []string{"auto", "always", "never"},
+		field: func(c *Config) *string { return &c.Color }},
+	{Name: "mqtt.broker", Env: "MANU_NODE_MQTT_BROKER", Flag: "mqtt-broker",
+		Description: "MQTT broker URL, e.g. tcp://localhost:1883",
+		field:       func(c *Config) *string { return &c.MQTT.Broker }, check: checkBroker},
+	{Name: "mqtt.client_id", Env: "MANU_NODE_MQTT_CLIENT_ID", Flag: "mqtt-client-id",
+		Description: "client ID presented to the broker",
+		field:       func(c *Config) *string { return &c.MQTT.ClientID }},
+	{Name: "mqtt.topic_prefix", Env: "MANU_NODE_MQTT_TOPIC_PREFIX", Flag: "mqtt-topic-prefix",
+		Description: "prefix of the topics nodes are published under",
+		field:       func(c *Config) *string { return &c.MQTT.TopicPrefix }},
+	{Name: "api.addr", Env: "MANU_NODE_API_ADDR", Flag: "api-addr",
+		Description: "address 'serve' listens on",
+		field:       func(c *Config) *string { return &c.API.Addr }},
+}
+
+// LookupKey finds a setting by its dotted name
+func LookupKey(name string) (Key, bool) {
+	for _, k := range Keys {
+		if k.Name == name {
+			return k, true
+		}
+	}
+	return Key{}, false
+}
+
+// Get returns the value of k in c
+func (k Key) Get(c *Config) string {
+	return *k.field(c)
+}
+
+// Validate checks a value for k
+func (k Key) Validate(value string) error {
+	if value == "" {
+		return nil
+	}
+	if k.Allowed != nil {
+		for _, a := range k.Allowed {
+			if value == a {
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid %s '%s': must be one of %s", k.Name, value, strings.Join(k.Allowed, ", "))
+	}
+	if k.check != nil {
+		if err := k.check(value); err != nil {
+			return fmt.Errorf("invalid %s '%s': %w", k.Name, value, err)
+		}
+	}
+	return nil
+}
+
+func checkBroker(value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "tcp", "ssl", "tls", "mqtt", "mqtts", "ws", "wss":
+	default:
+		return errors.New("scheme must be tcp, ssl, tls, mqtt, mqtts, ws or wss")
+	}
+	if u.Host == "" {
+		return errors.New("missing host")
+	}
+	return nil
+}
+
+// Defaults returns the built-in settings. The data directory is ./data when
+// that directory already holds a catalog, so existing checkouts keep working,
+// and the per-user data directory otherwise.
+func Defaults() Config {
+	return Config{
+		DataDir: defaultDataDir(),
+		Storage: "json",
+		Output:  "table",
+		Color:   "auto",
+		MQTT: MQTT{
+			Broker:      "tcp://localhost:1883",
+			ClientID:    "manu-node-cli",
+			TopicPrefix: "manu",
+		},
+		API: API{Addr: ":8080"},
+	}
+}
+
+func defaultDataDir() string {
+	legacy := filepath.Join(".", "data")
+	if _, err := os.Stat(filepath.Join(legacy, "nodes.json")); err == nil {
+		return legacy
+	}
+	if dir := dataHome(); dir != "" {
+		return dir
+	}
+	return legacy
+}
+
+// ContextDataDir is the data directory a new context gets unless one is
+// chosen explicitly
+func ContextDataDir(name string) string {
+	dir := dataHome()
+	if dir == "" {
+		dir = "data"
+	}
+	return filepath.Join(dir, "contexts", name)
+}
+
+func dataHome() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "manu-node")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".local", "share", "manu-node")
+	}
+	return ""
+}
+
+// ValidateContextName checks a context name
+func ValidateContextName(name string) error {
+	if name == DefaultContext {
+		return fmt.Errorf("'%s' is reserved for the settings outside any context", DefaultContext)
+	}
+	if !contextNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid context name '%s': use letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+}
+
+// UserFile returns the per-user config file path
+func UserFile() (string, error) {
+	dir, err := os.UserConfigDir() // honours $XDG_CONFIG_HOME
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the user config directory: %w", err)
+	}
+	return filepath.Join(dir, "manu-node", "config.yaml"), nil
+}
+
+// Overrides are the settings given on the command line
+type Overrides struct {
+	File    string            // replaces the user config file when set
+	Context string            // selects a context for this run
+	Values  map[string]string // by key name
+}
+
+// ParseArgs reads the global flags in front of the command and returns them
+// with the remaining arguments
+func ParseArgs(args []string) (Overrides, []string, error) {
+	fs := flag.NewFlagSet("manu-node-cli", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	file := fs.String("config", "", "config file to use instead of the user config file")
+	context := fs.String("context", "", "context to use for this run")
+	values := make(map[string]*string, len(Keys))
+	for _, k := range Keys {
+		values[k.Name] = fs.String(k.Flag, "", k.Description)
+	}
+	noColor := fs.Bool("no-color", false, "disable colored output")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return Overrides{}, []string{"help"}, nil
+		}
+		return Overrides{}, nil, err
+	}
+
+	o := Overrides{File: *file, Context: *context, Values: map[string]string{}}
+	fs.Visit(func(f *flag.Flag) {
+		for _, k := range Keys {
+			if k.Flag == f.Name {
+				o.Values[k.Name] = *values[k.Name]
+			}
+		}
+	})
+	if *noColor {
+		o.Values["color"] = "never"
+	}
+	return o, fs.Args(), nil
+}
+
+// Loaded is the resolved configuration
+type Loaded struct {
+	Config
+	// Sources names the layer each key's value came from
+	Sources map[string]string
+	// UserFile is where 'config set' writes
+	UserFile string
+
+	// Context is the selected context, empty for the default settings
+	Context       string
+	ContextSource string
+	// Contexts merges the contexts of both config files
+	Contexts map[string]Config
+
+	opts Options
+}
+
+// Options select the layers Load reads
+type Options struct {
+	SystemFile string
+	UserFile   string
+	Getenv     func(string) string
+	Overrides  Overrides
+}
+
+// Load resolves the configuration from every layer
+func Load(opts Options) (*Loaded, error) {
+	l := &Loaded{Config: Defaults(), Sources: map[string]string{}, UserFile: opts.UserFile,
+		Contexts: map[string]Config{}, opts: opts}
+	if opts.Overrides.File != "" {
+		l.UserFile = opts.Overrides.File
+	}
+	for _, k := range Keys {
+		l.Sources[k.Name] = "default"
+	}
+
+	set := func(k Key, value, source string) error {
+		if value == "" {
+			return nil
+		}
+		if err := k.Validate(value); err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+		*k.field(&l.Config) = value
+		l.Sources[k.Name] = source
+		return nil
+	}
+
+	for _, path := range []string{opts.SystemFile, l.UserFile} {
+		if path == "" {
+			continue
+		}
+		c, err := ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range Keys {
+			if err := set(k, k.Get(c), path); err != nil {
+				return nil, err
+			}
+		}
+		for name, ctx := range c.Contexts {
+			l.Contexts[name] = ctx
+		}
+		if c.CurrentContext != "" {
+			l.Context, l.ContextSource = c.CurrentContext, path
+		}
+	}
+
+	if opts.Getenv != nil && opts.Getenv("MANU_NODE_CONTEXT") != "" {
+		l.Context, l.ContextSource = opts.Getenv("MANU_NODE_CONTEXT"), "$MANU_NODE_CONTEXT"
+	}
+	if opts.Overrides.Context != "" {
+		l.Context, l.ContextSource = opts.Overrides.Context, "--context"
+	}
+	if l.Context == DefaultContext {
+		l.Context = ""
+	}
+	if l.Context != "" {
+		ctx, ok := l.Contexts[l.Context]
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown context '%s'", l.ContextSource, l.Context)
+		}
+		for _, k := range Keys {
+			if err := set(k, k.Get(&ctx), "context "+l.Context); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if opts.Getenv != nil {
+		for _, k := range Keys {
+			if err := set(k, opts.Getenv(k.Env), "$"+k.Env); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, k := range Keys {
+		if err := set(k, opts.Overrides.Values[k.Name], "--"+k.Flag); err != nil {
+			return nil, err
+		}
+	}
+
+	l.DataDir = expandHome(l.DataDir)
+	return l, nil
+}
+
+// WithContext resolves the configuration again with another context
+// selected, as if it had been given with --context
+func (l *Loaded) WithContext(name string) (*Loaded, error) {
+	opts := l.opts
+	opts.Overrides.Context = name
+	return Load(opts)
+}
+
+// ContextNames lists the default context followed by the named ones in
+// sorted order
+func (l *Loaded) ContextNames() []string {
+	names := make([]string, 0, len(l.Contexts))
+	for name := range l.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append([]string{DefaultContext}, names...)
+}
+
+func expandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~/")
+	if !ok {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, rest)
+}
+
+// ReadFile parses a config file, rejecting unknown keys
+func ReadFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	var c Config
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for name, ctx := range c.Contexts {
+		if err := ValidateContextName(name); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if ctx.CurrentContext != "" || len(ctx.Contexts) > 0 {
+			return nil, fmt.Errorf("%s: context '%s' cannot contain contexts", path, name)
+		}
+	}
+	return &c, nil
+}
+
+// update rewrites the config file at path through fn, creating it if needed
+func update(path string, fn func(c *Config) error) error {
+	c, err := ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		c, err = &Config{}, nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := fn(c); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	data := buf.Bytes()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace config: %w", err)
+	}
+	return nil
+}
+
+// Set stores value for key in the config file at path, creating the file if
+// needed. A non-empty context stores it in that context's settings instead
+// of the top level. An empty value removes the key.
+func Set(path, context, key, value string) error {
+	k, ok := LookupKey(key)
+	if !ok {
+		return fmt.Errorf("unknown config key '%s' (known: %s)", key, strings.Join(KeyNames(), ", "))
+	}
+	if err := k.Validate(value); err != nil {
+		return err
+	}
+	return update(path, func(c *Config) error {
+		if context == "" {
+			*k.field(c) = value
+			return nil
+		}
+		ctx, ok := c.Contexts[context]
+		if !ok {
+			return fmt.Errorf("context '%s' is not defined in %s", context, path)
+		}
+		*k.field(&ctx) = value
+		c.Contexts[context] = ctx
+		return nil
+	})
+}
+
+// AddContext stores a new context in the config file at path
+func AddContext(path, name string, settings Config) error {
+	if err := ValidateContextName(name); err != nil {
+		return err
+	}
+	for _, k := range Keys {
+		if err := k.Validate(k.Get(&settings)); err != nil {
+			return err
+		}
+	}
+	return update(path, func(c *Config) error {
+		if _, ok := c.Contexts[name]; ok {
+			return fmt.Errorf("context '%s' already exists", name)
+		}
+		if c.Contexts == nil {
+			c.Contexts = map[string]Config{}
+		}
+		c.Contexts[name] = settings
+		return nil
+	})
+}
+
+// UseContext makes name the current context in the config file at path;
+// the default context clears the selection
+func UseContext(path, name string) error {
+	if name == DefaultContext {
+		name = ""
+	}
+	return update(path, func(c *Config) error {
+		c.CurrentContext = name
+		return nil
+	})
+}
+
+// RemoveContext deletes a context from the config file at path. Its data
+// directory is left alone.
+func RemoveContext(path, name string) error {
+	return update(path, func(c *Config) error {
+		if _, ok := c.Contexts[name]; !ok {
+			return fmt.Errorf("context '%s' is not defined in %s", name, path)
+		}
+		delete(c.Contexts, name)
+		if c.CurrentContext == name {
+			c.CurrentContext = ""
+		}
+		return nil
+	})
+}
+
+// KeyNames lists the setting names in sorted order
+func KeyNames() []string {
+	names := make([]string, len(Keys))
+	for i, k := range Keys {
+		names[i] = k.Name
+	}
+	sort.Strings(names)
+	return names
+}
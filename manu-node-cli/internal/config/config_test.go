@@ -0,0 +1,192 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func TestLoadLayers(t *testing.T) {
+	dir := t.TempDir()
+	system := filepath.Join(dir, "system.yaml")
+	user := filepath.Join(dir, "user.yaml")
+	writeFile(t, system, "data_dir: /srv/manu\noutput: json\nmqtt:\n  broker: tcp://broker:1883\n")
+	writeFile(t, user, "output: yaml\napi:\n  addr: :9090\n")
+
+	env := map[string]string{"MANU_NODE_API_ADDR": ":7070", "MANU_NODE_COLOR": "never"}
+	cfg, err := Load(Options{
+		SystemFile: system,
+		UserFile:   user,
+		Getenv:     func(k string) string { return env[k] },
+		Overrides:  Overrides{Values: map[string]string{"color": "always"}},
+	})
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	tests := []struct {
+		key, value, source string
+	}{
+		{"data_dir", "/srv/manu", system},
+		{"storage", "json", "default"},
+		{"output", "yaml", user},
+		{"mqtt.broker", "tcp://broker:1883", system},
+		{"api.addr", ":7070", "$MANU_NODE_API_ADDR"},
+		{"color", "always", "--color"},
+	}
+	for _, tt := range tests {
+		k, _ := LookupKey(tt.key)
+		if got := k.Get(&cfg.Config); got != tt.value {
+			t.Errorf("%s: expected '%s', got '%s'", tt.key, tt.value, got)
+		}
+		if got := cfg.Sources[tt.key]; got != tt.source {
+			t.Errorf("%s: expected source '%s', got '%s'", tt.key, tt.source, got)
+		}
+	}
+}
+
+func TestLoadRejectsInvalidValues(t *testing.T) {
+	dir := t.TempDir()
+	user := filepath.Join(dir, "user.yaml")
+
+	writeFile(t, user, "outptu: json\n")
+	if _, err := Load(Options{UserFile: user}); err == nil {
+		t.Error("Expected an error for an unknown key")
+	}
+
+	writeFile(t, user, "storage: postgres\n")
+	if _, err := Load(Options{UserFile: user}); err == nil || !strings.Contains(err.Error(), user) {
+		t.Errorf("Expected an error naming the file, got %v", err)
+	}
+
+	env := func(k string) string {
+		if k == "MANU_NODE_MQTT_BROKER" {
+			return "localhost:1883"
+		}
+		return ""
+	}
+	if _, err := Load(Options{Getenv: env}); err == nil {
+		t.Error("Expected an error for a broker URL without a scheme")
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	o, rest, err := ParseArgs([]string{"--data-dir", "/tmp/x", "--no-color", "--config", "c.yaml", "list", "-o", "json"})
+	if err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+	if o.File != "c.yaml" {
+		t.Errorf("Expected config file 'c.yaml', got '%s'", o.File)
+	}
+	want := map[string]string{"data_dir": "/tmp/x", "color": "never"}
+	if !reflect.DeepEqual(o.Values, want) {
+		t.Errorf("Expected %v, got %v", want, o.Values)
+	}
+	if !reflect.DeepEqual(rest, []string{"list", "-o", "json"}) {
+		t.Errorf("Expected the command to be left alone, got %v", rest)
+	}
+}
+
+func TestSet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manu-node", "config.yaml")
+
+	if err := Set(path, "", "mqtt.client_id", "line-1"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := Set(path, "", "output", "json"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := Set(path, "", "output", "xml"); err == nil {
+		t.Error("Expected an error for an invalid value")
+	}
+	if err := Set(path, "", "nope", "x"); err == nil {
+		t.Error("Expected an error for an unknown key")
+	}
+
+	c, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if c.MQTT.ClientID != "line-1" || c.Output != "json" {
+		t.Errorf("Expected both values saved, got %+v", c)
+	}
+
+	if err := Set(path, "", "output", ""); err != nil {
+		t.Fatalf("Unset failed: %v", err)
+	}
+	if c, _ := ReadFile(path); c.Output != "" || c.MQTT.ClientID != "line-1" {
+		t.Errorf("Expected only output removed, got %+v", c)
+	}
+}
+
+func TestContexts(t *testing.T) {
+	dir := t.TempDir()
+	user := filepath.Join(dir, "user.yaml")
+	writeFile(t, user, "output: yaml\n")
+
+	if err := AddContext(user, "StribrneHory", Config{DataDir: "/srv/stribrne"}); err != nil {
+		t.Fatalf("AddContext failed: %v", err)
+	}
+	if err := AddContext(user, "StribrneHory", Config{}); err == nil {
+		t.Error("Expected an error for a duplicate context")
+	}
+	if err := AddContext(user, DefaultContext, Config{}); err == nil {
+		t.Error("Expected the default context name to be reserved")
+	}
+	if err := Set(user, "StribrneHory", "output", "json"); err != nil {
+		t.Fatalf("Set in context failed: %v", err)
+	}
+
+	// Without a current context the top-level settings apply
+	cfg, err := Load(Options{UserFile: user})
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Context != "" || cfg.Output != "yaml" {
+		t.Errorf("Expected the default context with yaml output, got '%s' and '%s'", cfg.Context, cfg.Output)
+	}
+
+	if err := UseContext(user, "StribrneHory"); err != nil {
+		t.Fatalf("UseContext failed: %v", err)
+	}
+	cfg, err = Load(Options{UserFile: user})
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Context != "StribrneHory" || cfg.DataDir != "/srv/stribrne" || cfg.Output != "json" {
+		t.Errorf("Expected the context's settings, got %s %s %s", cfg.Context, cfg.DataDir, cfg.Output)
+	}
+	if cfg.Sources["output"] != "context StribrneHory" {
+		t.Errorf("Expected output from the context, got '%s'", cfg.Sources["output"])
+	}
+	if got := cfg.ContextNames(); !reflect.DeepEqual(got, []string{DefaultContext, "StribrneHory"}) {
+		t.Errorf("Expected default and StribrneHory, got %v", got)
+	}
+
+	def, err := cfg.WithContext(DefaultContext)
+	if err != nil {
+		t.Fatalf("WithContext failed: %v", err)
+	}
+	if def.Context != "" || def.Output != "yaml" {
+		t.Errorf("Expected the default settings, got '%s' and '%s'", def.Context, def.Output)
+	}
+	if _, err := cfg.WithContext("nowhere"); err == nil {
+		t.Error("Expected an error for an unknown context")
+	}
+
+	if err := RemoveContext(user, "StribrneHory"); err != nil {
+		t.Fatalf("RemoveContext failed: %v", err)
+	}
+	if c, _ := ReadFile(user); c.CurrentContext != "" || len(c.Contexts) != 0 {
+		t.Errorf("Expected the context and its selection removed, got %+v", c)
+	}
+}
@@ -0,0 +1,216 @@
+// Package graph renders the plant layout as a diagram: nodes grouped into
+// nested clusters by the Site/Area/Line part of their UNS address, labeled
+// with their operations, and connected by their material flow links.
+package graph
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"manu-node-cli/internal/node"
+)
+
+// Format is a diagram language
+type Format string
+
+const (
+	FormatDOT     Format = "dot"
+	FormatMermaid Format = "mermaid"
+)
+
+// Formats lists the supported formats
+var Formats = []Format{FormatDOT, FormatMermaid}
+
+// clusterDepth is how many UNS levels become clusters: Site, Area and Line
+const clusterDepth = 3
+
+// cluster is one UNS level with the nodes placed directly in it
+type cluster struct {
+	name     string
+	children []*cluster
+	nodes    []*node.Node
+}
+
+func (c *cluster) child(name string) *cluster {
+	for _, ch := range c.children {
+		if ch.name == name {
+			return ch
+		}
+	}
+	ch := &cluster{name: name}
+	c.children = append(c.children, ch)
+	return ch
+}
+
+func (c *cluster) sort() {
+	sort.Slice(c.children, func(i, j int) bool { return c.children[i].name < c.children[j].name })
+	sort.SliceStable(c.nodes, func(i, j int) bool { return c.nodes[i].Title < c.nodes[j].Title })
+	for _, ch := range c.children {
+		ch.sort()
+	}
+}
+
+// layout places each node in the cluster of its parent UNS path, cut to
+// clusterDepth levels. The last segment of an address names the node
+// itself, so "Site/Area/Line/Cell" lands in Site > Area > Line.
+func layout(nodes []*node.Node) *cluster {
+	root := &cluster{}
+	for _, n := range nodes {
+		c := root
+		segments := strings.Split(strings.Trim(n.UNSAddress, "/"), "/")
+		for i := 0; i < len(segments)-1 && i < clusterDepth; i++ {
+			if segments[i] != "" {
+				c = c.child(segments[i])
+			}
+		}
+		c.nodes = append(c.nodes, n)
+	}
+	root.sort()
+	return root
+}
+
+// edge is a link whose both ends are drawn
+type edge struct {
+	from, to *node.Node
+	link     node.Link
+}
+
+func edges(nodes []*node.Node) []edge {
+	var out []edge
+	for _, n := range nodes {
+		for _, nb := range node.Downstream(nodes, n) {
+			out = append(out, edge{from: n, to: nb.Node, link: nb.Link})
+		}
+	}
+	return out
+}
+
+// nodeLabel is the title followed by the operations
+func nodeLabel(n *node.Node) []string {
+	lines := []string{n.Title}
+	if len(n.Operations) > 0 {
+		lines = append(lines, strings.Join(n.Operations, ", "))
+	}
+	return lines
+}
+
+// edgeLabel describes a link; plain conveyors without details get none
+func edgeLabel(l node.Link) string {
+	if l.Kind == node.LinkConveyor && l.Capacity == 0 && l.TransferTime == "" {
+		return ""
+	}
+	return l.String()
+}
+
+// Write renders nodes in format. Links to nodes that are not in nodes are
+// left out.
+func Write(w io.Writer, nodes []*node.Node, format Format) error {
+	switch format {
+	case FormatDOT:
+		return writeDOT(w, nodes)
+	case FormatMermaid:
+		return writeMermaid(w, nodes)
+	}
+	return fmt.Errorf("unsupported format '%s' (use dot or mermaid)", format)
+}
+
+func writeDOT(w io.Writer, nodes []*node.Node) error {
+	var b strings.Builder
+	b.WriteString("digraph plant {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=rounded];\n")
+
+	clusters := 0
+	var walk func(c *cluster, indent string)
+	walk = func(c *cluster, indent string) {
+		for _, ch := range c.children {
+			fmt.Fprintf(&b, "%ssubgraph cluster_%d {\n", indent, clusters)
+			clusters++
+			fmt.Fprintf(&b, "%s  label=%s;\n", indent, dotQuote(ch.name))
+			walk(ch, indent+"  ")
+			fmt.Fprintf(&b, "%s}\n", indent)
+		}
+		for _, n := range c.nodes {
+			fmt.Fprintf(&b, "%s%s [label=%s];\n", indent, dotQuote(n.ID), dotQuote(strings.Join(nodeLabel(n), "\n")))
+		}
+	}
+	walk(layout(nodes), "  ")
+
+	for _, e := range edges(nodes) {
+		var attrs []string
+		if label := edgeLabel(e.link); label != "" {
+			attrs = append(attrs, "label="+dotQuote(label))
+		}
+		switch e.link.Kind {
+		case node.LinkBuffer:
+			attrs = append(attrs, "style=bold")
+		case node.LinkManual:
+			attrs = append(attrs, "style=dashed")
+		}
+		line := fmt.Sprintf("  %s -> %s", dotQuote(e.from.ID), dotQuote(e.to.ID))
+		if len(attrs) > 0 {
+			line += " [" + strings.Join(attrs, ", ") + "]"
+		}
+		b.WriteString(line + ";\n")
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dotQuote makes s a DOT string, keeping newlines as line breaks
+func dotQuote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+	return `"` + s + `"`
+}
+
+func writeMermaid(w io.Writer, nodes []*node.Node) error {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+
+	// Mermaid IDs must be plain words, so nodes and clusters are numbered
+	ids := make(map[string]string, len(nodes))
+	clusters := 0
+	var walk func(c *cluster, indent string)
+	walk = func(c *cluster, indent string) {
+		for _, ch := range c.children {
+			fmt.Fprintf(&b, "%ssubgraph c%d[%s]\n", indent, clusters, mermaidQuote(ch.name))
+			clusters++
+			walk(ch, indent+"  ")
+			fmt.Fprintf(&b, "%send\n", indent)
+		}
+		for _, n := range c.nodes {
+			id := fmt.Sprintf("n%d", len(ids))
+			ids[n.ID] = id
+			fmt.Fprintf(&b, "%s%s[%s]\n", indent, id, mermaidQuote(strings.Join(nodeLabel(n), "<br/>")))
+		}
+	}
+	walk(layout(nodes), "  ")
+
+	for _, e := range edges(nodes) {
+		arrow := "-->"
+		switch e.link.Kind {
+		case node.LinkBuffer:
+			arrow = "==>"
+		case node.LinkManual:
+			arrow = "-.->"
+		}
+		if label := edgeLabel(e.link); label != "" {
+			arrow += "|" + mermaidQuote(label) + "|"
+		}
+		fmt.Fprintf(&b, "  %s %s %s\n", ids[e.from.ID], arrow, ids[e.to.ID])
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// mermaidQuote makes s a quoted Mermaid label. Quotes and angle brackets
+// become entity codes; the <br/> line breaks added by the caller are kept.
+func mermaidQuote(s string) string {
+	s = strings.NewReplacer(`"`, "#quot;", "<br/>", "<br/>", "<", "#lt;", ">", "#gt;").Replace(s)
+	return `"` + s + `"`
+}
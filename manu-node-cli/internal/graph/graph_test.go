@@ -0,0 +1,83 @@
+package graph
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"manu-node-cli/internal/node"
+)
+
+func plant() []*node.Node {
+	return []*node.Node{
+		{ID: "cnc", Title: "CNC", Operations: []string{"Milling", "Drilling"}, UNSAddress: "Site/Hall/Line1/CNC"},
+		{ID: "saw", Title: `Saw "big"`, Operations: []string{"Cut"}, UNSAddress: "Site/Hall/Line1/Cell2/Saw",
+			Links: []node.Link{
+				{To: "cnc", Kind: node.LinkBuffer, Capacity: 20},
+				{To: "gone", Kind: node.LinkConveyor},
+			}},
+		{ID: "pack", Title: "Pack", UNSAddress: "Site/Pack", Links: []node.Link{{To: "saw", Kind: node.LinkManual}}},
+		{ID: "spare", Title: "Spare"},
+	}
+}
+
+func render(t *testing.T, format Format) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Write(&buf, plant(), format); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	return buf.String()
+}
+
+func TestDOT(t *testing.T) {
+	out := render(t, FormatDOT)
+	want := `digraph plant {
+  rankdir=LR;
+  node [shape=box, style=rounded];
+  subgraph cluster_0 {
+    label="Site";
+    subgraph cluster_1 {
+      label="Hall";
+      subgraph cluster_2 {
+        label="Line1";
+        "cnc" [label="CNC\nMilling, Drilling"];
+        "saw" [label="Saw \"big\"\nCut"];
+      }
+    }
+    "pack" [label="Pack"];
+  }
+  "spare" [label="Spare"];
+  "saw" -> "cnc" [label="buffer, capacity 20", style=bold];
+  "pack" -> "saw" [label="manual", style=dashed];
+}
+`
+	if out != want {
+		t.Errorf("Unexpected DOT output:\n%s", out)
+	}
+}
+
+func TestMermaid(t *testing.T) {
+	out := render(t, FormatMermaid)
+	for _, want := range []string{
+		"flowchart LR\n",
+		"  subgraph c0[\"Site\"]\n",
+		"        n0[\"CNC<br/>Milling, Drilling\"]\n",
+		"        n1[\"Saw #quot;big#quot;<br/>Cut\"]\n",
+		"  n1 ==>|\"buffer, capacity 20\"| n0\n",
+		"  n2 -.->|\"manual\"| n1\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in:\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "subgraph") != strings.Count(out, "end\n") {
+		t.Errorf("Expected every subgraph to be closed:\n%s", out)
+	}
+}
+
+func TestUnsupportedFormat(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, plant(), "svg"); err == nil {
+		t.Error("Expected an unsupported format to be rejected")
+	}
+}
@@ -0,0 +1,125 @@
+// Package labels implements free-form key/value labels and
+// Kubernetes-style label selectors such as
+// "dept=woodshop,criticality in (high,medium),!retired"
+package labels
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	keyPattern   = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._/-]{0,62})$`)
+	valuePattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._-]{0,62})$`)
+)
+
+// ValidateKey checks that k can be used as a label key
+func ValidateKey(k string) error {
+	if !keyPattern.MatchString(k) {
+		return fmt.Errorf("invalid label key '%s': use up to 63 letters, digits, '.', '_', '-' or '/', starting with a letter or digit", k)
+	}
+	return nil
+}
+
+// ValidateValue checks that v can be used as a label value
+func ValidateValue(v string) error {
+	if !valuePattern.MatchString(v) {
+		return fmt.Errorf("invalid label value '%s': use up to 63 letters, digits, '.', '_' or '-', starting with a letter or digit", v)
+	}
+	return nil
+}
+
+// Validate checks every key and value of a label set
+func Validate(set map[string]string) error {
+	for _, k := range Keys(set) {
+		if err := ValidateKey(k); err != nil {
+			return err
+		}
+		if err := ValidateValue(set[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Keys returns the keys of set in sorted order
+func Keys(set map[string]string) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Format renders set as "k1=v1,k2=v2" with sorted keys
+func Format(set map[string]string) string {
+	parts := make([]string, 0, len(set))
+	for _, k := range Keys(set) {
+		parts = append(parts, k+"="+set[k])
+	}
+	return strings.Join(parts, ",")
+}
+
+// Parse reads a comma-separated list of key=value pairs
+func Parse(s string) (map[string]string, error) {
+	set := map[string]string{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid label '%s': expected key=value", part)
+		}
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		if err := ValidateKey(k); err != nil {
+			return nil, err
+		}
+		if err := ValidateValue(v); err != nil {
+			return nil, err
+		}
+		set[k] = v
+	}
+	if len(set) == 0 {
+		return nil, nil
+	}
+	return set, nil
+}
+
+// Edit applies kubectl-style edits to a copy of set: "key=value" sets a
+// label and "key-" removes it
+func Edit(set map[string]string, edits []string) (map[string]string, error) {
+	out := map[string]string{}
+	for k, v := range set {
+		out[k] = v
+	}
+	for _, e := range edits {
+		e = strings.TrimSpace(e)
+		if k, ok := strings.CutSuffix(e, "-"); ok && !strings.Contains(e, "=") {
+			if err := ValidateKey(k); err != nil {
+				return nil, err
+			}
+			delete(out, k)
+			continue
+		}
+		parsed, err := Parse(e)
+		if err != nil {
+			return nil, err
+		}
+		if len(parsed) == 0 {
+			return nil, errors.New("empty label edit")
+		}
+		for k, v := range parsed {
+			out[k] = v
+		}
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return out, nil
+}
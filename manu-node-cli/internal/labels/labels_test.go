@@ -0,0 +1,85 @@
+package labels
+
+import (
+	"testing"
+)
+
+func TestParseSelector(t *testing.T) {
+	set := map[string]string{"dept": "woodshop", "criticality": "high"}
+
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{"", true},
+		{"dept=woodshop", true},
+		{"dept==woodshop", true},
+		{"dept!=woodshop", false},
+		{"dept=metal", false},
+		{"criticality in (high,medium)", true},
+		{"criticality notin (high, medium)", false},
+		{"dept", true},
+		{"!retired", true},
+		{"!dept", false},
+		{"dept=woodshop,criticality in (high,medium),!retired", true},
+		{"dept=woodshop, retired", false},
+		{"shift!=night", true},
+		{"shift notin (night)", true},
+		{"shift in (night)", false},
+	}
+	for _, tt := range tests {
+		sel, err := ParseSelector(tt.selector)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", tt.selector, err)
+			continue
+		}
+		if got := sel.Matches(set); got != tt.want {
+			t.Errorf("%q: expected %v, got %v", tt.selector, tt.want, got)
+		}
+	}
+
+	for _, bad := range []string{"dept=", "a in (b", "a in b)", "a within (b)", "=x", "dept=wood shop", "!"} {
+		if _, err := ParseSelector(bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestSelectorString(t *testing.T) {
+	sel, err := ParseSelector("dept==woodshop , criticality  in ( high,medium ),!retired")
+	if err != nil {
+		t.Fatalf("Failed to parse selector: %v", err)
+	}
+	want := "dept=woodshop,criticality in (high,medium),!retired"
+	if got := sel.String(); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestParseAndEdit(t *testing.T) {
+	set, err := Parse("dept=woodshop, criticality=high")
+	if err != nil {
+		t.Fatalf("Failed to parse labels: %v", err)
+	}
+	if Format(set) != "criticality=high,dept=woodshop" {
+		t.Errorf("Unexpected labels: %s", Format(set))
+	}
+	if _, err := Parse("dept"); err == nil {
+		t.Errorf("Expected label without value to be rejected")
+	}
+
+	edited, err := Edit(set, []string{"criticality-", "owner=jan"})
+	if err != nil {
+		t.Fatalf("Failed to edit labels: %v", err)
+	}
+	if Format(edited) != "dept=woodshop,owner=jan" {
+		t.Errorf("Unexpected edited labels: %s", Format(edited))
+	}
+	if Format(set) != "criticality=high,dept=woodshop" {
+		t.Errorf("Expected Edit to leave the original untouched")
+	}
+
+	if cleared, _ := Edit(map[string]string{"a": "b"}, []string{"a-"}); cleared != nil {
+		t.Errorf("Expected removing the last label to return nil, got %v", cleared)
+	}
+}
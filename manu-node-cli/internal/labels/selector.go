@@ -0,0 +1,210 @@
+package labels
+
+import (
+	"fmt"
+	"strings"
+)
+
+// operator is the comparison in one selector requirement
+type operator string
+
+const (
+	opEquals       operator = "="
+	opNotEquals    operator = "!="
+	opIn           operator = "in"
+	opNotIn        operator = "notin"
+	opExists       operator = "exists"
+	opDoesNotExist operator = "!"
+)
+
+// requirement is one comma-separated term of a selector
+type requirement struct {
+	key    string
+	op     operator
+	values []string
+}
+
+func (r requirement) matches(set map[string]string) bool {
+	v, ok := set[r.key]
+	switch r.op {
+	case opExists:
+		return ok
+	case opDoesNotExist:
+		return !ok
+	case opEquals:
+		return ok && v == r.values[0]
+	case opNotEquals:
+		return !ok || v != r.values[0]
+	case opIn:
+		return ok && contains(r.values, v)
+	case opNotIn:
+		return !ok || !contains(r.values, v)
+	}
+	return false
+}
+
+func (r requirement) String() string {
+	switch r.op {
+	case opExists:
+		return r.key
+	case opDoesNotExist:
+		return "!" + r.key
+	case opIn, opNotIn:
+		return fmt.Sprintf("%s %s (%s)", r.key, r.op, strings.Join(r.values, ","))
+	default:
+		return r.key + string(r.op) + r.values[0]
+	}
+}
+
+// Selector matches label sets; every requirement must hold. The zero
+// Selector matches everything.
+type Selector struct {
+	requirements []requirement
+}
+
+// Empty reports whether the selector has no requirements
+func (s Selector) Empty() bool {
+	return len(s.requirements) == 0
+}
+
+// Matches reports whether set satisfies every requirement
+func (s Selector) Matches(set map[string]string) bool {
+	for _, r := range s.requirements {
+		if !r.matches(set) {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the selector in canonical form
+func (s Selector) String() string {
+	parts := make([]string, len(s.requirements))
+	for i, r := range s.requirements {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseSelector parses a selector such as
+// "dept=woodshop,criticality in (high,medium),!retired". Supported terms
+// are key=value, key==value, key!=value, key in (a,b), key notin (a,b),
+// key and !key.
+func ParseSelector(s string) (Selector, error) {
+	var sel Selector
+	terms, err := splitTerms(s)
+	if err != nil {
+		return Selector{}, err
+	}
+	for _, term := range terms {
+		r, err := parseRequirement(term)
+		if err != nil {
+			return Selector{}, err
+		}
+		sel.requirements = append(sel.requirements, r)
+	}
+	return sel, nil
+}
+
+// splitTerms splits on commas that are not inside a value list
+func splitTerms(s string) ([]string, error) {
+	var terms []string
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("invalid selector '%s': unbalanced ')'", s)
+			}
+		case ',':
+			if depth == 0 {
+				terms = append(terms, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("invalid selector '%s': missing ')'", s)
+	}
+	terms = append(terms, s[start:])
+
+	out := terms[:0]
+	for _, t := range terms {
+		if t = strings.TrimSpace(t); t != "" {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+func parseRequirement(term string) (requirement, error) {
+	invalid := func(reason string) (requirement, error) {
+		return requirement{}, fmt.Errorf("invalid selector term '%s': %s", term, reason)
+	}
+
+	// Set-based: key in (a,b) / key notin (a,b)
+	if open := strings.Index(term, "("); open >= 0 {
+		if !strings.HasSuffix(term, ")") {
+			return invalid("expected ')' at the end")
+		}
+		fields := strings.Fields(term[:open])
+		if len(fields) != 2 || (fields[1] != string(opIn) && fields[1] != string(opNotIn)) {
+			return invalid("expected 'key in (a,b)' or 'key notin (a,b)'")
+		}
+		if err := ValidateKey(fields[0]); err != nil {
+			return requirement{}, err
+		}
+		var values []string
+		for _, v := range strings.Split(term[open+1:len(term)-1], ",") {
+			v = strings.TrimSpace(v)
+			if err := ValidateValue(v); err != nil {
+				return requirement{}, err
+			}
+			values = append(values, v)
+		}
+		return requirement{key: fields[0], op: operator(fields[1]), values: values}, nil
+	}
+
+	// Equality-based
+	for _, op := range []string{"!=", "==", "="} {
+		if k, v, ok := strings.Cut(term, op); ok {
+			k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+			if err := ValidateKey(k); err != nil {
+				return requirement{}, err
+			}
+			if err := ValidateValue(v); err != nil {
+				return requirement{}, err
+			}
+			o := opEquals
+			if op == "!=" {
+				o = opNotEquals
+			}
+			return requirement{key: k, op: o, values: []string{v}}, nil
+		}
+	}
+
+	// Existence
+	if k, ok := strings.CutPrefix(term, "!"); ok {
+		k = strings.TrimSpace(k)
+		if err := ValidateKey(k); err != nil {
+			return requirement{}, err
+		}
+		return requirement{key: k, op: opDoesNotExist}, nil
+	}
+	if err := ValidateKey(term); err != nil {
+		return requirement{}, err
+	}
+	return requirement{key: term, op: opExists}, nil
+}
+
+func contains(values []string, v string) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
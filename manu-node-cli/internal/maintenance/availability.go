@@ -0,0 +1,125 @@
+package maintenance
+
+import (
+	"sort"
+	"time"
+)
+
+// Window is a period in which a node is out of service for maintenance
+type Window struct {
+	Start time.Time
+	End   time.Time
+	// Task is the task that plans the window; merged windows keep the first
+	Task *Task
+	// Projected marks a later occurrence of Task's plan that has no task yet
+	Projected bool
+}
+
+// Availability is a node's service time over a period once planned
+// maintenance is taken out
+type Availability struct {
+	From, To  time.Time
+	Blocked   []Window
+	Available time.Duration
+}
+
+// Total is the length of the period
+func (a *Availability) Total() time.Duration {
+	return a.To.Sub(a.From)
+}
+
+// Ratio is the share of the period the node is available, between 0 and 1
+func (a *Availability) Ratio() float64 {
+	if a.Total() <= 0 {
+		return 0
+	}
+	return float64(a.Available) / float64(a.Total())
+}
+
+// Availability returns the maintenance windows of a node that fall in
+// [from, to), clipped to the period and merged where they overlap, and the
+// time left for production. Besides the window of each open task, the later
+// occurrences of its plan are projected over the period, assuming every
+// task is done when due; meter plans recur at the rate of their readings.
+func (s *Schedule) Availability(nodeID string, from, to time.Time) *Availability {
+	a := &Availability{From: from, To: to}
+	var windows []Window
+	add := func(w Window) {
+		if !w.End.After(from) || !w.Start.Before(to) {
+			return
+		}
+		if w.Start.Before(from) {
+			w.Start = from
+		}
+		if w.End.After(to) {
+			w.End = to
+		}
+		windows = append(windows, w)
+	}
+	for _, t := range s.Tasks {
+		if t.NodeID != nodeID || !t.Open() {
+			continue
+		}
+		w, ok := s.Window(t)
+		if !ok {
+			continue
+		}
+		add(w)
+
+		every, length, ok := s.recurrence(t)
+		if !ok {
+			continue
+		}
+		for start := w.Start.Add(every); start.Before(to); start = start.Add(every) {
+			add(Window{Start: start, End: start.Add(length), Task: t, Projected: true})
+		}
+	}
+	sort.SliceStable(windows, func(i, j int) bool { return windows[i].Start.Before(windows[j].Start) })
+
+	blocked := time.Duration(0)
+	for _, w := range windows {
+		if n := len(a.Blocked); n > 0 && !w.Start.After(a.Blocked[n-1].End) {
+			if w.End.After(a.Blocked[n-1].End) {
+				blocked += w.End.Sub(a.Blocked[n-1].End)
+				a.Blocked[n-1].End = w.End
+			}
+			continue
+		}
+		a.Blocked = append(a.Blocked, w)
+		blocked += w.End.Sub(w.Start)
+	}
+	a.Available = a.Total() - blocked
+	if a.Available < 0 {
+		a.Available = 0
+	}
+	return a
+}
+
+// recurrence returns how often the plan of a task fires and how long each
+// window lasts; false means the plan is gone or its meter rate is unknown
+func (s *Schedule) recurrence(t *Task) (time.Duration, time.Duration, bool) {
+	p, err := s.Plan(t.PlanID)
+	if err != nil {
+		return 0, 0, false
+	}
+	var every time.Duration
+	if p.Trigger == TriggerCalendar {
+		if every, err = ParseInterval(p.Every); err != nil {
+			return 0, 0, false
+		}
+	} else {
+		rate, ok := meterRate(s.readings(t.NodeID, p.Trigger))
+		if !ok {
+			return 0, 0, false
+		}
+		every = time.Duration(p.Interval / rate)
+	}
+	if every <= 0 {
+		return 0, 0, false
+	}
+	length, err := ParseInterval(p.Duration)
+	if err != nil {
+		length = DefaultDuration
+	}
+	return every, length, true
+}
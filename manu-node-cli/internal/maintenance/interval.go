@@ -0,0 +1,67 @@
+package maintenance
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const day = 24 * time.Hour
+
+// ParseInterval reads a duration that may also use days and weeks, such
+// as "30d", "2w" or "1.5d", besides Go durations like "36h" or "90m"
+func ParseInterval(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	unit := time.Duration(0)
+	switch {
+	case strings.HasSuffix(s, "d"):
+		unit = day
+	case strings.HasSuffix(s, "w"):
+		unit = 7 * day
+	}
+	if unit != 0 {
+		n, err := strconv.ParseFloat(strings.TrimSpace(s[:len(s)-1]), 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid interval '%s': use e.g. 30d, 2w or 12h", s)
+		}
+		return time.Duration(n * float64(unit)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid interval '%s': use e.g. 30d, 2w or 12h", s)
+	}
+	return d, nil
+}
+
+// FormatInterval writes whole days as "30d" and anything else as a Go
+// duration without zero minutes or seconds ("3h", "1h30m"), so
+// ParseInterval reads it back unchanged
+func FormatInterval(d time.Duration) string {
+	if d > 0 && d%day == 0 {
+		return fmt.Sprintf("%dd", d/day)
+	}
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// timeLayouts are the accepted ways to write a date or time, tried in order
+var timeLayouts = []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02"}
+
+// ParseTime reads a date ("2026-10-20"), a local date and time
+// ("2026-10-20 06:00") or an RFC 3339 timestamp
+func ParseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time '%s': use YYYY-MM-DD or 'YYYY-MM-DD HH:MM'", s)
+}
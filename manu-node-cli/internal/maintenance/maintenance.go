@@ -0,0 +1,439 @@
+// Package maintenance schedules preventive maintenance per node. A plan
+// fires on a calendar interval, after a number of runtime hours or after a
+// number of cycles; each firing is a task with a due date and a window in
+// which the node is out of service. Completing a task records who did the
+// work and generates the next one.
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"manu-node-cli/internal/node"
+)
+
+// Triggers decide when a plan falls due
+const (
+	TriggerCalendar = "calendar"
+	TriggerRuntime  = "runtime" // meter in hours
+	TriggerCycles   = "cycles"  // meter in cycles
+)
+
+// DefaultDuration is the window length used when a plan gives none
+const DefaultDuration = 2 * time.Hour
+
+// ErrNotFound is returned for unknown plan and task IDs
+var ErrNotFound = errors.New("not found")
+
+// Plan is a recurring maintenance job on one node
+type Plan struct {
+	ID      string `json:"id"`
+	NodeID  string `json:"node_id"`
+	Name    string `json:"name"`
+	Trigger string `json:"trigger"`
+
+	// Every is the calendar interval, e.g. "30d"
+	Every string `json:"every,omitempty"`
+	// Interval is the runtime hours or cycles between services
+	Interval float64 `json:"interval,omitempty"`
+
+	// Duration is how long the work keeps the node out of service
+	Duration  string    `json:"duration"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Describe says when the plan fires, e.g. "every 30d" or "every 500 h"
+func (p *Plan) Describe() string {
+	switch p.Trigger {
+	case TriggerRuntime:
+		return "every " + formatMeter(p.Interval) + " h"
+	case TriggerCycles:
+		return "every " + formatMeter(p.Interval) + " cycles"
+	}
+	return "every " + p.Every
+}
+
+// Task is one occurrence of a plan
+type Task struct {
+	ID     string `json:"id"`
+	PlanID string `json:"plan_id"`
+	NodeID string `json:"node_id"`
+	Name   string `json:"name"`
+
+	// Due is when a calendar task falls due
+	Due time.Time `json:"due,omitempty"`
+	// DueMeter is the meter reading at which a runtime or cycle task falls
+	// due; its date is estimated from the readings
+	DueMeter float64 `json:"due_meter,omitempty"`
+
+	// Start is when the window is planned; unset means it starts when the
+	// task falls due
+	Start    *time.Time `json:"start,omitempty"`
+	Duration string     `json:"duration"`
+
+	Completed *Completion `json:"completed,omitempty"`
+}
+
+// Open reports whether the task still has to be done
+func (t *Task) Open() bool {
+	return t.Completed == nil
+}
+
+// Completion records who did a task and when
+type Completion struct {
+	At         time.Time `json:"at"`
+	Technician string    `json:"technician"`
+	Notes      string    `json:"notes,omitempty"`
+	// Meter is the reading the next task counts from
+	Meter float64 `json:"meter,omitempty"`
+}
+
+// Reading is a runtime hours or cycle count meter value of a node
+type Reading struct {
+	NodeID string    `json:"node_id"`
+	Meter  string    `json:"meter"`
+	Value  float64   `json:"value"`
+	At     time.Time `json:"at"`
+}
+
+// Schedule is everything the maintenance store keeps
+type Schedule struct {
+	Plans    []*Plan   `json:"plans"`
+	Tasks    []*Task   `json:"tasks"`
+	Readings []Reading `json:"readings"`
+	NextPlan int       `json:"next_plan"`
+	NextTask int       `json:"next_task"`
+}
+
+// AddPlan validates p, gives it an ID and generates its first task. A
+// calendar plan first falls due at first, or one interval from now when
+// first is zero.
+func (s *Schedule) AddPlan(p *Plan, now, first time.Time) (*Task, error) {
+	if p.NodeID == "" {
+		return nil, errors.New("plan needs a node")
+	}
+	if strings.TrimSpace(p.Name) == "" || !node.IsValidText(p.Name) {
+		return nil, errors.New("plan needs a name without control characters")
+	}
+	if p.Duration == "" {
+		p.Duration = FormatInterval(DefaultDuration)
+	}
+	d, err := ParseInterval(p.Duration)
+	if err != nil {
+		return nil, err
+	}
+	p.Duration = FormatInterval(d)
+
+	switch p.Trigger {
+	case TriggerCalendar:
+		every, err := ParseInterval(p.Every)
+		if err != nil {
+			return nil, err
+		}
+		if every <= 0 {
+			return nil, errors.New("calendar interval must be positive")
+		}
+		p.Every = FormatInterval(every)
+		p.Interval = 0
+		if first.IsZero() {
+			first = now.Add(every)
+		}
+	case TriggerRuntime, TriggerCycles:
+		if p.Interval <= 0 {
+			return nil, fmt.Errorf("%s interval must be positive", p.Trigger)
+		}
+		p.Every = ""
+	default:
+		return nil, fmt.Errorf("unknown trigger '%s' (use %s, %s or %s)", p.Trigger, TriggerCalendar, TriggerRuntime, TriggerCycles)
+	}
+
+	s.NextPlan++
+	p.ID = "P" + strconv.Itoa(s.NextPlan)
+	p.CreatedAt = now
+	s.Plans = append(s.Plans, p)
+
+	t := s.newTask(p)
+	if p.Trigger == TriggerCalendar {
+		t.Due = first
+	} else {
+		latest, _ := s.Latest(p.NodeID, p.Trigger)
+		t.DueMeter = latest.Value + p.Interval
+	}
+	return t, nil
+}
+
+func (s *Schedule) newTask(p *Plan) *Task {
+	s.NextTask++
+	t := &Task{ID: "T" + strconv.Itoa(s.NextTask), PlanID: p.ID, NodeID: p.NodeID, Name: p.Name, Duration: p.Duration}
+	s.Tasks = append(s.Tasks, t)
+	return t
+}
+
+// RemovePlan deletes a plan and its open task; completed tasks stay in the
+// history
+func (s *Schedule) RemovePlan(id string) (*Plan, error) {
+	p, err := s.Plan(id)
+	if err != nil {
+		return nil, err
+	}
+	var plans []*Plan
+	for _, other := range s.Plans {
+		if other != p {
+			plans = append(plans, other)
+		}
+	}
+	s.Plans = plans
+	var tasks []*Task
+	for _, t := range s.Tasks {
+		if t.PlanID != p.ID || !t.Open() {
+			tasks = append(tasks, t)
+		}
+	}
+	s.Tasks = tasks
+	return p, nil
+}
+
+// Plan returns the plan with the given ID (case-insensitive)
+func (s *Schedule) Plan(id string) (*Plan, error) {
+	for _, p := range s.Plans {
+		if strings.EqualFold(p.ID, id) {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: maintenance plan %s", ErrNotFound, id)
+}
+
+// Task returns the task with the given ID (case-insensitive)
+func (s *Schedule) Task(id string) (*Task, error) {
+	for _, t := range s.Tasks {
+		if strings.EqualFold(t.ID, id) {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: maintenance task %s", ErrNotFound, id)
+}
+
+// Record adds a meter reading. Meters only count up, so a value below the
+// latest reading is refused.
+func (s *Schedule) Record(r Reading) error {
+	if r.Meter != TriggerRuntime && r.Meter != TriggerCycles {
+		return fmt.Errorf("unknown meter '%s'", r.Meter)
+	}
+	if r.Value < 0 {
+		return errors.New("meter reading cannot be negative")
+	}
+	if latest, ok := s.Latest(r.NodeID, r.Meter); ok && r.Value < latest.Value {
+		return fmt.Errorf("%s meter cannot go back from %s to %s", r.Meter, formatMeter(latest.Value), formatMeter(r.Value))
+	}
+	s.Readings = append(s.Readings, r)
+	return nil
+}
+
+// readings returns a node's readings of one meter, oldest first
+func (s *Schedule) readings(nodeID, meter string) []Reading {
+	var out []Reading
+	for _, r := range s.Readings {
+		if r.NodeID == nodeID && r.Meter == meter {
+			out = append(out, r)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].At.Before(out[j].At) })
+	return out
+}
+
+// Latest returns a node's most recent reading of one meter
+func (s *Schedule) Latest(nodeID, meter string) (Reading, bool) {
+	rs := s.readings(nodeID, meter)
+	if len(rs) == 0 {
+		return Reading{}, false
+	}
+	return rs[len(rs)-1], true
+}
+
+// DueDate returns when a task falls due. For runtime and cycle tasks it is
+// the time the meter reached the threshold, or an estimate from the
+// average rate of the readings; false means there is not enough data.
+func (s *Schedule) DueDate(t *Task) (time.Time, bool) {
+	p, err := s.Plan(t.PlanID)
+	if err != nil || p.Trigger == TriggerCalendar {
+		return t.Due, !t.Due.IsZero()
+	}
+
+	rs := s.readings(t.NodeID, p.Trigger)
+	for i, r := range rs {
+		if r.Value < t.DueMeter {
+			continue
+		}
+		if i == 0 {
+			return r.At, true
+		}
+		prev := rs[i-1]
+		share := (t.DueMeter - prev.Value) / (r.Value - prev.Value)
+		return prev.At.Add(time.Duration(share * float64(r.At.Sub(prev.At)))), true
+	}
+	rate, ok := meterRate(rs)
+	if !ok {
+		return time.Time{}, false
+	}
+	last := rs[len(rs)-1]
+	return last.At.Add(time.Duration((t.DueMeter - last.Value) / rate)), true
+}
+
+// meterRate is the average increase of a meter per nanosecond over its
+// readings; false means there is not enough data
+func meterRate(rs []Reading) (float64, bool) {
+	if len(rs) < 2 {
+		return 0, false
+	}
+	first, last := rs[0], rs[len(rs)-1]
+	elapsed := last.At.Sub(first.At)
+	if elapsed <= 0 || last.Value <= first.Value {
+		return 0, false
+	}
+	return (last.Value - first.Value) / float64(elapsed), true
+}
+
+// Window returns the period a task keeps its node out of service: from its
+// planned start, or its due date, for its duration
+func (s *Schedule) Window(t *Task) (Window, bool) {
+	start := time.Time{}
+	if t.Start != nil {
+		start = *t.Start
+	} else if due, ok := s.DueDate(t); ok {
+		start = due
+	} else {
+		return Window{}, false
+	}
+	d, err := ParseInterval(t.Duration)
+	if err != nil {
+		d = DefaultDuration
+	}
+	return Window{Start: start, End: start.Add(d), Task: t}, true
+}
+
+// Reschedule plans the window of an open task
+func (s *Schedule) Reschedule(taskID string, start time.Time, duration string) (*Task, error) {
+	t, err := s.Task(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if !t.Open() {
+		return nil, fmt.Errorf("task %s is already completed", t.ID)
+	}
+	if duration != "" {
+		d, err := ParseInterval(duration)
+		if err != nil {
+			return nil, err
+		}
+		t.Duration = FormatInterval(d)
+	}
+	t.Start = &start
+	return t, nil
+}
+
+// Complete records the completion of an open task and generates the next
+// task of its plan, which is returned unless the plan was removed. The
+// next calendar task falls due one interval after the work was done; a
+// meter task one interval after the latest reading.
+func (s *Schedule) Complete(taskID string, c Completion) (*Task, *Task, error) {
+	t, err := s.Task(taskID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !t.Open() {
+		return nil, nil, fmt.Errorf("task %s is already completed", t.ID)
+	}
+	if strings.TrimSpace(c.Technician) == "" || !node.IsValidText(c.Technician) {
+		return nil, nil, errors.New("completion needs the technician's name")
+	}
+	if !node.IsValidMultilineText(c.Notes) {
+		return nil, nil, errors.New("notes contain invalid characters")
+	}
+
+	p, err := s.Plan(t.PlanID)
+	if err != nil {
+		t.Completed = &c
+		return t, nil, nil
+	}
+	next := s.newTask(p)
+	if p.Trigger == TriggerCalendar {
+		every, _ := ParseInterval(p.Every)
+		next.Due = c.At.Add(every)
+	} else {
+		latest, _ := s.Latest(t.NodeID, p.Trigger)
+		c.Meter = latest.Value
+		next.DueMeter = c.Meter + p.Interval
+	}
+	t.Completed = &c
+	return t, next, nil
+}
+
+// DueTask is an open task with its due date
+type DueTask struct {
+	Task    *Task
+	Plan    *Plan
+	Due     time.Time
+	Overdue bool
+}
+
+// Due lists the open tasks that fall due before until, overdue ones
+// included, soonest first. Tasks of nodes that keep fails are skipped.
+func (s *Schedule) Due(now, until time.Time, keep func(nodeID string) bool) []DueTask {
+	var out []DueTask
+	for _, t := range s.Tasks {
+		if !t.Open() || (keep != nil && !keep(t.NodeID)) {
+			continue
+		}
+		due, ok := s.DueDate(t)
+		if !ok || due.After(until) {
+			continue
+		}
+		p, _ := s.Plan(t.PlanID)
+		out = append(out, DueTask{Task: t, Plan: p, Due: due, Overdue: due.Before(now)})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Due.Before(out[j].Due) })
+	return out
+}
+
+// History returns a node's completed tasks, most recent first
+func (s *Schedule) History(nodeID string) []*Task {
+	var out []*Task
+	for _, t := range s.Tasks {
+		if t.NodeID == nodeID && !t.Open() {
+			out = append(out, t)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Completed.At.After(out[j].Completed.At) })
+	return out
+}
+
+// NodePlans returns the plans of one node, or of every node if nodeID is
+// empty
+func (s *Schedule) NodePlans(nodeID string) []*Plan {
+	var out []*Plan
+	for _, p := range s.Plans {
+		if nodeID == "" || p.NodeID == nodeID {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// OpenTask returns the pending task of a plan
+func (s *Schedule) OpenTask(planID string) *Task {
+	for _, t := range s.Tasks {
+		if t.PlanID == planID && t.Open() {
+			return t
+		}
+	}
+	return nil
+}
+
+// formatMeter writes a meter value without needless decimals
+func formatMeter(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,235 @@
+package maintenance
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+
+func TestParseInterval(t *testing.T) {
+	cases := map[string]time.Duration{
+		"30d":  30 * day,
+		"2w":   14 * day,
+		"1.5d": 36 * time.Hour,
+		"90m":  90 * time.Minute,
+	}
+	for in, want := range cases {
+		got, err := ParseInterval(in)
+		if err != nil || got != want {
+			t.Errorf("ParseInterval(%q): expected %v, got %v (%v)", in, want, got, err)
+		}
+	}
+	for _, in := range []string{"", "-1d", "soon", "d"} {
+		if _, err := ParseInterval(in); err == nil {
+			t.Errorf("Expected an error for %q", in)
+		}
+	}
+	if got := FormatInterval(30 * day); got != "30d" {
+		t.Errorf("Expected 30d, got %s", got)
+	}
+	for d, want := range map[time.Duration]string{3 * time.Hour: "3h", 90 * time.Minute: "1h30m", 45 * time.Second: "45s"} {
+		if got := FormatInterval(d); got != want {
+			t.Errorf("Expected %s, got %s", want, got)
+		}
+	}
+}
+
+func TestCalendarPlan(t *testing.T) {
+	s := &Schedule{}
+	task, err := s.AddPlan(&Plan{NodeID: "cnc", Name: "Lubricate", Trigger: TriggerCalendar, Every: "7d"}, now, time.Time{})
+	if err != nil {
+		t.Fatalf("Failed to add plan: %v", err)
+	}
+	if task.ID != "T1" || task.PlanID != "P1" || !task.Due.Equal(now.Add(7*day)) {
+		t.Errorf("Unexpected first task: %+v", task)
+	}
+	if task.Duration != "2h" {
+		t.Errorf("Expected the default duration, got %s", task.Duration)
+	}
+
+	if due := s.Due(now, now.Add(6*day), nil); len(due) != 0 {
+		t.Errorf("Expected nothing due within 6 days, got %d", len(due))
+	}
+	due := s.Due(now.Add(8*day), now.Add(8*day), nil)
+	if len(due) != 1 || !due[0].Overdue {
+		t.Fatalf("Expected one overdue task, got %+v", due)
+	}
+
+	doneAt := now.Add(9 * day)
+	_, next, err := s.Complete("t1", Completion{At: doneAt, Technician: "Ana", Notes: "Topped up"})
+	if err != nil {
+		t.Fatalf("Failed to complete: %v", err)
+	}
+	if next == nil || !next.Due.Equal(doneAt.Add(7*day)) {
+		t.Errorf("Expected the next task a week after completion, got %+v", next)
+	}
+	if _, _, err := s.Complete("T1", Completion{At: doneAt, Technician: "Ana"}); err == nil {
+		t.Error("Expected completing twice to fail")
+	}
+	if h := s.History("cnc"); len(h) != 1 || h[0].Completed.Technician != "Ana" {
+		t.Errorf("Expected one history entry by Ana, got %+v", h)
+	}
+
+	if _, err := s.RemovePlan("P1"); err != nil {
+		t.Fatalf("Failed to remove plan: %v", err)
+	}
+	if len(s.Tasks) != 1 || s.Tasks[0].ID != "T1" {
+		t.Errorf("Expected only the completed task to remain, got %d tasks", len(s.Tasks))
+	}
+	if _, err := s.Task("T2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestInvalidPlans(t *testing.T) {
+	s := &Schedule{}
+	plans := []*Plan{
+		{NodeID: "cnc", Name: "", Trigger: TriggerCalendar, Every: "7d"},
+		{NodeID: "cnc", Name: "Oil", Trigger: TriggerCalendar, Every: "0d"},
+		{NodeID: "cnc", Name: "Oil", Trigger: TriggerRuntime},
+		{NodeID: "cnc", Name: "Oil", Trigger: "weekly"},
+		{NodeID: "cnc", Name: "Oil", Trigger: TriggerCycles, Interval: 10, Duration: "long"},
+	}
+	for _, p := range plans {
+		if _, err := s.AddPlan(p, now, time.Time{}); err == nil {
+			t.Errorf("Expected an error for %+v", p)
+		}
+	}
+	if len(s.Plans) != 0 || s.NextPlan != 0 {
+		t.Errorf("Expected no plans to be added, got %d", len(s.Plans))
+	}
+}
+
+func TestMeterPlan(t *testing.T) {
+	s := &Schedule{}
+	if err := s.Record(Reading{NodeID: "cnc", Meter: TriggerRuntime, Value: 1000, At: now}); err != nil {
+		t.Fatal(err)
+	}
+	task, err := s.AddPlan(&Plan{NodeID: "cnc", Name: "Spindle", Trigger: TriggerRuntime, Interval: 500}, now, time.Time{})
+	if err != nil {
+		t.Fatalf("Failed to add plan: %v", err)
+	}
+	if task.DueMeter != 1500 {
+		t.Errorf("Expected due at 1500 h, got %v", task.DueMeter)
+	}
+	if _, ok := s.DueDate(task); ok {
+		t.Error("Expected no due date from a single reading")
+	}
+
+	// 100 h a day puts 1500 h four days after the 1100 h reading
+	if err := s.Record(Reading{NodeID: "cnc", Meter: TriggerRuntime, Value: 1100, At: now.Add(day)}); err != nil {
+		t.Fatal(err)
+	}
+	if due, ok := s.DueDate(task); !ok || !due.Equal(now.Add(5*day)) {
+		t.Errorf("Expected an estimate 5 days out, got %v", due)
+	}
+
+	// Once crossed, the due date is interpolated between readings
+	if err := s.Record(Reading{NodeID: "cnc", Meter: TriggerRuntime, Value: 1700, At: now.Add(3 * day)}); err != nil {
+		t.Fatal(err)
+	}
+	if due, ok := s.DueDate(task); !ok || !due.Equal(now.Add(day+day*4/3)) {
+		t.Errorf("Expected the interpolated crossing, got %v", due)
+	}
+
+	if err := s.Record(Reading{NodeID: "cnc", Meter: TriggerRuntime, Value: 1600, At: now.Add(4 * day)}); err == nil {
+		t.Error("Expected a decreasing reading to be refused")
+	}
+
+	_, next, err := s.Complete(task.ID, Completion{At: now.Add(3 * day), Technician: "Ben"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.DueMeter != 2200 {
+		t.Errorf("Expected the next task at 2200 h, got %v", next.DueMeter)
+	}
+}
+
+func TestAvailability(t *testing.T) {
+	s := &Schedule{}
+	first := now.Add(day)
+	if _, err := s.AddPlan(&Plan{NodeID: "cnc", Name: "Inspect", Trigger: TriggerCalendar, Every: "30d", Duration: "4h"}, now, first); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AddPlan(&Plan{NodeID: "cnc", Name: "Clean", Trigger: TriggerCalendar, Every: "30d"}, now, first.Add(3*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AddPlan(&Plan{NodeID: "saw", Name: "Blade", Trigger: TriggerCalendar, Every: "30d"}, now, first); err != nil {
+		t.Fatal(err)
+	}
+
+	a := s.Availability("cnc", now, now.Add(7*day))
+	if len(a.Blocked) != 1 {
+		t.Fatalf("Expected overlapping windows to merge, got %d", len(a.Blocked))
+	}
+	if w := a.Blocked[0]; !w.Start.Equal(first) || !w.End.Equal(first.Add(5*time.Hour)) {
+		t.Errorf("Unexpected window %v - %v", w.Start, w.End)
+	}
+	if a.Available != 7*day-5*time.Hour {
+		t.Errorf("Expected 163h available, got %v", a.Available)
+	}
+
+	// Rescheduling moves the window; clipping keeps it within the period
+	if _, err := s.Reschedule("T1", now.Add(-time.Hour), "3h"); err != nil {
+		t.Fatal(err)
+	}
+	a = s.Availability("cnc", now, now.Add(7*day))
+	if len(a.Blocked) != 2 || !a.Blocked[0].Start.Equal(now) || a.Available != 7*day-4*time.Hour {
+		t.Errorf("Expected a clipped 2h window and a 2h window, got %+v (%v available)", a.Blocked, a.Available)
+	}
+}
+
+func TestAvailabilityProjectsRecurrences(t *testing.T) {
+	s := &Schedule{}
+	if _, err := s.AddPlan(&Plan{NodeID: "cnc", Name: "Lubricate", Trigger: TriggerCalendar, Every: "1d", Duration: "2h"}, now, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	a := s.Availability("cnc", now, now.Add(7*day))
+	if len(a.Blocked) != 7 || a.Available != 7*day-14*time.Hour {
+		t.Fatalf("Expected seven 2h windows, got %d (%v available)", len(a.Blocked), a.Available)
+	}
+	if a.Blocked[0].Projected || !a.Blocked[1].Projected || !a.Blocked[6].Start.Equal(now.Add(6*day+time.Hour)) {
+		t.Errorf("Expected the open task followed by projected occurrences, got %+v", a.Blocked)
+	}
+
+	// Meter plans recur at the rate of the readings: 10 h a day
+	s = &Schedule{}
+	for i, v := range []float64{0, 10} {
+		if err := s.Record(Reading{NodeID: "saw", Meter: TriggerRuntime, Value: v, At: now.Add(time.Duration(i-1) * day)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.AddPlan(&Plan{NodeID: "saw", Name: "Blade", Trigger: TriggerRuntime, Interval: 20, Duration: "1h"}, now, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	a = s.Availability("saw", now, now.Add(7*day))
+	if len(a.Blocked) != 3 || !a.Blocked[0].Start.Equal(now.Add(2*day)) || a.Available != 7*day-3*time.Hour {
+		t.Errorf("Expected a window every two days, got %+v (%v available)", a.Blocked, a.Available)
+	}
+}
+
+func TestStore(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Update(func(s *Schedule) error {
+		_, err := s.AddPlan(&Plan{NodeID: "cnc", Name: "Oil", Trigger: TriggerCycles, Interval: 10000}, now, time.Time{})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Failed to update: %v", err)
+	}
+	if err := store.Update(func(s *Schedule) error { return errors.New("boom") }); err == nil {
+		t.Error("Expected the update error to be returned")
+	}
+	s, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Plans) != 1 || len(s.Tasks) != 1 || s.Plans[0].Describe() != "every 10000 cycles" {
+		t.Errorf("Expected the saved plan, got %+v", s.Plans)
+	}
+}
@@ -0,0 +1,79 @@
+package maintenance
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store handles persistence of the maintenance schedule
+type Store struct {
+	filePath string
+	mu       sync.RWMutex
+}
+
+// NewStore creates a maintenance store in the data directory
+func NewStore(dataDir string) (*Store, error) {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	return &Store{filePath: filepath.Join(dataDir, "maintenance.json")}, nil
+}
+
+// Load reads the schedule
+func (s *Store) Load() (*Schedule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.load()
+}
+
+// Update applies fn to the schedule and saves it if fn succeeds
+func (s *Store) Update(fn func(*Schedule) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sched, err := s.load()
+	if err != nil {
+		return err
+	}
+	if err := fn(sched); err != nil {
+		return err
+	}
+	return s.save(sched)
+}
+
+func (s *Store) load() (*Schedule, error) {
+	data, err := os.ReadFile(s.filePath)
+	if os.IsNotExist(err) {
+		return &Schedule{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read maintenance file: %w", err)
+	}
+	if len(data) == 0 {
+		return &Schedule{}, nil
+	}
+
+	var sched Schedule
+	if err := json.Unmarshal(data, &sched); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal maintenance schedule: %w", err)
+	}
+	return &sched, nil
+}
+
+func (s *Store) save(sched *Schedule) error {
+	data, err := json.MarshalIndent(sched, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal maintenance schedule: %w", err)
+	}
+	tmp := s.filePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write maintenance file: %w", err)
+	}
+	if err := os.Rename(tmp, s.filePath); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write maintenance file: %w", err)
+	}
+	return nil
+}
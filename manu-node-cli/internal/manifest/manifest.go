@@ -0,0 +1,467 @@
+package manifest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"manu-node-cli/internal/node"
+	"manu-node-cli/internal/storage"
+)
+
+// Spec is the declared state of one node. Nodes are matched to stored
+// nodes by ID when given, otherwise by title (case-insensitive).
+type Spec struct {
+	ID          string   `yaml:"id,omitempty" json:"id,omitempty"`
+	Title       string   `yaml:"title" json:"title"`
+	Description string   `yaml:"description,omitempty" json:"description,omitempty"`
+	Operations  []string `yaml:"operations,omitempty" json:"operations,omitempty"`
+	UNSAddress  string   `yaml:"uns_address,omitempty" json:"uns_address,omitempty"`
+
+	Attributes map[string]string `yaml:"attributes,omitempty" json:"attributes,omitempty"`
+	Labels     map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+
+	// Links are the material flow connections leaving the node
+	Links []LinkSpec `yaml:"links,omitempty" json:"links,omitempty"`
+
+	// Source is the file and document the spec was read from
+	Source string `yaml:"-" json:"-"`
+}
+
+// LinkSpec declares a link. To names the target node by ID or title.
+type LinkSpec struct {
+	To           string `yaml:"to" json:"to"`
+	Kind         string `yaml:"kind,omitempty" json:"kind,omitempty"`
+	Capacity     int    `yaml:"capacity,omitempty" json:"capacity,omitempty"`
+	TransferTime string `yaml:"transfer_time,omitempty" json:"transfer_time,omitempty"`
+}
+
+// document is one YAML document: either a single node or a list of nodes
+type document struct {
+	Spec  `yaml:",inline"`
+	Nodes []Spec `yaml:"nodes,omitempty"`
+}
+
+// Load reads every .yaml, .yml and .json manifest in path, which may be a
+// file or a directory (searched recursively, in lexical order)
+func Load(path string) ([]Spec, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	if info.IsDir() {
+		err = filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && isManifestFile(p) {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+	} else {
+		files = []string{path}
+	}
+
+	var specs []Spec
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := Parse(data, f)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, parsed...)
+	}
+	return specs, nil
+}
+
+// Parse decodes a multi-document YAML (or JSON) manifest. Unknown fields
+// are rejected so typos do not silently drop data.
+func Parse(data []byte, source string) ([]Spec, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	var specs []Spec
+	for i := 1; ; i++ {
+		var doc document
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: document %d: %w", source, i, err)
+		}
+
+		where := fmt.Sprintf("%s#%d", source, i)
+		if doc.Title != "" || doc.ID != "" {
+			doc.Spec.Source = where
+			specs = append(specs, doc.Spec)
+		}
+		for j, s := range doc.Nodes {
+			s.Source = fmt.Sprintf("%s nodes[%d]", where, j)
+			specs = append(specs, s)
+		}
+	}
+	return specs, nil
+}
+
+// Marshal renders nodes as a manifest that Load reads back unchanged
+func Marshal(nodes []*node.Node) ([]byte, error) {
+	doc := struct {
+		Nodes []Spec `yaml:"nodes"`
+	}{}
+	titles := map[string]string{}
+	for _, n := range nodes {
+		titles[n.ID] = n.Title
+	}
+	for _, n := range nodes {
+		spec := FromNode(n)
+		// Refer to exported targets by title, which is easier to read
+		for i, l := range spec.Links {
+			if title, ok := titles[l.To]; ok {
+				spec.Links[i].To = title
+			}
+		}
+		doc.Nodes = append(doc.Nodes, spec)
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// FromNode converts a stored node into its declarative form. Link targets
+// are given by ID.
+func FromNode(n *node.Node) Spec {
+	spec := Spec{
+		ID:          n.ID,
+		Title:       n.Title,
+		Description: n.Description,
+		Operations:  n.Operations,
+		UNSAddress:  n.UNSAddress,
+		Attributes:  n.Attributes,
+		Labels:      n.Labels,
+	}
+	for _, l := range n.Links {
+		spec.Links = append(spec.Links, LinkSpec{To: l.To, Kind: l.Kind, Capacity: l.Capacity, TransferTime: l.TransferTime})
+	}
+	return spec
+}
+
+// ResolveLinks turns declared links into node links, looking targets up in
+// nodes by ID first and then by title (case-insensitive)
+func ResolveLinks(specs []LinkSpec, nodes []*node.Node) ([]node.Link, error) {
+	var links []node.Link
+	for _, ls := range specs {
+		target := findID(nodes, ls.To)
+		if target == nil {
+			for _, n := range nodes {
+				if strings.EqualFold(n.Title, strings.TrimSpace(ls.To)) {
+					target = n
+					break
+				}
+			}
+		}
+		if target == nil {
+			return nil, fmt.Errorf("link target '%s' not found", ls.To)
+		}
+		l, err := node.NewLink(target.ID, ls.Kind, ls.Capacity, ls.TransferTime)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, nil
+}
+
+// Action is the storage call a change maps to
+type Action string
+
+const (
+	ActionCreate Action = "SaveNode"
+	ActionUpdate Action = "UpdateNode"
+	ActionDelete Action = "DeleteNode"
+)
+
+// Change is one planned storage call
+type Change struct {
+	Action   Action
+	Node     *node.Node // desired node; nil for deletes
+	Existing *node.Node // stored node; nil for creates
+	Diff     []string
+	Source   string
+}
+
+// Plan is the set of changes that reconcile storage with the manifests
+type Plan struct {
+	Changes   []Change
+	Unchanged int
+	Nodes     []*node.Node // the complete catalog after applying the plan
+}
+
+// Options controls planning
+type Options struct {
+	// Prune deletes stored nodes that no manifest declares
+	Prune bool
+	// Authorize, if set, is asked about every node that would be written
+	// or deleted
+	Authorize func(n *node.Node) error
+	// Normalize, if set, validates custom attributes and rewrites them in
+	// canonical form so unchanged values do not show up as updates
+	Normalize func(n *node.Node) error
+	// Merge keeps the attributes, labels and links of a stored node when
+	// its spec declares none, for formats such as B2MML that cannot carry
+	// them
+	Merge bool
+}
+
+// Count returns how many changes have the given action
+func (p *Plan) Count(a Action) int {
+	count := 0
+	for _, c := range p.Changes {
+		if c.Action == a {
+			count++
+		}
+	}
+	return count
+}
+
+// BuildPlan compares manifests with the stored catalog. It fails if any
+// spec is invalid, two specs claim the same node, titles collide, or a
+// change is not authorized; in that case nothing should be applied.
+func BuildPlan(current []*node.Node, specs []Spec, opts Options) (*Plan, error) {
+	var problems []string
+	problem := func(source, format string, args ...interface{}) {
+		problems = append(problems, source+": "+fmt.Sprintf(format, args...))
+	}
+
+	now := time.Now()
+	plan := &Plan{}
+	claimed := map[string]string{} // stored node ID -> spec source
+	var desired []*node.Node
+	type entry struct {
+		spec     Spec
+		want     *node.Node
+		existing *node.Node
+	}
+	var entries []entry
+
+	for _, s := range specs {
+		var existing *node.Node
+		for _, n := range current {
+			if (s.ID != "" && n.ID == s.ID) || (s.ID == "" && strings.EqualFold(n.Title, s.Title)) {
+				existing = n
+				break
+			}
+		}
+
+		want := &node.Node{
+			ID:          s.ID,
+			Title:       strings.TrimSpace(s.Title),
+			Description: strings.TrimSpace(s.Description),
+			Operations:  cleanOperations(s.Operations),
+			UNSAddress:  strings.TrimSpace(s.UNSAddress),
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		if len(s.Attributes) > 0 {
+			want.Attributes = make(map[string]string, len(s.Attributes))
+			for k, v := range s.Attributes {
+				want.Attributes[k] = v
+			}
+		}
+		if len(s.Labels) > 0 {
+			want.Labels = make(map[string]string, len(s.Labels))
+			for k, v := range s.Labels {
+				want.Labels[k] = v
+			}
+		}
+		if existing != nil {
+			want.ID = existing.ID
+			want.CreatedAt = existing.CreatedAt
+			want.UpdatedAt = existing.UpdatedAt
+			// Calibration records are history, not declared in manifests
+			want.Calibrations = existing.Calibrations
+			if opts.Merge {
+				if s.Attributes == nil {
+					want.Attributes = copyStrings(existing.Attributes)
+				}
+				if s.Labels == nil {
+					want.Labels = copyStrings(existing.Labels)
+				}
+			}
+		} else if want.ID == "" {
+			want.ID = node.UniqueID(func(id string) bool {
+				return findID(current, id) != nil || findID(desired, id) != nil
+			})
+		}
+
+		if err := want.Validate(); err != nil {
+			problem(s.Source, "%v", err)
+			continue
+		}
+		if opts.Normalize != nil {
+			if err := opts.Normalize(want); err != nil {
+				problem(s.Source, "%v", err)
+				continue
+			}
+		}
+		if prev, ok := claimed[want.ID]; ok {
+			problem(s.Source, "declares the same node as %s", prev)
+			continue
+		}
+		claimed[want.ID] = s.Source
+		desired = append(desired, want)
+		entries = append(entries, entry{spec: s, want: want, existing: existing})
+	}
+
+	// Build the resulting catalog in the stored order, new nodes last
+	byID := map[string]*node.Node{}
+	for _, n := range desired {
+		byID[n.ID] = n
+	}
+	var pruned []Change
+	for _, n := range current {
+		if want, ok := byID[n.ID]; ok {
+			plan.Nodes = append(plan.Nodes, want)
+			delete(byID, n.ID)
+		} else if opts.Prune {
+			pruned = append(pruned, Change{Action: ActionDelete, Existing: n, Source: "(pruned)"})
+		} else {
+			plan.Nodes = append(plan.Nodes, n)
+		}
+	}
+	for _, n := range desired {
+		if _, ok := byID[n.ID]; ok {
+			plan.Nodes = append(plan.Nodes, n)
+		}
+	}
+
+	// Links may point at nodes declared anywhere in the manifests, so they
+	// are resolved against the resulting catalog, without pruned nodes
+	for _, e := range entries {
+		links, err := ResolveLinks(e.spec.Links, plan.Nodes)
+		if opts.Merge && e.spec.Links == nil && e.existing != nil {
+			links = append([]node.Link(nil), e.existing.Links...)
+		}
+		if err == nil {
+			e.want.Links = links
+			err = e.want.Validate()
+		}
+		if err != nil {
+			problem(e.spec.Source, "%v", err)
+			continue
+		}
+
+		switch {
+		case e.existing == nil:
+			plan.Changes = append(plan.Changes, Change{Action: ActionCreate, Node: e.want, Source: e.spec.Source})
+		default:
+			if diff := node.Diff(e.existing, e.want); len(diff) > 0 {
+				e.want.UpdatedAt = now
+				plan.Changes = append(plan.Changes, Change{Action: ActionUpdate, Node: e.want,
+					Existing: e.existing, Diff: diff, Source: e.spec.Source})
+			} else {
+				plan.Unchanged++
+			}
+		}
+	}
+	plan.Changes = append(plan.Changes, pruned...)
+
+	// Titles must stay unique across the resulting catalog
+	for _, c := range plan.Changes {
+		if c.Node != nil && !storage.TitleUnique(plan.Nodes, c.Node.Title, c.Node.ID) {
+			problem(c.Source, "a node with title '%s' already exists", c.Node.Title)
+		}
+	}
+
+	if opts.Authorize != nil {
+		for _, c := range plan.Changes {
+			for _, n := range []*node.Node{c.Existing, c.Node} {
+				if n == nil {
+					continue
+				}
+				if err := opts.Authorize(n); err != nil {
+					problem(c.Source, "%v", err)
+					break
+				}
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return plan, &PlanError{Problems: problems}
+	}
+	return plan, nil
+}
+
+// PlanError lists every problem found while planning
+type PlanError struct {
+	Problems []string
+}
+
+func (e *PlanError) Error() string {
+	return fmt.Sprintf("manifests are invalid:\n  %s", strings.Join(e.Problems, "\n  "))
+}
+
+// Helper functions
+func isManifestFile(p string) bool {
+	switch strings.ToLower(filepath.Ext(p)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+func cleanOperations(ops []string) []string {
+	var cleaned []string
+	for _, op := range ops {
+		if op = strings.TrimSpace(op); op != "" {
+			cleaned = append(cleaned, op)
+		}
+	}
+	return cleaned
+}
+
+func copyStrings(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+func findID(nodes []*node.Node, id string) *node.Node {
+	for _, n := range nodes {
+		if n.ID == id {
+			return n
+		}
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package manifest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"manu-node-cli/internal/node"
+)
+
+func storedNodes() []*node.Node {
+	created := time.Date(2025, 7, 8, 15, 51, 12, 0, time.UTC)
+	return []*node.Node{
+		{ID: "20250708155112", Title: "CNC", Description: "Wardrobe / shelf making equipment",
+			Operations: []string{"BigShelf", "SmallShelf"}, UNSAddress: "StribrneHory/Dilna/NovaBudova/CNC",
+			CreatedAt: created, UpdatedAt: created},
+		{ID: "20250708161106", Title: "x2", UNSAddress: "ss", CreatedAt: created, UpdatedAt: created},
+		{ID: "saw", Title: "Saw", Operations: []string{"cut"}, UNSAddress: "StribrneHory/Dilna/StaraBudova/Saw"},
+	}
+}
+
+func TestLoadDirectory(t *testing.T) {
+	specs, err := Load("testdata/plant")
+	if err != nil {
+		t.Fatalf("Failed to load manifests: %v", err)
+	}
+
+	var titles []string
+	for _, s := range specs {
+		titles = append(titles, s.Title)
+	}
+	if got := strings.Join(titles, ","); got != "CNC,Edge Bander,Saw,Press" {
+		t.Errorf("Unexpected specs in lexical file order: %s", got)
+	}
+	if !strings.Contains(specs[1].Source, "line.yaml#1 nodes[0]") {
+		t.Errorf("Expected source to point at the document, got %s", specs[1].Source)
+	}
+}
+
+func TestParseRejectsUnknownFields(t *testing.T) {
+	_, err := Parse([]byte("title: CNC\noperation: [cut]\n"), "typo.yaml")
+	if err == nil {
+		t.Error("Expected unknown field to be rejected")
+	}
+}
+
+func TestBuildPlan(t *testing.T) {
+	specs, _ := Load("testdata/plant")
+	current := storedNodes()
+
+	plan, err := BuildPlan(current, specs, Options{})
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+	if plan.Count(ActionCreate) != 2 || plan.Count(ActionUpdate) != 1 || plan.Count(ActionDelete) != 0 {
+		t.Errorf("Unexpected plan: %+v", plan.Changes)
+	}
+	if plan.Unchanged != 1 {
+		t.Errorf("Expected Saw to be unchanged, got %d unchanged", plan.Unchanged)
+	}
+	if len(plan.Nodes) != 5 {
+		t.Errorf("Expected x2 to be kept without --prune, got %d nodes", len(plan.Nodes))
+	}
+
+	for _, c := range plan.Changes {
+		if c.Action == ActionUpdate {
+			if c.Existing.ID != "20250708155112" || len(c.Diff) != 1 {
+				t.Errorf("Expected operations diff on CNC, got %v", c.Diff)
+			}
+			if !c.Node.CreatedAt.Equal(c.Existing.CreatedAt) {
+				t.Error("Expected creation time to be preserved")
+			}
+		}
+	}
+
+	pruned, err := BuildPlan(current, specs, Options{Prune: true})
+	if err != nil {
+		t.Fatalf("Failed to plan with prune: %v", err)
+	}
+	if pruned.Count(ActionDelete) != 1 || len(pruned.Nodes) != 4 {
+		t.Errorf("Expected x2 to be pruned, got %+v", pruned.Changes)
+	}
+
+	// Applying the resulting catalog again is a no-op
+	again, err := BuildPlan(pruned.Nodes, specs, Options{Prune: true})
+	if err != nil || len(again.Changes) != 0 {
+		t.Errorf("Expected idempotent plan, got %v changes (err %v)", len(again.Changes), err)
+	}
+}
+
+func TestBuildPlanProblems(t *testing.T) {
+	specs := []Spec{
+		{Title: "CNC", Source: "a.yaml#1"},
+		{Title: "cnc", Source: "b.yaml#1"},
+		{Title: "", Source: "c.yaml#1"},
+		{ID: "saw", Title: "x2", Source: "d.yaml#1"},
+	}
+	_, err := BuildPlan(storedNodes(), specs, Options{})
+
+	var planErr *PlanError
+	if !errors.As(err, &planErr) {
+		t.Fatalf("Expected PlanError, got %v", err)
+	}
+	if len(planErr.Problems) != 3 {
+		t.Errorf("Expected duplicate, empty title and title collision, got %v", planErr.Problems)
+	}
+
+	_, err = BuildPlan(storedNodes(), []Spec{{Title: "New", UNSAddress: "Other"}}, Options{
+		Authorize: func(n *node.Node) error { return errors.New("denied") },
+	})
+	if err == nil {
+		t.Error("Expected unauthorized change to fail planning")
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	data, err := Marshal(storedNodes())
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	specs, err := Parse(data, "export.yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse exported manifest: %v", err)
+	}
+	plan, err := BuildPlan(storedNodes(), specs, Options{Prune: true})
+	if err != nil || len(plan.Changes) != 0 {
+		t.Errorf("Expected exported manifest to match storage, got %+v (err %v)", plan.Changes, err)
+	}
+}
+
+func TestLinks(t *testing.T) {
+	current := storedNodes()
+	specs, err := Parse([]byte(`nodes:
+  - title: Saw
+    operations: [cut]
+    uns_address: StribrneHory/Dilna/StaraBudova/Saw
+    links:
+      - to: cnc
+        kind: buffer
+        capacity: 10
+      - to: Press
+        transfer_time: 120s
+  - title: Press
+`), "links.yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	plan, err := BuildPlan(current, specs, Options{})
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+	var saw, press *node.Node
+	for _, n := range plan.Nodes {
+		switch n.Title {
+		case "Saw":
+			saw = n
+		case "Press":
+			press = n
+		}
+	}
+	if len(saw.Links) != 2 || saw.Links[0].To != "20250708155112" || saw.Links[1].To != press.ID {
+		t.Fatalf("Expected links resolved by title, got %+v", saw.Links)
+	}
+	if saw.Links[1].Kind != node.LinkConveyor || saw.Links[1].TransferTime != "2m0s" {
+		t.Errorf("Expected a conveyor taking 2m0s, got %+v", saw.Links[1])
+	}
+
+	// Links are written with target titles and read back unchanged
+	data, err := Marshal(plan.Nodes)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	if !strings.Contains(string(data), "to: CNC") {
+		t.Errorf("Expected the target title in the manifest:\n%s", data)
+	}
+	again, _ := Parse(data, "round-trip")
+	replan, err := BuildPlan(plan.Nodes, again, Options{})
+	if err != nil || replan.Unchanged != len(plan.Nodes) {
+		t.Errorf("Expected no changes after a round trip, got %+v (%v)", replan.Changes, err)
+	}
+
+	// A target that is not declared nor stored is a problem
+	specs[0].Links = []LinkSpec{{To: "Nowhere"}}
+	if _, err := BuildPlan(current, specs, Options{}); err == nil || !strings.Contains(err.Error(), "'Nowhere' not found") {
+		t.Errorf("Expected an unknown target to be reported, got %v", err)
+	}
+
+	// Pruned nodes cannot be link targets
+	specs = []Spec{{Title: "CNC"}, {Title: "x2", Links: []LinkSpec{{To: "Saw"}}}}
+	if _, err := BuildPlan(current, specs, Options{Prune: true}); err == nil {
+		t.Error("Expected a link to a pruned node to be refused")
+	}
+}
+
+func TestBuildPlanKeepsCalibrations(t *testing.T) {
+	current := storedNodes()
+	saw := current[2]
+	saw.Calibrations = []node.Calibration{{Instrument: "Laser", Certificate: "L-7",
+		Date: time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC), NextDue: time.Date(2027, 1, 5, 0, 0, 0, 0, time.UTC), Result: node.CalibrationWithin}}
+
+	specs := []Spec{FromNode(saw)}
+	plan, err := BuildPlan(current, specs, Options{})
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+	if plan.Unchanged != 1 || len(plan.Changes) != 0 {
+		t.Errorf("Expected Saw to be unchanged, got %+v", plan.Changes)
+	}
+	for _, n := range plan.Nodes {
+		if n.ID == "saw" && len(n.Calibrations) != 1 {
+			t.Errorf("Expected the calibration history to be kept, got %+v", n.Calibrations)
+		}
+	}
+}
+
+func TestBuildPlanMerge(t *testing.T) {
+	current := storedNodes()
+	saw := current[2]
+	saw.Labels = map[string]string{"dept": "wood"}
+	saw.Links = []node.Link{{To: "20250708155112", Kind: node.LinkConveyor}}
+
+	// The spec declares no labels or links, like a B2MML import
+	specs := []Spec{{ID: "saw", Title: "Saw", Operations: []string{"cut", "trim"}, UNSAddress: saw.UNSAddress}}
+	plan, err := BuildPlan(current, specs, Options{Merge: true})
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+	if len(plan.Changes) != 1 || len(plan.Changes[0].Diff) != 1 {
+		t.Fatalf("Expected only the operations to change, got %+v", plan.Changes)
+	}
+	if got := plan.Changes[0].Node; got.Labels["dept"] != "wood" || len(got.Links) != 1 {
+		t.Errorf("Expected labels and links to be kept, got %+v", got)
+	}
+
+	plan, _ = BuildPlan(current, specs, Options{})
+	if got := plan.Changes[0].Node; got.Labels != nil || got.Links != nil {
+		t.Errorf("Expected a plain plan to replace the node, got %+v", got)
+	}
+}
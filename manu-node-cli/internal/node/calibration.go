@@ -0,0 +1,130 @@
+package node
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Calibration results
+const (
+	CalibrationWithin = "within" // within tolerance
+	CalibrationOut    = "out"    // out of tolerance
+)
+
+// CalibrationResults lists the valid results
+var CalibrationResults = []string{CalibrationWithin, CalibrationOut}
+
+// ErrNotCalibrated is matched by errors for nodes that cannot take work
+// because the latest calibration of one of their instruments expired or
+// failed
+var ErrNotCalibrated = errors.New("node is not calibrated")
+
+// Calibration records one calibration of an instrument on a node
+type Calibration struct {
+	Instrument  string    `json:"instrument"`
+	Certificate string    `json:"certificate"`
+	Date        time.Time `json:"date"`
+	NextDue     time.Time `json:"next_due"`
+	Result      string    `json:"result"`
+}
+
+// Validate checks the fields of a calibration record
+func (c Calibration) Validate() error {
+	if strings.TrimSpace(c.Instrument) == "" || !IsValidText(c.Instrument) {
+		return errors.New("calibration needs an instrument name without control characters")
+	}
+	if strings.TrimSpace(c.Certificate) == "" || !IsValidText(c.Certificate) {
+		return fmt.Errorf("calibration of %s needs a certificate number", c.Instrument)
+	}
+	if c.Date.IsZero() || c.NextDue.IsZero() {
+		return fmt.Errorf("calibration of %s needs a date and a next due date", c.Instrument)
+	}
+	if !c.NextDue.After(c.Date) {
+		return fmt.Errorf("calibration of %s is due again before it was done", c.Instrument)
+	}
+	if c.Result != CalibrationWithin && c.Result != CalibrationOut {
+		return fmt.Errorf("unknown calibration result '%s' (use %s)", c.Result, strings.Join(CalibrationResults, " or "))
+	}
+	return nil
+}
+
+// Expired reports whether the calibration is past its next due date
+func (c Calibration) Expired(now time.Time) bool {
+	return !now.Before(c.NextDue)
+}
+
+// Status describes the calibration at now: "ok", "expired" or "out of
+// tolerance"
+func (c Calibration) Status(now time.Time) string {
+	switch {
+	case c.Result == CalibrationOut:
+		return "out of tolerance"
+	case c.Expired(now):
+		return "expired"
+	}
+	return "ok"
+}
+
+// CurrentCalibrations returns the latest calibration of each instrument,
+// sorted by instrument
+func (n *Node) CurrentCalibrations() []Calibration {
+	latest := map[string]Calibration{}
+	for _, c := range n.Calibrations {
+		key := strings.ToLower(c.Instrument)
+		if prev, ok := latest[key]; !ok || !c.Date.Before(prev.Date) {
+			latest[key] = c
+		}
+	}
+	out := make([]Calibration, 0, len(latest))
+	for _, c := range latest {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return strings.ToLower(out[i].Instrument) < strings.ToLower(out[j].Instrument) })
+	return out
+}
+
+// CalibrationError explains why a node cannot take work; it matches
+// ErrNotCalibrated with errors.Is
+type CalibrationError struct {
+	Node        *Node
+	Calibration Calibration
+	Status      string
+}
+
+func (e *CalibrationError) Error() string {
+	c := e.Calibration
+	if e.Status == "expired" {
+		return fmt.Sprintf("cannot assign work to '%s': calibration of %s expired on %s (certificate %s); record a new calibration with 'calibration add'",
+			e.Node.Title, c.Instrument, c.NextDue.Format("2006-01-02"), c.Certificate)
+	}
+	return fmt.Sprintf("cannot assign work to '%s': %s was out of tolerance on %s (certificate %s); record a passing calibration with 'calibration add'",
+		e.Node.Title, c.Instrument, c.Date.Format("2006-01-02"), c.Certificate)
+}
+
+func (e *CalibrationError) Is(target error) bool { return target == ErrNotCalibrated }
+
+// CheckCalibration returns a *CalibrationError if work must not be
+// assigned to the node at now because the latest calibration of one of
+// its instruments expired or was out of tolerance. Nodes without
+// calibration records are not tracked and always pass.
+func (n *Node) CheckCalibration(now time.Time) error {
+	for _, c := range n.CurrentCalibrations() {
+		if status := c.Status(now); status != "ok" {
+			return &CalibrationError{Node: n, Calibration: c, Status: status}
+		}
+	}
+	return nil
+}
+
+// validateCalibrations checks every calibration record
+func (n *Node) validateCalibrations() error {
+	for _, c := range n.Calibrations {
+		if err := c.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package node
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Link kinds describe how material moves between two nodes
+const (
+	LinkConveyor = "conveyor"
+	LinkBuffer   = "buffer"
+	LinkManual   = "manual"
+)
+
+// LinkKinds lists the valid link kinds
+var LinkKinds = []string{LinkConveyor, LinkBuffer, LinkManual}
+
+// Link is a directed material flow connection from the node holding it to
+// the node with ID To
+type Link struct {
+	To   string `json:"to"`
+	Kind string `json:"kind"`
+
+	// Capacity is how many units the connection can hold; 0 means unknown
+	Capacity int `json:"capacity,omitempty"`
+
+	// TransferTime is how long material takes to move, as a Go duration
+	// in canonical form (e.g. "1m30s"); empty means unknown
+	TransferTime string `json:"transfer_time,omitempty"`
+}
+
+// NewLink validates a link and stores its values in canonical form. An
+// empty kind means a conveyor.
+func NewLink(to, kind string, capacity int, transferTime string) (Link, error) {
+	l := Link{To: to, Kind: strings.ToLower(strings.TrimSpace(kind)), Capacity: capacity}
+	if l.Kind == "" {
+		l.Kind = LinkConveyor
+	}
+	if s := strings.TrimSpace(transferTime); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return Link{}, fmt.Errorf("invalid transfer time '%s': use a duration such as 90s or 5m", transferTime)
+		}
+		l.TransferTime = d.String()
+	}
+	return l, l.Validate()
+}
+
+// Validate checks the link on its own; whether the target exists is up to
+// the storage
+func (l Link) Validate() error {
+	if strings.TrimSpace(l.To) == "" {
+		return errors.New("link target cannot be empty")
+	}
+	if !IsValidLinkKind(l.Kind) {
+		return fmt.Errorf("invalid link kind '%s': use one of %s", l.Kind, strings.Join(LinkKinds, ", "))
+	}
+	if l.Capacity < 0 {
+		return errors.New("link capacity cannot be negative")
+	}
+	if l.TransferTime != "" {
+		d, err := time.ParseDuration(l.TransferTime)
+		if err != nil {
+			return fmt.Errorf("invalid transfer time '%s'", l.TransferTime)
+		}
+		if d < 0 {
+			return errors.New("transfer time cannot be negative")
+		}
+	}
+	return nil
+}
+
+// Transfer returns the transfer time, or 0 if it is unknown
+func (l Link) Transfer() time.Duration {
+	d, _ := time.ParseDuration(l.TransferTime)
+	return d
+}
+
+// String describes the link without its target, e.g. "buffer, capacity 20, 5m0s"
+func (l Link) String() string {
+	parts := []string{l.Kind}
+	if l.Capacity > 0 {
+		parts = append(parts, fmt.Sprintf("capacity %d", l.Capacity))
+	}
+	if l.TransferTime != "" {
+		parts = append(parts, l.TransferTime)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// IsValidLinkKind reports whether kind is one of LinkKinds
+func IsValidLinkKind(kind string) bool {
+	for _, k := range LinkKinds {
+		if kind == k {
+			return true
+		}
+	}
+	return false
+}
+
+// LinkTo returns the node's link to id, if any
+func (n *Node) LinkTo(id string) (Link, bool) {
+	for _, l := range n.Links {
+		if l.To == id {
+			return l, true
+		}
+	}
+	return Link{}, false
+}
+
+// Neighbor is a node connected to another one by a link
+type Neighbor struct {
+	Node *Node
+	Link Link
+}
+
+// Upstream returns the nodes among nodes that link to id, in catalog order
+func Upstream(nodes []*Node, id string) []Neighbor {
+	var out []Neighbor
+	for _, n := range nodes {
+		if l, ok := n.LinkTo(id); ok {
+			out = append(out, Neighbor{Node: n, Link: l})
+		}
+	}
+	return out
+}
+
+// Downstream returns the nodes among nodes that n links to, in link order.
+// Targets missing from nodes are skipped.
+func Downstream(nodes []*Node, n *Node) []Neighbor {
+	var out []Neighbor
+	for _, l := range n.Links {
+		for _, m := range nodes {
+			if m.ID == l.To {
+				out = append(out, Neighbor{Node: m, Link: l})
+				break
+			}
+		}
+	}
+	return out
+}
+
+// validateLinks checks every link and that no target repeats or is n itself
+func (n *Node) validateLinks() error {
+	seen := map[string]bool{}
+	for _, l := range n.Links {
+		if err := l.Validate(); err != nil {
+			return err
+		}
+		if l.To == n.ID {
+			return errors.New("a node cannot link to itself")
+		}
+		if seen[l.To] {
+			return fmt.Errorf("duplicate link to node %s", l.To)
+		}
+		seen[l.To] = true
+	}
+	return nil
+}
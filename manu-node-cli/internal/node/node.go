@@ -1,7 +1,16 @@
 package node
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
+
+	"manu-node-cli/internal/labels"
 )
 
 // Node represents a manufacturing node in the system
@@ -13,6 +22,20 @@ type Node struct {
 	UNSAddress  string    `json:"uns_address"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Attributes holds values for admin-defined attributes, keyed by
+	// attribute name and stored in canonical string form
+	Attributes map[string]string `json:"attributes,omitempty"`
+
+	// Labels are free-form key/value pairs used to group nodes and to
+	// select them with label selectors
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Links are the material flow connections leaving this node
+	Links []Link `json:"links,omitempty"`
+
+	// Calibrations is the calibration history of the node's instruments
+	Calibrations []Calibration `json:"calibrations,omitempty"`
 }
 
 // NewNode creates a new manufacturing node
@@ -28,7 +51,192 @@ func NewNode(title, description string, operations []string, unsAddress string)
 	}
 }
 
+// ETag returns a strong entity tag derived from the node's stored content.
+// Any change to a persisted field produces a different tag.
+func (n *Node) ETag() string {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// Validate checks the fields a user can edit
+func (n *Node) Validate() error {
+	if strings.TrimSpace(n.Title) == "" {
+		return errors.New("title cannot be empty")
+	}
+	if !IsValidText(n.Title) {
+		return errors.New("title contains invalid characters")
+	}
+	if !IsValidMultilineText(n.Description) {
+		return errors.New("description contains invalid characters")
+	}
+	for _, op := range n.Operations {
+		if strings.TrimSpace(op) == "" {
+			return errors.New("operations cannot be empty")
+		}
+		if !IsValidText(op) {
+			return fmt.Errorf("operation '%s' contains invalid characters", op)
+		}
+	}
+	if !IsValidText(n.UNSAddress) {
+		return errors.New("UNS address contains invalid characters")
+	}
+	if err := labels.Validate(n.Labels); err != nil {
+		return err
+	}
+	if err := n.validateLinks(); err != nil {
+		return err
+	}
+	if err := n.validateCalibrations(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// IsValidText reports whether s is free of control characters
+func IsValidText(s string) bool {
+	for _, r := range s {
+		if r < 32 || r == 127 {
+			return false
+		}
+	}
+	return true
+}
+
+// IsValidMultilineText is like IsValidText but also allows newlines and
+// tabs, for descriptions written in an editor
+func IsValidMultilineText(s string) bool {
+	return IsValidText(strings.NewReplacer("\n", "", "\r", "", "\t", "").Replace(s))
+}
+
+// HasUNSPrefix reports whether the node's UNS address lies under prefix.
+// Matching is done per path segment, so "Site/Area" matches
+// "Site/Area/Line" but not "Site/Area2".
+func (n *Node) HasUNSPrefix(prefix string) bool {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return true
+	}
+	addr := strings.Trim(n.UNSAddress, "/")
+	return addr == prefix || strings.HasPrefix(addr, prefix+"/")
+}
+
+// HasOperation reports whether the node supports op (case-insensitive)
+func (n *Node) HasOperation(op string) bool {
+	for _, o := range n.Operations {
+		if strings.EqualFold(o, op) {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff lists the user-editable fields that differ between two nodes
+func Diff(before, after *Node) []string {
+	var changes []string
+	add := func(field, old, new string) {
+		if old != new {
+			changes = append(changes, fmt.Sprintf("%s: %q -> %q", field, old, new))
+		}
+	}
+	add("title", before.Title, after.Title)
+	add("description", before.Description, after.Description)
+	add("operations", strings.Join(before.Operations, ", "), strings.Join(after.Operations, ", "))
+	add("uns_address", before.UNSAddress, after.UNSAddress)
+	for _, name := range unionKeys(before.Attributes, after.Attributes) {
+		add("attributes."+name, before.Attributes[name], after.Attributes[name])
+	}
+	for _, key := range unionKeys(before.Labels, after.Labels) {
+		add("labels."+key, before.Labels[key], after.Labels[key])
+	}
+	for _, to := range linkTargets(before, after) {
+		add("links."+to, linkString(before, to), linkString(after, to))
+	}
+	if len(before.Calibrations) != len(after.Calibrations) {
+		add("calibrations", fmt.Sprintf("%d record(s)", len(before.Calibrations)), fmt.Sprintf("%d record(s)", len(after.Calibrations)))
+	}
+	return changes
+}
+
+// unionKeys returns the sorted union of the keys of a and b
+func unionKeys(a, b map[string]string) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, m := range []map[string]string{a, b} {
+		for name := range m {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// linkTargets returns the targets linked from a or b, a's first
+func linkTargets(a, b *Node) []string {
+	seen := map[string]bool{}
+	var ids []string
+	for _, n := range []*Node{a, b} {
+		for _, l := range n.Links {
+			if !seen[l.To] {
+				seen[l.To] = true
+				ids = append(ids, l.To)
+			}
+		}
+	}
+	return ids
+}
+
+func linkString(n *Node, to string) string {
+	if l, ok := n.LinkTo(to); ok {
+		return l.String()
+	}
+	return ""
+}
+
+// Clone returns a deep copy of n
+func (n *Node) Clone() *Node {
+	c := *n
+	c.Operations = append([]string(nil), n.Operations...)
+	c.Attributes = copyMap(n.Attributes)
+	c.Labels = copyMap(n.Labels)
+	if n.Links != nil {
+		c.Links = append([]Link(nil), n.Links...)
+	}
+	if n.Calibrations != nil {
+		c.Calibrations = append([]Calibration(nil), n.Calibrations...)
+	}
+	return &c
+}
+
+func copyMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// UniqueID generates a node ID that exists() reports as unused. IDs are
+// timestamps, so nodes created within the same second get a numeric suffix.
+func UniqueID(exists func(id string) bool) string {
+	base := generateID()
+	id := base
+	for i := 2; exists(id); i++ {
+		id = fmt.Sprintf("%s-%d", base, i)
+	}
+	return id
+}
+
 // generateID creates a simple ID for the node
 func generateID() string {
 	return time.Now().Format("20060102150405")
-}
\ No newline at end of file
+}
@@ -1,6 +1,8 @@
 package node
 
 import (
+	"errors"
+	"strings"
 	"testing"
 	"time"
 )
@@ -80,4 +82,188 @@ func TestNodeStructure(t *testing.T) {
 	if n.Title != "Test Node" {
 		t.Errorf("Expected title Test Node, got %s", n.Title)
 	}
+}
+
+func TestETag(t *testing.T) {
+	n := NewNode("Tag", "desc", []string{"op1"}, "a/b")
+	tag := n.ETag()
+	if tag == "" {
+		t.Fatal("Expected non-empty ETag")
+	}
+	if tag != n.ETag() {
+		t.Error("Expected ETag to be stable for unchanged node")
+	}
+
+	n.Description = "changed"
+	if tag == n.ETag() {
+		t.Error("Expected ETag to change when a field changes")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := NewNode("CNC", "Milling", []string{"cut"}, "Site/Area/CNC")
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected valid node, got %v", err)
+	}
+	multiline := NewNode("CNC", "Five-axis mill.\n\tCoolant: oil\r\n", nil, "")
+	if err := multiline.Validate(); err != nil {
+		t.Errorf("Expected multi-line description to be valid, got %v", err)
+	}
+
+	tests := []struct {
+		name string
+		node *Node
+	}{
+		{"empty title", NewNode("  ", "", nil, "")},
+		{"control char in title", NewNode("bad\x01", "", nil, "")},
+		{"empty operation", NewNode("ok", "", []string{""}, "")},
+		{"control char in UNS", NewNode("ok", "", nil, "a\x7f")},
+		{"control char in description", NewNode("ok", "bell\x07", nil, "")},
+		{"newline in title", NewNode("two\nlines", "", nil, "")},
+	}
+	for _, tt := range tests {
+		if err := tt.node.Validate(); err == nil {
+			t.Errorf("%s: expected validation error", tt.name)
+		}
+	}
+}
+
+func TestIsValidMultilineText(t *testing.T) {
+	for _, s := range []string{"", "one line", "two\nlines", "tab\tseparated", "windows\r\nline"} {
+		if !IsValidMultilineText(s) {
+			t.Errorf("Expected %q to be valid multi-line text", s)
+		}
+	}
+	for _, s := range []string{"bell\x07", "escape\x1b[0m", "delete\x7f", "null\x00"} {
+		if IsValidMultilineText(s) {
+			t.Errorf("Expected %q to be rejected", s)
+		}
+	}
+	if IsValidText("two\nlines") {
+		t.Error("Expected single-line text to reject newlines")
+	}
+}
+
+func TestHasUNSPrefix(t *testing.T) {
+	n := &Node{UNSAddress: "StribrneHory/Dilna/NovaBudova/CNC"}
+
+	if !n.HasUNSPrefix("StribrneHory/Dilna") {
+		t.Error("Expected prefix match on whole segments")
+	}
+	if !n.HasUNSPrefix("") {
+		t.Error("Expected empty prefix to match everything")
+	}
+	if n.HasUNSPrefix("StribrneHory/Dil") {
+		t.Error("Expected partial segment not to match")
+	}
+}
+
+func TestUniqueID(t *testing.T) {
+	taken := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		id := UniqueID(func(id string) bool { return taken[id] })
+		if taken[id] {
+			t.Fatalf("UniqueID returned taken ID %s", id)
+		}
+		taken[id] = true
+	}
+}
+
+func TestLinks(t *testing.T) {
+	l, err := NewLink("lathe", "", 20, "90s")
+	if err != nil {
+		t.Fatalf("NewLink failed: %v", err)
+	}
+	if l.Kind != LinkConveyor || l.TransferTime != "1m30s" {
+		t.Errorf("Expected a conveyor taking 1m30s, got %+v", l)
+	}
+	if got := l.String(); got != "conveyor, capacity 20, 1m30s" {
+		t.Errorf("Unexpected description '%s'", got)
+	}
+	for _, bad := range [][]string{{"belt", ""}, {"buffer", "soon"}, {"buffer", "-5s"}} {
+		if _, err := NewLink("lathe", bad[0], 0, bad[1]); err == nil {
+			t.Errorf("Expected %v to be rejected", bad)
+		}
+	}
+	if _, err := NewLink("lathe", "buffer", -1, ""); err == nil {
+		t.Error("Expected a negative capacity to be rejected")
+	}
+
+	n := NewNode("CNC", "", nil, "")
+	n.Links = []Link{{To: n.ID, Kind: LinkManual}}
+	if err := n.Validate(); err == nil {
+		t.Error("Expected a link to itself to be rejected")
+	}
+	n.Links = []Link{l, {To: "lathe", Kind: LinkBuffer}}
+	if err := n.Validate(); err == nil {
+		t.Error("Expected a duplicate link to be rejected")
+	}
+
+	n.Links = []Link{l}
+	c := n.Clone()
+	c.Links[0].Kind = LinkBuffer
+	if n.Links[0].Kind != LinkConveyor {
+		t.Error("Expected Clone to copy the links")
+	}
+	diff := Diff(n, c)
+	if len(diff) != 1 || diff[0] != `links.lathe: "conveyor, capacity 20, 1m30s" -> "buffer, capacity 20, 1m30s"` {
+		t.Errorf("Unexpected diff %v", diff)
+	}
+
+	saw := &Node{ID: "saw", Links: []Link{{To: n.ID, Kind: LinkBuffer}}}
+	lathe := &Node{ID: "lathe"}
+	nodes := []*Node{saw, n, lathe}
+	if up := Upstream(nodes, n.ID); len(up) != 1 || up[0].Node != saw {
+		t.Errorf("Expected saw upstream, got %v", up)
+	}
+	if down := Downstream(nodes, n); len(down) != 1 || down[0].Node != lathe {
+		t.Errorf("Expected lathe downstream, got %v", down)
+	}
+}
+
+func TestCalibration(t *testing.T) {
+	day := 24 * time.Hour
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	n := &Node{ID: "cmm", Title: "CMM"}
+	if err := n.CheckCalibration(now); err != nil {
+		t.Errorf("Expected a node without records to pass, got %v", err)
+	}
+
+	n.Calibrations = []Calibration{
+		{Instrument: "Probe", Certificate: "C-1", Date: now.Add(-400 * day), NextDue: now.Add(-35 * day), Result: CalibrationWithin},
+		{Instrument: "probe", Certificate: "C-2", Date: now.Add(-35 * day), NextDue: now.Add(330 * day), Result: CalibrationWithin},
+		{Instrument: "Scale", Certificate: "S-1", Date: now.Add(-200 * day), NextDue: now.Add(-day), Result: CalibrationWithin},
+	}
+	if err := n.Validate(); err != nil {
+		t.Fatalf("Expected valid records, got %v", err)
+	}
+	current := n.CurrentCalibrations()
+	if len(current) != 2 || current[0].Certificate != "C-2" || current[1].Certificate != "S-1" {
+		t.Fatalf("Expected the latest record per instrument, got %+v", current)
+	}
+
+	err := n.CheckCalibration(now)
+	if !errors.Is(err, ErrNotCalibrated) || !strings.Contains(err.Error(), "calibration of Scale expired") {
+		t.Errorf("Expected the expired scale to block work, got %v", err)
+	}
+	n.Calibrations = append(n.Calibrations, Calibration{Instrument: "Scale", Certificate: "S-2", Date: now, NextDue: now.Add(365 * day), Result: CalibrationOut})
+	if err := n.CheckCalibration(now); err == nil || !strings.Contains(err.Error(), "out of tolerance") {
+		t.Errorf("Expected the failed scale to block work, got %v", err)
+	}
+	n.Calibrations = append(n.Calibrations, Calibration{Instrument: "Scale", Certificate: "S-3", Date: now.Add(time.Hour), NextDue: now.Add(365 * day), Result: CalibrationWithin})
+	if err := n.CheckCalibration(now); err != nil {
+		t.Errorf("Expected a passing recalibration to clear the block, got %v", err)
+	}
+
+	invalid := []Calibration{
+		{Certificate: "X", Date: now, NextDue: now.Add(day), Result: CalibrationWithin},
+		{Instrument: "Probe", Date: now, NextDue: now.Add(day), Result: CalibrationWithin},
+		{Instrument: "Probe", Certificate: "X", Date: now, NextDue: now, Result: CalibrationWithin},
+		{Instrument: "Probe", Certificate: "X", Date: now, NextDue: now.Add(day), Result: "fine"},
+	}
+	for _, c := range invalid {
+		if err := c.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", c)
+		}
+	}
 }
\ No newline at end of file
@@ -0,0 +1,412 @@
+// Package operators keeps the registry of machine operators, their
+// certifications and shifts, and the skills each operation requires. It
+// answers who may run an operation on a node during a shift, and flags
+// missing or expired certifications when an operator is assigned work.
+package operators
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"manu-node-cli/internal/node"
+)
+
+// ErrNotFound is returned for unknown operators and shifts
+var ErrNotFound = errors.New("not found")
+
+// ErrNotQualified is matched by errors for operators who lack a required
+// certification or whose certification expired
+var ErrNotQualified = errors.New("operator is not qualified")
+
+// Certification is a skill an operator is certified for
+type Certification struct {
+	Skill string `json:"skill"`
+	// Expires is when the certification lapses; zero means never
+	Expires time.Time `json:"expires,omitempty"`
+}
+
+// ValidAt reports whether the certification still holds at t
+func (c Certification) ValidAt(t time.Time) bool {
+	return c.Expires.IsZero() || t.Before(c.Expires)
+}
+
+// Operator is a person who runs nodes
+type Operator struct {
+	Name string `json:"name"`
+	// Shift is the name of the operator's usual shift; empty means the
+	// operator can be scheduled on any shift
+	Shift          string          `json:"shift,omitempty"`
+	Certifications []Certification `json:"certifications,omitempty"`
+}
+
+// Certification returns the operator's certification for skill
+// (case-insensitive)
+func (o *Operator) Certification(skill string) (Certification, bool) {
+	for _, c := range o.Certifications {
+		if strings.EqualFold(c.Skill, skill) {
+			return c, true
+		}
+	}
+	return Certification{}, false
+}
+
+// Shift is a daily working period in local time, e.g. 06:00-14:00. A shift
+// whose end is before its start runs over midnight.
+type Shift struct {
+	Name  string `json:"name"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// Window returns the occurrence of the shift that contains t, or false if
+// t falls outside it
+func (s *Shift) Window(t time.Time) (time.Time, time.Time, bool) {
+	start, _ := parseClock(s.Start)
+	end, _ := parseClock(s.End)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	// Try the occurrence starting today and, for overnight shifts, the one
+	// that started yesterday
+	for _, d := range []int{0, -1} {
+		from := midnight.AddDate(0, 0, d).Add(start)
+		to := midnight.AddDate(0, 0, d).Add(end)
+		if end <= start {
+			to = to.AddDate(0, 0, 1)
+		}
+		if !t.Before(from) && t.Before(to) {
+			return from, to, true
+		}
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+// Next returns the occurrence of the shift that contains t or, if t falls
+// outside the shift, the next one to start
+func (s *Shift) Next(t time.Time) (time.Time, time.Time) {
+	if from, to, ok := s.Window(t); ok {
+		return from, to
+	}
+	start, _ := parseClock(s.Start)
+	from := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()).Add(start)
+	if from.Before(t) {
+		from = from.AddDate(0, 0, 1)
+	}
+	_, to, _ := s.Window(from)
+	return from, to
+}
+
+// parseClock reads "HH:MM" as the time since midnight
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day '%s': use HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Requirement lists the skills needed to run an operation, on every node
+// or, if NodeID is set, on that node only
+type Requirement struct {
+	Operation string   `json:"operation"`
+	NodeID    string   `json:"node_id,omitempty"`
+	Skills    []string `json:"skills"`
+}
+
+// Registry is everything the operators store keeps
+type Registry struct {
+	Shifts       []*Shift       `json:"shifts"`
+	Operators    []*Operator    `json:"operators"`
+	Requirements []*Requirement `json:"requirements"`
+}
+
+// AddOperator registers a new operator
+func (r *Registry) AddOperator(name, shift string) (*Operator, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || !node.IsValidText(name) {
+		return nil, errors.New("operator needs a name without control characters")
+	}
+	if _, err := r.Operator(name); err == nil {
+		return nil, fmt.Errorf("operator '%s' already exists", name)
+	}
+	o := &Operator{Name: name}
+	if err := r.SetShift(o, shift); err != nil {
+		return nil, err
+	}
+	r.Operators = append(r.Operators, o)
+	return o, nil
+}
+
+// SetShift puts an operator on a defined shift; an empty name clears it
+func (r *Registry) SetShift(o *Operator, shift string) error {
+	if shift == "" {
+		o.Shift = ""
+		return nil
+	}
+	s, err := r.Shift(shift)
+	if err != nil {
+		return err
+	}
+	o.Shift = s.Name
+	return nil
+}
+
+// RemoveOperator deletes an operator
+func (r *Registry) RemoveOperator(name string) (*Operator, error) {
+	o, err := r.Operator(name)
+	if err != nil {
+		return nil, err
+	}
+	var kept []*Operator
+	for _, other := range r.Operators {
+		if other != o {
+			kept = append(kept, other)
+		}
+	}
+	r.Operators = kept
+	return o, nil
+}
+
+// Operator returns the operator with the given name (case-insensitive)
+func (r *Registry) Operator(name string) (*Operator, error) {
+	for _, o := range r.Operators {
+		if strings.EqualFold(o.Name, strings.TrimSpace(name)) {
+			return o, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: operator '%s'", ErrNotFound, name)
+}
+
+// Certify grants or renews a certification; a zero expiry never lapses
+func (r *Registry) Certify(name, skill string, expires time.Time) (*Operator, error) {
+	o, err := r.Operator(name)
+	if err != nil {
+		return nil, err
+	}
+	skill = strings.TrimSpace(skill)
+	if skill == "" || !node.IsValidText(skill) {
+		return nil, errors.New("certification needs a skill name without control characters")
+	}
+	for i, c := range o.Certifications {
+		if strings.EqualFold(c.Skill, skill) {
+			o.Certifications[i] = Certification{Skill: skill, Expires: expires}
+			return o, nil
+		}
+	}
+	o.Certifications = append(o.Certifications, Certification{Skill: skill, Expires: expires})
+	sort.Slice(o.Certifications, func(i, j int) bool {
+		return strings.ToLower(o.Certifications[i].Skill) < strings.ToLower(o.Certifications[j].Skill)
+	})
+	return o, nil
+}
+
+// Revoke removes a certification
+func (r *Registry) Revoke(name, skill string) (*Operator, error) {
+	o, err := r.Operator(name)
+	if err != nil {
+		return nil, err
+	}
+	for i, c := range o.Certifications {
+		if strings.EqualFold(c.Skill, skill) {
+			o.Certifications = append(o.Certifications[:i], o.Certifications[i+1:]...)
+			return o, nil
+		}
+	}
+	return nil, fmt.Errorf("'%s' is not certified for %s", o.Name, skill)
+}
+
+// DefineShift adds a shift or changes its hours
+func (r *Registry) DefineShift(name, start, end string) (*Shift, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || !node.IsValidText(name) {
+		return nil, errors.New("shift needs a name without control characters")
+	}
+	from, err := parseClock(start)
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseClock(end)
+	if err != nil {
+		return nil, err
+	}
+	if from == to {
+		return nil, errors.New("shift must not start and end at the same time")
+	}
+	// Store canonical HH:MM
+	start = time.Time{}.Add(from).Format("15:04")
+	end = time.Time{}.Add(to).Format("15:04")
+	if s, err := r.Shift(name); err == nil {
+		s.Start, s.End = start, end
+		return s, nil
+	}
+	s := &Shift{Name: name, Start: start, End: end}
+	r.Shifts = append(r.Shifts, s)
+	return s, nil
+}
+
+// Shift returns the shift with the given name (case-insensitive)
+func (r *Registry) Shift(name string) (*Shift, error) {
+	for _, s := range r.Shifts {
+		if strings.EqualFold(s.Name, strings.TrimSpace(name)) {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: shift '%s'", ErrNotFound, name)
+}
+
+// CurrentShift returns the shift that contains t
+func (r *Registry) CurrentShift(t time.Time) (*Shift, bool) {
+	for _, s := range r.Shifts {
+		if _, _, ok := s.Window(t); ok {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// Require sets the skills an operation needs, on every node or on one.
+// An empty list removes the requirement.
+func (r *Registry) Require(operation, nodeID string, skills []string) error {
+	operation = strings.TrimSpace(operation)
+	if operation == "" {
+		return errors.New("requirement needs an operation")
+	}
+	var clean []string
+	for _, s := range skills {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		if !node.IsValidText(s) {
+			return fmt.Errorf("skill '%s' contains invalid characters", s)
+		}
+		clean = append(clean, s)
+	}
+
+	var kept []*Requirement
+	for _, req := range r.Requirements {
+		if !strings.EqualFold(req.Operation, operation) || req.NodeID != nodeID {
+			kept = append(kept, req)
+		}
+	}
+	if len(clean) > 0 {
+		kept = append(kept, &Requirement{Operation: operation, NodeID: nodeID, Skills: clean})
+	}
+	r.Requirements = kept
+	return nil
+}
+
+// RequiredSkills returns the skills needed to run operation on a node: the
+// ones required everywhere plus the ones required on that node
+func (r *Registry) RequiredSkills(operation, nodeID string) []string {
+	seen := map[string]bool{}
+	var skills []string
+	for _, req := range r.Requirements {
+		if !strings.EqualFold(req.Operation, operation) || (req.NodeID != "" && req.NodeID != nodeID) {
+			continue
+		}
+		for _, s := range req.Skills {
+			if key := strings.ToLower(s); !seen[key] {
+				seen[key] = true
+				skills = append(skills, s)
+			}
+		}
+	}
+	return skills
+}
+
+// Problem is a required skill an operator lacks
+type Problem struct {
+	Skill string
+	// Expired is set when the operator was certified but it lapses before
+	// the work is done; Expires then says when
+	Expired bool
+	Expires time.Time
+}
+
+func (p Problem) String() string {
+	if p.Expired {
+		return fmt.Sprintf("%s expired %s", p.Skill, p.Expires.Format("2006-01-02"))
+	}
+	return p.Skill + " missing"
+}
+
+// Check lists the skills the operator lacks to work until until
+func (o *Operator) Check(skills []string, until time.Time) []Problem {
+	var problems []Problem
+	for _, skill := range skills {
+		c, ok := o.Certification(skill)
+		switch {
+		case !ok:
+			problems = append(problems, Problem{Skill: skill})
+		case !c.ValidAt(until):
+			problems = append(problems, Problem{Skill: skill, Expired: true, Expires: c.Expires})
+		}
+	}
+	return problems
+}
+
+// QualificationError flags an assignment of an operator who lacks
+// required certifications; it matches ErrNotQualified with errors.Is
+type QualificationError struct {
+	Operator  string
+	Operation string
+	Node      string
+	Problems  []Problem
+}
+
+func (e *QualificationError) Error() string {
+	parts := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		parts[i] = p.String()
+	}
+	return fmt.Sprintf("'%s' is not qualified to run %s on '%s': %s", e.Operator, e.Operation, e.Node, strings.Join(parts, ", "))
+}
+
+func (e *QualificationError) Is(target error) bool { return target == ErrNotQualified }
+
+// CheckAssignment is run when an operator is assigned to run operation on
+// n until the given time. It returns the node's *node.CalibrationError if
+// the node may not take work, a *QualificationError listing every missing
+// or expired certification, or nil if the assignment can go ahead.
+func (r *Registry) CheckAssignment(name, operation string, n *node.Node, until time.Time) error {
+	o, err := r.Operator(name)
+	if err != nil {
+		return err
+	}
+	if err := n.CheckCalibration(until); err != nil {
+		return err
+	}
+	if problems := o.Check(r.RequiredSkills(operation, n.ID), until); len(problems) > 0 {
+		return &QualificationError{Operator: o.Name, Operation: operation, Node: n.Title, Problems: problems}
+	}
+	return nil
+}
+
+// Candidate is an operator on a shift with the skills they lack
+type Candidate struct {
+	Operator *Operator
+	Problems []Problem
+}
+
+// Qualified returns the operators who can work operation on a node during
+// shift, qualified ones first, each with the certifications that are
+// missing or lapse before until. With no shift every operator is
+// considered; operators without a shift are considered for every shift.
+func (r *Registry) Qualified(operation, nodeID string, shift *Shift, until time.Time) []Candidate {
+	skills := r.RequiredSkills(operation, nodeID)
+	var out []Candidate
+	for _, o := range r.Operators {
+		if shift != nil && o.Shift != "" && !strings.EqualFold(o.Shift, shift.Name) {
+			continue
+		}
+		out = append(out, Candidate{Operator: o, Problems: o.Check(skills, until)})
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if qi, qj := len(out[i].Problems) == 0, len(out[j].Problems) == 0; qi != qj {
+			return qi
+		}
+		return strings.ToLower(out[i].Operator.Name) < strings.ToLower(out[j].Operator.Name)
+	})
+	return out
+}
@@ -0,0 +1,171 @@
+package operators
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"manu-node-cli/internal/node"
+)
+
+func at(s string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02 15:04", s, time.Local)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func registry(t *testing.T) *Registry {
+	r := &Registry{}
+	for _, s := range [][3]string{{"Morning", "06:00", "14:00"}, {"Night", "22:00", "6:00"}} {
+		if _, err := r.DefineShift(s[0], s[1], s[2]); err != nil {
+			t.Fatalf("Failed to define shift: %v", err)
+		}
+	}
+	for _, o := range [][2]string{{"Ana", "Morning"}, {"Ben", "Morning"}, {"Cyril", "Night"}, {"Dana", ""}} {
+		if _, err := r.AddOperator(o[0], o[1]); err != nil {
+			t.Fatalf("Failed to add operator: %v", err)
+		}
+	}
+	if _, err := r.Certify("Ana", "CNC basics", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Certify("ana", "5-axis", at("2027-01-01 00:00")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Certify("Ben", "CNC basics", at("2026-10-20 10:00")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Require("mill", "", []string{"CNC basics"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Require("mill", "dmu", []string{"5-axis", "cnc basics"}); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestShiftWindow(t *testing.T) {
+	r := registry(t)
+	night, _ := r.Shift("night")
+	from, to, ok := night.Window(at("2026-10-20 03:00"))
+	if !ok || !from.Equal(at("2026-10-19 22:00")) || !to.Equal(at("2026-10-20 06:00")) {
+		t.Errorf("Expected the night shift that started the evening before, got %v - %v", from, to)
+	}
+	if from, _ := night.Next(at("2026-10-20 15:00")); !from.Equal(at("2026-10-20 22:00")) {
+		t.Errorf("Expected the next night shift to start at 22:00, got %v", from)
+	}
+	if night.End != "06:00" {
+		t.Errorf("Expected canonical HH:MM, got %s", night.End)
+	}
+	if s, ok := r.CurrentShift(at("2026-10-20 15:00")); ok {
+		t.Errorf("Expected no shift at 15:00, got %s", s.Name)
+	}
+	if s, ok := r.CurrentShift(at("2026-10-20 06:00")); !ok || s.Name != "Morning" {
+		t.Errorf("Expected the morning shift at 06:00, got %v", s)
+	}
+	if _, err := r.DefineShift("Broken", "25:00", "06:00"); err == nil {
+		t.Error("Expected an invalid time to be rejected")
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := registry(t)
+	if _, err := r.AddOperator("ANA", ""); err == nil {
+		t.Error("Expected a duplicate operator to be rejected")
+	}
+	if _, err := r.AddOperator("Eva", "Weekend"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected an unknown shift to be rejected, got %v", err)
+	}
+	if got := r.RequiredSkills("Mill", "dmu"); len(got) != 2 || got[0] != "CNC basics" || got[1] != "5-axis" {
+		t.Errorf("Expected general and node skills without duplicates, got %v", got)
+	}
+	if got := r.RequiredSkills("mill", "haas"); len(got) != 1 {
+		t.Errorf("Expected only the general skill, got %v", got)
+	}
+	if err := r.Require("mill", "dmu", nil); err != nil || len(r.RequiredSkills("mill", "dmu")) != 1 {
+		t.Errorf("Expected the node requirement to be removed, got %v", r.RequiredSkills("mill", "dmu"))
+	}
+	if _, err := r.Revoke("Ben", "5-axis"); err == nil {
+		t.Error("Expected revoking a missing certification to fail")
+	}
+	if _, err := r.RemoveOperator("dana"); err != nil || len(r.Operators) != 3 {
+		t.Errorf("Expected Dana to be removed, got %v", err)
+	}
+}
+
+func TestQualified(t *testing.T) {
+	r := registry(t)
+	dmu := &node.Node{ID: "dmu", Title: "DMU 50"}
+	morning, _ := r.Shift("Morning")
+
+	// Ben's certification lapses during the shift on the 20th
+	_, end, _ := morning.Window(at("2026-10-20 07:00"))
+	got := r.Qualified("mill", dmu.ID, morning, end)
+	if len(got) != 3 {
+		t.Fatalf("Expected Ana, Ben and the floater Dana, got %d", len(got))
+	}
+	if got[0].Operator.Name != "Ana" || len(got[0].Problems) != 0 {
+		t.Errorf("Expected Ana to be qualified first, got %+v", got[0])
+	}
+	if got[1].Operator.Name != "Ben" || len(got[1].Problems) != 2 || !got[1].Problems[0].Expired {
+		t.Errorf("Expected Ben with an expired and a missing skill, got %+v", got[1].Problems)
+	}
+	if got[2].Operator.Name != "Dana" || len(got[2].Problems) != 2 || got[2].Problems[0].Expired {
+		t.Errorf("Expected Dana with two missing skills, got %+v", got[2].Problems)
+	}
+
+	err := r.CheckAssignment("Ben", "mill", dmu, end)
+	var qe *QualificationError
+	if !errors.Is(err, ErrNotQualified) || !errors.As(err, &qe) || len(qe.Problems) != 2 {
+		t.Fatalf("Expected Ben's assignment to be flagged, got %v", err)
+	}
+	if want := "'Ben' is not qualified to run mill on 'DMU 50': CNC basics expired 2026-10-20, 5-axis missing"; err.Error() != want {
+		t.Errorf("Expected %q, got %q", want, err.Error())
+	}
+	if err := r.CheckAssignment("Ben", "mill", dmu, at("2026-10-19 14:00")); err == nil {
+		t.Error("Expected the missing 5-axis skill to be flagged")
+	}
+	if err := r.CheckAssignment("Ana", "mill", dmu, end); err != nil {
+		t.Errorf("Expected Ana to be qualified, got %v", err)
+	}
+	if err := r.CheckAssignment("Cyril", "drill", dmu, end); err != nil {
+		t.Errorf("Expected an operation without requirements to need no skills, got %v", err)
+	}
+}
+
+func TestCheckAssignmentCalibration(t *testing.T) {
+	r := registry(t)
+	dmu := &node.Node{ID: "dmu", Title: "DMU 50", Calibrations: []node.Calibration{{Instrument: "Probe", Certificate: "P-1",
+		Date: at("2025-10-01 00:00"), NextDue: at("2026-10-01 00:00"), Result: node.CalibrationWithin}}}
+
+	err := r.CheckAssignment("Ana", "mill", dmu, at("2026-10-20 14:00"))
+	var ce *node.CalibrationError
+	if !errors.As(err, &ce) || ce.Status != "expired" {
+		t.Fatalf("Expected the expired calibration to block the assignment, got %v", err)
+	}
+	if err := r.CheckAssignment("Ana", "mill", dmu, at("2026-09-30 14:00")); err != nil {
+		t.Errorf("Expected Ana to be qualified while the calibration is valid, got %v", err)
+	}
+}
+
+func TestStore(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Update(func(r *Registry) error {
+		_, err := r.AddOperator("Ana", "")
+		return err
+	}); err != nil {
+		t.Fatalf("Failed to update: %v", err)
+	}
+	r, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Operator("ana"); err != nil {
+		t.Errorf("Expected the saved operator, got %v", err)
+	}
+}
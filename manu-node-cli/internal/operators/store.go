@@ -0,0 +1,79 @@
+package operators
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store handles persistence of the operators registry
+type Store struct {
+	filePath string
+	mu       sync.RWMutex
+}
+
+// NewStore creates an operators store in the data directory
+func NewStore(dataDir string) (*Store, error) {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	return &Store{filePath: filepath.Join(dataDir, "operators.json")}, nil
+}
+
+// Load reads the registry
+func (s *Store) Load() (*Registry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.load()
+}
+
+// Update applies fn to the registry and saves it if fn succeeds
+func (s *Store) Update(fn func(*Registry) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reg, err := s.load()
+	if err != nil {
+		return err
+	}
+	if err := fn(reg); err != nil {
+		return err
+	}
+	return s.save(reg)
+}
+
+func (s *Store) load() (*Registry, error) {
+	data, err := os.ReadFile(s.filePath)
+	if os.IsNotExist(err) {
+		return &Registry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read operators file: %w", err)
+	}
+	if len(data) == 0 {
+		return &Registry{}, nil
+	}
+
+	var reg Registry
+	if err := json.Unmarshal(data, &reg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal operators registry: %w", err)
+	}
+	return &reg, nil
+}
+
+func (s *Store) save(reg *Registry) error {
+	data, err := json.MarshalIndent(reg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal operators registry: %w", err)
+	}
+	tmp := s.filePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write operators file: %w", err)
+	}
+	if err := os.Rename(tmp, s.filePath); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write operators file: %w", err)
+	}
+	return nil
+}
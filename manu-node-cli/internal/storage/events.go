@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"manu-node-cli/internal/node"
+)
+
+// EventType describes what happened to a node
+type EventType string
+
+const (
+	EventCreated EventType = "created"
+	EventUpdated EventType = "updated"
+	EventDeleted EventType = "deleted"
+)
+
+// defaultEventHistory is how many past events are kept for resuming
+const defaultEventHistory = 1024
+
+// subscriberBuffer is how many undelivered events a subscriber may lag
+// behind before it is disconnected
+const subscriberBuffer = 64
+
+// ErrCursorExpired is returned when a subscriber asks to resume from an
+// event that is no longer retained; it has to reload the full node list
+var ErrCursorExpired = errors.New("event cursor is no longer available")
+
+// Event is a single change to the node catalog
+type Event struct {
+	Seq  uint64     `json:"seq"`
+	Type EventType  `json:"type"`
+	Node *node.Node `json:"node"`
+	Time time.Time  `json:"time"`
+}
+
+// EventBus fans out node changes to subscribers and keeps a bounded
+// history so clients can resume from the last sequence number they saw.
+// Sequence numbers live in memory only and start again at 1 when the
+// process restarts.
+type EventBus struct {
+	mu      sync.Mutex
+	seq     uint64
+	history []Event
+	limit   int
+	subs    map[chan Event]struct{}
+}
+
+// NewEventBus creates a bus retaining up to history past events
+func NewEventBus(history int) *EventBus {
+	if history <= 0 {
+		history = defaultEventHistory
+	}
+	return &EventBus{
+		limit: history,
+		subs:  make(map[chan Event]struct{}),
+	}
+}
+
+// Publish assigns the next sequence number and delivers the event.
+// Subscribers that cannot keep up are dropped rather than blocking writers.
+func (b *EventBus) Publish(typ EventType, n *node.Node) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	ev := Event{Seq: b.seq, Type: typ, Node: n, Time: time.Now()}
+
+	b.history = append(b.history, ev)
+	if len(b.history) > b.limit {
+		b.history = b.history[len(b.history)-b.limit:]
+	}
+
+	for ch := range b.subs {
+		select {
+		case ch <- ev:
+		default:
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+	return ev
+}
+
+// LastSeq returns the sequence number of the most recent event
+func (b *EventBus) LastSeq() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.seq
+}
+
+// Subscribe returns the events published after cursor followed by a channel
+// of live events. Pass cursor 0 to receive only new events. The channel is
+// closed when cancel is called or the subscriber falls too far behind.
+func (b *EventBus) Subscribe(cursor uint64) ([]Event, <-chan Event, func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var replay []Event
+	if cursor > 0 {
+		if cursor > b.seq {
+			return nil, nil, nil, ErrCursorExpired
+		}
+		// The event right after the cursor must still be in history
+		if cursor < b.seq && (len(b.history) == 0 || b.history[0].Seq > cursor+1) {
+			return nil, nil, nil, ErrCursorExpired
+		}
+		for _, ev := range b.history {
+			if ev.Seq > cursor {
+				replay = append(replay, ev)
+			}
+		}
+	}
+
+	ch := make(chan Event, subscriberBuffer)
+	b.subs[ch] = struct{}{}
+
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[ch]; ok {
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+	return replay, ch, cancel, nil
+}
+
+// diffEvents returns the events that turn before into after
+func diffEvents(before, after []*node.Node) []Event {
+	old := make(map[string]*node.Node, len(before))
+	for _, n := range before {
+		old[n.ID] = n
+	}
+
+	var events []Event
+	for _, n := range after {
+		prev, ok := old[n.ID]
+		switch {
+		case !ok:
+			events = append(events, Event{Type: EventCreated, Node: n})
+		case prev.ETag() != n.ETag():
+			events = append(events, Event{Type: EventUpdated, Node: n})
+		}
+		delete(old, n.ID)
+	}
+	// Keep deletions in the original order
+	for _, n := range before {
+		if _, ok := old[n.ID]; ok {
+			events = append(events, Event{Type: EventDeleted, Node: n})
+		}
+	}
+	return events
+}
@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"manu-node-cli/internal/node"
+)
+
+func TestStorageEvents(t *testing.T) {
+	store, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	_, ch, cancel, err := store.Events().Subscribe(0)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	defer cancel()
+
+	n := &node.Node{ID: "ev1", Title: "Event Node", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	store.SaveNode(n)
+	updated := *n
+	updated.Description = "changed"
+	store.UpdateNode("ev1", &updated)
+	store.DeleteNode("ev1")
+
+	want := []EventType{EventCreated, EventUpdated, EventDeleted}
+	for i, typ := range want {
+		select {
+		case ev := <-ch:
+			if ev.Type != typ {
+				t.Errorf("Event %d: expected %s, got %s", i, typ, ev.Type)
+			}
+			if ev.Seq != uint64(i+1) {
+				t.Errorf("Event %d: expected seq %d, got %d", i, i+1, ev.Seq)
+			}
+			if ev.Node == nil || ev.Node.ID != "ev1" {
+				t.Errorf("Event %d: expected full node payload", i)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for %s event", typ)
+		}
+	}
+}
+
+func TestSaveEmitsDiffEvents(t *testing.T) {
+	store, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	a := &node.Node{ID: "a", Title: "A"}
+	b := &node.Node{ID: "b", Title: "B"}
+	store.Save([]*node.Node{a, b})
+
+	changedA := *a
+	changedA.Title = "A2"
+	c := &node.Node{ID: "c", Title: "C"}
+	store.Save([]*node.Node{&changedA, c})
+
+	replay, _, cancel, err := store.Events().Subscribe(2)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	defer cancel()
+
+	got := map[string]EventType{}
+	for _, ev := range replay {
+		got[ev.Node.ID] = ev.Type
+	}
+	if got["a"] != EventUpdated || got["c"] != EventCreated || got["b"] != EventDeleted {
+		t.Errorf("Unexpected diff events: %v", got)
+	}
+}
+
+func TestEventBusResume(t *testing.T) {
+	bus := NewEventBus(3)
+	for i := 0; i < 5; i++ {
+		bus.Publish(EventCreated, &node.Node{ID: "n"})
+	}
+
+	replay, _, cancel, err := bus.Subscribe(3)
+	if err != nil {
+		t.Fatalf("Expected resume from retained cursor: %v", err)
+	}
+	cancel()
+	if len(replay) != 2 || replay[0].Seq != 4 {
+		t.Errorf("Expected events 4 and 5, got %+v", replay)
+	}
+
+	if _, _, _, err := bus.Subscribe(1); !errors.Is(err, ErrCursorExpired) {
+		t.Errorf("Expected ErrCursorExpired for trimmed cursor, got %v", err)
+	}
+	if _, _, _, err := bus.Subscribe(99); !errors.Is(err, ErrCursorExpired) {
+		t.Errorf("Expected ErrCursorExpired for future cursor, got %v", err)
+	}
+}
+
+func TestEventBusDropsSlowSubscriber(t *testing.T) {
+	bus := NewEventBus(0)
+	_, ch, cancel, _ := bus.Subscribe(0)
+	defer cancel()
+
+	for i := 0; i < subscriberBuffer+1; i++ {
+		bus.Publish(EventUpdated, &node.Node{ID: "n"})
+	}
+
+	count := 0
+	for range ch {
+		count++
+	}
+	if count != subscriberBuffer {
+		t.Errorf("Expected %d buffered events before close, got %d", subscriberBuffer, count)
+	}
+}
+
+func TestPollPublishesOtherWriters(t *testing.T) {
+	store, cleanup := setupTestStorage(t)
+	defer cleanup()
+	if err := store.Poll(); err != nil {
+		t.Fatalf("Failed to poll: %v", err)
+	}
+	_, ch, cancel, err := store.Events().Subscribe(0)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	defer cancel()
+
+	// Own writes are published once, not again by Poll
+	a := &node.Node{ID: "a", Title: "A"}
+	store.SaveNode(a)
+	if err := store.Poll(); err != nil {
+		t.Fatalf("Failed to poll: %v", err)
+	}
+
+	// A second process writing the same file, like the CLI next to serve
+	other, err := NewStorage(filepath.Dir(store.filePath))
+	if err != nil {
+		t.Fatalf("Failed to open second storage: %v", err)
+	}
+	changed := *a
+	changed.Description = "edited elsewhere"
+	other.UpdateNode("a", &changed)
+	other.SaveNode(&node.Node{ID: "b", Title: "B"})
+	if err := store.Poll(); err != nil {
+		t.Fatalf("Failed to poll: %v", err)
+	}
+
+	want := []EventType{EventCreated, EventUpdated, EventCreated}
+	for i, typ := range want {
+		select {
+		case ev := <-ch:
+			if ev.Type != typ {
+				t.Errorf("Event %d: expected %s, got %s", i, typ, ev.Type)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for %s event", typ)
+		}
+	}
+	select {
+	case ev := <-ch:
+		t.Errorf("Expected no further events, got %s of %s", ev.Type, ev.Node.ID)
+	default:
+	}
+}
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+
+	"manu-node-cli/internal/node"
+)
+
+// ErrLinked is returned when a write would remove a node that other nodes
+// still link to
+var ErrLinked = errors.New("node is linked from other nodes")
+
+// CheckLinks checks the material flow links affected by turning before
+// into after: links leaving a created or changed node must point at a node
+// in after, and no node may keep linking to a node that was removed. A
+// removed target is reported as a refused delete, naming the node that
+// still links to it. Links of untouched nodes are not checked, so a
+// dangling link left by a restore or hand edit does not block unrelated
+// writes.
+func CheckLinks(before, after []*node.Node) error {
+	present := make(map[string]bool, len(after))
+	for _, n := range after {
+		present[n.ID] = true
+	}
+	old := make(map[string]*node.Node, len(before))
+	removed := map[string]*node.Node{}
+	for _, n := range before {
+		old[n.ID] = n
+		if !present[n.ID] {
+			removed[n.ID] = n
+		}
+	}
+
+	for _, n := range after {
+		prev, existed := old[n.ID]
+		written := !existed || prev.ETag() != n.ETag()
+		for _, l := range n.Links {
+			if present[l.To] {
+				continue
+			}
+			if target, ok := removed[l.To]; ok {
+				return &lookupError{
+					msg: fmt.Sprintf("cannot delete '%s': it is linked from '%s' (%s); remove the link with unlink first",
+						target.Title, n.Title, l.Kind),
+					target: ErrLinked,
+				}
+			}
+			if written {
+				return fmt.Errorf("node '%s' links to unknown node %s", n.Title, l.To)
+			}
+		}
+	}
+	return nil
+}
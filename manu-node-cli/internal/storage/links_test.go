@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"manu-node-cli/internal/node"
+)
+
+func TestLinkIntegrity(t *testing.T) {
+	store, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	snapshots := 0
+	store.SetSnapshotHook(func(string) error {
+		snapshots++
+		return nil
+	})
+
+	saw := &node.Node{ID: "saw", Title: "Saw", Links: []node.Link{{To: "cnc", Kind: node.LinkConveyor}}}
+	if err := store.SaveNode(saw); err == nil {
+		t.Fatal("Expected a link to a missing node to be rejected")
+	}
+	if err := store.SaveNode(&node.Node{ID: "cnc", Title: "CNC"}); err != nil {
+		t.Fatalf("Failed to save node: %v", err)
+	}
+	if err := store.SaveNode(saw); err != nil {
+		t.Fatalf("Failed to save linked node: %v", err)
+	}
+
+	err := store.DeleteNode("cnc")
+	if !errors.Is(err, ErrLinked) || !strings.Contains(err.Error(), "linked from 'Saw'") {
+		t.Fatalf("Expected the delete to be refused, got %v", err)
+	}
+	if snapshots != 0 {
+		t.Error("Expected no backup for a refused delete")
+	}
+
+	err = store.Transaction(func(nodes []*node.Node) ([]*node.Node, error) {
+		return nodes[1:], nil
+	})
+	if !errors.Is(err, ErrLinked) {
+		t.Errorf("Expected the transaction to be refused, got %v", err)
+	}
+
+	// Deleting the source takes its links with it
+	if err := store.DeleteNode("saw"); err != nil {
+		t.Fatalf("Failed to delete the linking node: %v", err)
+	}
+	if err := store.DeleteNode("cnc"); err != nil {
+		t.Errorf("Expected the unlinked node to be deletable, got %v", err)
+	}
+}
+
+func TestUnrelatedWriteIgnoresDanglingLink(t *testing.T) {
+	store, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	// A restore or hand edit can leave a link to a node that is gone
+	saw := &node.Node{ID: "saw", Title: "Saw", Links: []node.Link{{To: "gone", Kind: node.LinkConveyor}}}
+	if err := store.save([]*node.Node{saw}); err != nil {
+		t.Fatalf("Failed to write catalog: %v", err)
+	}
+
+	if err := store.SaveNode(&node.Node{ID: "cnc", Title: "CNC"}); err != nil {
+		t.Errorf("Expected an unrelated write to succeed, got %v", err)
+	}
+	if err := store.UpdateNodeIfMatch("cnc", "", &node.Node{ID: "cnc", Title: "CNC 2"}); err != nil {
+		t.Errorf("Expected an unrelated update to succeed, got %v", err)
+	}
+
+	saw.Description = "edited"
+	if err := store.SaveNode(saw); err == nil || !strings.Contains(err.Error(), "unknown node gone") {
+		t.Errorf("Expected the dangling link to be reported when its node is written, got %v", err)
+	}
+}
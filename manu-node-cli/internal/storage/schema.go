@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"manu-node-cli/internal/node"
+)
+
+// CurrentSchemaVersion is the nodes.json layout written by this build
+const CurrentSchemaVersion = 6
+
+// legacySchemaVersion is assumed for files holding a bare JSON array
+const legacySchemaVersion = 1
+
+// ErrNewerSchema is returned when nodes.json was written by a newer build
+// that this one cannot read safely
+var ErrNewerSchema = errors.New("nodes file was written by a newer version")
+
+// Migration upgrades raw node documents from schema version From to From+1.
+// Documents are decoded generically so a migration can rename, split or
+// drop fields that no longer exist on node.Node.
+type Migration struct {
+	From        int
+	Description string
+	Migrate     func(docs []map[string]interface{}) error
+}
+
+// migrations is the ordered registry of schema upgrades; append new
+// entries here whenever the on-disk layout changes
+var migrations = []Migration{
+	{
+		From:        1,
+		Description: "wrap the bare node array in a versioned envelope",
+		Migrate:     func(docs []map[string]interface{}) error { return nil },
+	},
+	{
+		// Older builds would silently drop the new field on save, so the
+		// version is bumped to make them refuse the file instead
+		From:        2,
+		Description: "add optional custom attributes to nodes",
+		Migrate:     func(docs []map[string]interface{}) error { return nil },
+	},
+	{
+		From:        3,
+		Description: "add optional labels to nodes",
+		Migrate:     func(docs []map[string]interface{}) error { return nil },
+	},
+	{
+		From:        4,
+		Description: "add optional material flow links to nodes",
+		Migrate:     func(docs []map[string]interface{}) error { return nil },
+	},
+	{
+		From:        5,
+		Description: "add optional calibration records to nodes",
+		Migrate:     func(docs []map[string]interface{}) error { return nil },
+	},
+}
+
+// MigrationReport describes what loading or migrating a nodes file did
+type MigrationReport struct {
+	From    int
+	To      int
+	Applied []Migration
+	Nodes   int
+}
+
+// Pending reports whether the file on disk is older than this build
+func (r *MigrationReport) Pending() bool {
+	return r.From < r.To
+}
+
+// envelope is the on-disk layout from schema version 2 onwards
+type envelope struct {
+	SchemaVersion int             `json:"schema_version"`
+	Nodes         json.RawMessage `json:"nodes"`
+}
+
+// decodeNodes parses a nodes file of any supported schema version,
+// running migrations in memory when the file is older than this build
+func decodeNodes(data []byte) ([]*node.Node, *MigrationReport, error) {
+	report := &MigrationReport{To: CurrentSchemaVersion}
+
+	raw := bytes.TrimSpace(data)
+	if bytes.HasPrefix(raw, []byte("[")) {
+		report.From = legacySchemaVersion
+	} else {
+		var env envelope
+		if err := json.Unmarshal(raw, &env); err != nil {
+			return nil, nil, fmt.Errorf("failed to unmarshal nodes: %w", err)
+		}
+		if env.SchemaVersion < 1 {
+			return nil, nil, errors.New("failed to unmarshal nodes: missing schema_version")
+		}
+		report.From = env.SchemaVersion
+		raw = env.Nodes
+	}
+
+	if report.From > CurrentSchemaVersion {
+		return nil, nil, fmt.Errorf("%w: schema version %d, this build supports up to %d; upgrade manu-node-cli",
+			ErrNewerSchema, report.From, CurrentSchemaVersion)
+	}
+
+	if report.Pending() {
+		migrated, applied, err := migrate(raw, report.From)
+		if err != nil {
+			return nil, nil, err
+		}
+		raw = migrated
+		report.Applied = applied
+	}
+
+	nodes := []*node.Node{}
+	if len(raw) > 0 && !bytes.Equal(raw, []byte("null")) {
+		if err := json.Unmarshal(raw, &nodes); err != nil {
+			return nil, nil, fmt.Errorf("failed to unmarshal nodes: %w", err)
+		}
+	}
+	report.Nodes = len(nodes)
+	return nodes, report, nil
+}
+
+// migrate runs every registered migration from version up to
+// CurrentSchemaVersion over the raw node array
+func migrate(raw json.RawMessage, version int) (json.RawMessage, []Migration, error) {
+	var docs []map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&docs); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal nodes: %w", err)
+	}
+
+	var applied []Migration
+	for v := version; v < CurrentSchemaVersion; v++ {
+		m, ok := findMigration(v)
+		if !ok {
+			return nil, nil, fmt.Errorf("no migration registered from schema version %d", v)
+		}
+		if err := m.Migrate(docs); err != nil {
+			return nil, nil, fmt.Errorf("migration %d -> %d failed: %w", v, v+1, err)
+		}
+		applied = append(applied, m)
+	}
+
+	out, err := json.Marshal(docs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal migrated nodes: %w", err)
+	}
+	return out, applied, nil
+}
+
+func findMigration(from int) (Migration, bool) {
+	for _, m := range migrations {
+		if m.From == from {
+			return m, true
+		}
+	}
+	return Migration{}, false
+}
+
+// encodeNodes produces the current on-disk layout
+func encodeNodes(nodes []*node.Node) ([]byte, error) {
+	if nodes == nil {
+		nodes = []*node.Node{}
+	}
+	return json.MarshalIndent(struct {
+		SchemaVersion int          `json:"schema_version"`
+		Nodes         []*node.Node `json:"nodes"`
+	}{CurrentSchemaVersion, nodes}, "", "  ")
+}
+
+// Migrate upgrades nodes.json to CurrentSchemaVersion. With dryRun set it
+// only reports which migrations would run. The snapshot hook, if any, is
+// called before the file is rewritten.
+func (s *Storage) Migrate(dryRun bool) (*MigrationReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.filePath)
+	if os.IsNotExist(err) || (err == nil && len(bytes.TrimSpace(data)) == 0) {
+		return &MigrationReport{From: CurrentSchemaVersion, To: CurrentSchemaVersion}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read nodes file: %w", err)
+	}
+
+	nodes, report, err := decodeNodes(data)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun || !report.Pending() {
+		return report, nil
+	}
+
+	if s.snapshot != nil {
+		reason := fmt.Sprintf("before migrating nodes.json from schema v%d to v%d", report.From, report.To)
+		if err := s.snapshot(reason); err != nil {
+			return nil, fmt.Errorf("pre-migration backup failed, nothing was changed: %w", err)
+		}
+	}
+	if err := s.save(nodes); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const legacyNodes = `[
+  {"id": "1", "title": "Saw", "description": "Cuts", "operations": ["cut"], "uns_address": "plant/saw",
+   "created_at": "2024-01-01T00:00:00Z", "updated_at": "2024-01-01T00:00:00Z"}
+]`
+
+func TestLoadLegacyArray(t *testing.T) {
+	store, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	if err := os.WriteFile(store.filePath, []byte(legacyNodes), 0644); err != nil {
+		t.Fatalf("Failed to write legacy file: %v", err)
+	}
+
+	nodes, err := store.Load()
+	if err != nil {
+		t.Fatalf("Failed to load legacy file: %v", err)
+	}
+	if len(nodes) != 1 || nodes[0].Title != "Saw" || nodes[0].Operations[0] != "cut" {
+		t.Errorf("Expected legacy node to load, got %+v", nodes)
+	}
+
+	// Loading alone must not rewrite the file
+	data, _ := os.ReadFile(store.filePath)
+	if string(data) != legacyNodes {
+		t.Errorf("Expected Load to leave the file untouched")
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	store, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	if err := os.WriteFile(store.filePath, []byte(legacyNodes), 0644); err != nil {
+		t.Fatalf("Failed to write legacy file: %v", err)
+	}
+	var snapshots []string
+	store.SetSnapshotHook(func(reason string) error {
+		snapshots = append(snapshots, reason)
+		return nil
+	})
+
+	report, err := store.Migrate(true)
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if report.From != 1 || report.To != CurrentSchemaVersion || len(report.Applied) != CurrentSchemaVersion-1 || report.Nodes != 1 {
+		t.Errorf("Unexpected dry-run report: %+v", report)
+	}
+	data, _ := os.ReadFile(store.filePath)
+	if string(data) != legacyNodes || len(snapshots) != 0 {
+		t.Errorf("Expected dry run to change nothing")
+	}
+
+	if _, err := store.Migrate(false); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if len(snapshots) != 1 {
+		t.Errorf("Expected one snapshot before migrating, got %d", len(snapshots))
+	}
+
+	data, _ = os.ReadFile(store.filePath)
+	var env struct {
+		SchemaVersion int               `json:"schema_version"`
+		Nodes         []json.RawMessage `json:"nodes"`
+	}
+	if err := json.Unmarshal(data, &env); err != nil {
+		t.Fatalf("Expected an envelope after migrating: %v", err)
+	}
+	if env.SchemaVersion != CurrentSchemaVersion || len(env.Nodes) != 1 {
+		t.Errorf("Unexpected migrated file: %s", data)
+	}
+
+	report, err = store.Migrate(false)
+	if err != nil || report.Pending() || len(snapshots) != 1 {
+		t.Errorf("Expected a second migrate to be a no-op, got %+v, %v", report, err)
+	}
+}
+
+func TestRefuseNewerSchema(t *testing.T) {
+	store, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	newer := `{"schema_version": 99, "nodes": []}`
+	if err := os.WriteFile(store.filePath, []byte(newer), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	if _, err := store.Load(); !errors.Is(err, ErrNewerSchema) {
+		t.Errorf("Expected ErrNewerSchema from Load, got %v", err)
+	}
+	if err := store.Save(nil); !errors.Is(err, ErrNewerSchema) {
+		t.Errorf("Expected ErrNewerSchema from Save, got %v", err)
+	}
+	if _, err := store.Migrate(false); !errors.Is(err, ErrNewerSchema) {
+		t.Errorf("Expected ErrNewerSchema from Migrate, got %v", err)
+	}
+
+	data, _ := os.ReadFile(store.filePath)
+	if string(data) != newer {
+		t.Errorf("Expected newer file to be left untouched, got %s", data)
+	}
+}
+
+func TestSaveWritesEnvelope(t *testing.T) {
+	store, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	if err := store.Save(nil); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+	data, _ := os.ReadFile(store.filePath)
+	if !strings.Contains(string(data), `"schema_version": `+strconv.Itoa(CurrentSchemaVersion)) || !strings.Contains(string(data), `"nodes": []`) {
+		t.Errorf("Expected versioned envelope, got %s", data)
+	}
+}
@@ -1,7 +1,7 @@
 package storage
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -11,10 +11,44 @@ import (
 	"manu-node-cli/internal/node"
 )
 
+// ErrNotFound is returned when no node matches the requested ID or title
+var ErrNotFound = errors.New("node not found")
+
+// ErrAmbiguous is returned when a title matches more than one node
+var ErrAmbiguous = errors.New("node reference is ambiguous")
+
+// ErrPreconditionFailed is returned by conditional writes when the node
+// changed since the caller last read it
+var ErrPreconditionFailed = errors.New("node was modified since it was read")
+
+// lookupError keeps the original human readable message while still
+// matching one of the sentinel errors through errors.Is
+type lookupError struct {
+	msg    string
+	target error
+}
+
+func (e *lookupError) Error() string        { return e.msg }
+func (e *lookupError) Is(target error) bool { return target == e.target }
+
+func notFoundf(format string, args ...interface{}) error {
+	return &lookupError{msg: fmt.Sprintf(format, args...), target: ErrNotFound}
+}
+
 // Storage handles persistence of manufacturing nodes
 type Storage struct {
 	filePath string
 	mu       sync.RWMutex
+	events   *EventBus
+	snapshot func(reason string) error
+	validate func(n *node.Node) error
+
+	// seen is the catalog as last written or polled, and seenFile the
+	// nodes file it was read from, so Poll can tell what other processes
+	// changed since
+	seen     []*node.Node
+	seenFile os.FileInfo
+	synced   bool
 }
 
 // NewStorage creates a new storage instance
@@ -26,14 +60,59 @@ func NewStorage(dataDir string) (*Storage, error) {
 
 	return &Storage{
 		filePath: filepath.Join(dataDir, "nodes.json"),
+		events:   NewEventBus(defaultEventHistory),
 	}, nil
 }
 
+// SetSnapshotHook registers fn to be called before a node is deleted, so
+// the data can be backed up first. If fn fails the delete is aborted.
+func (s *Storage) SetSnapshotHook(fn func(reason string) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshot = fn
+}
+
+// SetValidator registers fn to check, and possibly normalize, every node
+// that is created or changed before it is written. If fn fails for any
+// node, nothing is written.
+func (s *Storage) SetValidator(fn func(n *node.Node) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.validate = fn
+}
+
+// check runs the validator over the created and updated nodes in events
+func (s *Storage) check(events []Event) error {
+	if s.validate == nil {
+		return nil
+	}
+	for _, ev := range events {
+		if ev.Type == EventDeleted {
+			continue
+		}
+		if err := s.validate(ev.Node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Events returns the bus that receives every change made through s, and
+// the changes made by other processes once Poll notices them
+func (s *Storage) Events() *EventBus {
+	return s.events
+}
+
 // Load reads all nodes from storage
 func (s *Storage) Load() ([]*node.Node, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	return s.load()
+}
+
+// load reads all nodes without locking; callers must hold s.mu
+func (s *Storage) load() ([]*node.Node, error) {
 	// Check if file exists
 	if _, err := os.Stat(s.filePath); os.IsNotExist(err) {
 		// Return empty slice if file doesn't exist yet
@@ -51,42 +130,169 @@ func (s *Storage) Load() ([]*node.Node, error) {
 		return []*node.Node{}, nil
 	}
 
-	// Unmarshal JSON
-	var nodes []*node.Node
-	if err := json.Unmarshal(data, &nodes); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal nodes: %w", err)
+	// Older layouts are migrated in memory; the upgraded file is written
+	// on the next save or by Migrate
+	nodes, _, err := decodeNodes(data)
+	if err != nil {
+		return nil, err
 	}
 
 	return nodes, nil
 }
 
-// Save writes all nodes to storage
+// Save writes all nodes to storage, publishing an event for every node
+// that was created, changed or removed compared to the stored catalog
 func (s *Storage) Save(nodes []*node.Node) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	before, err := s.load()
+	if err != nil {
+		return err
+	}
+	events := diffEvents(before, nodes)
+	if err := s.check(events); err != nil {
+		return err
+	}
+	if err := CheckLinks(before, nodes); err != nil {
+		return err
+	}
+	if err := s.save(nodes); err != nil {
+		return err
+	}
+	for _, ev := range events {
+		s.events.Publish(ev.Type, ev.Node)
+	}
+	return nil
+}
+
+// save writes all nodes without locking; callers must hold s.mu
+func (s *Storage) save(nodes []*node.Node) error {
 	// Marshal to JSON with indentation for readability
-	data, err := json.MarshalIndent(nodes, "", "  ")
+	data, err := encodeNodes(nodes)
 	if err != nil {
 		return fmt.Errorf("failed to marshal nodes: %w", err)
 	}
 
-	// Write to file
-	if err := os.WriteFile(s.filePath, data, 0644); err != nil {
+	// Write to a temporary file and rename it so readers never see a
+	// partially written catalog
+	tmp := s.filePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write nodes file: %w", err)
+	}
+	if err := os.Rename(tmp, s.filePath); err != nil {
+		os.Remove(tmp)
 		return fmt.Errorf("failed to write nodes file: %w", err)
 	}
 
+	// Our own writes are published by the caller, not by Poll
+	info, err := os.Stat(s.filePath)
+	if err != nil {
+		return fmt.Errorf("failed to write nodes file: %w", err)
+	}
+	s.remember(nodes, info)
+	return nil
+}
+
+// Poll publishes the changes that other processes, such as the CLI or a
+// REPL running next to the server, made to the nodes file since it was
+// last written or polled through s. The first call only records the
+// current catalog.
+func (s *Storage) Poll() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, err := os.Stat(s.filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read nodes file: %w", err)
+	}
+	if s.synced && sameFile(info, s.seenFile) {
+		return nil
+	}
+
+	nodes, err := s.load()
+	if err != nil {
+		return err
+	}
+	if s.synced {
+		for _, ev := range diffEvents(s.seen, nodes) {
+			s.events.Publish(ev.Type, ev.Node)
+		}
+	}
+	s.remember(nodes, info)
+	return nil
+}
+
+// remember records nodes as the catalog stored in the file described by
+// info; callers must hold s.mu
+func (s *Storage) remember(nodes []*node.Node, info os.FileInfo) {
+	s.seen = make([]*node.Node, len(nodes))
+	for i, n := range nodes {
+		s.seen[i] = n.Clone()
+	}
+	s.seenFile = info
+	s.synced = true
+}
+
+// sameFile reports whether a and b describe the same version of a file.
+// Every save renames a new file into place, so any write changes it.
+func sameFile(a, b os.FileInfo) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return os.SameFile(a, b) && a.ModTime().Equal(b.ModTime()) && a.Size() == b.Size()
+}
+
+// Transaction runs fn on the current catalog and saves the nodes it
+// returns as a single write. No other writer can interleave, and nothing
+// is written if fn returns an error.
+func (s *Storage) Transaction(fn func(nodes []*node.Node) ([]*node.Node, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	// Give fn its own copies so a failed transaction leaves no trace
+	working := make([]*node.Node, len(before))
+	for i, n := range before {
+		working[i] = n.Clone()
+	}
+
+	after, err := fn(working)
+	if err != nil {
+		return err
+	}
+	events := diffEvents(before, after)
+	if err := s.check(events); err != nil {
+		return err
+	}
+	if err := CheckLinks(before, after); err != nil {
+		return err
+	}
+	if err := s.save(after); err != nil {
+		return err
+	}
+	for _, ev := range events {
+		s.events.Publish(ev.Type, ev.Node)
+	}
 	return nil
 }
 
 // SaveNode adds or updates a single node
 func (s *Storage) SaveNode(n *node.Node) error {
-	nodes, err := s.Load()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	nodes, err := s.load()
 	if err != nil {
 		return err
 	}
 
 	// Check if node exists
+	before := append([]*node.Node(nil), nodes...)
 	found := false
 	for i, existing := range nodes {
 		if existing.ID == n.ID {
@@ -101,7 +307,24 @@ func (s *Storage) SaveNode(n *node.Node) error {
 		nodes = append(nodes, n)
 	}
 
-	return s.Save(nodes)
+	if s.validate != nil {
+		if err := s.validate(n); err != nil {
+			return err
+		}
+	}
+	if err := CheckLinks(before, nodes); err != nil {
+		return err
+	}
+
+	if err := s.save(nodes); err != nil {
+		return err
+	}
+	if found {
+		s.events.Publish(EventUpdated, n)
+	} else {
+		s.events.Publish(EventCreated, n)
+	}
+	return nil
 }
 
 // GetNode retrieves a node by ID
@@ -117,7 +340,7 @@ func (s *Storage) GetNode(id string) (*node.Node, error) {
 		}
 	}
 
-	return nil, fmt.Errorf("node with ID %s not found", id)
+	return nil, notFoundf("node with ID %s not found", id)
 }
 
 // GetNodeByTitle retrieves a node by title (case-insensitive)
@@ -137,10 +360,13 @@ func (s *Storage) GetNodeByTitle(title string) (*node.Node, error) {
 	}
 
 	if len(matches) == 0 {
-		return nil, fmt.Errorf("node with title '%s' not found", title)
+		return nil, notFoundf("node with title '%s' not found", title)
 	}
 	if len(matches) > 1 {
-		return nil, fmt.Errorf("multiple nodes found with title '%s'", title)
+		return nil, &lookupError{
+			msg:    fmt.Sprintf("multiple nodes found with title '%s'", title),
+			target: ErrAmbiguous,
+		}
 	}
 
 	return matches[0], nil
@@ -166,26 +392,43 @@ func (s *Storage) IsTitleUnique(title string, excludeID string) (bool, error) {
 		return false, err
 	}
 
+	return TitleUnique(nodes, title, excludeID), nil
+}
+
+// TitleUnique applies the IsTitleUnique rule to an in-memory node list
+func TitleUnique(nodes []*node.Node, title string, excludeID string) bool {
 	titleLower := strings.ToLower(title)
 	for _, n := range nodes {
 		if n.ID != excludeID && strings.ToLower(n.Title) == titleLower {
-			return false, nil
+			return false
 		}
 	}
-
-	return true, nil
+	return true
 }
 
 // UpdateNode updates an existing node
 func (s *Storage) UpdateNode(id string, updated *node.Node) error {
-	nodes, err := s.Load()
+	return s.UpdateNodeIfMatch(id, "", updated)
+}
+
+// UpdateNodeIfMatch updates an existing node only if its current ETag
+// equals etag. An empty etag updates unconditionally.
+func (s *Storage) UpdateNodeIfMatch(id, etag string, updated *node.Node) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	nodes, err := s.load()
 	if err != nil {
 		return err
 	}
 
+	before := append([]*node.Node(nil), nodes...)
 	found := false
 	for i, n := range nodes {
 		if n.ID == id {
+			if etag != "" && n.ETag() != etag {
+				return ErrPreconditionFailed
+			}
 			// Preserve original ID and creation time
 			updated.ID = id
 			updated.CreatedAt = n.CreatedAt
@@ -196,33 +439,71 @@ func (s *Storage) UpdateNode(id string, updated *node.Node) error {
 	}
 
 	if !found {
-		return fmt.Errorf("node with ID %s not found", id)
+		return notFoundf("node with ID %s not found", id)
 	}
 
-	return s.Save(nodes)
+	if s.validate != nil {
+		if err := s.validate(updated); err != nil {
+			return err
+		}
+	}
+	if err := CheckLinks(before, nodes); err != nil {
+		return err
+	}
+
+	if err := s.save(nodes); err != nil {
+		return err
+	}
+	s.events.Publish(EventUpdated, updated)
+	return nil
 }
 
 // DeleteNode removes a node by ID
 func (s *Storage) DeleteNode(id string) error {
-	nodes, err := s.Load()
+	return s.DeleteNodeIfMatch(id, "")
+}
+
+// DeleteNodeIfMatch removes a node only if its current ETag equals etag.
+// An empty etag deletes unconditionally.
+func (s *Storage) DeleteNodeIfMatch(id, etag string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	nodes, err := s.load()
 	if err != nil {
 		return err
 	}
 
 	// Find and remove node
-	found := false
+	var deleted *node.Node
 	var filtered []*node.Node
 	for _, n := range nodes {
 		if n.ID == id {
-			found = true
+			if etag != "" && n.ETag() != etag {
+				return ErrPreconditionFailed
+			}
+			deleted = n
 			continue
 		}
 		filtered = append(filtered, n)
 	}
 
-	if !found {
-		return fmt.Errorf("node with ID %s not found", id)
+	if deleted == nil {
+		return notFoundf("node with ID %s not found", id)
+	}
+	if err := CheckLinks(nodes, filtered); err != nil {
+		return err
+	}
+
+	if s.snapshot != nil {
+		if err := s.snapshot(fmt.Sprintf("before deleting node '%s' (%s)", deleted.Title, deleted.ID)); err != nil {
+			return fmt.Errorf("pre-delete backup failed, node was not deleted: %w", err)
+		}
 	}
 
-	return s.Save(filtered)
+	if err := s.save(filtered); err != nil {
+		return err
+	}
+	s.events.Publish(EventDeleted, deleted)
+	return nil
 }
\ No newline at end of file
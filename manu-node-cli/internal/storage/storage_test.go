@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -373,4 +374,131 @@ func TestConcurrentAccess(t *testing.T) {
 
 	// If we get here without deadlock or panic, concurrent access is safe
 	t.Log("Concurrent access test passed")
+}
+
+func TestConditionalWrites(t *testing.T) {
+	store, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	original := &node.Node{ID: "cond", Title: "Cond", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := store.SaveNode(original); err != nil {
+		t.Fatalf("Failed to save node: %v", err)
+	}
+	etag := original.ETag()
+
+	changed := *original
+	changed.Description = "first writer"
+	if err := store.UpdateNodeIfMatch("cond", etag, &changed); err != nil {
+		t.Fatalf("Expected matching ETag to succeed: %v", err)
+	}
+
+	stale := *original
+	stale.Description = "second writer"
+	if err := store.UpdateNodeIfMatch("cond", etag, &stale); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("Expected ErrPreconditionFailed, got %v", err)
+	}
+	if err := store.DeleteNodeIfMatch("cond", etag); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("Expected ErrPreconditionFailed on delete, got %v", err)
+	}
+
+	if _, err := store.GetNodeByIDOrTitle("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	store, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	store.SaveNode(&node.Node{ID: "t1", Title: "T1", Operations: []string{"op"}})
+
+	// A failing transaction leaves the catalog untouched
+	err := store.Transaction(func(nodes []*node.Node) ([]*node.Node, error) {
+		nodes[0].Operations[0] = "mutated"
+		return nil, errors.New("abort")
+	})
+	if err == nil {
+		t.Fatal("Expected transaction error to be returned")
+	}
+	n, _ := store.GetNode("t1")
+	if n.Operations[0] != "op" {
+		t.Errorf("Expected aborted transaction not to change data, got %v", n.Operations)
+	}
+
+	err = store.Transaction(func(nodes []*node.Node) ([]*node.Node, error) {
+		return append(nodes, &node.Node{ID: "t2", Title: "T2"}), nil
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+	nodes, _ := store.Load()
+	if len(nodes) != 2 {
+		t.Errorf("Expected 2 nodes after transaction, got %d", len(nodes))
+	}
+	if !TitleUnique(nodes, "t3", "") || TitleUnique(nodes, "t2", "") || !TitleUnique(nodes, "T2", "t2") {
+		t.Error("Unexpected TitleUnique result")
+	}
+}
+
+func TestSnapshotHook(t *testing.T) {
+	store, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	store.SaveNode(&node.Node{ID: "keep", Title: "Keep"})
+
+	var reasons []string
+	store.SetSnapshotHook(func(reason string) error {
+		reasons = append(reasons, reason)
+		return errors.New("disk full")
+	})
+	if err := store.DeleteNode("keep"); err == nil {
+		t.Fatal("Expected delete to fail when the snapshot fails")
+	}
+	if _, err := store.GetNode("keep"); err != nil {
+		t.Error("Expected node to survive a failed snapshot")
+	}
+	if len(reasons) != 1 {
+		t.Errorf("Expected hook to be called once, got %d", len(reasons))
+	}
+
+	store.SetSnapshotHook(func(reason string) error { return nil })
+	if err := store.DeleteNode("keep"); err != nil {
+		t.Errorf("Expected delete to succeed: %v", err)
+	}
+}
+
+func TestValidatorHook(t *testing.T) {
+	store, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	invalid := errors.New("invalid")
+	store.SetValidator(func(n *node.Node) error {
+		if n.Title == "bad" {
+			return invalid
+		}
+		n.Description = "checked"
+		return nil
+	})
+
+	good := &node.Node{ID: "1", Title: "good", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := store.SaveNode(good); err != nil {
+		t.Fatalf("Failed to save node: %v", err)
+	}
+	if n, _ := store.GetNode("1"); n.Description != "checked" {
+		t.Errorf("Expected validator to normalize the node, got %q", n.Description)
+	}
+
+	bad := &node.Node{ID: "2", Title: "bad"}
+	if err := store.SaveNode(bad); !errors.Is(err, invalid) {
+		t.Errorf("Expected SaveNode to fail validation, got %v", err)
+	}
+	err := store.Transaction(func(nodes []*node.Node) ([]*node.Node, error) {
+		return append(nodes, bad), nil
+	})
+	if !errors.Is(err, invalid) {
+		t.Errorf("Expected Transaction to fail validation, got %v", err)
+	}
+	if nodes, _ := store.Load(); len(nodes) != 1 {
+		t.Errorf("Expected nothing to be written, got %d nodes", len(nodes))
+	}
 }
\ No newline at end of file
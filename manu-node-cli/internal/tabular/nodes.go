@@ -0,0 +1,312 @@
+package tabular
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"manu-node-cli/internal/node"
+	"manu-node-cli/internal/storage"
+)
+
+// Node fields that can be mapped to columns
+const (
+	FieldID          = "id"
+	FieldTitle       = "title"
+	FieldDescription = "description"
+	FieldOperations  = "operations"
+	FieldUNSAddress  = "uns_address"
+)
+
+var importFields = []string{FieldID, FieldTitle, FieldDescription, FieldOperations, FieldUNSAddress}
+
+// DefaultOpsSeparator separates operations inside a single cell
+const DefaultOpsSeparator = ";"
+
+// Mapping assigns node fields to column headers (field -> header)
+type Mapping map[string]string
+
+// ParseMapping parses "title=Machine,uns_address=UNS Path"
+func ParseMapping(spec string) (Mapping, error) {
+	m := Mapping{}
+	if strings.TrimSpace(spec) == "" {
+		return m, nil
+	}
+	for _, pair := range strings.Split(spec, ",") {
+		field, column, ok := strings.Cut(pair, "=")
+		field = normalizeHeader(field)
+		if !ok || strings.TrimSpace(column) == "" {
+			return nil, fmt.Errorf("invalid mapping '%s' (expected field=Column)", pair)
+		}
+		if !isImportField(field) {
+			return nil, fmt.Errorf("unknown field '%s' (valid: %s)", field, strings.Join(importFields, ", "))
+		}
+		m[field] = strings.TrimSpace(column)
+	}
+	return m, nil
+}
+
+// resolve finds the column index for each mapped field. Fields without an
+// explicit mapping are matched by header name, so "UNS Address" maps to
+// uns_address automatically.
+func (m Mapping) resolve(header []string) (map[string]int, error) {
+	columns := map[string]int{}
+	for i, h := range header {
+		if f := normalizeHeader(h); isImportField(f) {
+			columns[f] = i
+		}
+	}
+	for field, column := range m {
+		found := false
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), column) {
+				columns[field] = i
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("column '%s' mapped to %s not found in header", column, field)
+		}
+	}
+	if _, ok := columns[FieldTitle]; !ok {
+		return nil, errors.New("no title column found; map one with title=<Column>")
+	}
+	return columns, nil
+}
+
+// Action is what an import does with one row
+type Action string
+
+const (
+	ActionCreate    Action = "create"
+	ActionUpdate    Action = "update"
+	ActionUnchanged Action = "unchanged"
+	ActionConflict  Action = "conflict"
+)
+
+// RowResult describes the planned outcome for one data row
+type RowResult struct {
+	Row      int // 1-based line in the file or row in the sheet, as Table.Line
+	Action   Action
+	Node     *node.Node // the node as it would be stored
+	Existing *node.Node // the node before the import, for updates
+	Changes  []string   // human readable field changes
+	Reason   string     // why the row conflicts
+}
+
+// ImportPlan is the outcome of planning an import against a catalog
+type ImportPlan struct {
+	Rows  []RowResult
+	Nodes []*node.Node // the complete catalog after applying the plan
+}
+
+// Count returns how many rows have the given action
+func (p *ImportPlan) Count(a Action) int {
+	count := 0
+	for _, r := range p.Rows {
+		if r.Action == a {
+			count++
+		}
+	}
+	return count
+}
+
+// HasConflicts reports whether any row blocks the import
+func (p *ImportPlan) HasConflicts() bool {
+	return p.Count(ActionConflict) > 0
+}
+
+// ImportOptions controls how rows are turned into nodes
+type ImportOptions struct {
+	Mapping      Mapping
+	OpsSeparator string
+	// Authorize, if set, is asked about every node that would be written,
+	// and about the stored node a row would update
+	Authorize func(n *node.Node) error
+}
+
+// PlanImport computes the creates, updates and conflicts an import would
+// cause. Rows are matched to existing nodes by ID, then by title
+// (case-insensitive); unmatched rows become new nodes. The existing slice
+// is not modified.
+func PlanImport(existing []*node.Node, t *Table, opts ImportOptions) (*ImportPlan, error) {
+	columns, err := opts.Mapping.resolve(t.Header)
+	if err != nil {
+		return nil, err
+	}
+	sep := opts.OpsSeparator
+	if sep == "" {
+		sep = DefaultOpsSeparator
+	}
+
+	catalog := make([]*node.Node, len(existing))
+	copy(catalog, existing)
+	touched := map[string]int{} // node ID -> row that wrote it
+	now := time.Now()
+	plan := &ImportPlan{}
+
+	for i, row := range t.Rows {
+		res := RowResult{Row: t.Line(i)}
+		cell := func(field string) (string, bool) {
+			idx, ok := columns[field]
+			if !ok {
+				return "", false
+			}
+			if idx >= len(row) {
+				return "", true
+			}
+			return strings.TrimSpace(row[idx]), true
+		}
+
+		// Find the node this row refers to
+		id, _ := cell(FieldID)
+		title, _ := cell(FieldTitle)
+		pos := -1
+		for j, n := range catalog {
+			if (id != "" && n.ID == id) || (id == "" && strings.EqualFold(n.Title, title)) {
+				pos = j
+				break
+			}
+		}
+
+		var target node.Node
+		if pos >= 0 {
+			target = *catalog[pos]
+			res.Existing = catalog[pos]
+		} else {
+			target = node.Node{CreatedAt: now, UpdatedAt: now}
+			if id != "" {
+				target.ID = id
+			} else {
+				target.ID = node.UniqueID(func(candidate string) bool { return containsID(catalog, candidate) })
+			}
+		}
+
+		// Overwrite every mapped field
+		if v, ok := cell(FieldTitle); ok {
+			target.Title = v
+		}
+		if v, ok := cell(FieldDescription); ok {
+			target.Description = v
+		}
+		if v, ok := cell(FieldOperations); ok {
+			target.Operations = splitOperations(v, sep)
+		}
+		if v, ok := cell(FieldUNSAddress); ok {
+			target.UNSAddress = v
+		}
+		res.Node = &target
+
+		// The node must be in scope both as stored and as it would become,
+		// so a row cannot move a node in from elsewhere
+		var denied error
+		if opts.Authorize != nil {
+			for _, n := range []*node.Node{res.Existing, &target} {
+				if n == nil {
+					continue
+				}
+				if denied = opts.Authorize(n); denied != nil {
+					break
+				}
+			}
+		}
+
+		switch {
+		case touched[target.ID] != 0:
+			res.Action, res.Reason = ActionConflict,
+				fmt.Sprintf("same node as row %d", touched[target.ID])
+		case target.Validate() != nil:
+			res.Action, res.Reason = ActionConflict, target.Validate().Error()
+		case !storage.TitleUnique(catalog, target.Title, target.ID):
+			res.Action, res.Reason = ActionConflict,
+				fmt.Sprintf("a node with title '%s' already exists", target.Title)
+		case denied != nil:
+			res.Action, res.Reason = ActionConflict, denied.Error()
+		case pos < 0:
+			res.Action = ActionCreate
+		default:
+			res.Changes = node.Diff(res.Existing, &target)
+			if len(res.Changes) == 0 {
+				res.Action = ActionUnchanged
+			} else {
+				res.Action = ActionUpdate
+			}
+		}
+
+		// Later rows see the effect of earlier ones
+		if res.Action == ActionCreate {
+			catalog = append(catalog, &target)
+		} else if res.Action == ActionUpdate {
+			target.UpdatedAt = now
+			catalog[pos] = &target
+		}
+		if res.Action != ActionConflict {
+			touched[target.ID] = res.Row
+		}
+		plan.Rows = append(plan.Rows, res)
+	}
+
+	plan.Nodes = catalog
+	return plan, nil
+}
+
+// NodesToTable converts nodes into an exportable table
+func NodesToTable(nodes []*node.Node, opsSeparator string) *Table {
+	if opsSeparator == "" {
+		opsSeparator = DefaultOpsSeparator
+	}
+	t := &Table{Header: []string{FieldID, FieldTitle, FieldDescription, FieldOperations,
+		FieldUNSAddress, "created_at", "updated_at"}}
+	for _, n := range nodes {
+		t.Rows = append(t.Rows, []string{
+			n.ID,
+			n.Title,
+			n.Description,
+			strings.Join(n.Operations, opsSeparator),
+			n.UNSAddress,
+			n.CreatedAt.Format(time.RFC3339),
+			n.UpdatedAt.Format(time.RFC3339),
+		})
+	}
+	return t
+}
+
+// Helper functions
+func normalizeHeader(h string) string {
+	h = strings.ToLower(strings.TrimSpace(h))
+	h = strings.NewReplacer(" ", "_", "-", "_").Replace(h)
+	if h == "uns" {
+		return FieldUNSAddress
+	}
+	return h
+}
+
+func isImportField(f string) bool {
+	for _, field := range importFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func splitOperations(v, sep string) []string {
+	var ops []string
+	for _, op := range strings.Split(v, sep) {
+		if op = strings.TrimSpace(op); op != "" {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+func containsID(nodes []*node.Node, id string) bool {
+	for _, n := range nodes {
+		if n.ID == id {
+			return true
+		}
+	}
+	return false
+}
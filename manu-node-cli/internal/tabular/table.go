@@ -0,0 +1,158 @@
+package tabular
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Table is a header row followed by data rows, independent of file format
+type Table struct {
+	Header []string
+	Rows   [][]string
+	// Lines holds the line in the file, or the row in the worksheet, each
+	// data row was read from; nil means the rows directly follow the header
+	Lines []int
+}
+
+// Line returns the 1-based line in the file of data row i
+func (t *Table) Line(i int) int {
+	if i < len(t.Lines) {
+		return t.Lines[i]
+	}
+	return i + 2
+}
+
+// Format identifies a supported file format
+type Format string
+
+const (
+	FormatCSV  Format = "csv"
+	FormatXLSX Format = "xlsx"
+)
+
+// DetectFormat picks the format from a file extension
+func DetectFormat(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".xlsx":
+		return FormatXLSX, nil
+	default:
+		return "", fmt.Errorf("unsupported file type '%s' (use .csv or .xlsx)", filepath.Ext(path))
+	}
+}
+
+// ReadFile reads a CSV or XLSX file based on its extension
+func ReadFile(path string) (*Table, error) {
+	format, err := DetectFormat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	if format == FormatXLSX {
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		return ReadXLSX(f, info.Size())
+	}
+	return ReadCSV(f)
+}
+
+// WriteFile writes a CSV or XLSX file based on its extension
+func WriteFile(path string, t *Table) error {
+	format, err := DetectFormat(path)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+
+	if format == FormatXLSX {
+		err = WriteXLSX(f, t)
+	} else {
+		err = WriteCSV(f, t)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// ReadCSV parses CSV with a header row. A UTF-8 byte order mark, as
+// written by Excel, is ignored.
+func ReadCSV(r io.Reader) (*Table, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	// Blank lines and quoted line breaks make records and lines differ, so
+	// the line each record starts on is kept for messages
+	var records [][]string
+	var lines []int
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		records = append(records, record)
+		lines = append(lines, line)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("file is empty")
+	}
+
+	header := records[0]
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	rows, rowLines := dropEmptyRows(records[1:], lines[1:])
+	return &Table{Header: header, Rows: rows, Lines: rowLines}, nil
+}
+
+// WriteCSV writes the table as CSV
+func WriteCSV(w io.Writer, t *Table) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(t.Header); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(t.Rows); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
+
+// dropEmptyRows removes rows where every cell is blank, together with
+// their line numbers
+func dropEmptyRows(rows [][]string, lines []int) ([][]string, []int) {
+	var kept [][]string
+	var keptLines []int
+	for i, row := range rows {
+		for _, cell := range row {
+			if strings.TrimSpace(cell) != "" {
+				kept = append(kept, row)
+				keptLines = append(keptLines, lines[i])
+				break
+			}
+		}
+	}
+	return kept, keptLines
+}
@@ -0,0 +1,185 @@
+package tabular
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"manu-node-cli/internal/node"
+)
+
+func existingNodes() []*node.Node {
+	return []*node.Node{
+		{ID: "n1", Title: "CNC", Description: "Wardrobe equipment", Operations: []string{"BigShelf"},
+			UNSAddress: "StribrneHory/Dilna/NovaBudova/CNC", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ID: "n2", Title: "Saw", Operations: []string{"cut"}, UNSAddress: "StribrneHory/Dilna/Saw"},
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	table := NodesToTable(existingNodes(), "")
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, table); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+	read, err := ReadCSV(strings.NewReader("\ufeff" + buf.String()))
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %v", err)
+	}
+	if read.Header[0] != "id" {
+		t.Errorf("Expected BOM to be stripped from header, got %q", read.Header[0])
+	}
+	if len(read.Rows) != 2 || read.Rows[0][3] != "BigShelf" {
+		t.Errorf("Unexpected rows: %v", read.Rows)
+	}
+}
+
+func TestXLSXRoundTrip(t *testing.T) {
+	table := &Table{
+		Header: []string{"Machine", "Ops", "Location"},
+		Rows:   [][]string{{"Edge <Bander>", "edge-band;trim", "Site/Area"}, {"Drill & Tap", "", "Site/B"}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteXLSX(&buf, table); err != nil {
+		t.Fatalf("Failed to write XLSX: %v", err)
+	}
+	read, err := ReadXLSX(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Failed to read XLSX: %v", err)
+	}
+	if strings.Join(read.Header, "|") != "Machine|Ops|Location" {
+		t.Errorf("Unexpected header %v", read.Header)
+	}
+	if read.Rows[0][0] != "Edge <Bander>" || read.Rows[1][0] != "Drill & Tap" {
+		t.Errorf("Expected escaped text to survive, got %v", read.Rows)
+	}
+}
+
+func TestRowLines(t *testing.T) {
+	data := "id,title,description\n\nn1,CNC,\" \"\n , ,\nn9,Press,\"two\nlines\"\nn2,Saw,\n"
+	table, err := ReadCSV(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %v", err)
+	}
+	plan, err := PlanImport(existingNodes(), table, ImportOptions{})
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+	var rows []int
+	for _, r := range plan.Rows {
+		rows = append(rows, r.Row)
+	}
+	if fmt.Sprint(rows) != "[3 5 7]" {
+		t.Errorf("Expected the lines the rows start on, got %v", rows)
+	}
+
+	// Empty worksheet rows keep their number
+	var buf bytes.Buffer
+	sheet := &Table{Header: []string{"title"}, Rows: [][]string{{"CNC"}, {}, {" "}, {"Saw"}}}
+	if err := WriteXLSX(&buf, sheet); err != nil {
+		t.Fatalf("Failed to write XLSX: %v", err)
+	}
+	read, err := ReadXLSX(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Failed to read XLSX: %v", err)
+	}
+	if len(read.Rows) != 2 || read.Line(0) != 2 || read.Line(1) != 5 {
+		t.Errorf("Expected sheet rows 2 and 5, got %v at %v", read.Rows, read.Lines)
+	}
+}
+
+func TestColumnNames(t *testing.T) {
+	for col, name := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(col); got != name {
+			t.Errorf("columnName(%d) = %s, want %s", col, got, name)
+		}
+		if got, _ := columnIndex(name + "7"); got != col {
+			t.Errorf("columnIndex(%s7) = %d, want %d", name, got, col)
+		}
+	}
+}
+
+func TestPlanImport(t *testing.T) {
+	csv := "Machine,Ops,Location\n" +
+		"cnc,BigShelf;SmallShelf,StribrneHory/Dilna/NovaBudova/CNC\n" + // update by title
+		"Saw,cut,StribrneHory/Dilna/Saw\n" + // unchanged
+		"Edge Bander,edge-band,StribrneHory/Dilna/EB\n" + // create
+		"edge bander,trim,StribrneHory/Dilna/EB2\n" // same node as previous row
+	table, err := ReadCSV(strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %v", err)
+	}
+	mapping, err := ParseMapping("title=Machine,operations=Ops,uns_address=Location")
+	if err != nil {
+		t.Fatalf("Failed to parse mapping: %v", err)
+	}
+
+	existing := existingNodes()
+	plan, err := PlanImport(existing, table, ImportOptions{Mapping: mapping})
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+
+	want := []Action{ActionUpdate, ActionUnchanged, ActionCreate, ActionConflict}
+	for i, a := range want {
+		if plan.Rows[i].Action != a {
+			t.Errorf("Row %d: expected %s, got %s (%s)", i+2, a, plan.Rows[i].Action, plan.Rows[i].Reason)
+		}
+	}
+	if plan.Rows[0].Node.Description != "Wardrobe equipment" {
+		t.Error("Expected unmapped fields to be preserved on update")
+	}
+	if existing[0].Title != "CNC" || len(existing[0].Operations) != 1 {
+		t.Error("Expected planning not to modify the existing nodes")
+	}
+	if !plan.HasConflicts() {
+		t.Error("Expected plan to report conflicts")
+	}
+}
+
+func TestPlanImportTitleCollision(t *testing.T) {
+	table := &Table{Header: []string{"id", "title"}, Rows: [][]string{{"n2", "CNC"}}}
+	plan, err := PlanImport(existingNodes(), table, ImportOptions{})
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+	if plan.Rows[0].Action != ActionConflict {
+		t.Errorf("Expected renaming onto an existing title to conflict, got %s", plan.Rows[0].Action)
+	}
+
+	if _, err := PlanImport(nil, &Table{Header: []string{"name"}}, ImportOptions{}); err == nil {
+		t.Error("Expected missing title column to be an error")
+	}
+	if _, err := ParseMapping("colour=Paint"); err == nil {
+		t.Error("Expected unknown field in mapping to be an error")
+	}
+}
+
+func TestPlanImportAuthorize(t *testing.T) {
+	// A row matching the CNC by ID cannot pull it into the user's scope
+	table := &Table{Header: []string{"id", "title", "uns_address"},
+		Rows: [][]string{{"n1", "CNC", "StribrneHory/Dilna/Saw/CNC"}, {"n2", "Saw", "StribrneHory/Dilna/Saw2"}}}
+	inScope := func(n *node.Node) error {
+		if !n.HasUNSPrefix("StribrneHory/Dilna/Saw") {
+			return fmt.Errorf("no access to '%s'", n.UNSAddress)
+		}
+		return nil
+	}
+	plan, err := PlanImport(existingNodes(), table, ImportOptions{Authorize: inScope})
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+	if plan.Rows[0].Action != ActionConflict || !strings.Contains(plan.Rows[0].Reason, "NovaBudova") {
+		t.Errorf("Expected the out-of-scope node to be refused, got %s (%s)", plan.Rows[0].Action, plan.Rows[0].Reason)
+	}
+	if plan.Rows[1].Action != ActionConflict {
+		t.Errorf("Expected moving a node out of scope to be refused, got %s", plan.Rows[1].Action)
+	}
+	if plan.Nodes[0].UNSAddress != "StribrneHory/Dilna/NovaBudova/CNC" {
+		t.Error("Expected the refused node to keep its address")
+	}
+}
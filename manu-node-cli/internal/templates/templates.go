@@ -0,0 +1,246 @@
+// Package templates stores reusable node blueprints. A template carries
+// operations, attributes and labels, and its title and UNS address may
+// contain {{variable}} placeholders filled in when a node is created.
+package templates
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"manu-node-cli/internal/node"
+)
+
+// ErrNotFound is returned when no template has the requested name
+var ErrNotFound = errors.New("template not found")
+
+var (
+	namePattern     = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+	variablePattern = regexp.MustCompile(`{{\s*([A-Za-z_][A-Za-z0-9_]*)\s*}}`)
+)
+
+// Template is a node blueprint
+type Template struct {
+	Name        string            `json:"name"`
+	Title       string            `json:"title"`
+	Description string            `json:"description,omitempty"`
+	Operations  []string          `json:"operations,omitempty"`
+	UNSAddress  string            `json:"uns_address,omitempty"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// FromNode builds a template from an existing node. Unless overridden,
+// the title becomes {{title}} and the last UNS segment is replaced by
+// {{title}} as well, so each instance gets its own address.
+func FromNode(name string, n *node.Node, titlePattern, unsPattern string) *Template {
+	if titlePattern == "" {
+		titlePattern = "{{title}}"
+	}
+	if unsPattern == "" && n.UNSAddress != "" {
+		if parent := path.Dir(n.UNSAddress); parent != "." && parent != "/" {
+			unsPattern = parent + "/{{title}}"
+		} else {
+			unsPattern = "{{title}}"
+		}
+	}
+	c := n.Clone()
+	return &Template{
+		Name:        name,
+		Title:       titlePattern,
+		Description: c.Description,
+		Operations:  c.Operations,
+		UNSAddress:  unsPattern,
+		Attributes:  c.Attributes,
+		Labels:      c.Labels,
+		CreatedAt:   time.Now(),
+	}
+}
+
+// Variables returns the placeholder names used in the title and UNS
+// address, in order of first appearance
+func (t *Template) Variables() []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, s := range []string{t.Title, t.UNSAddress} {
+		for _, m := range variablePattern.FindAllStringSubmatch(s, -1) {
+			if !seen[m[1]] {
+				seen[m[1]] = true
+				names = append(names, m[1])
+			}
+		}
+	}
+	return names
+}
+
+// render substitutes vars into s
+func render(s string, vars map[string]string) (string, error) {
+	var missing []string
+	out := variablePattern.ReplaceAllStringFunc(s, func(m string) string {
+		name := variablePattern.FindStringSubmatch(m)[1]
+		v, ok := vars[name]
+		if !ok {
+			missing = append(missing, name)
+		}
+		return v
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing template variable(s): %s", strings.Join(missing, ", "))
+	}
+	return out, nil
+}
+
+// Instantiate creates a new, unsaved node from the template
+func (t *Template) Instantiate(vars map[string]string) (*node.Node, error) {
+	title, err := render(t.Title, vars)
+	if err != nil {
+		return nil, err
+	}
+	uns, err := render(t.UNSAddress, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	blueprint := &node.Node{
+		Operations: t.Operations,
+		Attributes: t.Attributes,
+		Labels:     t.Labels,
+	}
+	c := blueprint.Clone()
+	n := node.NewNode(strings.TrimSpace(title), t.Description, c.Operations, strings.TrimSpace(uns))
+	n.Attributes = c.Attributes
+	n.Labels = c.Labels
+	if err := n.Validate(); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// Store handles persistence of templates
+type Store struct {
+	filePath string
+	mu       sync.RWMutex
+}
+
+// NewStore creates a template store in the data directory
+func NewStore(dataDir string) (*Store, error) {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	return &Store{filePath: filepath.Join(dataDir, "templates.json")}, nil
+}
+
+// Load reads all templates
+func (s *Store) Load() ([]*Template, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.load()
+}
+
+func (s *Store) load() ([]*Template, error) {
+	data, err := os.ReadFile(s.filePath)
+	if os.IsNotExist(err) {
+		return []*Template{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read templates file: %w", err)
+	}
+	if len(data) == 0 {
+		return []*Template{}, nil
+	}
+
+	var templates []*Template
+	if err := json.Unmarshal(data, &templates); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal templates: %w", err)
+	}
+	return templates, nil
+}
+
+func (s *Store) save(templates []*Template) error {
+	data, err := json.MarshalIndent(templates, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal templates: %w", err)
+	}
+	tmp := s.filePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write templates file: %w", err)
+	}
+	if err := os.Rename(tmp, s.filePath); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write templates file: %w", err)
+	}
+	return nil
+}
+
+// Get returns the template with the given name (case-insensitive)
+func (s *Store) Get(name string) (*Template, error) {
+	templates, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range templates {
+		if strings.EqualFold(t.Name, name) {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// Save adds a template or replaces the one with the same name
+func (s *Store) Save(t *Template) error {
+	if !namePattern.MatchString(t.Name) {
+		return fmt.Errorf("invalid template name '%s': use letters, digits, '.', '_' or '-'", t.Name)
+	}
+	if strings.TrimSpace(t.Title) == "" {
+		return errors.New("template title cannot be empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	templates, err := s.load()
+	if err != nil {
+		return err
+	}
+	replaced := false
+	for i, existing := range templates {
+		if strings.EqualFold(existing.Name, t.Name) {
+			templates[i] = t
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		templates = append(templates, t)
+	}
+	return s.save(templates)
+}
+
+// Remove deletes a template
+func (s *Store) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	templates, err := s.load()
+	if err != nil {
+		return err
+	}
+	var kept []*Template
+	for _, t := range templates {
+		if !strings.EqualFold(t.Name, name) {
+			kept = append(kept, t)
+		}
+	}
+	if len(kept) == len(templates) {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return s.save(kept)
+}
@@ -0,0 +1,109 @@
+package templates
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"manu-node-cli/internal/node"
+)
+
+func setupTestStore(t *testing.T) (*Store, func()) {
+	tempDir, err := os.MkdirTemp("", "templates_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	store, err := NewStore(tempDir)
+	if err != nil {
+		os.RemoveAll(tempDir)
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	return store, func() { os.RemoveAll(tempDir) }
+}
+
+func cncNode() *node.Node {
+	return &node.Node{
+		ID:          "1",
+		Title:       "CNC",
+		Description: "5-axis mill",
+		Operations:  []string{"mill", "drill"},
+		UNSAddress:  "Plant/Dilna/CNC",
+		Attributes:  map[string]string{"power": "12"},
+		Labels:      map[string]string{"dept": "machining"},
+		CreatedAt:   time.Now(),
+	}
+}
+
+func TestFromNodeAndInstantiate(t *testing.T) {
+	tmpl := FromNode("cnc", cncNode(), "", "")
+	if tmpl.Title != "{{title}}" || tmpl.UNSAddress != "Plant/Dilna/{{title}}" {
+		t.Errorf("Unexpected default patterns: %q, %q", tmpl.Title, tmpl.UNSAddress)
+	}
+
+	n, err := tmpl.Instantiate(map[string]string{"title": "CNC2"})
+	if err != nil {
+		t.Fatalf("Failed to instantiate: %v", err)
+	}
+	if n.Title != "CNC2" || n.UNSAddress != "Plant/Dilna/CNC2" || n.Description != "5-axis mill" {
+		t.Errorf("Unexpected node: %+v", n)
+	}
+	if len(n.Operations) != 2 || n.Attributes["power"] != "12" || n.Labels["dept"] != "machining" {
+		t.Errorf("Expected operations, attributes and labels to be copied, got %+v", n)
+	}
+
+	// Instances must not share maps with the template
+	n.Labels["dept"] = "changed"
+	if tmpl.Labels["dept"] != "machining" {
+		t.Errorf("Expected template labels to be independent of instances")
+	}
+
+	if _, err := tmpl.Instantiate(nil); err == nil {
+		t.Errorf("Expected missing variable to fail")
+	}
+}
+
+func TestVariables(t *testing.T) {
+	tmpl := &Template{Title: "Press {{ line }}-{{no}}", UNSAddress: "Plant/{{line}}/Press{{no}}"}
+	vars := tmpl.Variables()
+	if len(vars) != 2 || vars[0] != "line" || vars[1] != "no" {
+		t.Errorf("Expected [line no], got %v", vars)
+	}
+	n, err := tmpl.Instantiate(map[string]string{"line": "L1", "no": "3"})
+	if err != nil {
+		t.Fatalf("Failed to instantiate: %v", err)
+	}
+	if n.Title != "Press L1-3" || n.UNSAddress != "Plant/L1/Press3" {
+		t.Errorf("Unexpected substitution: %q, %q", n.Title, n.UNSAddress)
+	}
+}
+
+func TestStore(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	if err := store.Save(FromNode("bad name", cncNode(), "", "")); err == nil {
+		t.Errorf("Expected invalid name to be rejected")
+	}
+	if err := store.Save(FromNode("cnc", cncNode(), "", "")); err != nil {
+		t.Fatalf("Failed to save template: %v", err)
+	}
+	if err := store.Save(FromNode("CNC", cncNode(), "CNC {{no}}", "")); err != nil {
+		t.Fatalf("Failed to replace template: %v", err)
+	}
+
+	all, _ := store.Load()
+	if len(all) != 1 || all[0].Title != "CNC {{no}}" {
+		t.Errorf("Expected one replaced template, got %+v", all)
+	}
+	if _, err := store.Get("Cnc"); err != nil {
+		t.Errorf("Expected case-insensitive lookup, got %v", err)
+	}
+
+	if err := store.Remove("cnc"); err != nil {
+		t.Fatalf("Failed to remove template: %v", err)
+	}
+	if _, err := store.Get("cnc"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package tui
+
+import (
+	"unicode"
+
+	"manu-node-cli/internal/node"
+)
+
+// Field is one input of an editing form
+type Field struct {
+	Name  string // how the backend recognises the field
+	Label string
+	Hint  string // accepted input, shown after the label
+	Value string
+}
+
+// form edits the fields of a new or existing node
+type form struct {
+	title    string
+	existing *node.Node // nil when creating
+	fields   []Field
+	focus    int
+	pos      int // cursor position in runes within the focused value
+}
+
+func newForm(title string, existing *node.Node, fields []Field) *form {
+	f := &form{title: title, existing: existing, fields: fields}
+	f.moveTo(0)
+	return f
+}
+
+func (f *form) moveTo(i int) {
+	if i < 0 || i >= len(f.fields) {
+		return
+	}
+	f.focus = i
+	f.pos = len([]rune(f.fields[i].Value))
+}
+
+// handleKey edits the focused field and reports whether the form should be
+// submitted
+func (f *form) handleKey(k Key) (submit bool) {
+	value := []rune(f.fields[f.focus].Value)
+	set := func(v []rune, pos int) {
+		f.fields[f.focus].Value = string(v)
+		f.pos = pos
+	}
+
+	switch k.Code {
+	case KeyTab, KeyDown:
+		f.moveTo(f.focus + 1)
+	case KeyBacktab, KeyUp:
+		f.moveTo(f.focus - 1)
+	case KeyEnter:
+		if f.focus == len(f.fields)-1 {
+			return true
+		}
+		f.moveTo(f.focus + 1)
+	case KeyLeft:
+		if f.pos > 0 {
+			f.pos--
+		}
+	case KeyRight:
+		if f.pos < len(value) {
+			f.pos++
+		}
+	case KeyHome:
+		f.pos = 0
+	case KeyEnd:
+		f.pos = len(value)
+	case KeyBackspace:
+		if f.pos > 0 {
+			set(append(value[:f.pos-1:f.pos-1], value[f.pos:]...), f.pos-1)
+		}
+	case KeyDelete:
+		if f.pos < len(value) {
+			set(append(value[:f.pos:f.pos], value[f.pos+1:]...), f.pos)
+		}
+	case KeyCtrl:
+		switch k.Rune {
+		case 's':
+			return true
+		case 'a':
+			f.pos = 0
+		case 'e':
+			f.pos = len(value)
+		case 'u':
+			set(value[f.pos:], 0)
+		case 'k':
+			set(value[:f.pos], f.pos)
+		}
+	case KeyRune:
+		if unicode.IsPrint(k.Rune) {
+			v := append(append(value[:f.pos:f.pos], k.Rune), value[f.pos:]...)
+			set(v, f.pos+1)
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package tui
+
+import "unicode/utf8"
+
+// KeyCode identifies a key press
+type KeyCode int
+
+const (
+	KeyRune KeyCode = iota // a printable character in Key.Rune
+	KeyCtrl                // Ctrl plus the letter in Key.Rune
+	KeyEnter
+	KeyEsc
+	KeyTab
+	KeyBacktab
+	KeyBackspace
+	KeyDelete
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyHome
+	KeyEnd
+	KeyPgUp
+	KeyPgDn
+)
+
+// Key is one decoded key press
+type Key struct {
+	Code KeyCode
+	Rune rune
+}
+
+// Decode splits raw terminal input into key presses. An escape byte at the
+// end of the input is taken as the Esc key, since escape sequences arrive
+// in a single read.
+func Decode(b []byte) []Key {
+	var keys []Key
+	for len(b) > 0 {
+		k, n := decodeOne(b)
+		b = b[n:]
+		if k != nil {
+			keys = append(keys, *k)
+		}
+	}
+	return keys
+}
+
+func decodeOne(b []byte) (*Key, int) {
+	switch c := b[0]; {
+	case c == 0x1b:
+		return decodeEscape(b)
+	case c == '\r' || c == '\n':
+		return &Key{Code: KeyEnter}, 1
+	case c == '\t':
+		return &Key{Code: KeyTab}, 1
+	case c == 0x7f || c == 0x08:
+		return &Key{Code: KeyBackspace}, 1
+	case c < 0x20:
+		return &Key{Code: KeyCtrl, Rune: rune('a' + c - 1)}, 1
+	}
+	r, n := utf8.DecodeRune(b)
+	if r == utf8.RuneError {
+		return nil, n
+	}
+	return &Key{Code: KeyRune, Rune: r}, n
+}
+
+// decodeEscape reads a CSI (ESC [) or SS3 (ESC O) sequence
+func decodeEscape(b []byte) (*Key, int) {
+	if len(b) == 1 || (b[1] != '[' && b[1] != 'O') {
+		return &Key{Code: KeyEsc}, 1
+	}
+	i := 2
+	for i < len(b) && (b[i] >= '0' && b[i] <= '9' || b[i] == ';') {
+		i++
+	}
+	if i == len(b) {
+		return nil, len(b) // truncated sequence
+	}
+	params, final := string(b[2:i]), b[i]
+	n := i + 1
+
+	code := map[byte]KeyCode{
+		'A': KeyUp, 'B': KeyDown, 'C': KeyRight, 'D': KeyLeft,
+		'H': KeyHome, 'F': KeyEnd, 'Z': KeyBacktab,
+	}
+	if final == '~' {
+		switch params {
+		case "1", "7":
+			return &Key{Code: KeyHome}, n
+		case "4", "8":
+			return &Key{Code: KeyEnd}, n
+		case "3":
+			return &Key{Code: KeyDelete}, n
+		case "5":
+			return &Key{Code: KeyPgUp}, n
+		case "6":
+			return &Key{Code: KeyPgDn}, n
+		}
+		return nil, n
+	}
+	if c, ok := code[final]; ok {
+		return &Key{Code: c}, n
+	}
+	return nil, n
+}
@@ -0,0 +1,323 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"manu-node-cli/internal/labels"
+	"manu-node-cli/internal/node"
+)
+
+const (
+	styleReset   = "\033[0m"
+	styleBold    = "\033[1m"
+	styleDim     = "\033[2m"
+	styleReverse = "\033[7m"
+	styleRed     = "\033[31m"
+	styleGreen   = "\033[32m"
+	styleYellow  = "\033[33m"
+	styleCyan    = "\033[36m"
+)
+
+// styled wraps an already fitted string in a style
+func styled(style, s string) string {
+	if style == "" {
+		return s
+	}
+	return style + s + styleReset
+}
+
+// fit truncates s to width runes, marking the cut with an ellipsis, and
+// pads it with spaces to exactly width
+func fit(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	r := []rune(s)
+	if len(r) > width {
+		return string(r[:width-1]) + "…"
+	}
+	return s + strings.Repeat(" ", width-len(r))
+}
+
+// wrap breaks s into lines of at most width runes, at spaces when possible
+func wrap(s string, width int) []string {
+	if width <= 0 {
+		return nil
+	}
+	var lines []string
+	for _, para := range strings.Split(s, "\n") {
+		r := []rune(para)
+		for len(r) > width {
+			cut := width
+			for i := width; i > width/2; i-- {
+				if r[i] == ' ' {
+					cut = i
+					break
+				}
+			}
+			lines = append(lines, string(r[:cut]))
+			r = []rune(strings.TrimLeft(string(r[cut:]), " "))
+		}
+		lines = append(lines, string(r))
+	}
+	return lines
+}
+
+// Render draws the screen as height lines of width columns
+func (a *App) Render(width, height int) []string {
+	if height < 5 || width < 30 {
+		return []string{fit("Terminal too small", width)}
+	}
+	lines := make([]string, 0, height)
+	lines = append(lines, a.header(width))
+
+	bodyHeight := height - 3
+	a.pageSize = max(bodyHeight-1, 1)
+	leftWidth := min(max(width*2/5, 20), 50)
+	rightWidth := width - leftWidth - 1
+
+	left := a.treeLines(leftWidth, bodyHeight)
+	var right []string
+	switch a.mode {
+	case modeForm:
+		right = a.formLines(rightWidth, bodyHeight)
+	case modeHelp:
+		right = helpLines()
+	default:
+		right = a.detailLines(rightWidth)
+	}
+	for i := 0; i < bodyHeight; i++ {
+		r := ""
+		if i < len(right) {
+			r = right[i]
+		}
+		lines = append(lines, left[i]+styled(styleDim, "│")+" "+r)
+	}
+
+	lines = append(lines, a.statusLine(width), styled(styleDim, fit(a.hints(), width)))
+	return lines
+}
+
+func (a *App) header(width int) string {
+	title := " Manufacturing Nodes"
+	if a.title != "" {
+		title += " [" + a.title + "]"
+	}
+	count := fmt.Sprintf("%d node(s) ", len(a.nodes))
+	if a.query != "" {
+		shown := 0
+		for _, r := range a.rows {
+			if r.node != nil {
+				shown++
+			}
+		}
+		count = fmt.Sprintf("%d of %d match '%s' ", shown, len(a.nodes), a.query)
+	}
+	gap := width - len([]rune(title)) - len([]rune(count))
+	if gap < 1 {
+		return styled(styleReverse, fit(title, width))
+	}
+	return styled(styleReverse, title+strings.Repeat(" ", gap)+count)
+}
+
+// treeLines draws the visible part of the UNS tree, scrolling to keep the
+// cursor on screen
+func (a *App) treeLines(width, height int) []string {
+	if a.cursor < a.offset {
+		a.offset = a.cursor
+	}
+	if a.cursor >= a.offset+height {
+		a.offset = a.cursor - height + 1
+	}
+	if a.offset > max(len(a.rows)-height, 0) {
+		a.offset = max(len(a.rows)-height, 0)
+	}
+
+	lines := make([]string, height)
+	for i := range lines {
+		idx := a.offset + i
+		if idx >= len(a.rows) {
+			lines[i] = fit("", width)
+			if idx == 0 {
+				msg := " No nodes. Press n to create one."
+				if a.query != "" {
+					msg = " No matches."
+				}
+				lines[i] = styled(styleDim, fit(msg, width))
+			}
+			continue
+		}
+		r := a.rows[idx]
+		text := strings.Repeat("  ", r.depth)
+		style := ""
+		if r.node == nil {
+			marker := "▾ "
+			if a.collapsed[r.path] && a.query == "" {
+				marker = "▸ "
+			}
+			text += marker + r.name + fmt.Sprintf(" (%d)", r.count)
+			style = styleCyan
+		} else {
+			text += "• " + r.name
+		}
+		if idx == a.cursor {
+			style = styleReverse
+		}
+		lines[i] = styled(style, fit(" "+text, width))
+	}
+	return lines
+}
+
+func (a *App) detailLines(width int) []string {
+	r, ok := a.current()
+	if !ok {
+		return nil
+	}
+	if r.node == nil {
+		return []string{
+			styled(styleBold, fit(r.path, width-1)),
+			"",
+			fmt.Sprintf("Nodes beneath: %d", r.count),
+			"",
+			styled(styleDim, "Enter collapses or expands the folder;"),
+			styled(styleDim, "n creates a node in it."),
+		}
+	}
+	return nodeLines(r.node, width-1)
+}
+
+// nodeLines describes a node for the details pane
+func nodeLines(n *node.Node, width int) []string {
+	lines := []string{styled(styleBold, fit(n.Title, width)), ""}
+	field := func(label, value string) {
+		if value == "" {
+			value = "-"
+		}
+		for i, l := range wrap(value, width-14) {
+			if i == 0 {
+				lines = append(lines, styled(styleCyan, fmt.Sprintf("%-14s", label))+l)
+			} else {
+				lines = append(lines, strings.Repeat(" ", 14)+l)
+			}
+		}
+	}
+	field("ID", n.ID)
+	field("UNS Address", n.UNSAddress)
+	field("Operations", strings.Join(n.Operations, ", "))
+	field("Labels", labels.Format(n.Labels))
+
+	if len(n.Attributes) > 0 {
+		names := make([]string, 0, len(n.Attributes))
+		for name := range n.Attributes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for i, name := range names {
+			label := ""
+			if i == 0 {
+				label = "Attributes"
+			}
+			field(label, name+" = "+n.Attributes[name])
+		}
+	}
+	field("Created", n.CreatedAt.Format("2006-01-02 15:04:05"))
+	field("Updated", n.UpdatedAt.Format("2006-01-02 15:04:05"))
+
+	lines = append(lines, "", styled(styleCyan, "Description"))
+	if n.Description == "" {
+		lines = append(lines, "-")
+	} else {
+		lines = append(lines, wrap(n.Description, width)...)
+	}
+	return lines
+}
+
+// formLines draws the form, three lines per field, scrolled so the focused
+// field is visible
+func (a *App) formLines(width, height int) []string {
+	f := a.form
+	lines := []string{styled(styleBold, fit(f.title, width-1)), ""}
+	perScreen := max((height-len(lines))/3, 1)
+	first := max(f.focus-perScreen+1, 0)
+
+	for i := first; i < len(f.fields); i++ {
+		field := f.fields[i]
+		label := field.Label
+		if field.Hint != "" {
+			label += " <" + field.Hint + ">"
+		}
+		value := []rune(strings.ReplaceAll(field.Value, "\n", "↵"))
+		if i != f.focus {
+			lines = append(lines, fit(label, width-1), styled(styleDim, fit("  "+string(value), width-1)), "")
+			continue
+		}
+
+		// Keep the cursor inside the visible part of a long value
+		visible := width - 4
+		start := max(f.pos-visible+1, 0)
+		before := string(value[start:f.pos])
+		at, after := " ", ""
+		if f.pos < len(value) {
+			at = string(value[f.pos])
+			after = string(value[f.pos+1 : min(len(value), start+visible)])
+		}
+		lines = append(lines, styled(styleBold+styleCyan, fit(label, width-1)),
+			"> "+before+styled(styleReverse, at)+after, "")
+	}
+	return lines
+}
+
+func helpLines() []string {
+	return []string{
+		styled(styleBold, "Keys"),
+		"",
+		"↑/↓ j/k      move           PgUp/PgDn  page",
+		"←/→ h/l      collapse/expand a folder",
+		"Enter        toggle folder / edit node",
+		"/            search as you type (Esc clears)",
+		"n            new node in the selected folder",
+		"c            clone the selected node",
+		"e            edit the selected node",
+		"d            delete the selected node",
+		"r            reload from storage",
+		"q, Ctrl+C    quit",
+		"",
+		styled(styleBold, "Forms"),
+		"",
+		"Tab/↓ Shift+Tab/↑   next/previous field",
+		"Enter               next field, save on the last",
+		"Ctrl+S              save      Esc   cancel",
+		"Ctrl+U/Ctrl+K       clear before/after the cursor",
+		"",
+		styled(styleDim, "Press any key to close this help."),
+	}
+}
+
+func (a *App) statusLine(width int) string {
+	switch a.mode {
+	case modeSearch:
+		return fit("/"+a.query, width-1) + styled(styleReverse, " ")
+	case modeConfirm:
+		return styled(styleYellow, fit(fmt.Sprintf("Delete '%s' (ID: %s)? [y/N]", a.pending.Title, a.pending.ID), width))
+	}
+	if a.statusErr {
+		return styled(styleRed, fit("Error: "+a.status, width))
+	}
+	return styled(styleGreen, fit(a.status, width))
+}
+
+func (a *App) hints() string {
+	switch a.mode {
+	case modeSearch:
+		return " Type to filter · Enter keep filter · Esc clear · ↑/↓ move"
+	case modeForm:
+		return " Tab next · Shift+Tab previous · Enter/Ctrl+S save · Esc cancel"
+	case modeConfirm:
+		return " y delete · any other key cancels"
+	case modeHelp:
+		return " Press any key to close"
+	}
+	return " ↑↓ move  Enter edit  / search  n new  c clone  d delete  ? help  q quit"
+}
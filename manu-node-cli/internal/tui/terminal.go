@@ -0,0 +1,89 @@
+package tui
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/chzyer/readline"
+)
+
+const (
+	enterScreen = "\033[?1049h\033[?25l\033[?7l" // alternate screen, hide cursor, no line wrap
+	leaveScreen = "\033[?7h\033[?25h\033[?1049l"
+)
+
+// Run shows the app on the terminal behind in and out until the user quits
+func Run(a *App, in *os.File, out io.Writer) error {
+	fd := int(in.Fd())
+	if !readline.IsTerminal(fd) {
+		return errors.New("the TUI needs an interactive terminal")
+	}
+	state, err := readline.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer readline.Restore(fd, state)
+
+	io.WriteString(out, enterScreen)
+	defer io.WriteString(out, leaveScreen)
+
+	keys := make(chan []Key)
+	go func() {
+		defer close(keys)
+		buf := make([]byte, 256)
+		for {
+			n, err := in.Read(buf)
+			if n > 0 {
+				keys <- Decode(buf[:n])
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	resized := make(chan struct{}, 1)
+	readline.DefaultOnWidthChanged(func() {
+		select {
+		case resized <- struct{}{}:
+		default:
+		}
+	})
+
+	for {
+		width, height, err := readline.GetSize(fd)
+		if err != nil {
+			width, height = 80, 24
+		}
+		draw(out, a.Render(width, height))
+
+		select {
+		case batch, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			for _, k := range batch {
+				if a.HandleKey(k) {
+					return nil
+				}
+			}
+		case <-resized:
+		}
+	}
+}
+
+// draw repaints the whole screen in a single write
+func draw(out io.Writer, lines []string) {
+	var b strings.Builder
+	b.WriteString("\033[H")
+	for i, l := range lines {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString(l)
+		b.WriteString(styleReset + "\033[K")
+	}
+	b.WriteString("\033[J")
+	io.WriteString(out, b.String())
+}
@@ -0,0 +1,115 @@
+package tui
+
+import (
+	"sort"
+	"strings"
+
+	"manu-node-cli/internal/labels"
+	"manu-node-cli/internal/node"
+)
+
+// row is one line of the UNS tree: a folder for a UNS path prefix, or a
+// node listed under the folder of its parent path
+type row struct {
+	depth int
+	name  string
+	path  string     // folder rows only
+	node  *node.Node // node rows only
+	count int        // nodes beneath a folder
+}
+
+// key identifies a row across rebuilds
+func (r row) key() string {
+	if r.node != nil {
+		return "node:" + r.node.ID
+	}
+	return "folder:" + r.path
+}
+
+type folder struct {
+	path     string
+	name     string
+	children map[string]*folder
+	nodes    []*node.Node
+	count    int
+}
+
+func newFolder(path, name string) *folder {
+	return &folder{path: path, name: name, children: map[string]*folder{}}
+}
+
+// buildTree groups nodes by the segments of their UNS address. Nodes
+// without an address sit at the top level.
+func buildTree(nodes []*node.Node) *folder {
+	root := newFolder("", "")
+	for _, n := range nodes {
+		f := root
+		f.count++
+		segments := strings.Split(n.UNSAddress, "/")
+		if n.UNSAddress == "" {
+			segments = nil
+		}
+		for i, seg := range segments[:max(len(segments)-1, 0)] {
+			child, ok := f.children[seg]
+			if !ok {
+				child = newFolder(strings.Join(segments[:i+1], "/"), seg)
+				f.children[seg] = child
+			}
+			f = child
+			f.count++
+		}
+		f.nodes = append(f.nodes, n)
+	}
+	return root
+}
+
+// flatten lists the visible rows: folders first, then nodes by title.
+// Folders in collapsed stay closed unless expandAll is set.
+func (f *folder) flatten(depth int, collapsed map[string]bool, expandAll bool, out []row) []row {
+	names := make([]string, 0, len(f.children))
+	for name := range f.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		child := f.children[name]
+		out = append(out, row{depth: depth, name: child.name, path: child.path, count: child.count})
+		if expandAll || !collapsed[child.path] {
+			out = child.flatten(depth+1, collapsed, expandAll, out)
+		}
+	}
+
+	sorted := append([]*node.Node(nil), f.nodes...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return strings.ToLower(sorted[i].Title) < strings.ToLower(sorted[j].Title)
+	})
+	for _, n := range sorted {
+		out = append(out, row{depth: depth, name: n.Title, node: n})
+	}
+	return out
+}
+
+// matches reports whether every word of query occurs, ignoring case, in
+// one of the node's fields
+func matches(n *node.Node, query string) bool {
+	fields := []string{n.ID, n.Title, n.Description, n.UNSAddress, labels.Format(n.Labels)}
+	fields = append(fields, n.Operations...)
+	for _, v := range n.Attributes {
+		fields = append(fields, v)
+	}
+	haystack := strings.ToLower(strings.Join(fields, "\n"))
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		if !strings.Contains(haystack, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// parentPath returns the folder a UNS address is listed under
+func parentPath(uns string) string {
+	if i := strings.LastIndex(uns, "/"); i >= 0 {
+		return uns[:i]
+	}
+	return ""
+}
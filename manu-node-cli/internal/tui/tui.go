@@ -0,0 +1,397 @@
+// Package tui is a full-screen browser for the node catalog: a UNS tree on
+// the left, the selected node on the right, search-as-you-type and inline
+// editing forms. It draws with plain ANSI escape sequences and reaches the
+// catalog only through a Backend.
+package tui
+
+import (
+	"fmt"
+
+	"manu-node-cli/internal/node"
+)
+
+// Backend loads and changes nodes on behalf of the TUI, applying the same
+// checks as the line-based commands
+type Backend interface {
+	// Nodes returns the nodes the user may see
+	Nodes() ([]*node.Node, error)
+	// Fields returns the form inputs for n. For a new node n carries the
+	// values to start from and has no ID.
+	Fields(n *node.Node) ([]Field, error)
+	// Save stores the form as an update of existing, or as a new node when
+	// existing is nil, and returns the stored node
+	Save(existing *node.Node, fields []Field) (*node.Node, error)
+	// Delete removes n unless it changed since it was loaded
+	Delete(n *node.Node) error
+}
+
+type mode int
+
+const (
+	modeBrowse mode = iota
+	modeSearch
+	modeForm
+	modeConfirm
+	modeHelp
+)
+
+// App is the TUI state. It is driven by HandleKey and drawn by Render, so
+// it can be exercised without a terminal.
+type App struct {
+	backend Backend
+	title   string
+
+	nodes     []*node.Node
+	rows      []row
+	collapsed map[string]bool
+	cursor    int
+	offset    int // first tree row on screen
+	query     string
+
+	mode    mode
+	form    *form
+	pending *node.Node // awaiting delete confirmation
+
+	status    string
+	statusErr bool
+	pageSize  int
+}
+
+// New creates an App and loads the catalog. title is shown in the header.
+func New(backend Backend, title string) (*App, error) {
+	a := &App{backend: backend, title: title, collapsed: map[string]bool{}, pageSize: 10}
+	if err := a.reload(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// reload fetches the nodes again and keeps the selection where possible
+func (a *App) reload() error {
+	nodes, err := a.backend.Nodes()
+	if err != nil {
+		return err
+	}
+	a.nodes = nodes
+	a.rebuild()
+	return nil
+}
+
+// rebuild recomputes the visible rows after the nodes, the filter or the
+// collapsed folders changed
+func (a *App) rebuild() {
+	selected := ""
+	if r, ok := a.current(); ok {
+		selected = r.key()
+	}
+	a.selectKey(selected)
+}
+
+// selectKey rebuilds the rows and moves the cursor to the row with key
+func (a *App) selectKey(key string) {
+	shown := a.nodes
+	if a.query != "" {
+		shown = nil
+		for _, n := range a.nodes {
+			if matches(n, a.query) {
+				shown = append(shown, n)
+			}
+		}
+	}
+	a.rows = buildTree(shown).flatten(0, a.collapsed, a.query != "", nil)
+
+	for i, r := range a.rows {
+		if r.key() == key {
+			a.cursor = i
+			return
+		}
+	}
+	a.cursor = min(a.cursor, len(a.rows)-1)
+	if a.cursor < 0 {
+		a.cursor = 0
+	}
+}
+
+func (a *App) current() (row, bool) {
+	if a.cursor < 0 || a.cursor >= len(a.rows) {
+		return row{}, false
+	}
+	return a.rows[a.cursor], true
+}
+
+func (a *App) setStatus(format string, args ...interface{}) {
+	a.status, a.statusErr = fmt.Sprintf(format, args...), false
+}
+
+func (a *App) setError(err error) {
+	a.status, a.statusErr = err.Error(), true
+}
+
+// HandleKey processes one key press and reports whether the user quit
+func (a *App) HandleKey(k Key) (quit bool) {
+	if k.Code == KeyCtrl && k.Rune == 'c' {
+		return true
+	}
+	switch a.mode {
+	case modeSearch:
+		a.handleSearch(k)
+	case modeForm:
+		a.handleForm(k)
+	case modeConfirm:
+		a.handleConfirm(k)
+	case modeHelp:
+		a.mode = modeBrowse
+	default:
+		return a.handleBrowse(k)
+	}
+	return false
+}
+
+func (a *App) handleBrowse(k Key) bool {
+	a.status = ""
+	if k.Code == KeyRune {
+		switch k.Rune {
+		case 'q':
+			return true
+		case 'j':
+			k.Code = KeyDown
+		case 'k':
+			k.Code = KeyUp
+		case 'h':
+			k.Code = KeyLeft
+		case 'l':
+			k.Code = KeyRight
+		case 'g':
+			k.Code = KeyHome
+		case 'G':
+			k.Code = KeyEnd
+		case '/':
+			a.mode = modeSearch
+			return false
+		case '?':
+			a.mode = modeHelp
+			return false
+		case 'r':
+			if err := a.reload(); err != nil {
+				a.setError(err)
+			} else {
+				a.setStatus("Reloaded %d node(s)", len(a.nodes))
+			}
+			return false
+		case 'n':
+			a.openCreate(nil)
+			return false
+		case 'c':
+			if r, ok := a.current(); ok && r.node != nil {
+				a.openCreate(r.node)
+			} else {
+				a.setStatus("Select a node to clone")
+			}
+			return false
+		case 'e':
+			k.Code = KeyEnter
+		case 'd':
+			if r, ok := a.current(); ok && r.node != nil {
+				a.pending = r.node
+				a.mode = modeConfirm
+			} else {
+				a.setStatus("Select a node to delete")
+			}
+			return false
+		}
+	}
+
+	switch k.Code {
+	case KeyUp:
+		a.move(-1)
+	case KeyDown:
+		a.move(1)
+	case KeyPgUp:
+		a.move(-a.pageSize)
+	case KeyPgDn:
+		a.move(a.pageSize)
+	case KeyHome:
+		a.move(-len(a.rows))
+	case KeyEnd:
+		a.move(len(a.rows))
+	case KeyLeft:
+		a.collapseOrParent()
+	case KeyRight:
+		if r, ok := a.current(); ok && r.node == nil && a.collapsed[r.path] {
+			delete(a.collapsed, r.path)
+			a.rebuild()
+		}
+	case KeyEnter:
+		r, ok := a.current()
+		switch {
+		case !ok:
+		case r.node == nil:
+			a.collapsed[r.path] = !a.collapsed[r.path]
+			a.rebuild()
+		default:
+			a.openEdit(r.node)
+		}
+	case KeyEsc:
+		if a.query != "" {
+			a.query = ""
+			a.rebuild()
+		}
+	}
+	return false
+}
+
+func (a *App) move(delta int) {
+	a.cursor += delta
+	if a.cursor >= len(a.rows) {
+		a.cursor = len(a.rows) - 1
+	}
+	if a.cursor < 0 {
+		a.cursor = 0
+	}
+}
+
+// collapseOrParent closes the selected folder, or jumps to the folder
+// holding the selected row
+func (a *App) collapseOrParent() {
+	r, ok := a.current()
+	if !ok {
+		return
+	}
+	if r.node == nil && !a.collapsed[r.path] && a.query == "" {
+		a.collapsed[r.path] = true
+		a.rebuild()
+		return
+	}
+	for i := a.cursor - 1; i >= 0; i-- {
+		if a.rows[i].node == nil && a.rows[i].depth < r.depth {
+			a.cursor = i
+			return
+		}
+	}
+}
+
+func (a *App) handleSearch(k Key) {
+	switch k.Code {
+	case KeyEnter:
+		a.mode = modeBrowse
+		return
+	case KeyEsc:
+		a.query = ""
+		a.mode = modeBrowse
+	case KeyBackspace:
+		if q := []rune(a.query); len(q) > 0 {
+			a.query = string(q[:len(q)-1])
+		}
+	case KeyCtrl:
+		if k.Rune == 'u' {
+			a.query = ""
+		}
+	case KeyUp:
+		a.move(-1)
+		return
+	case KeyDown:
+		a.move(1)
+		return
+	case KeyRune:
+		a.query += string(k.Rune)
+	default:
+		return
+	}
+	a.rebuild()
+	// Jump to the first match while typing
+	for i, r := range a.rows {
+		if r.node != nil {
+			a.cursor = i
+			break
+		}
+	}
+}
+
+// openCreate starts a form for a new node, copying from when cloning. A new
+// node starts in the selected folder.
+func (a *App) openCreate(from *node.Node) {
+	start := &node.Node{}
+	title := "New node"
+	if from != nil {
+		start = from.Clone()
+		start.ID = ""
+		start.Title = ""
+		title = fmt.Sprintf("Clone of '%s'", from.Title)
+	} else if r, ok := a.current(); ok {
+		if r.node == nil {
+			start.UNSAddress = r.path + "/"
+		} else if p := parentPath(r.node.UNSAddress); p != "" {
+			start.UNSAddress = p + "/"
+		}
+	}
+	fields, err := a.backend.Fields(start)
+	if err != nil {
+		a.setError(err)
+		return
+	}
+	a.form = newForm(title, nil, fields)
+	a.mode = modeForm
+}
+
+func (a *App) openEdit(n *node.Node) {
+	fields, err := a.backend.Fields(n)
+	if err != nil {
+		a.setError(err)
+		return
+	}
+	a.form = newForm(fmt.Sprintf("Edit '%s'", n.Title), n, fields)
+	a.mode = modeForm
+}
+
+func (a *App) handleForm(k Key) {
+	if k.Code == KeyEsc {
+		a.form, a.mode = nil, modeBrowse
+		a.setStatus("Cancelled")
+		return
+	}
+	if !a.form.handleKey(k) {
+		return
+	}
+
+	saved, err := a.backend.Save(a.form.existing, a.form.fields)
+	if err != nil {
+		a.setError(err)
+		return
+	}
+	verb := "Created"
+	if a.form.existing != nil {
+		verb = "Saved"
+	}
+	a.form, a.mode = nil, modeBrowse
+	if err := a.reload(); err != nil {
+		a.setError(err)
+		return
+	}
+	// Show the saved node even if the filter would hide it
+	if a.query != "" && !matches(saved, a.query) {
+		a.query = ""
+	}
+	for p := parentPath(saved.UNSAddress); p != ""; p = parentPath(p) {
+		delete(a.collapsed, p)
+	}
+	a.selectKey("node:" + saved.ID)
+	a.setStatus("%s '%s'", verb, saved.Title)
+}
+
+func (a *App) handleConfirm(k Key) {
+	n := a.pending
+	a.pending, a.mode = nil, modeBrowse
+	if k.Code != KeyRune || (k.Rune != 'y' && k.Rune != 'Y') {
+		a.setStatus("Deletion cancelled")
+		return
+	}
+	if err := a.backend.Delete(n); err != nil {
+		a.setError(err)
+		return
+	}
+	if err := a.reload(); err != nil {
+		a.setError(err)
+		return
+	}
+	a.setStatus("Deleted '%s'", n.Title)
+}
@@ -0,0 +1,254 @@
+package tui
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"manu-node-cli/internal/node"
+)
+
+// fakeBackend keeps nodes in memory
+type fakeBackend struct {
+	nodes   []*node.Node
+	saveErr error
+	nextID  int
+}
+
+func (b *fakeBackend) Nodes() ([]*node.Node, error) {
+	return b.nodes, nil
+}
+
+func (b *fakeBackend) Fields(n *node.Node) ([]Field, error) {
+	return []Field{
+		{Name: "title", Label: "Title", Value: n.Title},
+		{Name: "uns", Label: "UNS Address", Value: n.UNSAddress},
+	}, nil
+}
+
+func (b *fakeBackend) Save(existing *node.Node, fields []Field) (*node.Node, error) {
+	if b.saveErr != nil {
+		return nil, b.saveErr
+	}
+	n := &node.Node{}
+	if existing != nil {
+		n = existing.Clone()
+	} else {
+		b.nextID++
+		n.ID = "new" + string(rune('0'+b.nextID))
+	}
+	n.Title, n.UNSAddress = fields[0].Value, strings.TrimSuffix(fields[1].Value, "/")
+
+	for i, old := range b.nodes {
+		if old.ID == n.ID {
+			b.nodes[i] = n
+			return n, nil
+		}
+	}
+	b.nodes = append(b.nodes, n)
+	return n, nil
+}
+
+func (b *fakeBackend) Delete(n *node.Node) error {
+	for i, old := range b.nodes {
+		if old.ID == n.ID {
+			b.nodes = append(b.nodes[:i], b.nodes[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("not found")
+}
+
+func setupTestApp(t *testing.T) (*App, *fakeBackend) {
+	t.Helper()
+	b := &fakeBackend{nodes: []*node.Node{
+		{ID: "n1", Title: "CNC-01", UNSAddress: "Plant/Line1/CNC-01", Operations: []string{"Milling"}},
+		{ID: "n2", Title: "Lathe", UNSAddress: "Plant/Line2/Lathe"},
+		{ID: "n3", Title: "Booth", UNSAddress: "Plant/Booth"},
+		{ID: "n4", Title: "Spare"},
+	}}
+	a, err := New(b, "")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return a, b
+}
+
+// rowNames renders the visible tree rows as indented names
+func rowNames(a *App) []string {
+	var names []string
+	for _, r := range a.rows {
+		names = append(names, strings.Repeat(" ", r.depth)+r.name)
+	}
+	return names
+}
+
+func typeKeys(a *App, s string) {
+	for _, r := range s {
+		a.HandleKey(Key{Code: KeyRune, Rune: r})
+	}
+}
+
+func TestTree(t *testing.T) {
+	a, _ := setupTestApp(t)
+	want := []string{"Plant", " Line1", "  CNC-01", " Line2", "  Lathe", " Booth", "Spare"}
+	if got := rowNames(a); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	if a.rows[0].count != 3 {
+		t.Errorf("Expected 3 nodes under Plant, got %d", a.rows[0].count)
+	}
+
+	// Collapse Line1, then jump from its node up to the folder
+	a.HandleKey(Key{Code: KeyDown})
+	a.HandleKey(Key{Code: KeyLeft})
+	want = []string{"Plant", " Line1", " Line2", "  Lathe", " Booth", "Spare"}
+	if got := rowNames(a); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected Line1 collapsed, got %v", got)
+	}
+	a.HandleKey(Key{Code: KeyRight})
+	a.HandleKey(Key{Code: KeyDown})
+	a.HandleKey(Key{Code: KeyLeft})
+	if r, _ := a.current(); r.path != "Plant/Line1" {
+		t.Errorf("Expected the cursor on Plant/Line1, got %q", r.name)
+	}
+}
+
+func TestSearchAsYouType(t *testing.T) {
+	a, _ := setupTestApp(t)
+
+	typeKeys(a, "/mill")
+	want := []string{"Plant", " Line1", "  CNC-01"}
+	if got := rowNames(a); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected only the milling node, got %v", got)
+	}
+	if r, _ := a.current(); r.node == nil || r.node.ID != "n1" {
+		t.Errorf("Expected the cursor on the first match, got %q", r.name)
+	}
+
+	a.HandleKey(Key{Code: KeyEnter})
+	if a.mode != modeBrowse || a.query != "mill" {
+		t.Errorf("Expected Enter to keep the filter, got mode %d and %q", a.mode, a.query)
+	}
+	a.HandleKey(Key{Code: KeyEsc})
+	if len(a.rows) != 7 {
+		t.Errorf("Expected Esc to clear the filter, got %v", rowNames(a))
+	}
+}
+
+func TestCreateInSelectedFolder(t *testing.T) {
+	a, b := setupTestApp(t)
+	a.HandleKey(Key{Code: KeyDown}) // Line1
+
+	typeKeys(a, "n")
+	if a.mode != modeForm || a.form.fields[1].Value != "Plant/Line1/" {
+		t.Fatalf("Expected a form starting in Plant/Line1/, got %+v", a.form)
+	}
+	typeKeys(a, "Mill-2")
+	a.HandleKey(Key{Code: KeyTab})
+	typeKeys(a, "Mill-2")
+	a.HandleKey(Key{Code: KeyEnter})
+
+	if a.mode != modeBrowse || len(b.nodes) != 5 {
+		t.Fatalf("Expected the node saved, got mode %d and %d nodes", a.mode, len(b.nodes))
+	}
+	if r, _ := a.current(); r.node == nil || r.node.UNSAddress != "Plant/Line1/Mill-2" {
+		t.Errorf("Expected the new node selected, got %q", r.name)
+	}
+}
+
+func TestEditKeepsFormOnError(t *testing.T) {
+	a, b := setupTestApp(t)
+	a.HandleKey(Key{Code: KeyEnd}) // Spare
+	a.HandleKey(Key{Code: KeyEnter})
+	if a.mode != modeForm || a.form.existing == nil {
+		t.Fatalf("Expected an edit form, got mode %d", a.mode)
+	}
+
+	a.HandleKey(Key{Code: KeyBackspace})
+	b.saveErr = errors.New("title taken")
+	a.HandleKey(Key{Code: KeyCtrl, Rune: 's'})
+	if a.mode != modeForm || !a.statusErr || a.status != "title taken" {
+		t.Fatalf("Expected the form to stay open with the error, got mode %d, %q", a.mode, a.status)
+	}
+
+	b.saveErr = nil
+	a.HandleKey(Key{Code: KeyCtrl, Rune: 's'})
+	if a.mode != modeBrowse || b.nodes[3].Title != "Spar" {
+		t.Errorf("Expected the title saved as 'Spar', got '%s'", b.nodes[3].Title)
+	}
+}
+
+func TestCloneAndDelete(t *testing.T) {
+	a, b := setupTestApp(t)
+	a.HandleKey(Key{Code: KeyEnd}) // Spare
+
+	typeKeys(a, "c")
+	if a.form.existing != nil || a.form.fields[0].Value != "" {
+		t.Fatalf("Expected a create form with an empty title, got %+v", a.form)
+	}
+	a.HandleKey(Key{Code: KeyEsc})
+	if a.mode != modeBrowse || len(b.nodes) != 4 {
+		t.Errorf("Expected Esc to cancel, got mode %d", a.mode)
+	}
+
+	typeKeys(a, "dn")
+	if len(b.nodes) != 4 {
+		t.Error("Expected any key but y to cancel the deletion")
+	}
+	typeKeys(a, "dy")
+	if len(b.nodes) != 3 || a.status != "Deleted 'Spare'" {
+		t.Errorf("Expected Spare deleted, got %d nodes and %q", len(b.nodes), a.status)
+	}
+}
+
+func TestFormEditing(t *testing.T) {
+	f := newForm("", nil, []Field{{Value: "abc"}})
+	f.handleKey(Key{Code: KeyLeft})
+	f.handleKey(Key{Code: KeyRune, Rune: 'X'})
+	f.handleKey(Key{Code: KeyHome})
+	f.handleKey(Key{Code: KeyDelete})
+	if got := f.fields[0].Value; got != "bXc" {
+		t.Errorf("Expected 'bXc', got '%s'", got)
+	}
+	f.handleKey(Key{Code: KeyCtrl, Rune: 'k'})
+	if got := f.fields[0].Value; got != "" {
+		t.Errorf("Expected Ctrl+K to clear the rest, got '%s'", got)
+	}
+	if !f.handleKey(Key{Code: KeyEnter}) {
+		t.Error("Expected Enter on the last field to submit")
+	}
+}
+
+func TestDecode(t *testing.T) {
+	got := Decode([]byte("a\x1b[A\x1b[6~\r\x7f\x03é\x1b[Z\x1b"))
+	want := []Key{
+		{Code: KeyRune, Rune: 'a'}, {Code: KeyUp}, {Code: KeyPgDn}, {Code: KeyEnter},
+		{Code: KeyBackspace}, {Code: KeyCtrl, Rune: 'c'}, {Code: KeyRune, Rune: 'é'},
+		{Code: KeyBacktab}, {Code: KeyEsc},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestRender(t *testing.T) {
+	a, _ := setupTestApp(t)
+	lines := a.Render(80, 12)
+	if len(lines) != 12 {
+		t.Fatalf("Expected 12 lines, got %d", len(lines))
+	}
+	screen := strings.Join(lines, "\n")
+	for _, want := range []string{"4 node(s)", "▾ Plant (3)", "• CNC-01", "Nodes beneath: 3", "q quit"} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("Expected the screen to contain %q:\n%s", want, screen)
+		}
+	}
+
+	// Scrolling keeps the cursor on screen
+	a.HandleKey(Key{Code: KeyEnd})
+	if screen := strings.Join(a.Render(80, 6), "\n"); !strings.Contains(screen, "Spare") {
+		t.Errorf("Expected the selected last row to be visible:\n%s", screen)
+	}
+}
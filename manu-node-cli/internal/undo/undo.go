@@ -0,0 +1,229 @@
+// Package undo keeps the catalog changes made during a session so they
+// can be reverted and reapplied, one command at a time
+package undo
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"manu-node-cli/internal/node"
+	"manu-node-cli/internal/storage"
+)
+
+// DefaultLimit is how many steps a History keeps
+const DefaultLimit = 100
+
+// Change is the state of one node before and after a command. Before is
+// nil for a created node and After is nil for a deleted one.
+type Change struct {
+	Before *node.Node
+	After  *node.Node
+}
+
+// ID returns the ID of the changed node
+func (c Change) ID() string {
+	if c.After != nil {
+		return c.After.ID
+	}
+	return c.Before.ID
+}
+
+// Title returns the most recent title of the changed node
+func (c Change) Title() string {
+	if c.After != nil {
+		return c.After.Title
+	}
+	return c.Before.Title
+}
+
+// Describe summarizes the change in a few words
+func (c Change) Describe() string {
+	switch {
+	case c.Before == nil:
+		return fmt.Sprintf("created '%s'", c.After.Title)
+	case c.After == nil:
+		return fmt.Sprintf("deleted '%s'", c.Before.Title)
+	default:
+		return fmt.Sprintf("updated '%s'", c.After.Title)
+	}
+}
+
+// Step is everything one command changed
+type Step struct {
+	Command string
+	Time    time.Time
+	Changes []Change
+}
+
+// Inverse returns the step that reverts s
+func (s Step) Inverse() Step {
+	inv := Step{Command: s.Command, Time: s.Time, Changes: make([]Change, len(s.Changes))}
+	for i, c := range s.Changes {
+		inv.Changes[len(s.Changes)-1-i] = Change{Before: c.After, After: c.Before}
+	}
+	return inv
+}
+
+// Summary describes the step, e.g. "delete x (deleted 'x')"
+func (s Step) Summary() string {
+	parts := make([]string, 0, len(s.Changes))
+	for _, c := range s.Changes {
+		parts = append(parts, c.Describe())
+	}
+	if len(parts) > 3 {
+		parts = append(parts[:3], fmt.Sprintf("%d more", len(s.Changes)-3))
+	}
+	return fmt.Sprintf("%s (%s)", s.Command, strings.Join(parts, ", "))
+}
+
+// Diff compares two catalog snapshots by node ID
+func Diff(before, after []*node.Node) []Change {
+	old := make(map[string]*node.Node, len(before))
+	for _, n := range before {
+		old[n.ID] = n
+	}
+
+	var changes []Change
+	for _, n := range after {
+		prev, ok := old[n.ID]
+		switch {
+		case !ok:
+			changes = append(changes, Change{After: n})
+		case prev.ETag() != n.ETag():
+			changes = append(changes, Change{Before: prev, After: n})
+		}
+		delete(old, n.ID)
+	}
+	for _, n := range before {
+		if _, ok := old[n.ID]; ok {
+			changes = append(changes, Change{Before: n})
+		}
+	}
+	return changes
+}
+
+// Conflicts returns the changes of s whose node no longer is in the
+// state s left it in, i.e. that were changed again since
+func Conflicts(nodes []*node.Node, s Step) []Change {
+	current := make(map[string]*node.Node, len(nodes))
+	for _, n := range nodes {
+		current[n.ID] = n
+	}
+
+	var conflicts []Change
+	for _, c := range s.Changes {
+		// Applying s moves each node from c.Before to c.After
+		if !sameState(current[c.ID()], c.Before) {
+			conflicts = append(conflicts, c)
+		}
+	}
+	return conflicts
+}
+
+func sameState(a, b *node.Node) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.ETag() == b.ETag()
+}
+
+// Apply returns nodes with every change of s applied: each node ends up
+// in its After state, overwriting whatever is there now. It fails if a
+// restored title is taken by another node.
+func Apply(nodes []*node.Node, s Step) ([]*node.Node, error) {
+	target := make(map[string]*node.Node, len(s.Changes))
+	for _, c := range s.Changes {
+		target[c.ID()] = c.After
+	}
+
+	result := make([]*node.Node, 0, len(nodes)+len(s.Changes))
+	for _, n := range nodes {
+		after, ok := target[n.ID]
+		if !ok {
+			result = append(result, n)
+			continue
+		}
+		if after != nil {
+			result = append(result, after.Clone())
+		}
+		delete(target, n.ID)
+	}
+	// Nodes missing from the catalog are (re)created
+	for _, c := range s.Changes {
+		if after, ok := target[c.ID()]; ok && after != nil {
+			result = append(result, after.Clone())
+		}
+	}
+
+	for _, c := range s.Changes {
+		if c.After != nil && !storage.TitleUnique(result, c.After.Title, c.After.ID) {
+			return nil, fmt.Errorf("cannot restore '%s': another node now has that title", c.After.Title)
+		}
+	}
+	return result, nil
+}
+
+// History is a bounded undo/redo stack. Recording a new step clears the
+// redo stack.
+type History struct {
+	done   []Step
+	undone []Step
+	limit  int
+}
+
+// NewHistory creates a history keeping up to limit steps
+func NewHistory(limit int) *History {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	return &History{limit: limit}
+}
+
+// Record adds a step; steps without changes are ignored
+func (h *History) Record(s Step) {
+	if len(s.Changes) == 0 {
+		return
+	}
+	h.done = append(h.done, s)
+	if len(h.done) > h.limit {
+		h.done = h.done[len(h.done)-h.limit:]
+	}
+	h.undone = nil
+}
+
+// NextUndo returns the most recent step, if any
+func (h *History) NextUndo() (Step, bool) {
+	if len(h.done) == 0 {
+		return Step{}, false
+	}
+	return h.done[len(h.done)-1], true
+}
+
+// NextRedo returns the most recently undone step, if any
+func (h *History) NextRedo() (Step, bool) {
+	if len(h.undone) == 0 {
+		return Step{}, false
+	}
+	return h.undone[len(h.undone)-1], true
+}
+
+// Undone moves the step returned by NextUndo to the redo stack
+func (h *History) Undone() {
+	if len(h.done) == 0 {
+		return
+	}
+	s := h.done[len(h.done)-1]
+	h.done = h.done[:len(h.done)-1]
+	h.undone = append(h.undone, s)
+}
+
+// Redone moves the step returned by NextRedo back to the undo stack
+func (h *History) Redone() {
+	if len(h.undone) == 0 {
+		return
+	}
+	s := h.undone[len(h.undone)-1]
+	h.undone = h.undone[:len(h.undone)-1]
+	h.done = append(h.done, s)
+}
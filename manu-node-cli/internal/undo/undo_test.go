@@ -0,0 +1,129 @@
+package undo
+
+import (
+	"testing"
+
+	"manu-node-cli/internal/node"
+)
+
+func testNode(id, title string) *node.Node {
+	n := node.NewNode(title, "", nil, "")
+	n.ID = id
+	return n
+}
+
+func titles(nodes []*node.Node) map[string]string {
+	out := map[string]string{}
+	for _, n := range nodes {
+		out[n.ID] = n.Title
+	}
+	return out
+}
+
+func TestDiff(t *testing.T) {
+	a, b, c := testNode("a", "A"), testNode("b", "B"), testNode("c", "C")
+	b2 := b.Clone()
+	b2.Description = "changed"
+
+	changes := Diff([]*node.Node{a, b}, []*node.Node{b2, c})
+	if len(changes) != 3 {
+		t.Fatalf("Expected 3 changes, got %d", len(changes))
+	}
+	if changes[0].Before != b || changes[0].After != b2 {
+		t.Errorf("Expected update of b first, got %s", changes[0].Describe())
+	}
+	if changes[1].Before != nil || changes[1].After != c {
+		t.Errorf("Expected creation of c, got %s", changes[1].Describe())
+	}
+	if changes[2].Before != a || changes[2].After != nil {
+		t.Errorf("Expected deletion of a, got %s", changes[2].Describe())
+	}
+}
+
+func TestApplyAndInverse(t *testing.T) {
+	a, b := testNode("a", "A"), testNode("b", "B")
+	a2 := a.Clone()
+	a2.Title = "A2"
+	before := []*node.Node{a, b}
+	after := []*node.Node{a2}
+
+	step := Step{Command: "test", Changes: Diff(before, after)}
+	if conflicts := Conflicts(before, step); len(conflicts) != 0 {
+		t.Errorf("Expected no conflicts, got %d", len(conflicts))
+	}
+
+	redone, err := Apply(before, step)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if got := titles(redone); len(got) != 1 || got["a"] != "A2" {
+		t.Errorf("Expected only A2, got %v", got)
+	}
+
+	reverted, err := Apply(after, step.Inverse())
+	if err != nil {
+		t.Fatalf("Apply of inverse failed: %v", err)
+	}
+	if got := titles(reverted); len(got) != 2 || got["a"] != "A" || got["b"] != "B" {
+		t.Errorf("Expected A and B restored, got %v", got)
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	a := testNode("a", "A")
+	a2 := a.Clone()
+	a2.Description = "first edit"
+	step := Step{Changes: []Change{{Before: a, After: a2}}}
+
+	a3 := a2.Clone()
+	a3.Description = "edited again"
+	conflicts := Conflicts([]*node.Node{a3}, step.Inverse())
+	if len(conflicts) != 1 || conflicts[0].ID() != "a" {
+		t.Errorf("Expected a conflict on a, got %v", conflicts)
+	}
+}
+
+func TestApplyRejectsTakenTitle(t *testing.T) {
+	x := testNode("x", "x")
+	step := Step{Changes: []Change{{Before: x}}} // x was deleted
+
+	// Another node took the title since
+	other := testNode("y", "X")
+	if _, err := Apply([]*node.Node{other}, step.Inverse()); err == nil {
+		t.Error("Expected an error when the restored title is taken")
+	}
+}
+
+func TestHistory(t *testing.T) {
+	h := NewHistory(2)
+	if _, ok := h.NextUndo(); ok {
+		t.Error("Expected nothing to undo")
+	}
+
+	change := []Change{{After: testNode("a", "A")}}
+	h.Record(Step{Command: "one", Changes: change})
+	h.Record(Step{Command: "empty"})
+	h.Record(Step{Command: "two", Changes: change})
+	h.Record(Step{Command: "three", Changes: change})
+
+	s, _ := h.NextUndo()
+	if s.Command != "three" {
+		t.Errorf("Expected 'three', got '%s'", s.Command)
+	}
+	h.Undone()
+	h.Undone()
+	h.Undone() // only two steps are kept
+	if _, ok := h.NextUndo(); ok {
+		t.Error("Expected the oldest step to be dropped")
+	}
+
+	s, _ = h.NextRedo()
+	if s.Command != "two" {
+		t.Errorf("Expected to redo 'two', got '%s'", s.Command)
+	}
+	h.Redone()
+	h.Record(Step{Command: "four", Changes: change})
+	if _, ok := h.NextRedo(); ok {
+		t.Error("Expected a new step to clear the redo stack")
+	}
+}